@@ -0,0 +1,19 @@
+-- Migration: Add Stage-Level Progress to Processing Jobs
+-- Version: 004
+-- Description: Latest pipeline progress reported by the worker (stage, percent, partial stats)
+
+ALTER TABLE fileprocess.processing_jobs
+  ADD COLUMN IF NOT EXISTS progress INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS progress_stage VARCHAR(50),
+  ADD COLUMN IF NOT EXISTS progress_details JSONB,
+  ADD COLUMN IF NOT EXISTS progress_updated_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE fileprocess.processing_jobs
+  DROP CONSTRAINT IF EXISTS valid_progress;
+
+ALTER TABLE fileprocess.processing_jobs
+  ADD CONSTRAINT valid_progress CHECK (progress >= 0 AND progress <= 100);
+
+COMMENT ON COLUMN fileprocess.processing_jobs.progress IS 'Overall pipeline progress 0-100';
+COMMENT ON COLUMN fileprocess.processing_jobs.progress_stage IS 'Current stage: download, analysis, ocr, layout, embedding, storage, artifact, graphrag';
+COMMENT ON COLUMN fileprocess.processing_jobs.progress_details IS 'Latest progress update including partial stats';
@@ -0,0 +1,24 @@
+-- Migration: Add Content-Hash Deduplication to Document DNA
+-- Version: 005
+-- Description: SHA-256 of the original file plus the pipeline fingerprint that produced the DNA,
+-- so re-uploads of the same file can be linked to an existing Document DNA instead of reprocessed
+
+ALTER TABLE fileprocess.document_dna
+  ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64),
+  ADD COLUMN IF NOT EXISTS pipeline_version VARCHAR(100);
+
+ALTER TABLE fileprocess.processing_jobs
+  ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64);
+
+-- Dedup lookup: newest DNA for a content hash produced by the current pipeline
+CREATE INDEX IF NOT EXISTS idx_dna_content_hash
+    ON fileprocess.document_dna(content_hash, pipeline_version, created_at DESC)
+    WHERE content_hash IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_content_hash
+    ON fileprocess.processing_jobs(content_hash)
+    WHERE content_hash IS NOT NULL;
+
+COMMENT ON COLUMN fileprocess.document_dna.content_hash IS 'SHA-256 (hex) of the original file bytes';
+COMMENT ON COLUMN fileprocess.document_dna.pipeline_version IS 'Pipeline fingerprint (revision/embedding model/dimensions) that produced this DNA';
+COMMENT ON COLUMN fileprocess.processing_jobs.content_hash IS 'SHA-256 (hex) of the processed file; jobs linked to an existing DNA share its hash';
@@ -0,0 +1,83 @@
+-- Migration: Create Completion Webhook Tables
+-- Version: 006
+-- Description: Per-user webhook registry, durable delivery queue with retry state,
+-- and an audit log of every delivery attempt
+
+-- Webhook Registry
+-- Endpoints notified when a user's jobs complete or fail
+CREATE TABLE IF NOT EXISTS fileprocess.webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,                       -- HMAC-SHA256 signing secret
+    events TEXT[] NOT NULL DEFAULT ARRAY['job.completed', 'job.failed'],
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT valid_webhook_url CHECK (url ~* '^https?://')
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_active
+    ON fileprocess.webhooks(user_id)
+    WHERE active;
+
+CREATE TRIGGER update_webhooks_updated_at
+    BEFORE UPDATE ON fileprocess.webhooks
+    FOR EACH ROW
+    EXECUTE FUNCTION fileprocess.update_updated_at_column();
+
+-- Webhook Deliveries
+-- One row per (event, endpoint); doubles as the durable retry queue
+CREATE TABLE IF NOT EXISTS fileprocess.webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id UUID REFERENCES fileprocess.webhooks(id) ON DELETE SET NULL, -- NULL for metadata.callbackUrl
+    job_id UUID NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    url TEXT NOT NULL,
+    payload JSONB NOT NULL,                     -- Exact body sent on every attempt
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP WITH TIME ZONE,      -- Claimed by a worker until this time
+    last_error TEXT,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT valid_delivery_status CHECK (status IN ('pending', 'delivered', 'failed'))
+);
+
+-- Dispatcher polling: due pending deliveries
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+    ON fileprocess.webhook_deliveries(next_attempt_at)
+    WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_job
+    ON fileprocess.webhook_deliveries(job_id);
+
+CREATE TRIGGER update_webhook_deliveries_updated_at
+    BEFORE UPDATE ON fileprocess.webhook_deliveries
+    FOR EACH ROW
+    EXECUTE FUNCTION fileprocess.update_updated_at_column();
+
+-- Webhook Delivery Attempts
+-- Audit log of every HTTP attempt
+CREATE TABLE IF NOT EXISTS fileprocess.webhook_delivery_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id UUID NOT NULL REFERENCES fileprocess.webhook_deliveries(id) ON DELETE CASCADE,
+    attempt INTEGER NOT NULL,
+    status_code INTEGER,                        -- NULL when no response was received
+    error TEXT,
+    response_body TEXT,                         -- Truncated
+    duration_ms INTEGER NOT NULL,
+    attempted_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_delivery
+    ON fileprocess.webhook_delivery_attempts(delivery_id, attempt);
+
+COMMENT ON TABLE fileprocess.webhooks IS 'Per-user completion webhook endpoints';
+COMMENT ON TABLE fileprocess.webhook_deliveries IS 'Webhook deliveries and their retry state';
+COMMENT ON TABLE fileprocess.webhook_delivery_attempts IS 'Audit log of webhook delivery attempts';
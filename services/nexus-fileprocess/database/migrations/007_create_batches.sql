@@ -0,0 +1,66 @@
+-- Migration: Create Batch Tables
+-- Version: 007
+-- Description: Groups related jobs (e.g. a Drive folder import) under one batch with
+-- aggregate counters maintained by the worker as children complete or fail
+
+-- Batches
+-- total_jobs is set by whoever creates the batch; 0 means the size is not known yet
+-- and the batch stays open
+CREATE TABLE IF NOT EXISTS fileprocess.batches (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL DEFAULT 'anonymous',
+    name TEXT,
+    status VARCHAR(30) NOT NULL DEFAULT 'processing',
+    total_jobs INTEGER NOT NULL DEFAULT 0,
+    completed_jobs INTEGER NOT NULL DEFAULT 0,
+    failed_jobs INTEGER NOT NULL DEFAULT 0,
+    total_pages INTEGER NOT NULL DEFAULT 0,
+    tier_usage JSONB NOT NULL DEFAULT '{}'::jsonb,  -- OCR tier -> completed children
+    metadata JSONB DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT valid_batch_status CHECK (status IN ('processing', 'completed', 'completed_with_errors', 'failed')),
+    CONSTRAINT valid_batch_counts CHECK (completed_jobs >= 0 AND failed_jobs >= 0 AND total_jobs >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_batches_user_created
+    ON fileprocess.batches(user_id, created_at DESC);
+
+CREATE TRIGGER update_batches_updated_at
+    BEFORE UPDATE ON fileprocess.batches
+    FOR EACH ROW
+    EXECUTE FUNCTION fileprocess.update_updated_at_column();
+
+-- Batch Jobs
+-- Final outcome of each child; keeps the counters exact when a child is redelivered
+-- or replayed from the dead-letter queue
+CREATE TABLE IF NOT EXISTS fileprocess.batch_jobs (
+    batch_id VARCHAR(255) NOT NULL REFERENCES fileprocess.batches(id) ON DELETE CASCADE,
+    job_id VARCHAR(255) NOT NULL,
+    filename TEXT,
+    status VARCHAR(20) NOT NULL,
+    ocr_tier_used VARCHAR(50),
+    page_count INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    finished_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (batch_id, job_id),
+    CONSTRAINT valid_batch_job_status CHECK (status IN ('completed', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_batch_jobs_failed
+    ON fileprocess.batch_jobs(batch_id)
+    WHERE status = 'failed';
+
+ALTER TABLE fileprocess.processing_jobs
+  ADD COLUMN IF NOT EXISTS batch_id VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_batch_id
+    ON fileprocess.processing_jobs(batch_id)
+    WHERE batch_id IS NOT NULL;
+
+COMMENT ON TABLE fileprocess.batches IS 'Groups of jobs tracked together, with aggregate status';
+COMMENT ON TABLE fileprocess.batch_jobs IS 'Final outcome of each job in a batch';
+COMMENT ON COLUMN fileprocess.processing_jobs.batch_id IS 'Batch this job belongs to, if any';
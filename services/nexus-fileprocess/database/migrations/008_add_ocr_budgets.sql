@@ -0,0 +1,31 @@
+-- Migration: Add OCR Cost Tracking and Budgets
+-- Version: 008
+-- Description: Per-job OCR spend for chargeback, and per-user daily/monthly OCR budgets
+-- enforced by the worker's OCR cascade
+
+-- OCR spend of each job, incremented by the worker as paid OCR tiers run
+-- (includes tiers whose result was escalated and attempts that were retried)
+ALTER TABLE fileprocess.processing_jobs
+  ADD COLUMN IF NOT EXISTS ocr_cost_usd NUMERIC(12,6) NOT NULL DEFAULT 0;
+
+-- OCR Budgets
+-- Spend in a period is the ocr_cost_usd of the user's jobs created in that period (UTC)
+CREATE TABLE IF NOT EXISTS fileprocess.ocr_budgets (
+    user_id VARCHAR(255) NOT NULL,
+    period VARCHAR(10) NOT NULL,
+    limit_usd NUMERIC(12,4) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, period),
+    CONSTRAINT valid_budget_period CHECK (period IN ('daily', 'monthly')),
+    CONSTRAINT valid_budget_limit CHECK (limit_usd >= 0)
+);
+
+CREATE TRIGGER update_ocr_budgets_updated_at
+    BEFORE UPDATE ON fileprocess.ocr_budgets
+    FOR EACH ROW
+    EXECUTE FUNCTION fileprocess.update_updated_at_column();
+
+COMMENT ON TABLE fileprocess.ocr_budgets IS 'Daily and monthly OCR spend limits per user';
+COMMENT ON COLUMN fileprocess.processing_jobs.ocr_cost_usd IS 'OCR spend of the job in USD, as reported by MageAgent or priced per page';
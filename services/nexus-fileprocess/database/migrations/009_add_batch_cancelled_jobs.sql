@@ -0,0 +1,21 @@
+-- Migration: Count Cancelled Batch Children
+-- Version: 009
+-- Description: Cancelled children are a final outcome of a batch, so a batch with
+-- a cancelled child still finishes and emits batch:completed
+
+ALTER TABLE fileprocess.batches
+  ADD COLUMN IF NOT EXISTS cancelled_jobs INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE fileprocess.batches
+  DROP CONSTRAINT IF EXISTS valid_batch_status,
+  ADD CONSTRAINT valid_batch_status CHECK (status IN ('processing', 'completed', 'completed_with_errors', 'failed', 'cancelled'));
+
+ALTER TABLE fileprocess.batches
+  DROP CONSTRAINT IF EXISTS valid_batch_counts,
+  ADD CONSTRAINT valid_batch_counts CHECK (completed_jobs >= 0 AND failed_jobs >= 0 AND cancelled_jobs >= 0 AND total_jobs >= 0);
+
+ALTER TABLE fileprocess.batch_jobs
+  DROP CONSTRAINT IF EXISTS valid_batch_job_status,
+  ADD CONSTRAINT valid_batch_job_status CHECK (status IN ('completed', 'failed', 'cancelled'));
+
+COMMENT ON COLUMN fileprocess.batches.cancelled_jobs IS 'Children cancelled before they completed';
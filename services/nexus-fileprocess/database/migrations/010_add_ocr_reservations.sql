@@ -0,0 +1,12 @@
+-- Migration: Reserve OCR Spend Before Paid Tiers Run
+-- Version: 010
+-- Description: Concurrent jobs of a user could each pass the budget check before any of
+-- them recorded its cost. The worker now reserves a paid tier's estimated cost, under a
+-- lock on the user's budget rows, and settles the reservation once the tier has run.
+
+-- Estimated cost of paid OCR calls in flight, counted against the user's budgets
+-- until the actual cost is added to ocr_cost_usd
+ALTER TABLE fileprocess.processing_jobs
+  ADD COLUMN IF NOT EXISTS ocr_reserved_usd NUMERIC(12,6) NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN fileprocess.processing_jobs.ocr_reserved_usd IS 'Estimated OCR spend of paid calls in flight, cleared when they finish or the job ends';
@@ -0,0 +1,58 @@
+/**
+ * Job Cancellation CLI
+ *
+ *   worker cancel <jobId> [<jobId>...]
+ *
+ * Requests cancellation of jobs by their PostgreSQL job ID. Waiting jobs are
+ * skipped when picked up; a worker processing the job aborts it.
+ * Only REDIS_URL is required; the rest of the worker configuration is not loaded.
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
+	"github.com/redis/go-redis/v9"
+)
+
+// runCancelCommand executes the cancel subcommand and returns the process exit code
+func runCancelCommand(args []string) int {
+	fs := flag.NewFlagSet("cancel", flag.ContinueOnError)
+	redisURL := fs.String("redis-url", getEnvOrDefault("REDIS_URL", "redis://nexus-redis:6379"), "Redis URL")
+	queueName := fs.String("queue", "fileprocess:jobs", "Queue name")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: worker cancel [--redis-url URL] [--queue NAME] <jobId> [<jobId>...]")
+		return 2
+	}
+
+	opt, err := redis.ParseURL(*redisURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse Redis URL: %v\n", err)
+		return 1
+	}
+	client := redis.NewClient(opt)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, jobID := range fs.Args() {
+		if err := queue.RequestCancel(ctx, client, *queueName, jobID); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		fmt.Printf("cancel requested: %s\n", jobID)
+	}
+
+	return 0
+}
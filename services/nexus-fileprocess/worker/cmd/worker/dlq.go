@@ -0,0 +1,195 @@
+/**
+ * Dead-Letter Queue CLI
+ *
+ * Admin subcommands for jobs that exhausted their retries:
+ *   worker dlq list    [filters] [--offset N] [--limit N]
+ *   worker dlq show    <queueJobId>
+ *   worker dlq replay  <queueJobId> | --all | [filters]
+ *   worker dlq purge   <queueJobId> | --all | [filters]
+ *
+ * Filters: --user, --mime, --error, --before, --after (RFC3339)
+ * Only REDIS_URL is required; the rest of the worker configuration is not loaded.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
+	"github.com/redis/go-redis/v9"
+)
+
+const dlqUsage = `Usage: worker dlq <command> [options]
+
+Commands:
+  list                         List dead-lettered jobs (newest first)
+  show <queueJobId>            Show a dead-lettered job with its attempt errors
+  replay <queueJobId>|--all    Re-queue jobs with their attempt counter reset
+  purge <queueJobId>|--all     Permanently delete dead-lettered jobs
+
+replay and purge accept the list filters instead of a job ID.
+`
+
+// runDLQCommand executes a dlq subcommand and returns the process exit code
+func runDLQCommand(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, dlqUsage)
+		return 2
+	}
+
+	command := args[0]
+	fs := flag.NewFlagSet("dlq "+command, flag.ContinueOnError)
+	redisURL := fs.String("redis-url", getEnvOrDefault("REDIS_URL", "redis://nexus-redis:6379"), "Redis URL")
+	queueName := fs.String("queue", "fileprocess:jobs", "Queue name")
+	userID := fs.String("user", "", "Only jobs for this user ID")
+	mimeType := fs.String("mime", "", "Only jobs with this MIME type")
+	errorContains := fs.String("error", "", "Only jobs whose last error contains this text")
+	before := fs.String("before", "", "Only jobs dead-lettered before this time (RFC3339)")
+	after := fs.String("after", "", "Only jobs dead-lettered after this time (RFC3339)")
+	all := fs.Bool("all", false, "Apply to every matching job (replay/purge)")
+	offset := fs.Int("offset", 0, "Skip this many matches (list)")
+	limit := fs.Int("limit", 50, "Maximum jobs to list, 0 for all (list)")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	filter := &queue.DeadLetterFilter{
+		UserID:        *userID,
+		MimeType:      *mimeType,
+		ErrorContains: *errorContains,
+	}
+	for _, bound := range []struct {
+		value  string
+		target *time.Time
+		name   string
+	}{
+		{*before, &filter.Before, "--before"},
+		{*after, &filter.After, "--after"},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid %s time %q: %v\n", bound.name, bound.value, err)
+			return 2
+		}
+		*bound.target = parsed
+	}
+	hasFilter := *filter != queue.DeadLetterFilter{}
+
+	opt, err := redis.ParseURL(*redisURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse Redis URL: %v\n", err)
+		return 1
+	}
+	client := redis.NewClient(opt)
+	defer client.Close()
+
+	dlq, err := queue.NewDeadLetterQueue(client, *queueName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open dead-letter queue: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	switch command {
+	case "list":
+		entries, err := dlq.List(ctx, filter, *offset, *limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list dead-lettered jobs: %v\n", err)
+			return 1
+		}
+		total, _ := dlq.Count(ctx)
+		fmt.Printf("%-36s  %-36s  %-20s  %-8s  %s\n", "QUEUE ID", "JOB ID", "DEAD-LETTERED", "ATTEMPTS", "LAST ERROR")
+		for _, entry := range entries {
+			fmt.Printf("%-36s  %-36s  %-20s  %-8d  %s\n",
+				entry.Job.ID, entry.Job.Payload.JobID,
+				entry.DeadLetteredAt.UTC().Format(time.RFC3339),
+				entry.Job.Attempts, truncate(lastAttemptError(entry), 80))
+		}
+		fmt.Printf("\n%d shown, %d dead-lettered in total\n", len(entries), total)
+
+	case "show":
+		if fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: worker dlq show <queueJobId>")
+			return 2
+		}
+		entry, err := dlq.Get(ctx, fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		// Keep the output readable: the file buffer is not useful on a terminal
+		entry.Job.Payload.FileBuffer = nil
+		out, _ := json.MarshalIndent(entry, "", "  ")
+		fmt.Println(string(out))
+
+	case "replay", "purge":
+		var count int
+		switch {
+		case fs.NArg() == 1:
+			if command == "replay" {
+				err = dlq.Replay(ctx, fs.Arg(0))
+			} else {
+				err = dlq.Purge(ctx, fs.Arg(0))
+			}
+			if err == nil {
+				count = 1
+			}
+		case *all || hasFilter:
+			if command == "replay" {
+				count, err = dlq.ReplayFiltered(ctx, filter)
+			} else {
+				count, err = dlq.PurgeFiltered(ctx, filter)
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Usage: worker dlq %s <queueJobId> | --all | [filters]\n", command)
+			return 2
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s failed after %d job(s): %v\n", command, count, err)
+			return 1
+		}
+		fmt.Printf("%s: %d job(s)\n", command, count)
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown dlq command %q\n\n%s", command, dlqUsage)
+		return 2
+	}
+
+	return 0
+}
+
+// lastAttemptError returns the most recent attempt error of a dead-lettered job
+func lastAttemptError(entry *queue.DeadLetterEntry) string {
+	if len(entry.Errors) == 0 {
+		return entry.Job.LastError
+	}
+	return entry.Errors[len(entry.Errors)-1].Error
+}
+
+// truncate shortens s to at most n characters for tabular output
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
+
+// getEnvOrDefault gets environment variable or returns default
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
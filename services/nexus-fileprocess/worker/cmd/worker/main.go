@@ -30,6 +30,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -37,6 +38,7 @@ import (
 	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
 	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
 	"github.com/adverant/nexus/fileprocess-worker/internal/storage"
+	"github.com/adverant/nexus/fileprocess-worker/internal/webhook"
 	"github.com/joho/godotenv"
 )
 
@@ -46,6 +48,16 @@ func main() {
 		log.Printf("Warning: .env.nexus not found, using system environment variables")
 	}
 
+	// Admin subcommands run against Redis only and exit without starting the worker
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "dlq":
+			os.Exit(runDLQCommand(os.Args[2:]))
+		case "cancel":
+			os.Exit(runCancelCommand(os.Args[2:]))
+		}
+	}
+
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -62,6 +74,7 @@ func main() {
 		cfg.DatabaseURL,
 		cfg.QdrantURL,
 		cfg.QdrantCollection,
+		cfg.EmbeddingDimensions, // Qdrant vector size
 	)
 	if err != nil {
 		log.Fatalf("Failed to initialize storage manager: %v", err)
@@ -69,10 +82,44 @@ func main() {
 	defer storageManager.Close()
 	log.Printf("Storage manager initialized (PostgreSQL + Qdrant)")
 
+	// Initialize blob store for claim-check payloads (optional)
+	blobStore, err := storage.NewBlobStore(&storage.BlobStoreConfig{
+		Type:              cfg.BlobStore,
+		Path:              cfg.BlobStorePath,
+		S3Endpoint:        cfg.S3Endpoint,
+		S3Region:          cfg.S3Region,
+		S3Bucket:          cfg.S3Bucket,
+		S3AccessKeyID:     cfg.S3AccessKeyID,
+		S3SecretAccessKey: cfg.S3SecretAccessKey,
+		S3ForcePathStyle:  cfg.S3ForcePathStyle,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize blob store: %v", err)
+	}
+	log.Printf("Blob store: %s (inline payload limit=%d bytes)", cfg.BlobStore, cfg.InlinePayloadMaxBytes)
+
+	// Initialize embedding provider
+	embeddingAPIKey := cfg.EmbeddingAPIKey
+	if cfg.EmbeddingProvider == processor.EmbeddingProviderVoyage {
+		embeddingAPIKey = cfg.VoyageAPIKey
+	}
+	embedder, err := processor.NewEmbedder(processor.EmbeddingConfig{
+		Provider:   cfg.EmbeddingProvider,
+		URL:        cfg.EmbeddingURL,
+		APIKey:     embeddingAPIKey,
+		Model:      cfg.EmbeddingModel,
+		Dimensions: cfg.EmbeddingDimensions,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize embedding provider: %v", err)
+	}
+	log.Printf("Embedding provider: %s (model=%s, dimensions=%d)", cfg.EmbeddingProvider, embedder.Model(), embedder.Dimensions())
+
 	// Initialize document processor
-	log.Printf("Initializing document processor with MageAgent integration...")
+	log.Printf("Initializing document processor (mode: %s)...", cfg.ProcessingMode)
 	proc, err := processor.NewDocumentProcessor(&processor.ProcessorConfig{
-		VoyageAPIKey:      cfg.VoyageAPIKey,
+		ProcessingMode:    cfg.ProcessingMode,
+		Embedder:          embedder,
 		TesseractPath:     cfg.TesseractPath,
 		TempDir:           cfg.TempDir,
 		MaxFileSize:       cfg.MaxFileSize,
@@ -80,19 +127,87 @@ func main() {
 		GraphRAGURL:       cfg.GraphRAGURL,
 		MageAgentURL:      cfg.MageAgentURL,       // Delegate OCR to MageAgent (zero hardcoded models)
 		FileProcessAPIURL: cfg.FileProcessAPIURL,  // Artifact storage for permanent file access
+		DedupEnabled:      cfg.DedupEnabled,
+		DedupScope:        cfg.DedupScope,
+		BlobStore:         blobStore,
+		ArchiveLimits: processor.ArchiveLimits{
+			MaxEntries:   cfg.ArchiveMaxEntries,
+			MaxTotalSize: cfg.ArchiveMaxTotalSize,
+			MaxDepth:     cfg.ArchiveMaxDepth,
+			MaxRatio:     cfg.ArchiveMaxRatio,
+		},
+		PDFRasterizerPath: cfg.PDFRasterizerPath,
+		OCRLanguages:      cfg.OCRLanguages,
+		OCRPolicy: processor.OCRPolicy{
+			Tiers: strings.Split(strings.ReplaceAll(cfg.OCRTiers, " ", ""), ","),
+			Thresholds: map[string]float64{
+				processor.OCRTierTesseract: cfg.OCRThresholdTesseract,
+				processor.OCRTierBalanced:  cfg.OCRThresholdTier2,
+				processor.OCRTierAccurate:  cfg.OCRThresholdTier3,
+			},
+			MaxTier: cfg.OCRMaxTier,
+		},
+		OCRPrices: cfg.OCRPrices,
+		Chunking: processor.ChunkOptions{
+			MaxChars:     cfg.EmbeddingChunkSize,
+			OverlapChars: cfg.EmbeddingChunkOverlap,
+			MaxChunks:    cfg.EmbeddingMaxChunks,
+		},
 	})
 	if err != nil {
 		log.Fatalf("Failed to initialize document processor: %v", err)
 	}
-	log.Printf("Document processor initialized (MageAgent-powered OCR)")
+	if cfg.ProcessingMode == processor.ProcessingModeLocalOnly {
+		log.Printf("Document processor initialized (local-only: Tesseract OCR, no external AI services)")
+	} else {
+		log.Printf("Document processor initialized (MageAgent-powered OCR)")
+	}
+
+	// Initialize completion webhooks (optional)
+	var notifier queue.JobNotifier
+	var webhooks *webhook.Dispatcher
+	if cfg.WebhooksEnabled {
+		webhooks, err = webhook.NewDispatcher(storageManager, &webhook.Config{
+			SigningSecret: cfg.WebhookSigningSecret,
+			MaxAttempts:   cfg.WebhookMaxAttempts,
+			RetryPolicy: &queue.RetryPolicy{
+				BaseDelay: time.Duration(cfg.WebhookRetryBaseDelay) * time.Millisecond,
+				MaxDelay:  time.Duration(cfg.WebhookRetryMaxDelay) * time.Millisecond,
+				Jitter:    0.2,
+			},
+			Timeout:         time.Duration(cfg.WebhookTimeout) * time.Millisecond,
+			AllowedNetworks: strings.Split(cfg.WebhookAllowedNetworks, ","),
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize webhook dispatcher: %v", err)
+		}
+		webhooks.Start()
+		notifier = webhooks
+		if cfg.WebhookSigningSecret == "" {
+			log.Printf("Warning: WEBHOOK_SIGNING_SECRET not set, metadata.callbackUrl will be ignored")
+		}
+	}
 
 	// Initialize queue consumer
-	log.Printf("Connecting to Redis queue...")
-	queueConsumer, err := queue.NewRedisConsumer(&queue.RedisConsumerConfig{
-		RedisURL:    cfg.RedisURL,
-		QueueName:   "fileprocess:jobs",
-		Concurrency: cfg.WorkerConcurrency,
-		Processor:   proc,
+	log.Printf("Connecting to Redis queue (backend=%s)...", cfg.QueueBackend)
+	queueConsumer, err := queue.NewBackend(cfg.QueueBackend, &queue.RedisConsumerConfig{
+		RedisURL:          cfg.RedisURL,
+		QueueName:         "fileprocess:jobs",
+		Concurrency:       cfg.WorkerConcurrency,
+		Processor:         proc,
+		ProcessingTimeout: int64(cfg.ProcessingTimeout),
+		WorkerID:          cfg.WorkerID,
+		VisibilityTimeout: cfg.QueueVisibilityTimeout,
+		RetryPolicy: &queue.RetryPolicy{
+			BaseDelay: time.Duration(cfg.QueueRetryBaseDelay) * time.Millisecond,
+			MaxDelay:  time.Duration(cfg.QueueRetryMaxDelay) * time.Millisecond,
+			Jitter:    cfg.QueueRetryJitter,
+		},
+		LaneWeights:       cfg.QueueLaneWeights,
+		BlobStore:         blobStore,
+		InlineBufferLimit: cfg.InlinePayloadMaxBytes,
+		Notifier:          notifier,
+		Batches:           storageManager,
 	})
 	if err != nil {
 		log.Fatalf("Failed to initialize queue consumer: %v", err)
@@ -136,6 +251,12 @@ func main() {
 		log.Printf("Queue consumer stopped successfully")
 	}
 
+	// Stop webhook dispatcher after the queue so final notifications are enqueued
+	if webhooks != nil {
+		webhooks.Stop()
+		log.Printf("Webhook dispatcher stopped")
+	}
+
 	// Close storage manager
 	log.Printf("Closing storage manager...")
 	if err := storageManager.Close(); err != nil {
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/adverant/nexus/fileprocess-worker/internal/logging"
@@ -43,6 +44,7 @@ type VisionOCRRequest struct {
 	Format          string                 `json:"format"`          // "base64", "url", or "buffer"
 	PreferAccuracy  bool                   `json:"preferAccuracy"`  // true = use highest accuracy models (Claude Opus)
 	Language        string                 `json:"language"`        // Optional: "en", "multi", etc.
+	Languages       []string               `json:"languages,omitempty"` // Optional: every language of a multi-language page
 	Metadata        map[string]interface{} `json:"metadata"`        // Optional metadata
 	JobID           string                 `json:"jobId,omitempty"` // Optional: FileProcess job ID for tracking
 	Async           bool                   `json:"async,omitempty"` // Optional: Use async mode for job tracking
@@ -111,6 +113,7 @@ type VisionOCRData struct {
 	Confidence     float64 `json:"confidence"`
 	ModelUsed      string  `json:"modelUsed"`
 	ProcessingTime int64   `json:"processingTime"` // milliseconds
+	Cost           float64 `json:"cost,omitempty"`   // USD, when MageAgent reports it
 	JobID          string  `json:"jobId,omitempty"`
 	Metadata       struct {
 		Language       string `json:"language"`
@@ -194,7 +197,8 @@ func (c *MageAgentClient) ExtractText(ctx context.Context, req *VisionOCRRequest
 	return &ocrResp, nil
 }
 
-// ExtractTextFromBytes is a convenience method that handles base64 encoding (SYNC mode)
+// ExtractTextFromBytes is a convenience method that handles base64 encoding (SYNC mode).
+// language is one code ("en") or a comma-separated list ("ja,en").
 func (c *MageAgentClient) ExtractTextFromBytes(ctx context.Context, imageData []byte, preferAccuracy bool, language string) (*VisionOCRResponse, error) {
 	// Encode image to base64
 	base64Image := base64.StdEncoding.EncodeToString(imageData)
@@ -203,17 +207,32 @@ func (c *MageAgentClient) ExtractTextFromBytes(ctx context.Context, imageData []
 		Image:          base64Image,
 		Format:         "base64",
 		PreferAccuracy: preferAccuracy,
-		Language:       language,
 		Async:          false, // Synchronous mode
 		Metadata: map[string]interface{}{
 			"source":    "fileprocess-worker",
 			"timestamp": time.Now().Unix(),
 		},
 	}
+	req.Language, req.Languages = languageFields(language)
 
 	return c.ExtractText(ctx, req)
 }
 
+// languageFields splits a comma-separated language list into the request's
+// language ("multi" for several or none) and languages
+func languageFields(language string) (string, []string) {
+	var languages []string
+	for _, code := range strings.Split(language, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			languages = append(languages, code)
+		}
+	}
+	if len(languages) == 1 {
+		return languages[0], languages
+	}
+	return "multi", languages
+}
+
 // ExtractTextAsync starts an async OCR task and returns the taskId for polling
 func (c *MageAgentClient) ExtractTextAsync(ctx context.Context, req *VisionOCRRequest) (*VisionOCRAsyncResponse, error) {
 	c.logger.Info("Starting async text extraction from MageAgent",
@@ -389,10 +408,11 @@ func getFloatFromMap(m map[string]interface{}, key string) float64 {
 
 // LayoutAnalysisRequest represents a request to analyze document layout
 type LayoutAnalysisRequest struct {
-	Image    string `json:"image"`    // Base64 encoded image
-	Format   string `json:"format"`   // "base64", "url", or "buffer"
-	Language string `json:"language"` // Optional: "en", "multi", etc.
-	JobID    string `json:"jobId,omitempty"`
+	Image     string   `json:"image"`               // Base64 encoded image
+	Format    string   `json:"format"`              // "base64", "url", or "buffer"
+	Language  string   `json:"language"`            // Optional: "en", "multi", etc.
+	Languages []string `json:"languages,omitempty"` // Optional: every language of a multi-language page
+	JobID     string   `json:"jobId,omitempty"`
 }
 
 // LayoutAnalysisResponse represents the response from layout analysis
@@ -499,8 +519,8 @@ func (c *MageAgentClient) AnalyzeLayoutFromBytes(ctx context.Context, imageData
 	req := &LayoutAnalysisRequest{
 		Image:    base64Image,
 		Format:   "base64",
-		Language: language,
 	}
+	req.Language, req.Languages = languageFields(language)
 
 	return c.AnalyzeLayout(ctx, req)
 }
@@ -509,11 +529,12 @@ func (c *MageAgentClient) AnalyzeLayoutFromBytes(ctx context.Context, imageData
 
 // TableExtractionRequest represents a request to extract table structure
 type TableExtractionRequest struct {
-	Image    string `json:"image"`    // Base64 encoded image
-	Format   string `json:"format"`   // "base64", "url", or "buffer"
-	Language string `json:"language"` // Optional: "en", "multi", etc.
-	JobID    string `json:"jobId,omitempty"`
-	Async    bool   `json:"async,omitempty"`
+	Image     string   `json:"image"`               // Base64 encoded image
+	Format    string   `json:"format"`              // "base64", "url", or "buffer"
+	Language  string   `json:"language"`            // Optional: "en", "multi", etc.
+	Languages []string `json:"languages,omitempty"` // Optional: every language of a multi-language page
+	JobID     string   `json:"jobId,omitempty"`
+	Async     bool     `json:"async,omitempty"`
 }
 
 // TableExtractionResponse represents response from table extraction endpoint
@@ -622,8 +643,8 @@ func (c *MageAgentClient) ExtractTableFromBytes(ctx context.Context, imageData [
 	req := &TableExtractionRequest{
 		Image:    base64Image,
 		Format:   "base64",
-		Language: language,
 	}
+	req.Language, req.Languages = languageFields(language)
 
 	return c.ExtractTable(ctx, req)
 }
@@ -666,8 +687,9 @@ type FileProcessRequest struct {
 
 // FileProcessOptions contains options for file processing
 type FileProcessOptions struct {
-	EnableOCR     bool `json:"enableOcr"`
-	ExtractTables bool `json:"extractTables"`
+	EnableOCR     bool     `json:"enableOcr"`
+	ExtractTables bool     `json:"extractTables"`
+	Languages     []string `json:"languages,omitempty"` // OCR languages (ISO 639-1); detected by MageAgent when empty
 }
 
 // FileProcessResponse represents the response from /file-process endpoint
@@ -687,6 +709,7 @@ type FileProcessData struct {
 	Confidence     float64                `json:"confidence"`     // Overall confidence
 	ModelUsed      string                 `json:"modelUsed"`      // Model used for processing
 	ProcessingTime int64                  `json:"processingTime"` // Processing time in ms
+	Cost           float64                `json:"cost,omitempty"` // USD, when MageAgent reports it
 }
 
 // FileProcessPage represents a single page's content
@@ -10,10 +10,14 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config holds worker configuration
 type Config struct {
+	// Processing mode: cloud (MageAgent, GraphRAG, VoyageAI) or local_only (no external AI services)
+	ProcessingMode string
+
 	// Redis configuration
 	RedisURL string
 
@@ -30,6 +34,20 @@ type Config struct {
 	GoogleClientID   string
 	GoogleClientSecret string
 
+	// Embeddings: voyage (VoyageAI API) or openai (OpenAI-compatible /v1/embeddings
+	// endpoint such as OpenAI, Ollama or TEI). Dimensions also size the Qdrant collection.
+	EmbeddingProvider   string // Default: voyage in cloud mode, openai in local_only mode
+	EmbeddingURL        string // Endpoint (required for openai; voyage uses the VoyageAI API)
+	EmbeddingAPIKey     string // Bearer token for openai (voyage uses VOYAGE_API_KEY)
+	EmbeddingModel      string // Default voyage-3 for voyage; required for openai
+	EmbeddingDimensions int
+
+	// Chunk embeddings: the text is also embedded in chunks of up to
+	// EmbeddingChunkSize bytes, overlapping by EmbeddingChunkOverlap
+	EmbeddingChunkSize    int
+	EmbeddingChunkOverlap int
+	EmbeddingMaxChunks    int // Per document
+
 	// Service URLs
 	GraphRAGURL       string
 	MageAgentURL      string
@@ -43,8 +61,62 @@ type Config struct {
 	ChunkSize         int64
 	ProcessingTimeout int
 
+	// Queue delivery configuration
+	QueueBackend           string // Queue transport: list, streams or asynq (default: list)
+	WorkerID               string // Identity for this worker's in-flight list (default: hostname-pid)
+	QueueVisibilityTimeout int64  // Milliseconds without a heartbeat before a worker's jobs are reclaimed
+	QueueRetryBaseDelay    int64   // Milliseconds before the first retry of a failed job
+	QueueRetryMaxDelay     int64   // Upper bound on the retry backoff in milliseconds
+	QueueRetryJitter       float64 // Fraction of each retry delay to randomize (0.0-1.0)
+	QueueLaneWeights       map[string]int // Relative share of pulls per priority lane (lane=weight,...)
+
+	// Claim-check payloads (file contents referenced by blobRef instead of inlined in Redis)
+	BlobStore             string // Blob store backend: none, local or s3 (default: none)
+	BlobStorePath         string // Root directory of the local blob store
+	S3Endpoint            string
+	S3Region              string
+	S3Bucket              string
+	S3AccessKeyID         string
+	S3SecretAccessKey     string
+	S3ForcePathStyle      bool
+	InlinePayloadMaxBytes int64 // Largest file buffer kept inline in the Redis job hash
+
+	// Deduplication of re-uploaded content
+	DedupEnabled bool   // Link files already processed by this pipeline to their existing Document DNA
+	DedupScope   string // Which jobs may share a Document DNA: user (same user only) or global
+
+	// Archive expansion (zip bomb limits)
+	ArchiveMaxEntries   int     // Files extracted per archive
+	ArchiveMaxTotalSize int64   // Uncompressed bytes per archive
+	ArchiveMaxDepth     int     // Nesting depth of archives within archives
+	ArchiveMaxRatio     float64 // Uncompressed/compressed size ratio
+
+	// Completion webhooks
+	WebhooksEnabled        bool   // Notify metadata.callbackUrl and registered webhooks when jobs finish
+	WebhookSigningSecret   string // HMAC secret for metadata.callbackUrl deliveries (callbacks are skipped without it)
+	WebhookMaxAttempts     int    // Delivery attempts before a webhook is marked failed
+	WebhookRetryBaseDelay  int64  // Milliseconds before the first redelivery
+	WebhookRetryMaxDelay   int64  // Upper bound on the redelivery backoff in milliseconds
+	WebhookTimeout         int64  // Per-request timeout in milliseconds
+	WebhookAllowedNetworks string // Internal CIDRs webhooks may reach, comma-separated (default: public addresses only)
+
 	// Tesseract configuration
 	TesseractPath string
+	OCRLanguages  string // Languages tried when detection is inconclusive (ISO 639-1, comma-separated)
+
+	// OCR cascade policy (jobs may override it with metadata.ocrPolicy)
+	OCRTiers              string  // Tiers in order: tesseract, tier2 (balanced vision), tier3 (accurate vision)
+	OCRThresholdTesseract float64 // Confidence at which each tier's result is accepted
+	OCRThresholdTier2     float64
+	OCRThresholdTier3     float64
+	OCRMaxTier            string // Most expensive tier that may run
+
+	// Per-page USD prices of paid OCR by model or tier ("tier2=0.02,gpt-4o=0.015"),
+	// used when MageAgent does not report a cost
+	OCRPrices map[string]float64
+
+	// pdftoppm (poppler-utils) renders scanned PDF pages for the per-page OCR cascade
+	PDFRasterizerPath string
 
 	// Temporary directory for file processing
 	TempDir string
@@ -56,14 +128,23 @@ type Config struct {
 // LoadConfig loads configuration from environment variables
 func LoadConfig() (*Config, error) {
 	cfg := &Config{
+		ProcessingMode:     getEnvOrDefault("PROCESSING_MODE", "cloud"),
 		RedisURL:           getEnvOrDefault("REDIS_URL", "redis://nexus-redis:6379"),
 		DatabaseURL:        getEnvOrThrow("DATABASE_URL"),
 		QdrantURL:          getEnvOrDefault("QDRANT_URL", "nexus-qdrant:6334"),
 		QdrantCollection:   getEnvOrDefault("QDRANT_COLLECTION", "fileprocess_documents"),
-		VoyageAPIKey:       getEnvOrThrow("VOYAGE_API_KEY"),
-		OpenRouterAPIKey:   getEnvOrThrow("OPENROUTER_API_KEY"),
+		VoyageAPIKey:       getEnvOrDefault("VOYAGE_API_KEY", ""),     // Required in cloud mode
+		OpenRouterAPIKey:   getEnvOrDefault("OPENROUTER_API_KEY", ""), // Required in cloud mode
 		GoogleClientID:     getEnvOrDefault("GOOGLE_CLIENT_ID", ""),
 		GoogleClientSecret: getEnvOrDefault("GOOGLE_CLIENT_SECRET", ""),
+		EmbeddingProvider:   getEnvOrDefault("EMBEDDING_PROVIDER", ""),
+		EmbeddingURL:        getEnvOrDefault("EMBEDDING_URL", ""),
+		EmbeddingAPIKey:     getEnvOrDefault("EMBEDDING_API_KEY", ""),
+		EmbeddingModel:      getEnvOrDefault("EMBEDDING_MODEL", ""),
+		EmbeddingDimensions: getEnvAsIntOrDefault("EMBEDDING_DIMENSIONS", 1024),
+		EmbeddingChunkSize:    getEnvAsIntOrDefault("EMBEDDING_CHUNK_SIZE", 2000),   // ~500 tokens
+		EmbeddingChunkOverlap: getEnvAsIntOrDefault("EMBEDDING_CHUNK_OVERLAP", 200),
+		EmbeddingMaxChunks:    getEnvAsIntOrDefault("EMBEDDING_MAX_CHUNKS", 2000),
 		GraphRAGURL:        getEnvOrDefault("GRAPHRAG_URL", "http://nexus-graphrag:8090"),
 		MageAgentURL:       getEnvOrDefault("MAGEAGENT_URL", "http://nexus-mageagent:8080/api/internal/orchestrate"),
 		LearningAgentURL:   getEnvOrDefault("LEARNINGAGENT_URL", "http://nexus-learningagent:8091"),
@@ -73,11 +154,59 @@ func LoadConfig() (*Config, error) {
 		MaxFileSize:        getEnvAsInt64OrDefault("MAX_FILE_SIZE", 5368709120),  // 5GB
 		ChunkSize:          getEnvAsInt64OrDefault("CHUNK_SIZE", 65536),          // 64KB
 		ProcessingTimeout:  getEnvAsIntOrDefault("PROCESSING_TIMEOUT", 300000),    // 5 minutes
+		QueueBackend:       getEnvOrDefault("QUEUE_BACKEND", "list"),
+		WorkerID:           getEnvOrDefault("WORKER_ID", ""),
+		QueueVisibilityTimeout: getEnvAsInt64OrDefault("QUEUE_VISIBILITY_TIMEOUT", 60000), // 1 minute
+		QueueRetryBaseDelay: getEnvAsInt64OrDefault("QUEUE_RETRY_BASE_DELAY", 5000),   // 5 seconds
+		QueueRetryMaxDelay:  getEnvAsInt64OrDefault("QUEUE_RETRY_MAX_DELAY", 60000),   // 1 minute
+		QueueRetryJitter:    getEnvAsFloat64OrDefault("QUEUE_RETRY_JITTER", 0.2),
+		QueueLaneWeights:    getEnvAsWeightsOrDefault("QUEUE_LANE_WEIGHTS", "interactive=6,bulk=3,reprocess=1"),
+		BlobStore:           getEnvOrDefault("BLOB_STORE", "none"),
+		BlobStorePath:       getEnvOrDefault("BLOB_STORE_PATH", "/var/lib/fileprocess/blobs"),
+		S3Endpoint:          getEnvOrDefault("S3_ENDPOINT", ""),
+		S3Region:            getEnvOrDefault("S3_REGION", "us-east-1"),
+		S3Bucket:            getEnvOrDefault("S3_BUCKET", ""),
+		S3AccessKeyID:       getEnvOrDefault("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:   getEnvOrDefault("S3_SECRET_ACCESS_KEY", ""),
+		S3ForcePathStyle:    getEnvAsBoolOrDefault("S3_FORCE_PATH_STYLE", true),
+		InlinePayloadMaxBytes: getEnvAsInt64OrDefault("INLINE_PAYLOAD_MAX_BYTES", 10485760), // 10MB
+		DedupEnabled:        getEnvAsBoolOrDefault("DEDUP_ENABLED", true),
+		DedupScope:          getEnvOrDefault("DEDUP_SCOPE", "user"),
+		ArchiveMaxEntries:   getEnvAsIntOrDefault("ARCHIVE_MAX_ENTRIES", 1000),
+		ArchiveMaxTotalSize: getEnvAsInt64OrDefault("ARCHIVE_MAX_TOTAL_SIZE", 1073741824), // 1GB
+		ArchiveMaxDepth:     getEnvAsIntOrDefault("ARCHIVE_MAX_DEPTH", 3),
+		ArchiveMaxRatio:     getEnvAsFloat64OrDefault("ARCHIVE_MAX_RATIO", 100),
+		WebhooksEnabled:        getEnvAsBoolOrDefault("WEBHOOKS_ENABLED", true),
+		WebhookSigningSecret:   getEnvOrDefault("WEBHOOK_SIGNING_SECRET", ""),
+		WebhookMaxAttempts:     getEnvAsIntOrDefault("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookRetryBaseDelay:  getEnvAsInt64OrDefault("WEBHOOK_RETRY_BASE_DELAY", 30000),   // 30 seconds
+		WebhookRetryMaxDelay:   getEnvAsInt64OrDefault("WEBHOOK_RETRY_MAX_DELAY", 3600000),  // 1 hour
+		WebhookTimeout:         getEnvAsInt64OrDefault("WEBHOOK_TIMEOUT", 10000),            // 10 seconds
+		WebhookAllowedNetworks: getEnvOrDefault("WEBHOOK_ALLOWED_NETWORKS", ""),
 		TesseractPath:      getEnvOrDefault("TESSERACT_PATH", "/usr/bin/tesseract"),
+		OCRLanguages:       getEnvOrDefault("OCR_LANGUAGES", "en"),
+		OCRTiers:              getEnvOrDefault("OCR_TIERS", "tesseract,tier2,tier3"),
+		OCRThresholdTesseract: getEnvAsFloat64OrDefault("OCR_THRESHOLD_TESSERACT", 0.85),
+		OCRThresholdTier2:     getEnvAsFloat64OrDefault("OCR_THRESHOLD_TIER2", 0.90),
+		OCRThresholdTier3:     getEnvAsFloat64OrDefault("OCR_THRESHOLD_TIER3", 0),
+		OCRMaxTier:            getEnvOrDefault("OCR_MAX_TIER", "tier3"),
+		OCRPrices:             getEnvAsPricesOrDefault("OCR_PRICES", "tier2=0.02,tier3=0.08,file_process=0.02"),
+		PDFRasterizerPath:  getEnvOrDefault("PDFTOPPM_PATH", "/usr/bin/pdftoppm"),
 		TempDir:            getEnvOrDefault("TEMP_DIR", "/tmp/fileprocess"),
 		NodeEnv:            getEnvOrDefault("NODE_ENV", "development"),
 	}
 
+	// Local-only workers cannot reach VoyageAI
+	if cfg.EmbeddingProvider == "" {
+		cfg.EmbeddingProvider = "voyage"
+		if cfg.ProcessingMode == "local_only" {
+			cfg.EmbeddingProvider = "openai"
+		}
+	}
+	if cfg.EmbeddingModel == "" && cfg.EmbeddingProvider == "voyage" {
+		cfg.EmbeddingModel = "voyage-3"
+	}
+
 	// Validate required fields
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("configuration validation failed: %w", err)
@@ -96,14 +225,50 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("DATABASE_URL is required")
 	}
 
-	if c.VoyageAPIKey == "" {
-		return fmt.Errorf("VOYAGE_API_KEY is required")
+	switch c.ProcessingMode {
+	case "cloud", "local_only":
+	default:
+		return fmt.Errorf("PROCESSING_MODE must be cloud or local_only, got %q", c.ProcessingMode)
+	}
+
+	switch c.EmbeddingProvider {
+	case "voyage":
+		if c.VoyageAPIKey == "" {
+			return fmt.Errorf("VOYAGE_API_KEY is required")
+		}
+	case "openai":
+		if c.EmbeddingURL == "" || c.EmbeddingModel == "" {
+			return fmt.Errorf("EMBEDDING_URL and EMBEDDING_MODEL are required when EMBEDDING_PROVIDER=openai")
+		}
+	default:
+		return fmt.Errorf("EMBEDDING_PROVIDER must be voyage or openai, got %q", c.EmbeddingProvider)
+	}
+
+	// No external AI services in local_only mode: embeddings come from a local server
+	if c.ProcessingMode == "local_only" && c.EmbeddingProvider != "openai" {
+		return fmt.Errorf("EMBEDDING_PROVIDER must be openai (a local server) when PROCESSING_MODE=local_only, got %q", c.EmbeddingProvider)
 	}
 
-	if c.OpenRouterAPIKey == "" {
+	if c.ProcessingMode == "cloud" && c.OpenRouterAPIKey == "" {
 		return fmt.Errorf("OPENROUTER_API_KEY is required")
 	}
 
+	if c.EmbeddingDimensions < 1 || c.EmbeddingDimensions > 65536 { // Qdrant's vector size limit
+		return fmt.Errorf("EMBEDDING_DIMENSIONS must be between 1 and 65536, got %d", c.EmbeddingDimensions)
+	}
+
+	if c.EmbeddingChunkSize < 200 {
+		return fmt.Errorf("EMBEDDING_CHUNK_SIZE must be at least 200, got %d", c.EmbeddingChunkSize)
+	}
+
+	if c.EmbeddingChunkOverlap < 0 || c.EmbeddingChunkOverlap >= c.EmbeddingChunkSize {
+		return fmt.Errorf("EMBEDDING_CHUNK_OVERLAP must be between 0 and EMBEDDING_CHUNK_SIZE, got %d", c.EmbeddingChunkOverlap)
+	}
+
+	if c.EmbeddingMaxChunks < 1 {
+		return fmt.Errorf("EMBEDDING_MAX_CHUNKS must be at least 1, got %d", c.EmbeddingMaxChunks)
+	}
+
 	if c.WorkerConcurrency < 1 || c.WorkerConcurrency > 100 {
 		return fmt.Errorf("WORKER_CONCURRENCY must be between 1 and 100, got %d", c.WorkerConcurrency)
 	}
@@ -116,6 +281,74 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("CHUNK_SIZE must be between 1KB and 1MB, got %d", c.ChunkSize)
 	}
 
+	switch c.QueueBackend {
+	case "list", "streams", "asynq":
+	default:
+		return fmt.Errorf("QUEUE_BACKEND must be one of list, streams or asynq, got %q", c.QueueBackend)
+	}
+
+	if c.QueueVisibilityTimeout < 5000 { // Heartbeats need room to land before jobs are reclaimed
+		return fmt.Errorf("QUEUE_VISIBILITY_TIMEOUT must be at least 5000ms, got %d", c.QueueVisibilityTimeout)
+	}
+
+	if c.QueueRetryBaseDelay < 0 || c.QueueRetryMaxDelay < c.QueueRetryBaseDelay {
+		return fmt.Errorf("QUEUE_RETRY_MAX_DELAY (%d) must be >= QUEUE_RETRY_BASE_DELAY (%d) >= 0",
+			c.QueueRetryMaxDelay, c.QueueRetryBaseDelay)
+	}
+
+	if c.QueueRetryJitter < 0 || c.QueueRetryJitter > 1 {
+		return fmt.Errorf("QUEUE_RETRY_JITTER must be between 0 and 1, got %f", c.QueueRetryJitter)
+	}
+
+	if len(c.QueueLaneWeights) == 0 {
+		return fmt.Errorf("QUEUE_LANE_WEIGHTS must define at least one lane as lane=weight")
+	}
+
+	if _, ok := c.QueueLaneWeights["interactive"]; !ok {
+		return fmt.Errorf("QUEUE_LANE_WEIGHTS must include the default interactive lane")
+	}
+
+	switch c.BlobStore {
+	case "none":
+	case "local":
+		if c.BlobStorePath == "" {
+			return fmt.Errorf("BLOB_STORE_PATH is required when BLOB_STORE=local")
+		}
+	case "s3":
+		if c.S3Endpoint == "" || c.S3Bucket == "" || c.S3AccessKeyID == "" || c.S3SecretAccessKey == "" {
+			return fmt.Errorf("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required when BLOB_STORE=s3")
+		}
+	default:
+		return fmt.Errorf("BLOB_STORE must be one of none, local or s3, got %q", c.BlobStore)
+	}
+
+	if c.InlinePayloadMaxBytes < 0 {
+		return fmt.Errorf("INLINE_PAYLOAD_MAX_BYTES must be >= 0, got %d", c.InlinePayloadMaxBytes)
+	}
+
+	switch c.DedupScope {
+	case "user", "global":
+	default:
+		return fmt.Errorf("DEDUP_SCOPE must be user or global, got %q", c.DedupScope)
+	}
+
+	if c.ArchiveMaxEntries < 1 || c.ArchiveMaxTotalSize < 1 || c.ArchiveMaxDepth < 1 || c.ArchiveMaxRatio < 1 {
+		return fmt.Errorf("ARCHIVE_MAX_ENTRIES, ARCHIVE_MAX_TOTAL_SIZE, ARCHIVE_MAX_DEPTH and ARCHIVE_MAX_RATIO must be >= 1")
+	}
+
+	if c.WebhookMaxAttempts < 1 {
+		return fmt.Errorf("WEBHOOK_MAX_ATTEMPTS must be >= 1, got %d", c.WebhookMaxAttempts)
+	}
+
+	if c.WebhookRetryBaseDelay < 0 || c.WebhookRetryMaxDelay < c.WebhookRetryBaseDelay {
+		return fmt.Errorf("WEBHOOK_RETRY_MAX_DELAY (%d) must be >= WEBHOOK_RETRY_BASE_DELAY (%d) >= 0",
+			c.WebhookRetryMaxDelay, c.WebhookRetryBaseDelay)
+	}
+
+	if c.WebhookTimeout <= 0 {
+		return fmt.Errorf("WEBHOOK_TIMEOUT must be > 0, got %d", c.WebhookTimeout)
+	}
+
 	return nil
 }
 
@@ -165,3 +398,81 @@ func getEnvAsInt64OrDefault(key string, defaultValue int64) int64 {
 
 	return value
 }
+
+// getEnvAsFloat64OrDefault gets environment variable as float64 or returns default
+func getEnvAsFloat64OrDefault(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvAsBoolOrDefault gets environment variable as bool or returns default
+func getEnvAsBoolOrDefault(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvAsWeightsOrDefault parses a "name=weight,..." environment variable.
+// Entries with a missing or non-positive weight are skipped.
+func getEnvAsWeightsOrDefault(key string, defaultValue string) map[string]int {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		valueStr = defaultValue
+	}
+
+	weights := make(map[string]int)
+	for _, entry := range strings.Split(valueStr, ",") {
+		name, weightStr, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			continue
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(weightStr))
+		if err != nil || weight <= 0 {
+			continue
+		}
+		weights[strings.ToLower(strings.TrimSpace(name))] = weight
+	}
+
+	return weights
+}
+
+// getEnvAsPricesOrDefault parses a "name=price,..." environment variable.
+// Entries with a missing or negative price are skipped.
+func getEnvAsPricesOrDefault(key string, defaultValue string) map[string]float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		valueStr = defaultValue
+	}
+
+	prices := make(map[string]float64)
+	for _, entry := range strings.Split(valueStr, ",") {
+		name, priceStr, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			continue
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(priceStr), 64)
+		if err != nil || price < 0 {
+			continue
+		}
+		prices[strings.TrimSpace(name)] = price
+	}
+
+	return prices
+}
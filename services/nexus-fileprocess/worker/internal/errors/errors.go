@@ -1,6 +1,7 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 	"time"
 )
@@ -20,6 +21,12 @@ const (
 	ErrorProcessingTimeout ErrorCode = "PROCESSING_TIMEOUT"
 	ErrorOCRFailed         ErrorCode = "OCR_FAILED"
 	ErrorUnsupportedFormat ErrorCode = "UNSUPPORTED_FORMAT"
+	ErrorMalformedPayload  ErrorCode = "MALFORMED_PAYLOAD"
+	ErrorArchiveRejected   ErrorCode = "ARCHIVE_REJECTED"
+	ErrorOCRPolicyRejected ErrorCode = "OCR_POLICY_REJECTED"
+
+	// Configuration errors
+	ErrorMageAgentUnavailable ErrorCode = "MAGEAGENT_UNAVAILABLE"
 
 	// Storage errors
 	ErrorStorageFailed  ErrorCode = "STORAGE_FAILED"
@@ -51,6 +58,31 @@ func (e *ProcessingError) Unwrap() error {
 	return e.Cause
 }
 
+// Permanent reports whether retrying cannot succeed because the input itself was
+// refused or the worker is not configured to process it
+func (e *ProcessingError) Permanent() bool {
+	switch e.Code {
+	case ErrorArchiveRejected, ErrorMalformedPayload, ErrorOCRPolicyRejected, ErrorMageAgentUnavailable:
+		return true
+	}
+	return false
+}
+
+// IsPermanent reports whether err wraps a ProcessingError that retrying cannot fix
+func IsPermanent(err error) bool {
+	var processingErr *ProcessingError
+	return stderrors.As(err, &processingErr) && processingErr.Permanent()
+}
+
+// CodeOf returns the code of the ProcessingError err wraps, or "" if there is none
+func CodeOf(err error) ErrorCode {
+	var processingErr *ProcessingError
+	if stderrors.As(err, &processingErr) {
+		return processingErr.Code
+	}
+	return ""
+}
+
 // Factory functions for common errors
 
 func NewProcessingTimeoutError(jobID string, duration time.Duration, cause error) *ProcessingError {
@@ -91,6 +123,57 @@ func NewUnsupportedFormatError(jobID string, mimeType string) *ProcessingError {
 	}
 }
 
+func NewMalformedPayloadError(jobID string, queueJobID string, cause error) *ProcessingError {
+	return &ProcessingError{
+		Code:      ErrorMalformedPayload,
+		Message:   "Job payload could not be decoded",
+		JobID:     jobID,
+		Timestamp: time.Now(),
+		Details: map[string]interface{}{
+			"queue_job_id": queueJobID,
+		},
+		Cause: cause,
+	}
+}
+
+func NewArchiveRejectedError(jobID string, reason string) *ProcessingError {
+	return &ProcessingError{
+		Code:      ErrorArchiveRejected,
+		Message:   fmt.Sprintf("Archive rejected: %s", reason),
+		JobID:     jobID,
+		Timestamp: time.Now(),
+		Details: map[string]interface{}{
+			"reason": reason,
+		},
+	}
+}
+
+func NewOCRPolicyRejectedError(jobID string, policy string, cause error) *ProcessingError {
+	return &ProcessingError{
+		Code:      ErrorOCRPolicyRejected,
+		Message:   fmt.Sprintf("OCR policy %s does not allow the OCR this file needs", policy),
+		JobID:     jobID,
+		Timestamp: time.Now(),
+		Details: map[string]interface{}{
+			"ocr_policy": policy,
+		},
+		Cause: cause,
+	}
+}
+
+func NewMageAgentUnavailableError(jobID string, feature string, cause error) *ProcessingError {
+	return &ProcessingError{
+		Code:      ErrorMageAgentUnavailable,
+		Message:   fmt.Sprintf("%s requires MageAgent, which local-only mode does not use", feature),
+		JobID:     jobID,
+		Timestamp: time.Now(),
+		Details: map[string]interface{}{
+			"feature": feature,
+		},
+		Cause: cause,
+	}
+}
+
 func NewStorageFailedError(jobID string, cause error) *ProcessingError {
 	return &ProcessingError{
 		Code:      ErrorStorageFailed,
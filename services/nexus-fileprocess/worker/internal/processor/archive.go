@@ -0,0 +1,411 @@
+/**
+ * Archive Expansion
+ *
+ * ZIP, TAR, TAR.GZ (and single-file GZIP) uploads are not sent through OCR.
+ * Their entries are extracted in memory and returned as child documents; the
+ * queue enqueues each one as a job of its own, linked to the parent job.
+ *
+ * Zip bomb protection - an archive is rejected when it exceeds any of:
+ * - MaxEntries    files per archive
+ * - MaxTotalSize  uncompressed bytes per archive (enforced on bytes actually read)
+ * - MaxRatio      uncompressed/compressed ratio, per ZIP entry and overall (above 1MB)
+ * - MaxDepth      nesting of archives within archives (deeper entries are skipped)
+ *
+ * Entry names are sanitized: absolute paths, ".." traversal, links, devices
+ * and OS metadata (__MACOSX, .DS_Store) are skipped.
+ */
+
+package processor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/errors"
+)
+
+// Archive formats
+const (
+	ArchiveZip   = "zip"
+	ArchiveTar   = "tar"
+	ArchiveTarGz = "tar.gz"
+	ArchiveGzip  = "gzip" // Single compressed file
+)
+
+// OCRTierArchive is reported as the tier of jobs that were expanded instead of processed
+const OCRTierArchive = "archive_expansion"
+
+const (
+	defaultArchiveMaxEntries   = 1000
+	defaultArchiveMaxTotalSize = 1 << 30 // 1GB
+	defaultArchiveMaxDepth     = 3
+	defaultArchiveMaxRatio     = 100
+
+	// Ratios are only enforced above this size; tiny highly compressible files are harmless
+	archiveRatioMinSize = 1 << 20 // 1MB
+)
+
+// ArchiveLimits bound what a single archive may expand to
+type ArchiveLimits struct {
+	MaxEntries   int     // Files extracted per archive
+	MaxTotalSize int64   // Uncompressed bytes per archive
+	MaxDepth     int     // Archives nested deeper than this are not expanded (top-level upload = depth 0)
+	MaxRatio     float64 // Uncompressed/compressed size ratio
+}
+
+// ChildDocument is a file extracted from an archive
+type ChildDocument struct {
+	Path     string // Sanitized path inside the archive
+	Filename string
+	MimeType string
+	Data     []byte
+	Depth    int // Archive nesting depth of the child
+}
+
+// withDefaults fills in unset limits
+func (l ArchiveLimits) withDefaults() ArchiveLimits {
+	if l.MaxEntries <= 0 {
+		l.MaxEntries = defaultArchiveMaxEntries
+	}
+	if l.MaxTotalSize <= 0 {
+		l.MaxTotalSize = defaultArchiveMaxTotalSize
+	}
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = defaultArchiveMaxDepth
+	}
+	if l.MaxRatio <= 0 {
+		l.MaxRatio = defaultArchiveMaxRatio
+	}
+	return l
+}
+
+// DetectArchive returns the archive format of a file, or "" if it is not an archive.
+// ZIP-based documents (DOCX, XLSX, PPTX, ODF, EPUB) are not archives.
+func DetectArchive(mimeType, filename string, data []byte) string {
+	name := strings.ToLower(filename)
+	isGzip := len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0x50, 0x4B, 0x03, 0x04}) || bytes.HasPrefix(data, []byte{0x50, 0x4B, 0x05, 0x06}):
+		if isPackagedDocument(data) {
+			return ""
+		}
+		if mimeType == "application/zip" || mimeType == "application/x-zip-compressed" ||
+			mimeType == "application/octet-stream" || mimeType == "" || strings.HasSuffix(name, ".zip") {
+			return ArchiveZip
+		}
+	case isTar(data):
+		return ArchiveTar
+	case isGzip:
+		if strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz") {
+			return ArchiveTarGz
+		}
+		// Peek at the decompressed header to tell a tarball from a single file
+		if zr, err := gzip.NewReader(bytes.NewReader(data)); err == nil {
+			header := make([]byte, 512)
+			n, _ := io.ReadFull(zr, header)
+			zr.Close()
+			if isTar(header[:n]) {
+				return ArchiveTarGz
+			}
+		}
+		return ArchiveGzip
+	}
+	return ""
+}
+
+// isTar checks for the ustar magic of POSIX and GNU tar headers
+func isTar(data []byte) bool {
+	return len(data) >= 262 && bytes.Equal(data[257:262], []byte("ustar"))
+}
+
+// isPackagedDocument reports ZIP containers that are documents in their own right
+func isPackagedDocument(data []byte) bool {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return false
+	}
+	for _, f := range zr.File {
+		switch f.Name {
+		case "[Content_Types].xml", "mimetype", "META-INF/manifest.xml":
+			return true
+		}
+	}
+	return false
+}
+
+// archiveDepth is the nesting depth recorded on a child job (0 for uploads)
+func archiveDepth(req *ProcessRequest) int {
+	switch depth := req.Metadata["archiveDepth"].(type) {
+	case float64:
+		return int(depth)
+	case int:
+		return depth
+	}
+	return 0
+}
+
+// expandArchive extracts the entries of an archive as child documents
+func (p *DocumentProcessor) expandArchive(ctx context.Context, req *ProcessRequest, fileData []byte, format, contentHash string) (*ProcessResult, error) {
+	result, err := ExpandArchive(ctx, req, fileData, format, p.config.ArchiveLimits, p.config.MaxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	result.ContentHash = contentHash
+	return result, nil
+}
+
+// ExpandArchive extracts the entries of an archive within limits. maxEntry bounds
+// each entry (0 = no extra limit); the nesting depth comes from req.Metadata.
+func ExpandArchive(ctx context.Context, req *ProcessRequest, fileData []byte, format string, limits ArchiveLimits, maxEntry int64) (*ProcessResult, error) {
+	limits = limits.withDefaults()
+	depth := archiveDepth(req)
+	if depth >= limits.MaxDepth {
+		return nil, errors.NewArchiveRejectedError(req.JobID, fmt.Sprintf("nested %d levels deep (limit %d)", depth, limits.MaxDepth))
+	}
+
+	log.Printf("[Job %s] Step 2: Expanding %s archive (%d bytes, depth %d)", req.JobID, format, len(fileData), depth)
+	reportProgress(ctx, req.JobID, StageOCR, 0, "Expanding archive", nil)
+
+	x := &archiveExtractor{
+		jobID:      req.JobID,
+		limits:     limits,
+		maxEntry:   maxEntry,
+		compressed: int64(len(fileData)),
+		depth:      depth + 1,
+	}
+
+	var err error
+	switch format {
+	case ArchiveZip:
+		err = x.extractZip(ctx, fileData)
+	case ArchiveTar:
+		err = x.extractTar(ctx, bytes.NewReader(fileData))
+	case ArchiveTarGz, ArchiveGzip:
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(bytes.NewReader(fileData)); err != nil {
+			break
+		}
+		defer zr.Close()
+		if format == ArchiveTarGz {
+			err = x.extractTar(ctx, zr)
+		} else {
+			name := strings.TrimSuffix(path.Base(req.Filename), path.Ext(req.Filename))
+			if zr.Name != "" {
+				name = zr.Name
+			}
+			err = x.addEntry(name, zr, -1)
+		}
+	default:
+		err = fmt.Errorf("unsupported archive format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Job %s] Archive expanded: %d entries (%d bytes), %d skipped",
+		req.JobID, len(x.children), x.total, x.skipped)
+	reportProgress(ctx, req.JobID, StageOCR, 1, "Archive expanded", map[string]interface{}{
+		"entries": len(x.children),
+		"skipped": x.skipped,
+		"bytes":   x.total,
+	})
+
+	return &ProcessResult{
+		Confidence:     1.0,
+		OCRTierUsed:    OCRTierArchive,
+		Children:       x.children,
+		SkippedEntries: x.skipped,
+	}, nil
+}
+
+// archiveExtractor accumulates entries while enforcing the archive limits
+type archiveExtractor struct {
+	jobID      string
+	limits     ArchiveLimits
+	maxEntry   int64 // Largest single entry (MAX_FILE_SIZE); 0 = no extra limit
+	compressed int64 // Size of the archive itself
+	depth      int   // Depth assigned to children
+	total      int64 // Uncompressed bytes read so far
+	skipped    int
+	children   []*ChildDocument
+}
+
+func (x *archiveExtractor) extractZip(ctx context.Context, data []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %w", err)
+	}
+
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			x.skip(f.Name, "not a regular file")
+			continue
+		}
+
+		// Declared sizes are checked up front; bytes read are checked again while inflating
+		if f.UncompressedSize64 > archiveRatioMinSize && f.CompressedSize64 > 0 &&
+			float64(f.UncompressedSize64)/float64(f.CompressedSize64) > x.limits.MaxRatio {
+			return errors.NewArchiveRejectedError(x.jobID, fmt.Sprintf("entry %q compression ratio exceeds %.0f", f.Name, x.limits.MaxRatio))
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open zip entry %q: %w", f.Name, err)
+		}
+		err = x.addEntry(f.Name, rc, int64(f.CompressedSize64))
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *archiveExtractor) extractTar(ctx context.Context, r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			if header.Typeflag != tar.TypeDir {
+				x.skip(header.Name, "not a regular file")
+			}
+			continue
+		}
+		if err := x.addEntry(header.Name, tr, -1); err != nil {
+			return err
+		}
+	}
+}
+
+// addEntry reads one entry within the remaining budget and records it as a child.
+// compressedSize is the entry's own compressed size, or -1 when unknown.
+func (x *archiveExtractor) addEntry(name string, r io.Reader, compressedSize int64) error {
+	clean, ok := sanitizeArchivePath(name)
+	if !ok {
+		x.skip(name, "unsafe path")
+		return nil
+	}
+	if isArchiveMetadata(clean) {
+		return nil
+	}
+
+	if len(x.children) >= x.limits.MaxEntries {
+		return errors.NewArchiveRejectedError(x.jobID, fmt.Sprintf("more than %d entries", x.limits.MaxEntries))
+	}
+
+	// Read one byte past the budget so overruns are detected rather than truncated
+	budget := x.limits.MaxTotalSize - x.total
+	if x.maxEntry > 0 && x.maxEntry < budget {
+		budget = x.maxEntry
+	}
+	data, err := io.ReadAll(io.LimitReader(r, budget+1))
+	if err != nil {
+		return fmt.Errorf("failed to extract %q: %w", clean, err)
+	}
+	if int64(len(data)) > budget {
+		return errors.NewArchiveRejectedError(x.jobID, fmt.Sprintf("entry %q exceeds the uncompressed size limit", clean))
+	}
+
+	x.total += int64(len(data))
+	if len(data) > archiveRatioMinSize && compressedSize > 0 && float64(len(data))/float64(compressedSize) > x.limits.MaxRatio {
+		return errors.NewArchiveRejectedError(x.jobID, fmt.Sprintf("entry %q compression ratio exceeds %.0f", clean, x.limits.MaxRatio))
+	}
+	if x.total > archiveRatioMinSize && x.compressed > 0 && float64(x.total)/float64(x.compressed) > x.limits.MaxRatio {
+		return errors.NewArchiveRejectedError(x.jobID, fmt.Sprintf("archive compression ratio exceeds %.0f", x.limits.MaxRatio))
+	}
+
+	if len(data) == 0 {
+		x.skip(clean, "empty")
+		return nil
+	}
+
+	child := &ChildDocument{
+		Path:     clean,
+		Filename: path.Base(clean),
+		MimeType: entryMimeType(clean, data),
+		Data:     data,
+		Depth:    x.depth,
+	}
+	if x.depth >= x.limits.MaxDepth && DetectArchive(child.MimeType, child.Filename, data) != "" {
+		x.skip(clean, "nested archive too deep")
+		return nil
+	}
+
+	x.children = append(x.children, child)
+	return nil
+}
+
+func (x *archiveExtractor) skip(name, reason string) {
+	x.skipped++
+	log.Printf("[Job %s] Skipping archive entry %q: %s", x.jobID, name, reason)
+}
+
+// sanitizeArchivePath normalizes an entry name to a relative slash-separated path.
+// Absolute paths, drive letters, ".." traversal and control characters are rejected.
+func sanitizeArchivePath(name string) (string, bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if name == "" || strings.HasPrefix(name, "/") || (len(name) >= 2 && name[1] == ':') {
+		return "", false
+	}
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return "", false
+		}
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", false
+		}
+	}
+
+	clean := path.Clean(name)
+	if clean == "." || strings.HasSuffix(name, "/") {
+		return "", false
+	}
+	return clean, true
+}
+
+// isArchiveMetadata reports files added by archivers and file managers rather than users
+func isArchiveMetadata(clean string) bool {
+	base := path.Base(clean)
+	return strings.HasPrefix(clean, "__MACOSX/") || base == ".DS_Store" || base == "Thumbs.db" ||
+		strings.HasPrefix(base, "._")
+}
+
+// entryMimeType detects an entry's type from its content, then its extension
+func entryMimeType(clean string, data []byte) string {
+	if detected := detectMimeTypeFromMagicBytes(data); detected != "" {
+		return detected
+	}
+	if byExt := mime.TypeByExtension(strings.ToLower(path.Ext(clean))); byExt != "" {
+		if mediaType, _, err := mime.ParseMediaType(byExt); err == nil {
+			return mediaType
+		}
+	}
+	return "application/octet-stream"
+}
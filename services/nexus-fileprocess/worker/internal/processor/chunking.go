@@ -0,0 +1,357 @@
+/**
+ * Text Chunking - Splits a document's text into chunks for embedding
+ *
+ * One embedding of a long document only represents its first pages, so the
+ * text is also embedded chunk by chunk. Chunks follow the document's
+ * structure:
+ * - Layout regions found in the text, in reading order (paragraphs split on
+ *   blank lines when the regions cannot be matched to the text)
+ * - A heading or a new page always starts a new chunk
+ * - Sections longer than MaxChars are split into windows at word boundaries
+ *   that overlap by OverlapChars, as are chunks that grow too long
+ *
+ * Chunk offsets are byte offsets into the document text, like the page
+ * boundaries sent to GraphRAG.
+ */
+
+package processor
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ChunkOptions bounds the chunks embedded for a document
+type ChunkOptions struct {
+	MaxChars     int // Longest chunk in bytes (default 2000)
+	OverlapChars int // Text repeated from the previous chunk when a section is split (default 200)
+	MaxChunks    int // Chunks per document; text past the last one is covered by the document vector only (default 2000)
+}
+
+// DefaultChunkOptions are ~500-token chunks overlapping by ~50 tokens
+func DefaultChunkOptions() ChunkOptions {
+	return ChunkOptions{MaxChars: 2000, OverlapChars: 200, MaxChunks: 2000}
+}
+
+// TextChunk is a span of the document text embedded as its own vector
+type TextChunk struct {
+	Index      int
+	Text       string
+	PageNumber int // 0 when unknown
+	RegionID   int // Layout region the chunk starts in, -1 when not aligned with regions
+	StartChar  int // Byte offsets of Text in the document text
+	EndChar    int
+}
+
+// chunkSegment is a structural unit of the text (a region or paragraph)
+type chunkSegment struct {
+	start, end int
+	page       int
+	regionID   int
+	heading    bool
+}
+
+// paragraphBreak separates paragraphs of plain text
+var paragraphBreak = regexp.MustCompile(`\n[ \t\r]*\n`)
+
+// regionAnchorLen is how much of a region's first line locates it in the text
+const regionAnchorLen = 64
+
+// ChunkText splits a document's text into chunks aligned with its layout
+// regions, headings and pages. pages and layout may be empty.
+func ChunkText(text string, pages []OCRPage, layout *LayoutResult, opts ChunkOptions) []TextChunk {
+	defaults := DefaultChunkOptions()
+	if opts.MaxChars <= 0 {
+		opts.MaxChars = defaults.MaxChars
+	}
+	if opts.OverlapChars < 0 || opts.OverlapChars >= opts.MaxChars {
+		opts.OverlapChars = 0
+	}
+	if opts.MaxChunks <= 0 {
+		opts.MaxChunks = defaults.MaxChunks
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	segments := regionSegments(text, layout)
+	if segments == nil {
+		segments = paragraphSegments(text)
+	}
+	assignPages(text, segments, pages)
+
+	c := &chunker{text: text, opts: opts}
+	for _, seg := range segments {
+		if len(c.chunks) >= opts.MaxChunks {
+			break
+		}
+		c.add(seg)
+	}
+	c.flush(false)
+
+	if len(c.chunks) > opts.MaxChunks {
+		c.chunks = c.chunks[:opts.MaxChunks]
+	}
+	for i := range c.chunks {
+		c.chunks[i].Index = i
+	}
+	return c.chunks
+}
+
+// chunker packs segments into chunks
+type chunker struct {
+	text   string
+	opts   ChunkOptions
+	chunks []TextChunk
+
+	open       bool // A chunk is being filled
+	start, end int
+	page       int
+	regionID   int
+}
+
+// add appends a segment to the open chunk, closing it first at headings,
+// page changes and when the segment would make it too long
+func (c *chunker) add(seg chunkSegment) {
+	if seg.end-seg.start > c.opts.MaxChars {
+		c.flush(false)
+		c.split(seg)
+		return
+	}
+
+	if c.open && (seg.heading || seg.page != c.page) {
+		c.flush(false)
+	} else if c.open && seg.end-c.start > c.opts.MaxChars {
+		// Too long: the next chunk repeats the end of this one
+		c.flush(true)
+		if c.open && seg.end-c.start > c.opts.MaxChars {
+			c.start, c.regionID = seg.start, seg.regionID
+		}
+	}
+
+	if !c.open {
+		c.open, c.start, c.page, c.regionID = true, seg.start, seg.page, seg.regionID
+	}
+	c.end = seg.end
+}
+
+// flush closes the open chunk. With overlap, a new chunk is opened on the
+// tail of the closed one.
+func (c *chunker) flush(overlap bool) {
+	if !c.open {
+		return
+	}
+	c.open = false
+	c.emit(c.start, c.end, c.page, c.regionID)
+
+	if overlap && c.opts.OverlapChars > 0 {
+		if start := overlapStart(c.text, c.start, c.end, c.opts.OverlapChars); start < c.end {
+			c.open, c.start = true, start
+		}
+	}
+}
+
+// split cuts a segment longer than MaxChars into overlapping windows
+func (c *chunker) split(seg chunkSegment) {
+	start := seg.start
+	for start < seg.end {
+		end := seg.end
+		if end-start > c.opts.MaxChars {
+			end = wordBoundary(c.text, start+c.opts.MaxChars/2, start+c.opts.MaxChars)
+		}
+		c.emit(start, end, seg.page, seg.regionID)
+		if end >= seg.end {
+			return
+		}
+
+		next := overlapStart(c.text, start, end, c.opts.OverlapChars)
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+}
+
+// emit appends text[start:end] as a chunk, without surrounding whitespace
+func (c *chunker) emit(start, end, page, regionID int) {
+	span := c.text[start:end]
+	chunk := strings.TrimSpace(span)
+	if chunk == "" {
+		return
+	}
+	start += len(span) - len(strings.TrimLeftFunc(span, unicode.IsSpace))
+	c.chunks = append(c.chunks, TextChunk{
+		Text:       chunk,
+		PageNumber: page,
+		RegionID:   regionID,
+		StartChar:  start,
+		EndChar:    start + len(chunk),
+	})
+}
+
+// regionSegments locates the layout regions in the text by the start of
+// their first line. A region runs until the next one found. nil when fewer
+// than half of the regions with content are found.
+func regionSegments(text string, layout *LayoutResult) []chunkSegment {
+	if layout == nil || len(layout.Regions) == 0 {
+		return nil
+	}
+
+	order := layout.ReadingOrder
+	if len(order) != len(layout.Regions) {
+		order = make([]int, len(layout.Regions))
+		for i := range order {
+			order[i] = i
+		}
+	}
+
+	var segments []chunkSegment
+	cursor, candidates := 0, 0
+	for _, index := range order {
+		if index < 0 || index >= len(layout.Regions) {
+			return nil
+		}
+		region := layout.Regions[index]
+		anchor := regionAnchor(region.Content)
+		if anchor == "" {
+			continue
+		}
+		candidates++
+
+		offset := strings.Index(text[cursor:], anchor)
+		if offset < 0 {
+			continue // Its text joins the previous region's
+		}
+		start := cursor + offset
+		if len(segments) > 0 {
+			segments[len(segments)-1].end = start
+		}
+		segments = append(segments, chunkSegment{
+			start:    start,
+			page:     region.PageNumber,
+			regionID: region.ID,
+			heading:  region.Type == RegionHeading,
+		})
+		cursor = start + len(anchor)
+	}
+	if len(segments) == 0 || len(segments)*2 < candidates {
+		return nil
+	}
+	segments[len(segments)-1].end = len(text)
+
+	// Text before the first region found
+	if strings.TrimSpace(text[:segments[0].start]) != "" {
+		segments = append([]chunkSegment{{start: 0, end: segments[0].start, regionID: -1}}, segments...)
+	}
+	return segments
+}
+
+// regionAnchor is the start of a region's first line
+func regionAnchor(content string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	line = strings.TrimSpace(line)
+	if len(line) > regionAnchorLen {
+		line = line[:runeBoundary(line, regionAnchorLen)]
+	}
+	return line
+}
+
+// paragraphSegments splits text on blank lines
+func paragraphSegments(text string) []chunkSegment {
+	var segments []chunkSegment
+	start := 0
+	for _, gap := range paragraphBreak.FindAllStringIndex(text, -1) {
+		if strings.TrimSpace(text[start:gap[0]]) != "" {
+			segments = append(segments, chunkSegment{start: start, end: gap[0], regionID: -1})
+		}
+		start = gap[1]
+	}
+	if strings.TrimSpace(text[start:]) != "" {
+		segments = append(segments, chunkSegment{start: start, end: len(text), regionID: -1})
+	}
+	return segments
+}
+
+// assignPages sets the page of segments without one from where the pages'
+// text is found in the document text
+func assignPages(text string, segments []chunkSegment, pages []OCRPage) {
+	type pageSpan struct{ number, start int }
+	var spans []pageSpan
+	cursor := 0
+	for i, page := range pages {
+		number := page.PageNumber
+		if number == 0 {
+			number = i + 1
+		}
+		anchor := regionAnchor(page.Text)
+		if anchor == "" {
+			continue
+		}
+		if offset := strings.Index(text[cursor:], anchor); offset >= 0 {
+			spans = append(spans, pageSpan{number, cursor + offset})
+			cursor += offset + len(anchor)
+		}
+	}
+	if len(spans) == 0 {
+		return
+	}
+
+	for i := range segments {
+		if segments[i].page != 0 {
+			continue
+		}
+		segments[i].page = spans[0].number
+		for _, span := range spans {
+			if span.start > segments[i].start {
+				break
+			}
+			segments[i].page = span.number
+		}
+	}
+}
+
+// overlapStart is where a chunk overlapping the last overlap bytes of
+// text[start:end] begins: the first word at or after end-overlap
+func overlapStart(text string, start, end, overlap int) int {
+	if overlap <= 0 {
+		return end
+	}
+	pos := max(end-overlap, start)
+	for pos < end {
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		pos += size
+		if unicode.IsSpace(r) {
+			break
+		}
+	}
+	for pos < end {
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		pos += size
+	}
+	return pos
+}
+
+// wordBoundary is the last whitespace in text[from:to], else to at a rune boundary
+func wordBoundary(text string, from, to int) int {
+	to = runeBoundary(text, to)
+	if i := strings.LastIndexFunc(text[from:to], unicode.IsSpace); i >= 0 {
+		return from + i
+	}
+	return to
+}
+
+// runeBoundary moves i back to the start of the UTF-8 sequence it falls in
+func runeBoundary(text string, i int) int {
+	if i >= len(text) {
+		return len(text)
+	}
+	for i > 0 && !utf8.RuneStart(text[i]) {
+		i--
+	}
+	return i
+}
@@ -0,0 +1,131 @@
+/**
+ * Content Deduplication and Idempotent Processing
+ *
+ * Re-uploads of the same file and redeliveries of the same job should not pay
+ * for OCR and embeddings again:
+ * - Redelivered JobID:  the Document DNA already stored for the job is reused
+ * - Same file content:  the DNA of a completed job with the same SHA-256 and
+ *                       pipeline fingerprint is linked to the new job
+ *
+ * The pipeline fingerprint changes whenever the output of the pipeline would,
+ * so DNA produced by an older OCR/layout revision or embedding model is never
+ * reused. Jobs overriding metadata.ocrPolicy or metadata.language extend the
+ * fingerprint with those choices, so their DNA is only shared with jobs that
+ * made the same ones. Content matches can be scoped to the uploading user (DedupScopeUser)
+ * or shared across users (DedupScopeGlobal). Jobs with metadata.forceReprocess
+ * always run the full pipeline.
+ */
+
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/storage"
+)
+
+// pipelineRevision must be bumped whenever OCR, layout or structural output changes
+const pipelineRevision = 2 // 2: chunk-level embeddings
+
+// Dedup scopes for content matches
+const (
+	DedupScopeUser   = "user"   // Only reuse DNA from the same user's jobs
+	DedupScopeGlobal = "global" // Reuse DNA from any user's jobs
+)
+
+// ContentHash returns the hex SHA-256 of the file bytes
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// PipelineFingerprint identifies the pipeline output a Document DNA was produced with
+func PipelineFingerprint(embeddingModel string, dimensions int) string {
+	return fmt.Sprintf("r%d/%s/%d", pipelineRevision, embeddingModel, dimensions)
+}
+
+// JobPipelineFingerprint extends a pipeline fingerprint with the OCR choices a job's
+// metadata overrides (ocrPolicy, language); without overrides it is returned as is
+func JobPipelineFingerprint(pipeline string, metadata map[string]interface{}) string {
+	var overrides []string
+	if policy, ok := metadata["ocrPolicy"]; ok {
+		encoded, _ := json.Marshal(policy) // Map keys are sorted, so equal overrides encode equally
+		overrides = append(overrides, "ocrPolicy="+string(encoded))
+	}
+	if languages := ParseLanguages(metadata["language"]); len(languages) > 0 {
+		overrides = append(overrides, "language="+strings.Join(languages, "+"))
+	}
+	if len(overrides) == 0 {
+		return pipeline
+	}
+	sum := sha256.Sum256([]byte(strings.Join(overrides, "\n")))
+	return pipeline + "/" + hex.EncodeToString(sum[:8])
+}
+
+// findReusableDNA looks up a Document DNA that can complete this job without reprocessing.
+// Lookup failures are logged and treated as a miss so deduplication never fails a job.
+func (p *DocumentProcessor) findReusableDNA(ctx context.Context, req *ProcessRequest, contentHash string) *storage.DocumentDNAOutput {
+	if !p.config.DedupEnabled {
+		return nil
+	}
+	if force, ok := req.Metadata["forceReprocess"].(bool); ok && force {
+		return nil
+	}
+
+	userID := ""
+	if p.config.DedupScope != DedupScopeGlobal {
+		userID = req.UserID
+	}
+
+	dna, err := p.storage.FindDocumentDNAByContent(ctx, contentHash, p.pipelineVersion(req), userID)
+	if err != nil {
+		log.Printf("[Job %s] WARNING: Dedup lookup failed, processing normally: %v", req.JobID, err)
+		return nil
+	}
+	return dna
+}
+
+// pipelineVersion is the fingerprint of this processor's pipeline as the job runs it
+func (p *DocumentProcessor) pipelineVersion(req *ProcessRequest) string {
+	return JobPipelineFingerprint(PipelineFingerprint(p.embedder.Model(), p.embedder.Dimensions()), req.Metadata)
+}
+
+// resultFromDocumentDNA rebuilds the processing result of a stored Document DNA
+func resultFromDocumentDNA(dna *storage.DocumentDNAOutput) *ProcessResult {
+	result := &ProcessResult{
+		DocumentDNAID:      dna.ID,
+		EmbeddingGenerated: true,
+	}
+
+	ocrConfidence, layoutConfidence := 0.0, 0.0
+	if metadata, ok := dna.StructuralData["metadata"].(map[string]interface{}); ok {
+		result.OCRTierUsed, _ = metadata["ocrTier"].(string)
+		ocrConfidence, _ = metadata["ocrConfidence"].(float64)
+		if pageCount, ok := metadata["pageCount"].(float64); ok {
+			result.PageCount = int(pageCount)
+		}
+		if chunkCount, ok := metadata["chunkCount"].(float64); ok {
+			result.ChunksEmbedded = int(chunkCount)
+		}
+	}
+	if layout, ok := dna.StructuralData["layout"].(map[string]interface{}); ok {
+		layoutConfidence, _ = layout["confidence"].(float64)
+		if regions, ok := layout["regions"].([]interface{}); ok {
+			result.RegionsExtracted = len(regions)
+		}
+	}
+	if tables, ok := dna.StructuralData["tables"].([]interface{}); ok {
+		result.TablesExtracted = len(tables)
+	}
+
+	// Same weighting as ProcessDocument
+	result.Confidence = ocrConfidence*0.4 + layoutConfidence*0.6
+
+	return result
+}
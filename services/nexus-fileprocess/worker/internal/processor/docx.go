@@ -0,0 +1,509 @@
+/**
+ * DOCX Extraction
+ *
+ * Parses OOXML WordprocessingML (word/document.xml) into headings,
+ * paragraphs, list items and tables:
+ * - Heading levels come from the paragraph's outline level or its style
+ *   ("heading N", "Title", or a style with an outline level, following basedOn)
+ * - List items are paragraphs with numbering (numPr), directly or via their style
+ * - Table cells spanning grid columns (gridSpan) map to ColSpan, vertically
+ *   merged cells (vMerge) to RowSpan on the first cell of the merge. Spans and
+ *   skipped columns (gridBefore) are kept within the table grid (tblGrid, at
+ *   most 63 columns as in Word)
+ *
+ * DOCX has no fixed pages; pages are split at explicit page breaks and at the
+ * page breaks Word recorded when the file was last rendered. Deleted text
+ * (tracked changes), field codes and fallback copies of text boxes are skipped.
+ */
+
+package processor
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MimeTypeDOCX is the MIME type of Word documents
+const MimeTypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+// OCRTierNativeDOCX is reported as the tier of natively parsed Word documents
+const OCRTierNativeDOCX = "native_docx"
+
+// maxDOCXTableColumns is Word's column limit, used when a table has no grid
+const maxDOCXTableColumns = 63
+
+// docxSkippedElements are subtrees that duplicate or no longer hold document text
+var docxSkippedElements = map[string]bool{
+	"Fallback":     true, // mc:AlternateContent fallback (VML copy of a text box)
+	"del":          true, // Tracked deletion
+	"moveFrom":     true, // Tracked move source
+	"pPrChange":    true, // Previous paragraph properties
+	"rPrChange":    true,
+	"sectPrChange": true,
+	"tblPrChange":  true,
+	"trPrChange":   true,
+	"tcPrChange":   true,
+}
+
+// ExtractDOCX parses a Word document into pages, layout regions and tables
+func ExtractDOCX(data []byte) (*OCRResult, *LayoutResult, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid DOCX package: %w", err)
+	}
+
+	body := findZipFile(zr, "word/document.xml")
+	if body == nil {
+		return nil, nil, fmt.Errorf("invalid DOCX package: word/document.xml not found")
+	}
+
+	// Styles only refine region types; a document without readable styles is still extracted
+	styles := docxStyles{}
+	if f := findZipFile(zr, "word/styles.xml"); f != nil {
+		if parsed, err := parseDOCXStyles(f); err == nil {
+			styles = parsed
+		}
+	}
+
+	rc, err := openZipPart(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open DOCX body: %w", err)
+	}
+	defer rc.Close()
+
+	p := &docxParser{
+		doc:        newNativeDocument(OCRTierNativeDOCX),
+		styles:     styles,
+		containers: []string{"body"},
+	}
+	if err := p.parse(xml.NewDecoder(rc)); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse DOCX body: %w", err)
+	}
+
+	ocrResult, layoutResult := p.doc.result()
+	return ocrResult, layoutResult, nil
+}
+
+// docxStyle holds the properties of a paragraph style that matter for extraction
+type docxStyle struct {
+	basedOn      string
+	headingLevel int  // 0 when the style itself is not a heading
+	numbered     bool // Style applies list numbering
+	numberingOff bool // Style explicitly removes numbering (numId 0)
+}
+
+type docxStyles map[string]*docxStyle
+
+// headingLevel resolves a style's heading level through its basedOn chain
+func (s docxStyles) headingLevel(styleID string) int {
+	for i := 0; i < 10 && styleID != ""; i++ {
+		style, ok := s[styleID]
+		if !ok {
+			return 0
+		}
+		if style.headingLevel > 0 {
+			return style.headingLevel
+		}
+		styleID = style.basedOn
+	}
+	return 0
+}
+
+// numbered resolves whether a style applies list numbering through its basedOn chain
+func (s docxStyles) numbered(styleID string) bool {
+	for i := 0; i < 10 && styleID != ""; i++ {
+		style, ok := s[styleID]
+		if !ok {
+			return false
+		}
+		if style.numbered || style.numberingOff {
+			return style.numbered
+		}
+		styleID = style.basedOn
+	}
+	return false
+}
+
+// parseDOCXStyles reads the paragraph styles of word/styles.xml
+func parseDOCXStyles(f *zip.File) (docxStyles, error) {
+	rc, err := openZipPart(f)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	styles := docxStyles{}
+	var current *docxStyle
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return styles, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "style" {
+				current = nil
+				if attr(t, "type") == "paragraph" {
+					current = &docxStyle{}
+					styles[attr(t, "styleId")] = current
+				}
+				continue
+			}
+			if current == nil {
+				continue
+			}
+			val := attr(t, "val")
+			switch t.Name.Local {
+			case "name":
+				current.headingLevel = max(current.headingLevel, headingLevelFromStyleName(val))
+			case "basedOn":
+				current.basedOn = val
+			case "outlineLvl":
+				if level, err := strconv.Atoi(val); err == nil && level >= 0 && level < 9 {
+					current.headingLevel = level + 1
+				}
+			case "numId":
+				current.numbered = val != "0"
+				current.numberingOff = val == "0"
+			}
+		case xml.EndElement:
+			if t.Name.Local == "style" {
+				current = nil
+			}
+		}
+	}
+}
+
+// headingLevelFromStyleName maps built-in style names ("heading 2", "Title") to heading levels
+func headingLevelFromStyleName(name string) int {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "title" {
+		return 1
+	}
+	if rest, ok := strings.CutPrefix(name, "heading "); ok {
+		if level, err := strconv.Atoi(rest); err == nil && level >= 1 && level <= 9 {
+			return level
+		}
+	}
+	return 0
+}
+
+// docxParagraph is a paragraph being read
+type docxParagraph struct {
+	style     string
+	outline   int // Direct outline level + 1, 0 when unset
+	numbering int // Direct numbering: 1 numbered, -1 removed, 0 inherited from the style
+	listLevel int // ilvl
+	text      strings.Builder
+}
+
+// docxCell is a table cell being read
+type docxCell struct {
+	cell    TableCell
+	vMerge  string // "", "restart" or "continue"
+	content []string
+}
+
+// docxTable is a table being read
+type docxTable struct {
+	rows   []TableRow
+	grid   int            // Grid columns declared in tblGrid
+	col    int            // Grid column of the next cell in the current row
+	cell   *docxCell      // Cell being read
+	merges map[int][2]int // Grid column -> row/cell index of the cell a vertical merge started in
+}
+
+// width is the number of grid columns cells may occupy
+func (t *docxTable) width() int {
+	if t.grid == 0 || t.grid > maxDOCXTableColumns {
+		return maxDOCXTableColumns
+	}
+	return t.grid
+}
+
+// docxParser walks word/document.xml. Containers track where finished
+// paragraphs go: the document body, a table cell or a text box.
+type docxParser struct {
+	doc        *nativeDocument
+	styles     docxStyles
+	paragraphs []*docxParagraph
+	tables     []*docxTable
+	containers []string
+	runDepth   int
+	inText     bool
+}
+
+func (p *docxParser) parse(decoder *xml.Decoder) error {
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if docxSkippedElements[t.Name.Local] {
+				if err := decoder.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			p.start(t)
+		case xml.EndElement:
+			p.end(t)
+		case xml.CharData:
+			if p.inText && len(p.paragraphs) > 0 {
+				p.paragraph().text.Write(t)
+			}
+		}
+	}
+}
+
+func (p *docxParser) paragraph() *docxParagraph {
+	return p.paragraphs[len(p.paragraphs)-1]
+}
+
+func (p *docxParser) table() *docxTable {
+	return p.tables[len(p.tables)-1]
+}
+
+func (p *docxParser) container() string {
+	return p.containers[len(p.containers)-1]
+}
+
+// inBody reports whether content goes straight to the document (pages can break here)
+func (p *docxParser) inBody() bool {
+	return len(p.tables) == 0 && p.container() == "body"
+}
+
+func (p *docxParser) start(t xml.StartElement) {
+	val := attr(t, "val")
+
+	switch t.Name.Local {
+	case "p":
+		p.paragraphs = append(p.paragraphs, &docxParagraph{})
+	case "r":
+		p.runDepth++
+	case "t":
+		p.inText = p.runDepth > 0
+	case "txbxContent":
+		p.containers = append(p.containers, "txbx")
+	case "tbl":
+		p.tables = append(p.tables, &docxTable{merges: map[int][2]int{}})
+	case "tr":
+		if len(p.tables) > 0 {
+			table := p.table()
+			table.rows = append(table.rows, TableRow{RowNumber: len(table.rows)})
+			table.col = 0
+		}
+	case "gridCol":
+		if len(p.tables) > 0 {
+			p.table().grid++
+		}
+	case "gridBefore":
+		if n, err := strconv.Atoi(val); err == nil && n > 0 && len(p.tables) > 0 {
+			table := p.table()
+			table.col = min(table.col+n, table.width())
+		}
+	case "tc":
+		if len(p.tables) > 0 {
+			table := p.table()
+			table.cell = &docxCell{cell: TableCell{ColumnNumber: table.col, Confidence: 1.0, RowSpan: 1, ColSpan: 1}}
+			p.containers = append(p.containers, "cell")
+		}
+	case "gridSpan":
+		if n, err := strconv.Atoi(val); err == nil && n > 1 && len(p.tables) > 0 && p.table().cell != nil {
+			table := p.table()
+			table.cell.cell.ColSpan = max(min(n, table.width()-table.cell.cell.ColumnNumber), 1)
+		}
+	case "vMerge":
+		if len(p.tables) > 0 && p.table().cell != nil {
+			p.table().cell.vMerge = "continue"
+			if val == "restart" {
+				p.table().cell.vMerge = "restart"
+			}
+		}
+	}
+
+	if len(p.paragraphs) == 0 {
+		return
+	}
+	para := p.paragraph()
+
+	switch t.Name.Local {
+	case "pStyle":
+		para.style = val
+	case "outlineLvl":
+		if level, err := strconv.Atoi(val); err == nil && level >= 0 && level < 9 {
+			para.outline = level + 1
+		}
+	case "ilvl":
+		if level, err := strconv.Atoi(val); err == nil {
+			para.listLevel = level
+		}
+	case "numId":
+		para.numbering = 1
+		if val == "0" {
+			para.numbering = -1
+		}
+	case "pageBreakBefore":
+		if val != "0" && val != "false" && p.inBody() {
+			p.doc.breakPage()
+		}
+	}
+
+	if p.runDepth == 0 {
+		return
+	}
+	switch t.Name.Local {
+	case "tab":
+		para.text.WriteString("\t")
+	case "cr":
+		para.text.WriteString("\n")
+	case "noBreakHyphen":
+		para.text.WriteString("-")
+	case "br":
+		if attr(t, "type") != "page" {
+			para.text.WriteString("\n")
+			break
+		}
+		fallthrough
+	case "lastRenderedPageBreak":
+		if p.inBody() {
+			// Text before the break stays on the current page
+			p.flushParagraph(para)
+			para.text.Reset()
+			p.doc.breakPage()
+		}
+	}
+}
+
+func (p *docxParser) end(t xml.EndElement) {
+	switch t.Name.Local {
+	case "p":
+		if len(p.paragraphs) > 0 {
+			para := p.paragraph()
+			p.paragraphs = p.paragraphs[:len(p.paragraphs)-1]
+			p.flushParagraph(para)
+		}
+	case "r":
+		if p.runDepth > 0 {
+			p.runDepth--
+		}
+	case "t":
+		p.inText = false
+	case "txbxContent":
+		p.popContainer()
+	case "tc":
+		if len(p.tables) > 0 && p.table().cell != nil {
+			p.popContainer()
+			p.finishCell(p.table())
+		}
+	case "tbl":
+		if len(p.tables) > 0 {
+			table := p.table()
+			p.tables = p.tables[:len(p.tables)-1]
+			p.finishTable(table)
+		}
+	}
+}
+
+func (p *docxParser) popContainer() {
+	if len(p.containers) > 1 {
+		p.containers = p.containers[:len(p.containers)-1]
+	}
+}
+
+// flushParagraph sends the paragraph's text to its container
+func (p *docxParser) flushParagraph(para *docxParagraph) {
+	text := strings.TrimSpace(para.text.String())
+	if text == "" {
+		return
+	}
+
+	if p.container() == "cell" && len(p.tables) > 0 && p.table().cell != nil {
+		cell := p.table().cell
+		cell.content = append(cell.content, text)
+		return
+	}
+
+	regionType, level := p.classify(para)
+	p.doc.addText(regionType, level, text)
+}
+
+// classify determines a paragraph's region type and level
+func (p *docxParser) classify(para *docxParagraph) (string, int) {
+	level := para.outline
+	if level == 0 {
+		level = p.styles.headingLevel(para.style)
+	}
+	if level > 0 {
+		return RegionHeading, level
+	}
+
+	numbered := para.numbering == 1 || (para.numbering == 0 && p.styles.numbered(para.style))
+	if numbered {
+		return RegionListItem, para.listLevel + 1
+	}
+	return RegionParagraph, 0
+}
+
+// finishCell places a cell in its row, or extends the vertical merge it continues
+func (p *docxParser) finishCell(table *docxTable) {
+	cell := table.cell
+	table.cell = nil
+	if len(table.rows) == 0 {
+		return
+	}
+
+	col := cell.cell.ColumnNumber
+	table.col = col + cell.cell.ColSpan
+	content := strings.Join(cell.content, "\n")
+
+	if cell.vMerge == "continue" {
+		if origin, ok := table.merges[col]; ok {
+			merged := &table.rows[origin[0]].Cells[origin[1]]
+			merged.RowSpan++
+			if content != "" {
+				merged.Content = strings.TrimSpace(merged.Content + "\n" + content)
+			}
+			return
+		}
+	}
+
+	row := &table.rows[len(table.rows)-1]
+	cell.cell.Content = content
+	row.Cells = append(row.Cells, cell.cell)
+
+	for c := col; c < col+cell.cell.ColSpan; c++ {
+		delete(table.merges, c)
+	}
+	if cell.vMerge == "restart" {
+		table.merges[col] = [2]int{len(table.rows) - 1, len(row.Cells) - 1}
+	}
+}
+
+// finishTable adds a table to the document, or flattens it into the enclosing cell
+func (p *docxParser) finishTable(table *docxTable) {
+	if p.container() == "cell" && len(p.tables) > 0 && p.table().cell != nil {
+		if text := renderTableText(table.rows); text != "" {
+			cell := p.table().cell
+			cell.content = append(cell.content, text)
+		}
+		return
+	}
+	p.doc.addTable(Table{Rows: table.rows})
+}
@@ -1,7 +1,14 @@
 /**
- * Embedding Client for FileProcessAgent
+ * Embedding Providers for FileProcessAgent
  *
- * Generates VoyageAI voyage-3 embeddings (1024 dimensions) for Document DNA semantic layer.
+ * Generates embeddings for the Document DNA semantic layer through an Embedder:
+ * - voyage: VoyageAI API (voyage-3, 1024 dimensions by default)
+ * - openai: any OpenAI-compatible /v1/embeddings endpoint - OpenAI itself or
+ *           a local server such as Ollama, TEI or vLLM
+ *
+ * Model and dimensions come from config (EMBEDDING_*). The dimensions are
+ * also the vector size of the Qdrant collection, so switching to a model of
+ * another size needs a new collection.
  */
 
 package processor
@@ -17,23 +24,94 @@ import (
 	"time"
 )
 
-// EmbeddingClient handles VoyageAI embedding generation
+// Embedder generates embeddings of a fixed size with one model
+type Embedder interface {
+	GenerateEmbedding(ctx context.Context, text string) ([]float32, error)
+	GenerateEmbeddingBatch(ctx context.Context, texts []string) ([][]float32, error)
+	Model() string
+	Dimensions() int
+}
+
+// Embedding providers
+const (
+	EmbeddingProviderVoyage = "voyage" // VoyageAI API
+	EmbeddingProviderOpenAI = "openai" // OpenAI-compatible /v1/embeddings endpoint
+)
+
+// Defaults of the voyage provider
+const (
+	defaultVoyageURL   = "https://api.voyageai.com/v1/embeddings"
+	defaultVoyageModel = "voyage-3"
+	// DefaultEmbeddingDimensions is the size of voyage-3 embeddings
+	DefaultEmbeddingDimensions = 1024
+)
+
+// voyageOutputDimensions lists the VoyageAI models that accept output_dimension and the sizes they offer
+var voyageOutputDimensions = map[string][]int{
+	"voyage-3-large":   {256, 512, 1024, 2048},
+	"voyage-3.5":       {256, 512, 1024, 2048},
+	"voyage-3.5-lite":  {256, 512, 1024, 2048},
+	"voyage-code-3":    {256, 512, 1024, 2048},
+	"voyage-context-3": {256, 512, 1024, 2048},
+}
+
+// voyageFixedDimensions is the only size of VoyageAI models that reject output_dimension
+var voyageFixedDimensions = map[string]int{
+	"voyage-3":              1024,
+	"voyage-3-lite":         512,
+	"voyage-multilingual-2": 1024,
+	"voyage-finance-2":      1024,
+	"voyage-law-2":          1024,
+	"voyage-code-2":         1536,
+	"voyage-large-2":        1536,
+	"voyage-2":              1024,
+}
+
+// EmbeddingConfig selects and configures an embedding provider
+type EmbeddingConfig struct {
+	Provider   string // EmbeddingProviderVoyage (default) or EmbeddingProviderOpenAI
+	URL        string // Embeddings endpoint (required for openai; voyage defaults to the VoyageAI API)
+	APIKey     string // Bearer token (required for voyage; optional for local servers)
+	Model      string // Required for openai; voyage defaults to voyage-3
+	Dimensions int    // Size of the model's embeddings (default 1024)
+}
+
+// NewEmbedder creates the embedder of the configured provider
+func NewEmbedder(cfg EmbeddingConfig) (Embedder, error) {
+	switch cfg.Provider {
+	case "", EmbeddingProviderVoyage:
+		return NewVoyageEmbedder(cfg)
+	case EmbeddingProviderOpenAI:
+		return NewOpenAIEmbedder(cfg)
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", cfg.Provider)
+	}
+}
+
+// EmbeddingClient generates embeddings over HTTP. VoyageAI and OpenAI-compatible
+// servers share the request ({input, model}) and response ({data: [{embedding, index}]}) shape.
 type EmbeddingClient struct {
-	apiKey     string
-	httpClient *http.Client
-	baseURL    string
+	apiKey          string // Sent as a bearer token when set (local servers need none)
+	httpClient      *http.Client
+	baseURL         string
+	model           string
+	dimensions      int
+	outputDimension int    // VoyageAI output_dimension, for models that support several sizes
+	provider        string // "VoyageAI" or "OpenAI-compatible" for logs
 }
 
 // VoyageEmbeddingRequest represents the request to VoyageAI API (single text)
 type VoyageEmbeddingRequest struct {
-	Input string `json:"input"`
-	Model string `json:"model"`
+	Input           string `json:"input"`
+	Model           string `json:"model"`
+	OutputDimension int    `json:"output_dimension,omitempty"`
 }
 
 // VoyageBatchEmbeddingRequest represents a batch request to VoyageAI API (multiple texts)
 type VoyageBatchEmbeddingRequest struct {
-	Input []string `json:"input"` // Array of texts for batch processing
-	Model string   `json:"model"`
+	Input           []string `json:"input"` // Array of texts for batch processing
+	Model           string   `json:"model"`
+	OutputDimension int      `json:"output_dimension,omitempty"`
 }
 
 // VoyageEmbeddingResponse represents the response from VoyageAI API
@@ -48,40 +126,118 @@ type VoyageEmbeddingResponse struct {
 	} `json:"usage"`
 }
 
-// NewEmbeddingClient creates a new embedding client
-func NewEmbeddingClient(apiKey string) (*EmbeddingClient, error) {
-	if apiKey == "" {
+// NewVoyageEmbedder creates an embedder for the VoyageAI API
+func NewVoyageEmbedder(cfg EmbeddingConfig) (*EmbeddingClient, error) {
+	if cfg.APIKey == "" {
 		return nil, fmt.Errorf("VoyageAI API key is required")
 	}
 
-	return &EmbeddingClient{
-		apiKey:  apiKey,
-		baseURL: "https://api.voyageai.com/v1/embeddings",
+	client := &EmbeddingClient{
+		apiKey:     cfg.APIKey,
+		baseURL:    valueOrDefault(cfg.URL, defaultVoyageURL),
+		model:      valueOrDefault(cfg.Model, defaultVoyageModel),
+		dimensions: cfg.Dimensions,
+		provider:   "VoyageAI",
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+	}
+	if client.dimensions <= 0 {
+		client.dimensions = DefaultEmbeddingDimensions
+		return client, nil
+	}
+
+	if sizes, ok := voyageOutputDimensions[client.model]; ok {
+		if !containsInt(sizes, client.dimensions) {
+			return nil, fmt.Errorf("VoyageAI model %s does not offer %d dimensions (supported: %v)",
+				client.model, client.dimensions, sizes)
+		}
+		// These models default to 1024
+		if client.dimensions != DefaultEmbeddingDimensions {
+			client.outputDimension = client.dimensions
+		}
+	} else if size, ok := voyageFixedDimensions[client.model]; ok && client.dimensions != size {
+		return nil, fmt.Errorf("VoyageAI model %s only produces %d-dimensional embeddings, not %d",
+			client.model, size, client.dimensions)
+	}
+	// Models in neither table are never sent output_dimension; a size mismatch fails on the first response
+	return client, nil
+}
+
+// containsInt reports whether values contains v
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// NewOpenAIEmbedder creates an embedder for an OpenAI-compatible endpoint
+// ("https://api.openai.com/v1/embeddings", "http://ollama:11434/v1/embeddings")
+func NewOpenAIEmbedder(cfg EmbeddingConfig) (*EmbeddingClient, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("embedding URL is required for OpenAI-compatible embeddings")
+	}
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("embedding model is required for OpenAI-compatible embeddings")
+	}
+
+	dimensions := cfg.Dimensions
+	if dimensions <= 0 {
+		dimensions = DefaultEmbeddingDimensions
+	}
+	return &EmbeddingClient{
+		apiKey:     cfg.APIKey,
+		baseURL:    cfg.URL,
+		model:      cfg.Model,
+		dimensions: dimensions,
+		provider:   "OpenAI-compatible",
+		httpClient: &http.Client{
+			Timeout: 120 * time.Second, // Local servers on CPU are slower than hosted APIs
+		},
 	}, nil
 }
 
-// GenerateEmbedding generates a 1024-dimensional embedding for the given text
+// valueOrDefault returns value, or fallback when value is empty
+func valueOrDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// Model returns the embedding model name
+func (e *EmbeddingClient) Model() string {
+	return e.model
+}
+
+// Dimensions returns the size of the model's embeddings
+func (e *EmbeddingClient) Dimensions() int {
+	return e.dimensions
+}
+
+// GenerateEmbedding generates an embedding of the configured dimensions for the given text
 func (e *EmbeddingClient) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
 	if text == "" {
 		return nil, fmt.Errorf("text is required")
 	}
 
-	log.Printf("Generating VoyageAI embedding (model: voyage-3, dimensions: 1024)")
+	log.Printf("Generating %s embedding (model: %s, dimensions: %d)", e.provider, e.model, e.dimensions)
 
-	// Truncate text if too long (VoyageAI has token limits)
+	// Truncate text if too long (embedding models have token limits)
 	maxChars := 16000 // Approximate limit
 	if len(text) > maxChars {
 		log.Printf("Warning: Text too long (%d chars), truncating to %d chars", len(text), maxChars)
-		text = text[:maxChars]
+		text = text[:runeBoundary(text, maxChars)] // Never split a UTF-8 sequence
 	}
 
 	// Build request
 	reqBody := VoyageEmbeddingRequest{
-		Input: text,
-		Model: "voyage-3",
+		Input:           text,
+		Model:           e.model,
+		OutputDimension: e.outputDimension,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -96,7 +252,9 @@ func (e *EmbeddingClient) GenerateEmbedding(ctx context.Context, text string) ([
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", e.apiKey))
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", e.apiKey))
+	}
 
 	// Send request
 	startTime := time.Now()
@@ -116,7 +274,7 @@ func (e *EmbeddingClient) GenerateEmbedding(ctx context.Context, text string) ([
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("VoyageAI API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("%s embedding API returned status %d: %s", e.provider, resp.StatusCode, string(body))
 	}
 
 	// Parse response
@@ -132,28 +290,28 @@ func (e *EmbeddingClient) GenerateEmbedding(ctx context.Context, text string) ([
 
 	embedding := voyageResp.Data[0].Embedding
 
-	log.Printf("VoyageAI embedding generated: dimensions=%d, tokens=%d, duration=%v",
-		len(embedding), voyageResp.Usage.TotalTokens, duration)
+	log.Printf("%s embedding generated: dimensions=%d, tokens=%d, duration=%v",
+		e.provider, len(embedding), voyageResp.Usage.TotalTokens, duration)
 
 	// Validate embedding dimensions
-	if len(embedding) != 1024 {
-		return nil, fmt.Errorf("unexpected embedding dimensions: got %d, expected 1024", len(embedding))
+	if len(embedding) != e.dimensions {
+		return nil, fmt.Errorf("unexpected embedding dimensions: got %d, expected %d", len(embedding), e.dimensions)
 	}
 
 	return embedding, nil
 }
 
-// GenerateEmbeddingBatch generates embeddings for multiple texts using VoyageAI batch API
-// Implements chunking at 100 texts per batch (VoyageAI API limit)
+// GenerateEmbeddingBatch generates embeddings for multiple texts using the batch API
+// Implements chunking at 100 texts per batch (within the VoyageAI and OpenAI limits)
 // Falls back to individual processing if batch API fails
 func (e *EmbeddingClient) GenerateEmbeddingBatch(ctx context.Context, texts []string) ([][]float32, error) {
 	if len(texts) == 0 {
 		return nil, fmt.Errorf("no texts provided")
 	}
 
-	log.Printf("Generating batch embeddings for %d texts (VoyageAI voyage-3, batch size: 100)", len(texts))
+	log.Printf("Generating batch embeddings for %d texts (%s %s, batch size: 100)", len(texts), e.provider, e.model)
 
-	// VoyageAI batch API limit: 100 texts per request
+	// Batch API limit: 100 texts per request
 	const batchSize = 100
 	allEmbeddings := make([][]float32, 0, len(texts))
 
@@ -189,7 +347,7 @@ func (e *EmbeddingClient) GenerateEmbeddingBatch(ctx context.Context, texts []st
 	return allEmbeddings, nil
 }
 
-// generateBatchInternal makes the actual batch API call to VoyageAI
+// generateBatchInternal makes the actual batch API call
 func (e *EmbeddingClient) generateBatchInternal(ctx context.Context, texts []string) ([][]float32, error) {
 	// Truncate texts if too long
 	maxChars := 16000
@@ -197,7 +355,7 @@ func (e *EmbeddingClient) generateBatchInternal(ctx context.Context, texts []str
 	for i, text := range texts {
 		if len(text) > maxChars {
 			log.Printf("Warning: Text %d too long (%d chars), truncating to %d chars", i, len(text), maxChars)
-			truncatedTexts[i] = text[:maxChars]
+			truncatedTexts[i] = text[:runeBoundary(text, maxChars)]
 		} else {
 			truncatedTexts[i] = text
 		}
@@ -205,8 +363,9 @@ func (e *EmbeddingClient) generateBatchInternal(ctx context.Context, texts []str
 
 	// Build batch request
 	reqBody := VoyageBatchEmbeddingRequest{
-		Input: truncatedTexts,
-		Model: "voyage-3",
+		Input:           truncatedTexts,
+		Model:           e.model,
+		OutputDimension: e.outputDimension,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -221,7 +380,9 @@ func (e *EmbeddingClient) generateBatchInternal(ctx context.Context, texts []str
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", e.apiKey))
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", e.apiKey))
+	}
 
 	// Send request
 	startTime := time.Now()
@@ -241,7 +402,7 @@ func (e *EmbeddingClient) generateBatchInternal(ctx context.Context, texts []str
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("VoyageAI batch API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("%s embedding batch API returned status %d: %s", e.provider, resp.StatusCode, string(body))
 	}
 
 	// Parse response
@@ -263,13 +424,13 @@ func (e *EmbeddingClient) generateBatchInternal(ctx context.Context, texts []str
 		embeddings[data.Index] = data.Embedding
 
 		// Validate embedding dimensions
-		if len(data.Embedding) != 1024 {
-			return nil, fmt.Errorf("unexpected embedding dimensions for text %d: got %d, expected 1024", data.Index, len(data.Embedding))
+		if len(data.Embedding) != e.dimensions {
+			return nil, fmt.Errorf("unexpected embedding dimensions for text %d: got %d, expected %d", data.Index, len(data.Embedding), e.dimensions)
 		}
 	}
 
-	log.Printf("VoyageAI batch embedding complete: %d texts, %d tokens, duration=%v",
-		len(texts), voyageResp.Usage.TotalTokens, duration)
+	log.Printf("%s batch embedding complete: %d texts, %d tokens, duration=%v",
+		e.provider, len(texts), voyageResp.Usage.TotalTokens, duration)
 
 	return embeddings, nil
 }
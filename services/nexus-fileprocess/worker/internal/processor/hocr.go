@@ -0,0 +1,165 @@
+/**
+ * hOCR Parsing - Word geometry from Tesseract
+ *
+ * Tesseract's hOCR output nests text areas (ocr_carea), paragraphs
+ * (ocr_par), lines (ocr_line and its header/caption variants) and words
+ * (ocrx_word). Each element's title carries its "bbox x0 y0 x1 y1" in image
+ * pixels; words also carry "x_wconf", their confidence from 0 to 100.
+ *
+ * The page confidence is the mean word confidence. Page text keeps lines on
+ * their own line and separates paragraphs with a blank line.
+ */
+
+package processor
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// hOCR classes of line elements
+var hocrLineClasses = map[string]bool{
+	"ocr_line": true, "ocr_header": true, "ocr_caption": true, "ocr_textfloat": true,
+}
+
+// ParseHOCR reads a Tesseract hOCR page into words, lines and page text
+func ParseHOCR(hocr string) (*OCRPage, error) {
+	decoder := xml.NewDecoder(strings.NewReader(hocr))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	page := &OCRPage{PageNumber: 1, Words: []OCRWord{}}
+	var classes []string // Class of every open element
+	var line *OCRLine
+	var lineWords []OCRWord
+	var word *OCRWord
+	var text strings.Builder
+	block, paragraph := 0, 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid hOCR: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			class := attr(t, "class")
+			classes = append(classes, class)
+			title := attr(t, "title")
+
+			switch {
+			case class == "ocr_page":
+				box := hocrBBox(title)
+				page.Width, page.Height = box.X+box.Width, box.Y+box.Height
+			case class == "ocr_carea":
+				block++
+			case class == "ocr_par":
+				paragraph++
+			case hocrLineClasses[class]:
+				line = &OCRLine{BoundingBox: hocrBBox(title), Block: max(block, 1), Paragraph: max(paragraph, 1)}
+				lineWords = nil
+			case class == "ocrx_word":
+				word = &OCRWord{BoundingBox: hocrBBox(title), Confidence: hocrConfidence(title)}
+				text.Reset()
+			}
+		case xml.CharData:
+			if word != nil {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if len(classes) == 0 {
+				break
+			}
+			class := classes[len(classes)-1]
+			classes = classes[:len(classes)-1]
+
+			switch {
+			case class == "ocrx_word" && word != nil:
+				word.Text = strings.TrimSpace(text.String())
+				if word.Text != "" {
+					page.Words = append(page.Words, *word)
+					lineWords = append(lineWords, *word)
+				}
+				word = nil
+			case hocrLineClasses[class] && line != nil:
+				if len(lineWords) > 0 {
+					texts := make([]string, len(lineWords))
+					for i, w := range lineWords {
+						texts[i] = w.Text
+						line.Confidence += w.Confidence
+					}
+					line.Text = strings.Join(texts, " ")
+					line.Confidence /= float64(len(lineWords))
+					page.Lines = append(page.Lines, *line)
+				}
+				line = nil
+			}
+		}
+	}
+
+	// Lines of a paragraph on consecutive lines, paragraphs separated by a blank line
+	var out strings.Builder
+	for i, l := range page.Lines {
+		if i > 0 {
+			out.WriteString("\n")
+			if l.Paragraph != page.Lines[i-1].Paragraph {
+				out.WriteString("\n")
+			}
+		}
+		out.WriteString(l.Text)
+	}
+	page.Text = out.String()
+
+	for _, w := range page.Words {
+		page.Confidence += w.Confidence
+	}
+	if len(page.Words) > 0 {
+		page.Confidence /= float64(len(page.Words))
+	}
+	return page, nil
+}
+
+// hocrProperty returns the value of a property of an hOCR title ("bbox 0 0 10 10; x_wconf 95")
+func hocrProperty(title, name string) []string {
+	for _, property := range strings.Split(title, ";") {
+		fields := strings.Fields(property)
+		if len(fields) > 0 && fields[0] == name {
+			return fields[1:]
+		}
+	}
+	return nil
+}
+
+// hocrBBox parses the bbox property into a bounding box
+func hocrBBox(title string) BoundingBox {
+	values := hocrProperty(title, "bbox")
+	if len(values) != 4 {
+		return BoundingBox{}
+	}
+	var coords [4]int
+	for i, value := range values {
+		coords[i], _ = strconv.Atoi(value)
+	}
+	return BoundingBox{X: coords[0], Y: coords[1], Width: coords[2] - coords[0], Height: coords[3] - coords[1]}
+}
+
+// hocrConfidence parses x_wconf (0-100) into a 0-1 confidence
+func hocrConfidence(title string) float64 {
+	values := hocrProperty(title, "x_wconf")
+	if len(values) != 1 {
+		return 0
+	}
+	confidence, err := strconv.ParseFloat(values[0], 64)
+	if err != nil || confidence < 0 {
+		return 0
+	}
+	return min(confidence, 100) / 100
+}
@@ -0,0 +1,304 @@
+/**
+ * OCR Languages - Language selection and detection
+ *
+ * Jobs may name their languages in metadata.language ("de", "de,en",
+ * ["ja", "en"]; ISO 639-1 or Tesseract codes). Otherwise they are detected:
+ * - From text, when a PDF text layer or text file has some: Unicode scripts
+ *   (kana → ja, Hangul → ko, Cyrillic → ru, ...) and, for Latin text,
+ *   the most frequent stopwords
+ * - From scans, with Tesseract's orientation and script detection (OSD)
+ *   followed by a first OCR pass whose text is detected as above
+ *
+ * Languages are kept as ISO 639-1 codes and mapped to Tesseract's
+ * traineddata names ("deu+eng") when Tesseract is configured.
+ */
+
+package processor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	minDetectLetters   = 20  // Letters needed before text is trusted for detection
+	minLanguageShare   = 0.2 // Share of letters a script needs to add its language
+	minStopwordMatches = 2   // Stopword hits needed to name a Latin language
+	minOSDConfidence   = 1.0 // Tesseract OSD script confidence below which the script is ignored
+)
+
+// tesseractLanguages maps ISO 639-1 codes to Tesseract traineddata names
+var tesseractLanguages = map[string]string{
+	"ar": "ara", "cs": "ces", "da": "dan", "de": "deu", "el": "ell", "en": "eng",
+	"es": "spa", "fa": "fas", "fi": "fin", "fr": "fra", "he": "heb", "hi": "hin",
+	"hu": "hun", "it": "ita", "ja": "jpn", "ko": "kor", "nl": "nld", "no": "nor",
+	"pl": "pol", "pt": "por", "ro": "ron", "ru": "rus", "sv": "swe", "th": "tha",
+	"tr": "tur", "uk": "ukr", "vi": "vie", "zh": "chi_sim",
+}
+
+// isoLanguages is the reverse of tesseractLanguages, plus ISO 639-2 aliases
+var isoLanguages = func() map[string]string {
+	m := map[string]string{"chi_tra": "zh", "ger": "de", "fre": "fr", "dut": "nl", "chi": "zh", "zho": "zh"}
+	for iso, tess := range tesseractLanguages {
+		m[tess] = iso
+	}
+	return m
+}()
+
+// osdScriptLanguages maps Tesseract OSD scripts to languages. Latin is absent:
+// its language is detected from text.
+var osdScriptLanguages = map[string]string{
+	"Arabic": "ar", "Cyrillic": "ru", "Devanagari": "hi", "Greek": "el", "Han": "zh",
+	"HanS": "zh", "HanT": "zh", "Hangul": "ko", "Hebrew": "he", "Japanese": "ja",
+	"Katakana": "ja", "Hiragana": "ja", "Korean": "ko", "Thai": "th",
+}
+
+// latinStopwords are frequent short words that tell Latin-script languages apart
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "that", "for", "with", "on", "are", "this", "be", "from", "by", "not"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "den", "von", "zu", "ein", "eine", "auf", "für", "sich", "dem", "des"},
+	"fr": {"le", "les", "et", "des", "est", "une", "pour", "que", "dans", "du", "pas", "sur", "au", "qui", "avec"},
+	"es": {"el", "los", "las", "y", "que", "en", "es", "por", "con", "una", "para", "del", "se", "como", "está"},
+	"it": {"il", "di", "che", "è", "per", "non", "una", "sono", "con", "della", "gli", "nel", "anche", "questo"},
+	"pt": {"os", "que", "do", "da", "em", "um", "uma", "para", "com", "não", "são", "mais", "pelo", "dos"},
+	"nl": {"het", "een", "en", "van", "is", "dat", "op", "te", "niet", "voor", "met", "zijn", "ook", "wordt"},
+}
+
+// ParseLanguages normalizes a metadata.language value to ISO 639-1 codes.
+// It returns nil for "auto", empty values and values without a known language.
+func ParseLanguages(value interface{}) []string {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = []string{v}
+	case []string:
+		items = v
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				items = append(items, s)
+			}
+		}
+	}
+
+	var languages []string
+	for _, item := range items {
+		for _, code := range strings.FieldsFunc(item, func(r rune) bool { return r == ',' || r == '+' || unicode.IsSpace(r) }) {
+			if language := normalizeLanguage(code); language != "" && !containsString(languages, language) {
+				languages = append(languages, language)
+			}
+		}
+	}
+	return languages
+}
+
+// normalizeLanguage maps "de", "DE", "de-AT", "deu" or "ger" to "de"
+func normalizeLanguage(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if _, ok := tesseractLanguages[code]; ok {
+		return code
+	}
+	if iso, ok := isoLanguages[code]; ok {
+		return iso
+	}
+	if i := strings.IndexAny(code, "-_"); i > 0 {
+		if _, ok := tesseractLanguages[code[:i]]; ok {
+			return code[:i]
+		}
+	}
+	return ""
+}
+
+// TesseractLanguage joins languages into Tesseract's "-l" form ("deu+eng")
+func TesseractLanguage(languages []string) string {
+	codes := make([]string, 0, len(languages))
+	for _, language := range languages {
+		if code, ok := tesseractLanguages[language]; ok {
+			codes = append(codes, code)
+		}
+	}
+	return strings.Join(codes, "+")
+}
+
+// DetectLanguages returns the languages of a text, the main language first.
+// Each script holding a fair share of the letters contributes one language.
+func DetectLanguages(text string) []string {
+	counts := map[string]int{}
+	total := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		total++
+		switch {
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			counts["kana"]++
+		case unicode.Is(unicode.Han, r):
+			counts["han"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["ar"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["ru"]++
+		case unicode.Is(unicode.Greek, r):
+			counts["el"]++
+		case unicode.Is(unicode.Hebrew, r):
+			counts["he"]++
+		case unicode.Is(unicode.Devanagari, r):
+			counts["hi"]++
+		case unicode.Is(unicode.Thai, r):
+			counts["th"]++
+		case unicode.Is(unicode.Latin, r):
+			counts["latin"]++
+		}
+	}
+	if total < minDetectLetters {
+		return nil
+	}
+
+	// Japanese mixes kanji with kana; Han without kana is Chinese
+	if counts["kana"] > 0 {
+		counts["ja"] = counts["kana"] + counts["han"]
+	} else {
+		counts["zh"] = counts["han"]
+	}
+	delete(counts, "kana")
+	delete(counts, "han")
+
+	scripts := make([]string, 0, len(counts))
+	for script, count := range counts {
+		if float64(count)/float64(total) >= minLanguageShare {
+			scripts = append(scripts, script)
+		}
+	}
+	sort.Slice(scripts, func(i, j int) bool {
+		if counts[scripts[i]] != counts[scripts[j]] {
+			return counts[scripts[i]] > counts[scripts[j]]
+		}
+		return scripts[i] < scripts[j]
+	})
+
+	languages := make([]string, 0, len(scripts))
+	for _, script := range scripts {
+		if script == "latin" {
+			script = latinLanguage(text)
+		}
+		if !containsString(languages, script) {
+			languages = append(languages, script)
+		}
+	}
+	return languages
+}
+
+// latinLanguage picks the Latin-script language with the most stopword hits, English by default
+func latinLanguage(text string) string {
+	words := map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		words[word]++
+	}
+
+	best, bestScore := "en", 0
+	for _, language := range []string{"en", "de", "fr", "es", "it", "pt", "nl"} {
+		score := 0
+		for _, stopword := range latinStopwords[language] {
+			score += words[stopword]
+		}
+		if score > bestScore {
+			best, bestScore = language, score
+		}
+	}
+	if bestScore < minStopwordMatches {
+		return "en"
+	}
+	return best
+}
+
+// DetectScript runs Tesseract's orientation and script detection on an image.
+// It returns the languages implied by the script, or nil for Latin and
+// uncertain scripts.
+func (t *TesseractOCR) DetectScript(ctx context.Context, image []byte) ([]string, error) {
+	cmd := exec.CommandContext(ctx, t.tesseractPath, "stdin", "stdout", "--psm", "0", "-l", "osd")
+	cmd.Stdin = bytes.NewReader(image)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("tesseract OSD failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	// "Script: Japanese" and "Script confidence: 2.51" lines
+	var script string
+	var confidence float64
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Script":
+			script = strings.TrimSpace(value)
+		case "Script confidence":
+			confidence, _ = strconv.ParseFloat(strings.TrimSpace(value), 64)
+		}
+	}
+	if script == "" {
+		return nil, fmt.Errorf("tesseract OSD found no script")
+	}
+	if confidence < minOSDConfidence {
+		return nil, nil
+	}
+	if language, ok := osdScriptLanguages[script]; ok {
+		return []string{language}, nil
+	}
+	return nil, nil
+}
+
+// Where a job's languages came from, recorded in its structural metadata
+const (
+	LanguageSourceMetadata = "metadata" // metadata.language
+	LanguageSourceDetected = "detected" // Detected from text or a first OCR pass
+	LanguageSourceDefault  = "default"  // Detection failed; the configured OCR languages
+)
+
+// ocrLanguages returns the job's OCR languages, detecting them on the first
+// page that needs OCR. The Tesseract pass used for detection is returned
+// too when it already ran with the detected languages.
+func (p *DocumentProcessor) ocrLanguages(ctx context.Context, req *ProcessRequest, pageNumber int, image []byte) ([]string, *OCRResult) {
+	if len(req.Languages) > 0 || p.tesseractOCR == nil {
+		// Without Tesseract, vision models are left to recognize any language
+		return req.Languages, nil
+	}
+
+	candidates := p.defaultLanguages
+	script, err := p.tesseractOCR.DetectScript(ctx, image)
+	if err != nil {
+		log.Printf("[Job %s page %d] Script detection failed: %v", req.JobID, pageNumber, err)
+	} else if len(script) > 0 {
+		candidates = append(script, p.defaultLanguages...)
+	}
+	candidates = ParseLanguages(candidates) // Deduplicated
+
+	firstPass, err := p.tesseractOCR.Process(ctx, image, candidates)
+	if err != nil {
+		log.Printf("[Job %s page %d] Language detection pass failed: %v, using %v", req.JobID, pageNumber, err, candidates)
+		req.Languages, req.LanguageSource = candidates, LanguageSourceDefault
+		return req.Languages, nil
+	}
+
+	req.Languages, req.LanguageSource = DetectLanguages(firstPass.Text), LanguageSourceDetected
+	if len(req.Languages) == 0 {
+		req.Languages, req.LanguageSource = candidates, LanguageSourceDefault
+	}
+	log.Printf("[Job %s page %d] OCR languages: %v (%s, script=%v)", req.JobID, pageNumber, req.Languages, req.LanguageSource, script)
+
+	if TesseractLanguage(req.Languages) != TesseractLanguage(candidates) {
+		return req.Languages, nil
+	}
+	return req.Languages, firstPass
+}
@@ -16,6 +16,7 @@ package processor
 import (
 	"context"
 	"log"
+	"strings"
 
 	"github.com/adverant/nexus/fileprocess-worker/internal/clients"
 )
@@ -32,15 +33,18 @@ type LayoutResult struct {
 	Regions      []LayoutRegion
 	Tables       []Table
 	ReadingOrder []int
+	Sheets       []string // Sheet names of spreadsheets, in workbook order
 }
 
 // LayoutRegion represents a region in the document
 type LayoutRegion struct {
 	ID          int
-	Type        string // "text", "image", "table", "header", "footer"
+	Type        string // "text", "image", "table", "header", "footer"; native extraction also "heading", "paragraph", "list_item"
 	BoundingBox BoundingBox
 	Confidence  float64
 	Content     string
+	Level       int // Heading level or list nesting (1 = top), 0 when not applicable
+	PageNumber  int // 0 when unknown
 }
 
 // Table represents an extracted table
@@ -49,6 +53,16 @@ type Table struct {
 	BoundingBox BoundingBox
 	Rows        []TableRow
 	Confidence  float64
+	Name        string        // Sheet the table was read from (spreadsheets)
+	HasHeader   bool          // First row holds column names
+	Columns     []TableColumn // Column names and inferred types (spreadsheets)
+}
+
+// TableColumn describes a column of a spreadsheet table
+type TableColumn struct {
+	Index int
+	Name  string // Header cell, empty without a header row
+	Type  string // ColumnTypeNumber, ColumnTypeDate, ColumnTypeCurrency or ColumnTypeText
 }
 
 // TableRow represents a row in a table
@@ -95,7 +109,7 @@ func (l *LayoutAnalyzer) analyzeWithVision(ctx context.Context, ocrResult *OCRRe
 	log.Printf("Sending %d bytes of image data to MageAgent for layout analysis", len(ocrResult.ImageData))
 
 	// Call MageAgent's layout analysis endpoint
-	resp, err := l.mageAgentClient.AnalyzeLayoutFromBytes(ctx, ocrResult.ImageData, "en")
+	resp, err := l.mageAgentClient.AnalyzeLayoutFromBytes(ctx, ocrResult.ImageData, strings.Join(ocrResult.Languages, ","))
 	if err != nil {
 		log.Printf("Vision analysis failed: %v, falling back to text analysis", err)
 		return l.analyzeFromText(ctx, ocrResult)
@@ -153,7 +167,7 @@ func (l *LayoutAnalyzer) convertMageAgentResponse(ctx context.Context, resp *cli
 	}
 
 	// Extract tables from elements with vision-based extraction (Phase 2.3)
-	tables := l.extractTablesFromElements(ctx, resp.Data.Elements, ocrResult.ImageData)
+	tables := l.extractTablesFromElements(ctx, resp.Data.Elements, ocrResult.ImageData, strings.Join(ocrResult.Languages, ","))
 
 	result := &LayoutResult{
 		Confidence:   resp.Data.Confidence,
@@ -183,7 +197,8 @@ func (l *LayoutAnalyzer) mapElementTypeToRegionType(elementType string) string {
 }
 
 // extractTablesFromElements extracts table elements with cell-by-cell extraction (Phase 2.3)
-func (l *LayoutAnalyzer) extractTablesFromElements(ctx context.Context, elements []clients.LayoutElement, imageData []byte) []Table {
+// language is a comma-separated list of the document's languages ("" when unknown)
+func (l *LayoutAnalyzer) extractTablesFromElements(ctx context.Context, elements []clients.LayoutElement, imageData []byte, language string) []Table {
 	tables := make([]Table, 0)
 
 	// Only process if we have image data and MageAgent client
@@ -197,7 +212,7 @@ func (l *LayoutAnalyzer) extractTablesFromElements(ctx context.Context, elements
 			log.Printf("Extracting table %d using GPT-4 Vision (97.9%% accuracy target)", element.ID)
 
 			// Extract table content using MageAgent
-			tableResp, err := l.mageAgentClient.ExtractTableFromBytes(ctx, imageData, "en")
+			tableResp, err := l.mageAgentClient.ExtractTableFromBytes(ctx, imageData, language)
 			if err != nil {
 				log.Printf("Table extraction failed for element %d: %v, using basic structure", element.ID, err)
 				// Fallback to basic structure
@@ -258,29 +273,84 @@ func (l *LayoutAnalyzer) extractTablesFromElements(ctx context.Context, elements
 	return tables
 }
 
-// extractRegions extracts layout regions from OCR result
+// extractRegions extracts layout regions from OCR result: one region per
+// text block when the OCR tier reported line geometry, else one per page
 func (l *LayoutAnalyzer) extractRegions(ocrResult *OCRResult) []LayoutRegion {
 	regions := []LayoutRegion{}
 
-	// Placeholder: Create one text region per page
 	for i, page := range ocrResult.Pages {
+		if len(page.Lines) > 0 {
+			regions = append(regions, blockRegions(page, len(regions))...)
+			continue
+		}
+
+		// Placeholder: the whole page, at its real size when known
+		box := BoundingBox{
+			Width:  8500,  // A4 width in pixels (assuming 300 DPI)
+			Height: 11000, // A4 height in pixels
+		}
+		if page.Width > 0 && page.Height > 0 {
+			box.Width, box.Height = page.Width, page.Height
+		}
+		pageNumber := page.PageNumber
+		if pageNumber == 0 {
+			pageNumber = i + 1
+		}
 		regions = append(regions, LayoutRegion{
-			ID:   i,
-			Type: "text",
-			BoundingBox: BoundingBox{
-				X:      0,
-				Y:      0,
-				Width:  8500,  // A4 width in pixels (assuming 300 DPI)
-				Height: 11000, // A4 height in pixels
-			},
-			Confidence: page.Confidence,
-			Content:    page.Text,
+			ID:          len(regions),
+			Type:        "text",
+			BoundingBox: box,
+			Confidence:  page.Confidence,
+			Content:     page.Text,
+			PageNumber:  pageNumber,
 		})
 	}
 
 	return regions
 }
 
+// blockRegions groups a page's lines by text block into regions bounding their lines
+func blockRegions(page OCRPage, firstID int) []LayoutRegion {
+	var regions []LayoutRegion
+	var lines []OCRLine
+
+	flush := func() {
+		if len(lines) == 0 {
+			return
+		}
+		region := LayoutRegion{ID: firstID + len(regions), Type: "text", PageNumber: page.PageNumber}
+		texts := make([]string, len(lines))
+		for i, line := range lines {
+			texts[i] = line.Text
+			region.Confidence += line.Confidence
+			region.BoundingBox = unionBox(region.BoundingBox, line.BoundingBox, i == 0)
+		}
+		region.Content = strings.Join(texts, "\n")
+		region.Confidence /= float64(len(lines))
+		regions = append(regions, region)
+		lines = nil
+	}
+
+	for i, line := range page.Lines {
+		if i > 0 && line.Block != page.Lines[i-1].Block {
+			flush()
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return regions
+}
+
+// unionBox returns the box covering a and b (just b when first)
+func unionBox(a, b BoundingBox, first bool) BoundingBox {
+	if first {
+		return b
+	}
+	x0, y0 := min(a.X, b.X), min(a.Y, b.Y)
+	x1, y1 := max(a.X+a.Width, b.X+b.Width), max(a.Y+a.Height, b.Y+b.Height)
+	return BoundingBox{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0}
+}
+
 // extractTables extracts tables from OCR result using Strategy Pattern
 // Strategy 1: Vision-based extraction (MageAgent) - 97.9% accuracy target
 // Strategy 2: Text-based heuristics (fallback) - lower accuracy
@@ -290,7 +360,7 @@ func (l *LayoutAnalyzer) extractTables(ocrResult *OCRResult) []Table {
 		log.Printf("Attempting vision-based table extraction (97.9%% accuracy target)")
 
 		ctx := context.Background()
-		tableResp, err := l.mageAgentClient.ExtractTableFromBytes(ctx, ocrResult.ImageData, "en")
+		tableResp, err := l.mageAgentClient.ExtractTableFromBytes(ctx, ocrResult.ImageData, strings.Join(ocrResult.Languages, ","))
 		if err != nil {
 			log.Printf("Vision-based table extraction failed: %v, falling back to text heuristics", err)
 			// Fall through to Strategy 2
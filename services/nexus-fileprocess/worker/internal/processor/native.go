@@ -0,0 +1,342 @@
+/**
+ * Native Document Extraction
+ *
+ * Office documents carry their text and structure explicitly, so they are
+ * parsed in-process instead of being rendered and OCRed: headings, list items
+ * and tables come straight from the markup, at confidence 1.0 and with no
+ * LLM call. Extractors produce the same OCRResult/LayoutResult pair as the
+ * OCR path, so the rest of the pipeline is unchanged.
+ */
+
+package processor
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Region types produced by native extractors (OCR layout analysis uses "text")
+const (
+	RegionHeading   = "heading"
+	RegionParagraph = "paragraph"
+	RegionListItem  = "list_item"
+	RegionTable     = "table"
+	RegionImage     = "image"
+	RegionNotes     = "speaker_notes"
+)
+
+// maxOOXMLPartSize bounds the uncompressed size of a single XML part
+const maxOOXMLPartSize = 256 << 20 // 256MB
+
+// nativeExtractor parses a document without OCR
+type nativeExtractor func(data []byte) (*OCRResult, *LayoutResult, error)
+
+// nativeExtractors maps MIME types to their native extractor
+var nativeExtractors = map[string]nativeExtractor{
+	MimeTypeDOCX: ExtractDOCX,
+	MimeTypeXLSX: ExtractXLSX,
+	MimeTypeCSV:  ExtractCSV,
+	MimeTypeTSV:  ExtractTSV,
+	MimeTypePPTX: ExtractPPTX,
+}
+
+// nativeExtractorFor returns the native extractor for a MIME type, or nil
+func nativeExtractorFor(mimeType string) nativeExtractor {
+	return nativeExtractors[mimeType]
+}
+
+// detectOOXMLMimeType identifies Office Open XML packages by their main part
+func detectOOXMLMimeType(data []byte) string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ""
+	}
+	for _, f := range zr.File {
+		switch f.Name {
+		case "word/document.xml":
+			return MimeTypeDOCX
+		case "xl/workbook.xml":
+			return MimeTypeXLSX
+		case "ppt/presentation.xml":
+			return MimeTypePPTX
+		}
+	}
+	return ""
+}
+
+// findZipFile returns the named part of a package, or nil
+func findZipFile(zr *zip.Reader, name string) *zip.File {
+	for _, f := range zr.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// openZipPart opens a package part, refusing parts that decompress beyond maxOOXMLPartSize.
+// archive/zip fails reads that run past the declared size, so the header check is enough.
+func openZipPart(f *zip.File) (io.ReadCloser, error) {
+	if f.UncompressedSize64 > maxOOXMLPartSize {
+		return nil, fmt.Errorf("%s is too large (%d bytes uncompressed)", f.Name, f.UncompressedSize64)
+	}
+	return f.Open()
+}
+
+// packageRelationship is a resolved relationship of a package part
+type packageRelationship struct {
+	kind   string // Relationship type, last path segment ("slide", "notesSlide", ...)
+	target string // Part path inside the package
+}
+
+// readRelationships reads the relationships of a part (<dir>/_rels/<name>.rels), keyed by ID
+func readRelationships(zr *zip.Reader, part string) (map[string]packageRelationship, error) {
+	rels := map[string]packageRelationship{}
+	dir, name := path.Split(part)
+	f := findZipFile(zr, dir+"_rels/"+name+".rels")
+	if f == nil {
+		return rels, nil
+	}
+
+	err := walkXML(f, func(t xml.StartElement) {
+		if t.Name.Local == "Relationship" && attr(t, "TargetMode") != "External" {
+			rels[attr(t, "Id")] = packageRelationship{
+				kind:   path.Base(attr(t, "Type")),
+				target: resolvePartPath(strings.TrimSuffix(dir, "/"), attr(t, "Target")),
+			}
+		}
+	})
+	return rels, err
+}
+
+// resolvePartPath resolves a relationship target relative to the source part's directory
+func resolvePartPath(dir, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(path.Clean(target), "/")
+	}
+	return path.Clean(path.Join(dir, target))
+}
+
+// relationshipID returns the r:id attribute (an "id" in the relationships namespace)
+func relationshipID(t xml.StartElement) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == "id" && a.Name.Space != "" {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// walkXML calls fn for every start element of a package part
+func walkXML(f *zip.File, fn func(xml.StartElement)) error {
+	rc, err := openZipPart(f)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if t, ok := token.(xml.StartElement); ok {
+			fn(t)
+		}
+	}
+}
+
+// attr returns the value of an attribute by local name, ignoring its namespace
+func attr(t xml.StartElement, local string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// nativeDocument accumulates the pages, regions and tables of a natively parsed document
+type nativeDocument struct {
+	tier    string
+	pages   []OCRPage
+	regions []LayoutRegion
+	tables  []Table
+	sheets  []string // Spreadsheet sheet names
+	blocks  []string // Rendered blocks of the current page
+}
+
+func newNativeDocument(tier string) *nativeDocument {
+	return &nativeDocument{tier: tier}
+}
+
+// pageNumber is the 1-based number of the page being filled
+func (d *nativeDocument) pageNumber() int {
+	return len(d.pages) + 1
+}
+
+// addText adds a heading, paragraph or list item. level is the heading level or list nesting (1 = top).
+// The returned region (nil for empty text) is only valid until the next add.
+func (d *nativeDocument) addText(regionType string, level int, text string) *LayoutRegion {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	d.regions = append(d.regions, LayoutRegion{
+		ID:         len(d.regions),
+		Type:       regionType,
+		Confidence: 1.0,
+		Content:    text,
+		Level:      level,
+		PageNumber: d.pageNumber(),
+	})
+
+	// Page text is lightweight Markdown so structure survives into chunking and embeddings
+	switch regionType {
+	case RegionHeading:
+		d.blocks = append(d.blocks, strings.Repeat("#", max(level, 1))+" "+text)
+	case RegionListItem:
+		d.blocks = append(d.blocks, strings.Repeat("  ", max(level-1, 0))+"- "+text)
+	case RegionNotes:
+		d.blocks = append(d.blocks, "Speaker notes: "+text)
+	default:
+		d.blocks = append(d.blocks, text)
+	}
+	return &d.regions[len(d.regions)-1]
+}
+
+// addImage records an embedded image; its description (alt text) is part of the page text
+func (d *nativeDocument) addImage(description string) *LayoutRegion {
+	description = strings.TrimSpace(description)
+	d.regions = append(d.regions, LayoutRegion{
+		ID:         len(d.regions),
+		Type:       RegionImage,
+		Confidence: 1.0,
+		Content:    description,
+		PageNumber: d.pageNumber(),
+	})
+	if description != "" {
+		d.blocks = append(d.blocks, "[Image: "+description+"]")
+	}
+	return &d.regions[len(d.regions)-1]
+}
+
+// addTable adds a table; its region and Table share the same ID
+func (d *nativeDocument) addTable(table Table) *LayoutRegion {
+	if len(table.Rows) == 0 {
+		return nil
+	}
+
+	id := len(d.regions)
+	text := renderTableText(table.Rows)
+	table.ID = id
+	table.Confidence = 1.0
+	d.tables = append(d.tables, table)
+	d.regions = append(d.regions, LayoutRegion{
+		ID:          id,
+		Type:        RegionTable,
+		BoundingBox: table.BoundingBox,
+		Confidence:  1.0,
+		Content:     text,
+		PageNumber:  d.pageNumber(),
+	})
+	d.blocks = append(d.blocks, text)
+	return &d.regions[len(d.regions)-1]
+}
+
+// breakPage starts a new page unless the current one is still empty
+func (d *nativeDocument) breakPage() {
+	if len(d.blocks) > 0 {
+		d.endPage()
+	}
+}
+
+// endPage finishes the current page, even when it has no text (an empty slide is still a page)
+func (d *nativeDocument) endPage() {
+	text := strings.Join(d.blocks, "\n\n")
+	d.pages = append(d.pages, OCRPage{
+		PageNumber: d.pageNumber(),
+		Text:       text,
+		Confidence: 1.0,
+		Words:      []OCRWord{},
+		TierUsed:   d.tier,
+	})
+	d.blocks = nil
+}
+
+// result finishes the document. A document without text still has one (empty) page.
+func (d *nativeDocument) result() (*OCRResult, *LayoutResult) {
+	d.breakPage()
+	if len(d.pages) == 0 {
+		d.pages = append(d.pages, OCRPage{PageNumber: 1, Confidence: 1.0, Words: []OCRWord{}, TierUsed: d.tier})
+	}
+
+	texts := make([]string, len(d.pages))
+	for i, page := range d.pages {
+		texts[i] = page.Text
+	}
+
+	readingOrder := make([]int, len(d.regions))
+	for i := range d.regions {
+		readingOrder[i] = i
+	}
+
+	ocrResult := &OCRResult{
+		Text:       strings.Join(texts, "\n\n"),
+		Confidence: 1.0,
+		Pages:      d.pages,
+		TierUsed:   d.tier,
+	}
+	layoutResult := &LayoutResult{
+		Confidence:   1.0,
+		Regions:      d.regions,
+		Tables:       d.tables,
+		ReadingOrder: readingOrder,
+		Sheets:       d.sheets,
+	}
+	return ocrResult, layoutResult
+}
+
+// renderTableText renders a table as a Markdown pipe table. Merged cells keep
+// their content in the top-left grid position; covered positions are empty.
+// Cells at negative columns are dropped.
+func renderTableText(rows []TableRow) string {
+	width := 0
+	for _, row := range rows {
+		for _, cell := range row.Cells {
+			if cell.ColumnNumber >= 0 {
+				width = max(width, cell.ColumnNumber+max(cell.ColSpan, 1))
+			}
+		}
+	}
+	if width == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i, row := range rows {
+		grid := make([]string, width)
+		for _, cell := range row.Cells {
+			if cell.ColumnNumber < 0 {
+				continue
+			}
+			content := strings.Join(strings.Fields(cell.Content), " ")
+			grid[cell.ColumnNumber] = strings.ReplaceAll(content, "|", "\\|")
+		}
+		sb.WriteString("| " + strings.Join(grid, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
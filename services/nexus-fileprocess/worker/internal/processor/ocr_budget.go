@@ -0,0 +1,168 @@
+/**
+ * OCR Budget - Spend tracking and limits for paid OCR tiers
+ *
+ * A page costs what MageAgent reports for it or, when it reports nothing,
+ * the per-page price of the model (or of the tier) from OCR_PRICES.
+ * Tesseract is free.
+ *
+ * A job's limit is the lowest of its metadata.maxCostUSD and what is left
+ * of its user's daily and monthly budgets. The cascade does not run a tier
+ * whose price would exceed the limit and keeps the best result so far.
+ *
+ * Other jobs of the user spend from the same budgets, so each paid call
+ * first reserves its estimated price in PostgreSQL (refused when the
+ * budgets cannot cover it) and settles the reservation with the actual cost.
+ */
+
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// errOCRBudgetExceeded marks OCR refused because it would exceed the job's budget
+var errOCRBudgetExceeded = errors.New("OCR budget exhausted")
+
+// OCRTierFileProcess is the price table key of PDFs converted by MageAgent /file-process
+const OCRTierFileProcess = "file_process"
+
+// OCRPriceTable holds per-page USD prices by model name or tier
+type OCRPriceTable map[string]float64
+
+// DefaultOCRPrices are per-page estimates for the vision tiers ($0.01-0.03 and $0.05-0.10)
+func DefaultOCRPrices() OCRPriceTable {
+	return OCRPriceTable{OCRTierBalanced: 0.02, OCRTierAccurate: 0.08, OCRTierFileProcess: 0.02}
+}
+
+// PageCost prices one page: the model's price (exact, else the longest key
+// the model name contains), else the tier's price
+func (t OCRPriceTable) PageCost(tier, model string) float64 {
+	if tier == OCRTierTesseract {
+		return 0
+	}
+	if model != "" {
+		if price, ok := t[model]; ok {
+			return price
+		}
+		lower := strings.ToLower(model)
+		best, bestLen := 0.0, 0
+		for key, price := range t {
+			if ocrTierLevels[key] == 0 && key != OCRTierFileProcess && len(key) > bestLen && strings.Contains(lower, strings.ToLower(key)) {
+				best, bestLen = price, len(key)
+			}
+		}
+		if bestLen > 0 {
+			return best
+		}
+	}
+	return t[tier]
+}
+
+// FileProcessEstimate prices sending a whole PDF of pages pages to MageAgent /file-process,
+// which OCRs and charges every page. An unknown page count (0) cannot be priced, so it is
+// refused when the job has a budget and counted as one page otherwise.
+func (t OCRPriceTable) FileProcessEstimate(pages int, budget *OCRBudget) (float64, error) {
+	if pages <= 0 {
+		if budget.Limited() {
+			return 0, fmt.Errorf("%w: /file-process cannot be priced without a page count, %s", errOCRBudgetExceeded, budget)
+		}
+		pages = 1
+	}
+	return t.PageCost(OCRTierFileProcess, "") * float64(pages), nil
+}
+
+// OCRBudget tracks a job's OCR spend against its limit
+type OCRBudget struct {
+	LimitUSD float64 // Most the job may spend, counting earlier attempts
+	Source   string  // What set the limit ("maxCostUSD", "daily budget", ...); "" for no limit
+	SpentUSD float64 // Spent by this job, including earlier attempts
+}
+
+// Limited reports whether the budget has a limit
+func (b *OCRBudget) Limited() bool {
+	return b != nil && b.Source != ""
+}
+
+// Allows reports whether spending cost more stays within the limit
+func (b *OCRBudget) Allows(cost float64) bool {
+	return !b.Limited() || b.SpentUSD+cost <= b.LimitUSD+1e-9
+}
+
+// String describes the budget for logs and decisions ("$0.0800 of $0.1000 spent (maxCostUSD)")
+func (b *OCRBudget) String() string {
+	if b == nil || b.Source == "" {
+		return "no limit"
+	}
+	return fmt.Sprintf("$%.4f of $%.4f spent (%s)", b.SpentUSD, b.LimitUSD, b.Source)
+}
+
+// resolveOCRBudget sets the job's OCR limit from metadata.maxCostUSD and its
+// user's budgets. Budgets that cannot be read are logged and not enforced.
+func (p *DocumentProcessor) resolveOCRBudget(ctx context.Context, req *ProcessRequest) {
+	budget := &OCRBudget{}
+	limit := func(usd float64, source string) {
+		if budget.Source == "" || usd < budget.LimitUSD {
+			budget.LimitUSD, budget.Source = usd, source
+		}
+	}
+
+	if maxCost, ok := req.Metadata["maxCostUSD"].(float64); ok && maxCost >= 0 {
+		limit(maxCost, "maxCostUSD")
+	}
+
+	// Reservations left by an earlier attempt of this job were never settled
+	if err := p.storage.ReleaseOCRReservations(ctx, req.JobID); err != nil {
+		log.Printf("[Job %s] WARNING: %v", req.JobID, err)
+	}
+
+	spend, err := p.storage.GetOCRSpend(ctx, req.JobID, req.UserID)
+	if err != nil {
+		log.Printf("[Job %s] WARNING: Could not read OCR budgets, not enforcing them: %v", req.JobID, err)
+	} else {
+		budget.SpentUSD = spend.JobSpentUSD
+		for _, b := range spend.Budgets {
+			// The user's spend already includes this job's earlier attempts
+			limit(b.RemainingUSD()+spend.JobSpentUSD, b.Period+" budget")
+		}
+	}
+
+	req.OCRBudget = budget
+	log.Printf("[Job %s] OCR budget: %s", req.JobID, budget)
+}
+
+// reserveOCR reserves the estimated cost of a paid OCR call against the user's budgets.
+// ok is false when they cannot cover it. Budgets that cannot be checked are logged and
+// not enforced. reserved is what chargeOCR settles.
+func (p *DocumentProcessor) reserveOCR(ctx context.Context, req *ProcessRequest, estimate float64) (reserved float64, ok bool) {
+	if estimate <= 0 {
+		return 0, true
+	}
+	ok, err := p.storage.ReserveOCRSpend(ctx, req.JobID, req.UserID, estimate)
+	if err != nil {
+		log.Printf("[Job %s] WARNING: Could not reserve OCR spend, not enforcing user budgets: %v", req.JobID, err)
+		return 0, true
+	}
+	if !ok {
+		return 0, false
+	}
+	return estimate, true
+}
+
+// chargeOCR adds the cost of a paid OCR call to the job, in memory and in processing_jobs,
+// and settles its reservation. It is recorded even if the job was cancelled meanwhile.
+func (p *DocumentProcessor) chargeOCR(ctx context.Context, req *ProcessRequest, cost, reserved float64) {
+	if cost <= 0 && reserved <= 0 {
+		return
+	}
+	if req.OCRBudget == nil {
+		req.OCRBudget = &OCRBudget{}
+	}
+	req.OCRBudget.SpentUSD += max(cost, 0)
+	if err := p.storage.AddJobOCRCost(context.WithoutCancel(ctx), req.JobID, max(cost, 0), reserved); err != nil {
+		log.Printf("[Job %s] WARNING: Failed to record OCR cost $%.4f: %v", req.JobID, cost, err)
+	}
+}
@@ -0,0 +1,324 @@
+/**
+ * OCR Pages - Page splitting for the per-page OCR cascade
+ *
+ * The OCR cascade runs on one page at a time, so a single smudged page does
+ * not push a whole scan to the most expensive tier:
+ * - Multi-page TIFFs are split by their image file directories (IFDs); each
+ *   page is decoded and re-encoded as PNG for Tesseract and the vision models
+ * - PDF pages without a text layer are rasterized with pdftoppm (poppler-utils);
+ *   every page is when the text layer cannot be parsed at all
+ *
+ * Page results are aggregated into one OCRResult: texts joined in page
+ * order, costs summed, confidence averaged over pages.
+ */
+
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image/png"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/image/tiff"
+)
+
+const (
+	ocrRasterDPI     = 300   // Resolution PDF pages are rendered at for OCR
+	maxTIFFPages     = 10000 // IFDs followed before a TIFF is considered corrupt
+	tiffTagSubfile   = 254   // NewSubfileType
+	tiffReducedImage = 1     // NewSubfileType bit of thumbnails and previews
+)
+
+// TIFFPageCount returns the number of pages of a TIFF, 0 if data is not a TIFF
+func TIFFPageCount(data []byte) int {
+	_, offsets := tiffPageOffsets(data)
+	return len(offsets)
+}
+
+// EachTIFFPage decodes the pages of a TIFF in order and passes each to fn as PNG
+func EachTIFFPage(data []byte, fn func(page int, image []byte) error) error {
+	order, offsets := tiffPageOffsets(data)
+	if len(offsets) == 0 {
+		return fmt.Errorf("invalid TIFF")
+	}
+
+	for i, offset := range offsets {
+		// Decode the file as if its header pointed at this page's IFD
+		reader := tiffPageReader{Reader: bytes.NewReader(data)}
+		copy(reader.header[:4], data[:4])
+		order.PutUint32(reader.header[4:], offset)
+
+		img, err := tiff.Decode(reader)
+		if err != nil {
+			return fmt.Errorf("failed to decode TIFF page %d: %w", i+1, err)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return fmt.Errorf("failed to encode TIFF page %d: %w", i+1, err)
+		}
+		if err := fn(i+1, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tiffPageOffsets walks the IFD chain of a classic TIFF, skipping reduced-resolution images
+func tiffPageOffsets(data []byte) (binary.ByteOrder, []uint32) {
+	if len(data) < 8 {
+		return nil, nil
+	}
+	var order binary.ByteOrder
+	switch string(data[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return nil, nil // Not a TIFF, or a BigTIFF
+	}
+
+	var offsets []uint32
+	seen := map[uint32]bool{}
+	offset := order.Uint32(data[4:8])
+	for offset != 0 && !seen[offset] && len(seen) < maxTIFFPages {
+		seen[offset] = true
+		start := int64(offset)
+		if start+2 > int64(len(data)) {
+			break
+		}
+		count := int64(order.Uint16(data[start:]))
+		next := start + 2 + 12*count
+		if next+4 > int64(len(data)) {
+			break
+		}
+
+		reduced := false
+		for entry := start + 2; entry < next; entry += 12 {
+			if order.Uint16(data[entry:]) == tiffTagSubfile {
+				reduced = order.Uint32(data[entry+8:])&tiffReducedImage != 0
+			}
+		}
+		if !reduced {
+			offsets = append(offsets, offset)
+		}
+		offset = order.Uint32(data[next:])
+	}
+	return order, offsets
+}
+
+// tiffPageReader serves a TIFF with its header replaced, without copying the file
+type tiffPageReader struct {
+	*bytes.Reader
+	header [8]byte
+}
+
+func (r tiffPageReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.Reader.ReadAt(p, off)
+	for i := 0; i < n && off+int64(i) < int64(len(r.header)); i++ {
+		p[i] = r.header[off+int64(i)]
+	}
+	return n, err
+}
+
+// PDFRasterizer renders PDF pages to PNG with pdftoppm
+type PDFRasterizer struct {
+	path    string
+	tempDir string
+}
+
+// NewPDFRasterizer returns nil when the pdftoppm binary is not available
+func NewPDFRasterizer(path, tempDir string) *PDFRasterizer {
+	if path == "" {
+		return nil
+	}
+	if _, err := exec.LookPath(path); err != nil {
+		return nil
+	}
+	return &PDFRasterizer{path: path, tempDir: tempDir}
+}
+
+// Render renders the given pages of a PDF in order and passes each to fn as PNG.
+// nil pages renders every page.
+func (r *PDFRasterizer) Render(ctx context.Context, data []byte, pages []int, fn func(page int, image []byte) error) error {
+	if r.tempDir != "" {
+		if err := os.MkdirAll(r.tempDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create temp dir: %w", err)
+		}
+	}
+	dir, err := os.MkdirTemp(r.tempDir, "pdf-raster-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input.pdf")
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+	if pages == nil {
+		return r.renderAll(ctx, dir, input, fn)
+	}
+
+	for _, page := range pages {
+		number := strconv.Itoa(page)
+		prefix := filepath.Join(dir, "page-"+number)
+		cmd := exec.CommandContext(ctx, r.path, "-r", strconv.Itoa(ocrRasterDPI), "-f", number, "-l", number, "-png", "-singlefile", input, prefix)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("pdftoppm failed on page %d: %w: %s", page, err, strings.TrimSpace(string(output)))
+		}
+
+		image, err := os.ReadFile(prefix + ".png")
+		if err != nil {
+			return fmt.Errorf("failed to read rendered page %d: %w", page, err)
+		}
+		os.Remove(prefix + ".png")
+
+		if err := fn(page, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderAll renders every page of input into dir in one pdftoppm run, then passes
+// the pages to fn in order. pdftoppm names them page-1.png, or page-01.png and so on.
+func (r *PDFRasterizer) renderAll(ctx context.Context, dir, input string, fn func(page int, image []byte) error) error {
+	cmd := exec.CommandContext(ctx, r.path, "-r", strconv.Itoa(ocrRasterDPI), "-png", input, filepath.Join(dir, "page"))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pdftoppm failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "page-*.png"))
+	if err != nil {
+		return fmt.Errorf("failed to list rendered pages: %w", err)
+	}
+	rendered := make(map[int]string, len(files))
+	numbers := make([]int, 0, len(files))
+	for _, file := range files {
+		number, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "page-"), ".png"))
+		if err != nil {
+			continue
+		}
+		rendered[number] = file
+		numbers = append(numbers, number)
+	}
+	if len(numbers) == 0 {
+		return fmt.Errorf("pdftoppm rendered no pages")
+	}
+	sort.Ints(numbers)
+
+	for _, page := range numbers {
+		image, err := os.ReadFile(rendered[page])
+		if err != nil {
+			return fmt.Errorf("failed to read rendered page %d: %w", page, err)
+		}
+		os.Remove(rendered[page])
+
+		if err := fn(page, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AggregateOCRPages combines single-page cascade results into one result.
+// The result's tier is the most expensive tier any page needed.
+func AggregateOCRPages(results []*OCRResult) *OCRResult {
+	aggregate := &OCRResult{Pages: make([]OCRPage, 0, len(results))}
+	texts := make([]string, 0, len(results))
+	models := []string{}
+
+	for _, result := range results {
+		for _, page := range result.Pages {
+			if page.TierUsed == "" {
+				page.TierUsed = result.TierUsed
+			}
+			aggregate.Pages = append(aggregate.Pages, page)
+			texts = append(texts, page.Text)
+			aggregate.Confidence += page.Confidence
+		}
+		aggregate.Cost += result.Cost
+		aggregate.Duration += result.Duration
+		aggregate.Decisions = append(aggregate.Decisions, result.Decisions...)
+
+		if aggregate.TierUsed == "" || ocrTierRank(result.TierUsed) > ocrTierRank(aggregate.TierUsed) {
+			aggregate.TierUsed = result.TierUsed
+		}
+		if result.Model != "" && !containsString(models, result.Model) {
+			models = append(models, result.Model)
+		}
+	}
+
+	if len(aggregate.Pages) > 0 {
+		aggregate.Confidence /= float64(len(aggregate.Pages))
+	}
+	aggregate.Text = strings.Join(texts, "\n\n")
+	aggregate.Model = strings.Join(models, ",")
+	return aggregate
+}
+
+// ocrTierRank orders result tiers ("tier2_gpt-4o") by cost: tesseract, tier 2, tier 3
+func ocrTierRank(tier string) int {
+	return ocrTierLevels[ocrPolicyTier(tier)]
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ocrPages runs the cascade on every page passed to it by each (EachTIFFPage
+// or PDFRasterizer.Render) and aggregates the results. total is 0 when the
+// page count is not known up front.
+func (p *DocumentProcessor) ocrPages(ctx context.Context, req *ProcessRequest, total int, each func(fn func(page int, image []byte) error) error) (*OCRResult, error) {
+	startTime := time.Now()
+	results := make([]*OCRResult, 0, total)
+
+	err := each(func(page int, image []byte) error {
+		if err := checkCancelled(ctx, req.JobID, "OCR"); err != nil {
+			return err
+		}
+		result, err := p.ocrPageCascade(ctx, req, page, image)
+		if err != nil {
+			return fmt.Errorf("page %d: %w", page, err)
+		}
+		results = append(results, result)
+
+		stats := map[string]interface{}{
+			"pagesCompleted": len(results),
+			"ocrTier":        result.TierUsed,
+		}
+		if total <= 0 {
+			reportProgress(ctx, req.JobID, StageOCR, 0, fmt.Sprintf("OCR page %d", len(results)), stats)
+			return nil
+		}
+		stats["pageCount"] = total
+		reportProgress(ctx, req.JobID, StageOCR, float64(len(results))/float64(total),
+			fmt.Sprintf("OCR page %d/%d", len(results), total), stats)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	aggregate := AggregateOCRPages(results)
+	aggregate.Duration = time.Since(startTime)
+	log.Printf("[Job %s] Per-page OCR complete: pages=%d, tier=%s, confidence=%.2f, cost=$%.4f",
+		req.JobID, len(aggregate.Pages), aggregate.TierUsed, aggregate.Confidence, aggregate.Cost)
+	return aggregate, nil
+}
@@ -0,0 +1,232 @@
+/**
+ * OCR Policy - Escalation rules of the OCR cascade
+ *
+ * The cascade runs its tiers in order until one reaches its confidence
+ * threshold:
+ * - tesseract: local Tesseract (free)
+ * - tier2: MageAgent vision with preferAccuracy=false (balanced)
+ * - tier3: MageAgent vision with preferAccuracy=true (most accurate)
+ *
+ * The worker's policy comes from config (OCR_TIERS, OCR_THRESHOLD_*,
+ * OCR_MAX_TIER). Jobs override it with metadata.ocrPolicy:
+ *
+ *	{"mode": "local_only", "tiers": ["tier2", "tier3"],
+ *	 "thresholds": {"tesseract": 0.8}, "maxTier": "tier2"}
+ *
+ * When no tier reaches its threshold, the most confident result is used.
+ * Every tier run or skipped is recorded as an OCRDecision.
+ */
+
+package processor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OCR cascade tiers
+const (
+	OCRTierTesseract = "tesseract"
+	OCRTierBalanced  = "tier2"
+	OCRTierAccurate  = "tier3"
+)
+
+// ocrTierLevels orders tiers by cost
+var ocrTierLevels = map[string]int{OCRTierTesseract: 1, OCRTierBalanced: 2, OCRTierAccurate: 3}
+
+// ocrModes are named tier selections for metadata.ocrPolicy.mode
+var ocrModes = map[string][]string{
+	"full":        {OCRTierTesseract, OCRTierBalanced, OCRTierAccurate},
+	"local_only":  {OCRTierTesseract},
+	"vision_only": {OCRTierBalanced, OCRTierAccurate},
+}
+
+// Outcomes of an OCRDecision
+const (
+	OCRDecisionAccepted  = "accepted"
+	OCRDecisionEscalated = "escalated"
+	OCRDecisionFailed    = "failed"
+	OCRDecisionSkipped   = "skipped"
+)
+
+// OCRPolicy controls which tiers the cascade runs and when it escalates
+type OCRPolicy struct {
+	Tiers      []string           // Tiers in the order they are tried
+	Thresholds map[string]float64 // Confidence at which a tier's result is accepted (missing: always accepted)
+	MaxTier    string             // Most expensive tier that may run ("" for no limit)
+
+	tiersFromJob bool // Tiers chosen by the job, not adjusted for preferAccuracy
+}
+
+// OCRDecision records why the cascade accepted, escalated or skipped a tier on a page
+type OCRDecision struct {
+	Page       int
+	Tier       string
+	Model      string
+	Outcome    string // OCRDecisionAccepted, OCRDecisionEscalated, OCRDecisionFailed or OCRDecisionSkipped
+	Confidence float64
+	Threshold  float64
+	Cost       float64 // USD spent on the tier for this page
+	Reason     string
+}
+
+// DefaultOCRPolicy is Tesseract (≥ 0.85), then balanced vision (≥ 0.90), then accurate vision
+func DefaultOCRPolicy() OCRPolicy {
+	return OCRPolicy{
+		Tiers:      []string{OCRTierTesseract, OCRTierBalanced, OCRTierAccurate},
+		Thresholds: map[string]float64{OCRTierTesseract: 0.85, OCRTierBalanced: 0.90, OCRTierAccurate: 0},
+		MaxTier:    OCRTierAccurate,
+	}
+}
+
+// Validate checks tier names and thresholds
+func (p OCRPolicy) Validate() error {
+	if len(p.Tiers) == 0 {
+		return fmt.Errorf("no OCR tiers")
+	}
+	seen := map[string]bool{}
+	for _, tier := range p.Tiers {
+		if ocrTierLevels[tier] == 0 {
+			return fmt.Errorf("unknown OCR tier %q", tier)
+		}
+		if seen[tier] {
+			return fmt.Errorf("OCR tier %q listed twice", tier)
+		}
+		seen[tier] = true
+	}
+	for tier, threshold := range p.Thresholds {
+		if ocrTierLevels[tier] == 0 {
+			return fmt.Errorf("threshold for unknown OCR tier %q", tier)
+		}
+		if threshold < 0 || threshold > 1 {
+			return fmt.Errorf("threshold for %s must be between 0 and 1, got %v", tier, threshold)
+		}
+	}
+	if p.MaxTier != "" && ocrTierLevels[p.MaxTier] == 0 {
+		return fmt.Errorf("unknown max OCR tier %q", p.MaxTier)
+	}
+	return nil
+}
+
+// WithOverrides applies a metadata.ocrPolicy object to a copy of the policy
+func (p OCRPolicy) WithOverrides(value interface{}) (OCRPolicy, error) {
+	overrides, ok := value.(map[string]interface{})
+	if !ok {
+		return p, fmt.Errorf("ocrPolicy must be an object")
+	}
+
+	policy := OCRPolicy{
+		Tiers:        append([]string(nil), p.Tiers...),
+		Thresholds:   make(map[string]float64, len(p.Thresholds)),
+		MaxTier:      p.MaxTier,
+		tiersFromJob: p.tiersFromJob,
+	}
+	for tier, threshold := range p.Thresholds {
+		policy.Thresholds[tier] = threshold
+	}
+
+	if mode, ok := overrides["mode"].(string); ok {
+		tiers, known := ocrModes[mode]
+		if !known {
+			return p, fmt.Errorf("unknown OCR mode %q", mode)
+		}
+		policy.Tiers = append([]string(nil), tiers...)
+		policy.tiersFromJob = true
+	}
+	if value, ok := overrides["tiers"]; ok {
+		list, ok := value.([]interface{})
+		if !ok {
+			return p, fmt.Errorf("ocrPolicy.tiers must be a list")
+		}
+		policy.Tiers = policy.Tiers[:0]
+		for _, item := range list {
+			tier, ok := item.(string)
+			if !ok {
+				return p, fmt.Errorf("ocrPolicy.tiers must hold tier names")
+			}
+			policy.Tiers = append(policy.Tiers, tier)
+		}
+		policy.tiersFromJob = true
+	}
+	if value, ok := overrides["thresholds"]; ok {
+		thresholds, ok := value.(map[string]interface{})
+		if !ok {
+			return p, fmt.Errorf("ocrPolicy.thresholds must be an object")
+		}
+		for tier, threshold := range thresholds {
+			t, ok := threshold.(float64)
+			if !ok {
+				return p, fmt.Errorf("ocrPolicy.thresholds.%s must be a number", tier)
+			}
+			policy.Thresholds[tier] = t
+		}
+	}
+	if value, ok := overrides["maxTier"]; ok {
+		tier, ok := value.(string)
+		if !ok {
+			return p, fmt.Errorf("ocrPolicy.maxTier must be a tier name")
+		}
+		policy.MaxTier = tier
+	}
+
+	if err := policy.Validate(); err != nil {
+		return p, err
+	}
+	return policy, nil
+}
+
+// PreferringAccuracy skips the balanced vision tier when the accurate one
+// follows it, unless the job chose its tiers
+func (p OCRPolicy) PreferringAccuracy() OCRPolicy {
+	if p.tiersFromJob || !containsString(p.Tiers, OCRTierAccurate) {
+		return p
+	}
+	tiers := make([]string, 0, len(p.Tiers))
+	for _, tier := range p.Tiers {
+		if tier != OCRTierBalanced {
+			tiers = append(tiers, tier)
+		}
+	}
+	p.Tiers = tiers
+	return p
+}
+
+// LocalOnly restricts the policy to Tesseract, whatever tiers it or the job
+// chose, for workers without MageAgent
+func (p OCRPolicy) LocalOnly() OCRPolicy {
+	p.Tiers = append([]string(nil), ocrModes["local_only"]...)
+	p.MaxTier = OCRTierTesseract
+	return p
+}
+
+// allows reports whether the policy's maximum tier permits a tier
+func (p OCRPolicy) allows(tier string) bool {
+	return p.MaxTier == "" || ocrTierLevels[tier] <= ocrTierLevels[p.MaxTier]
+}
+
+// AllowsVision reports whether any MageAgent vision tier may run. PDFs sent
+// whole to MageAgent /file-process are OCRed by vision models too.
+func (p OCRPolicy) AllowsVision() bool {
+	for _, tier := range p.Tiers {
+		if tier != OCRTierTesseract && p.allows(tier) {
+			return true
+		}
+	}
+	return false
+}
+
+// String summarizes the policy for logs ("tesseract≥0.85 → tier2≥0.90 → tier3")
+func (p OCRPolicy) String() string {
+	steps := make([]string, 0, len(p.Tiers))
+	for _, tier := range p.Tiers {
+		if !p.allows(tier) {
+			continue
+		}
+		if threshold := p.Thresholds[tier]; threshold > 0 {
+			steps = append(steps, fmt.Sprintf("%s≥%.2f", tier, threshold))
+		} else {
+			steps = append(steps, tier)
+		}
+	}
+	return strings.Join(steps, " → ")
+}
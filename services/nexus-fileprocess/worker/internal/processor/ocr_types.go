@@ -20,6 +20,8 @@ type OCRResult struct {
 	Cost       float64 // Cost of OCR operation (if applicable)
 	Duration   time.Duration
 	ImageData  []byte  // Original image data for layout analysis (optional)
+	Languages  []string // ISO 639-1 languages the text was recognized in (optional)
+	Decisions  []OCRDecision // Why the cascade accepted, escalated or skipped each tier (optional)
 }
 
 // OCRPage represents a single page of OCR results
@@ -28,6 +30,19 @@ type OCRPage struct {
 	Text       string
 	Confidence float64
 	Words      []OCRWord
+	Lines      []OCRLine // Words grouped into lines, when the tier reports geometry
+	Width      int       // Page size in the units of the bounding boxes, 0 when unknown
+	Height     int
+	TierUsed   string // Tier that produced the page ("pdf_text_layer" or an OCR tier)
+}
+
+// OCRLine is a line of words within a text block
+type OCRLine struct {
+	Text        string
+	Confidence  float64
+	BoundingBox BoundingBox
+	Block       int // Text block (column or area) the line belongs to, from 1
+	Paragraph   int // Paragraph within the page, from 1
 }
 
 // OCRWord represents a single word with bounding box
@@ -0,0 +1,580 @@
+/**
+ * PDF Text Layer Extraction
+ *
+ * Born-digital PDFs carry their text in the page content streams, and reading
+ * it is exact and free where OCR is neither. The text-showing operators of
+ * each page (including form XObjects) are interpreted to place every glyph;
+ * glyphs are grouped into words by their spacing and into lines by their
+ * baselines. Word bounding boxes are in pixels at 96 DPI from the top-left
+ * of the page's media box.
+ *
+ * A page is escalated to OCR when it has no usable text:
+ * - No text at all (scans, text drawn as outlines)
+ * - A page-sized image with only a few characters on top (a stamped scan)
+ * - Garbled text: glyphs without a Unicode mapping (missing or broken
+ *   ToUnicode CMaps), control or private-use characters, or too few letters
+ *   and digits to be prose
+ */
+
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// OCRTierPDFTextLayer is reported for pages read from a PDF's embedded text layer
+const OCRTierPDFTextLayer = "pdf_text_layer"
+
+const (
+	pdfPixelsPerPoint   = 96.0 / 72.0
+	pdfMaxFormDepth     = 8    // Nesting of form XObjects followed per page
+	pdfMaxUnmappedRatio = 0.1  // Share of characters without a Unicode mapping
+	pdfMinAlnumRatio    = 0.5  // Share of letters and digits among non-space characters
+	pdfScanCoverage     = 0.5  // Share of the page covered by an image to count as a scan
+	pdfScanMinChars     = 100  // Characters needed on a scanned page to trust its text
+	pdfWordGap          = 0.2  // Horizontal gap between glyphs that separates words, in ems
+	pdfDefaultWidth     = 500. // Glyph width for fonts without metrics, in 1/1000 em
+)
+
+// PDFTextLayer is the embedded text of a PDF, page by page
+type PDFTextLayer struct {
+	Pages    []OCRPage // One per page; pages needing OCR have no text
+	NeedsOCR []int     // Numbers of the pages without usable text
+}
+
+// ExtractPDFTextLayer reads the text layer of every page of a PDF
+func ExtractPDFTextLayer(data []byte) (layer *PDFTextLayer, err error) {
+	// The PDF parser panics on malformed objects
+	defer func() {
+		if r := recover(); r != nil {
+			layer, err = nil, fmt.Errorf("invalid PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PDF: %w", err)
+	}
+
+	layer = &PDFTextLayer{}
+	for number := 1; number <= reader.NumPage(); number++ {
+		page := OCRPage{PageNumber: number, Words: []OCRWord{}}
+		if content, ok := readPDFPage(reader.Page(number)); ok && content.usable() {
+			page.Words, page.Text = content.words()
+			page.Width = int(content.width * pdfPixelsPerPoint)
+			page.Height = int(content.height * pdfPixelsPerPoint)
+			page.Confidence = 1.0
+			page.TierUsed = OCRTierPDFTextLayer
+		} else {
+			layer.NeedsOCR = append(layer.NeedsOCR, number)
+		}
+		layer.Pages = append(layer.Pages, page)
+	}
+	if len(layer.Pages) == 0 {
+		return nil, fmt.Errorf("invalid PDF: no pages")
+	}
+	return layer, nil
+}
+
+// pdfPageObject matches the type entry of a page object, not of a /Pages node
+var pdfPageObject = regexp.MustCompile(`/Type\s*/Page\b`)
+
+// PDFPageCount counts the pages of a PDF whose text layer could not be read: the
+// page tree when the parser can open it, else the page objects in the raw file.
+// Returns 0 when neither finds a page, e.g. pages in compressed object streams.
+func PDFPageCount(data []byte) (count int) {
+	defer func() {
+		if r := recover(); r != nil {
+			count = len(pdfPageObject.FindAllIndex(data, -1))
+		}
+	}()
+
+	if reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data))); err == nil && reader.NumPage() > 0 {
+		return reader.NumPage()
+	}
+	return len(pdfPageObject.FindAllIndex(data, -1))
+}
+
+// Text returns the text of the pages with a usable text layer
+func (l *PDFTextLayer) Text() string {
+	needsOCR := make(map[int]bool, len(l.NeedsOCR))
+	for _, number := range l.NeedsOCR {
+		needsOCR[number] = true
+	}
+	var texts []string
+	for _, page := range l.Pages {
+		if !needsOCR[page.PageNumber] {
+			texts = append(texts, page.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// Result combines the text layer with the OCR of the pages that needed it.
+// ocr may be nil when no page needed OCR. ocr holds either every page of the
+// PDF or just the pages that needed OCR, matched by page number; any other
+// OCR result (such as one page for the whole file) is used as a whole.
+func (l *PDFTextLayer) Result(ocr *OCRResult) *OCRResult {
+	result := &OCRResult{TierUsed: OCRTierPDFTextLayer}
+	pages := l.Pages
+
+	if ocr != nil {
+		for i := range ocr.Pages {
+			if ocr.Pages[i].TierUsed == "" {
+				ocr.Pages[i].TierUsed = ocr.TierUsed
+			}
+		}
+		byNumber := make(map[int]OCRPage, len(ocr.Pages))
+		for _, page := range ocr.Pages {
+			byNumber[page.PageNumber] = page
+		}
+		if len(ocr.Pages) != len(l.Pages) && len(ocr.Pages) != len(l.NeedsOCR) {
+			return ocr
+		}
+		pages = make([]OCRPage, len(l.Pages))
+		copy(pages, l.Pages)
+		for _, number := range l.NeedsOCR {
+			page, ok := byNumber[number]
+			if !ok {
+				return ocr
+			}
+			pages[number-1] = page
+		}
+
+		result.Model = ocr.Model
+		result.Cost = ocr.Cost
+		result.Decisions = ocr.Decisions
+		result.Duration = ocr.Duration
+		result.TierUsed = ocr.TierUsed
+		if len(l.NeedsOCR) < len(l.Pages) {
+			result.TierUsed = OCRTierPDFTextLayer + "+" + ocr.TierUsed
+		}
+	}
+
+	texts := make([]string, len(pages))
+	for i, page := range pages {
+		texts[i] = page.Text
+		result.Confidence += page.Confidence
+	}
+	result.Text = strings.Join(texts, "\n\n")
+	result.Confidence /= float64(len(pages))
+	result.Pages = pages
+	return result
+}
+
+// pdfGlyph is a character placed on the page, in PDF user space (points, y up)
+type pdfGlyph struct {
+	text  string
+	x, y  float64 // Baseline origin
+	width float64
+	size  float64 // Font size on the page
+}
+
+// pdfPageContent is what a page draws: its glyphs and how much of it images cover
+type pdfPageContent struct {
+	glyphs    []pdfGlyph
+	left, top float64 // Media box corner, for top-down coordinates
+	width     float64
+	height    float64
+	area      float64
+	imageArea float64 // Largest image drawn, in square points
+}
+
+// readPDFPage interprets a page's content streams; ok is false for pages that cannot be read
+func readPDFPage(page pdf.Page) (content *pdfPageContent, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			content, ok = nil, false
+		}
+	}()
+
+	if page.V.IsNull() {
+		return nil, false
+	}
+	content = &pdfPageContent{}
+	if box := pdfInherited(page.V, "MediaBox"); box.Len() == 4 {
+		x0, y0 := box.Index(0).Float64(), box.Index(1).Float64()
+		x1, y1 := box.Index(2).Float64(), box.Index(3).Float64()
+		content.left, content.top = math.Min(x0, x1), math.Max(y0, y1)
+		content.width, content.height = math.Abs(x1-x0), math.Abs(y1-y0)
+		content.area = content.width * content.height
+	}
+
+	interp := &pdfInterpreter{content: content, state: pdfTextState{ctm: pdfIdentity, scale: 1}}
+	interp.run(page.V.Key("Contents"), page.Resources(), 0)
+	return content, true
+}
+
+// pdfInherited looks a page attribute up through the page tree
+func pdfInherited(v pdf.Value, key string) pdf.Value {
+	for ; !v.IsNull(); v = v.Key("Parent") {
+		if value := v.Key(key); !value.IsNull() {
+			return value
+		}
+	}
+	return pdf.Value{}
+}
+
+// usable reports whether the page's text can stand in for OCR
+func (c *pdfPageContent) usable() bool {
+	var total, unmapped, alnum int
+	for _, glyph := range c.glyphs {
+		for _, r := range glyph.text {
+			switch {
+			case unicode.IsSpace(r):
+				continue
+			case r == unicode.ReplacementChar, unicode.IsControl(r), unicode.Is(unicode.Co, r):
+				unmapped++
+			case unicode.IsLetter(r), unicode.IsDigit(r):
+				alnum++
+			}
+			total++
+		}
+	}
+
+	if total == 0 {
+		return false
+	}
+	if c.area > 0 && c.imageArea >= pdfScanCoverage*c.area && total < pdfScanMinChars {
+		return false
+	}
+	return float64(unmapped) <= pdfMaxUnmappedRatio*float64(total) &&
+		float64(alnum) >= pdfMinAlnumRatio*float64(total)
+}
+
+// words groups the glyphs into words and lines, in content stream order
+func (c *pdfPageContent) words() ([]OCRWord, string) {
+	var words []OCRWord
+	var lines []string
+	var line []string
+	var word strings.Builder
+	var prev *pdfGlyph
+	var minX, maxX, minY, maxY float64
+
+	flushWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		words = append(words, OCRWord{
+			Text:       word.String(),
+			Confidence: 1.0,
+			BoundingBox: BoundingBox{
+				X:      int((minX - c.left) * pdfPixelsPerPoint),
+				Y:      int((c.top - maxY) * pdfPixelsPerPoint),
+				Width:  int(math.Ceil((maxX - minX) * pdfPixelsPerPoint)),
+				Height: int(math.Ceil((maxY - minY) * pdfPixelsPerPoint)),
+			},
+		})
+		line = append(line, word.String())
+		word.Reset()
+	}
+	flushLine := func() {
+		flushWord()
+		if len(line) > 0 {
+			lines = append(lines, strings.Join(line, " "))
+			line = nil
+		}
+	}
+
+	space := false
+	for i := range c.glyphs {
+		glyph := &c.glyphs[i]
+		if strings.TrimSpace(glyph.text) == "" {
+			space = true
+			continue
+		}
+
+		if prev != nil {
+			size := math.Max(glyph.size, prev.size)
+			gap := glyph.x - (prev.x + prev.width)
+			switch {
+			case math.Abs(glyph.y-prev.y) > size/2 || gap < -size:
+				flushLine()
+			case space || gap > pdfWordGap*size:
+				flushWord()
+			}
+		}
+		space = false
+
+		// Box from the descender to the ascender, approximated from the font size
+		bottom, top := glyph.y-0.2*glyph.size, glyph.y+0.8*glyph.size
+		if word.Len() == 0 {
+			minX, maxX, minY, maxY = glyph.x, glyph.x+glyph.width, bottom, top
+		} else {
+			minX, maxX = math.Min(minX, glyph.x), math.Max(maxX, glyph.x+glyph.width)
+			minY, maxY = math.Min(minY, bottom), math.Max(maxY, top)
+		}
+		word.WriteString(glyph.text)
+		prev = glyph
+	}
+	flushLine()
+
+	if words == nil {
+		words = []OCRWord{}
+	}
+	return words, strings.Join(lines, "\n")
+}
+
+// pdfMatrix is an affine transform [a b c d e f]
+type pdfMatrix [6]float64
+
+var pdfIdentity = pdfMatrix{1, 0, 0, 1, 0, 0}
+
+// mul returns m applied before n
+func (m pdfMatrix) mul(n pdfMatrix) pdfMatrix {
+	return pdfMatrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// pdfTextState is the graphics and text state relevant to placing glyphs
+type pdfTextState struct {
+	ctm, tm, tlm pdfMatrix
+	font         *pdfFont
+	size         float64
+	charSpace    float64
+	wordSpace    float64
+	scale        float64 // Horizontal scaling (Tz / 100)
+	leading      float64
+	rise         float64
+}
+
+// pdfFont decodes a font's character codes and knows their widths
+type pdfFont struct {
+	encoding pdf.TextEncoding
+	twoByte  bool // Composite (Type0) fonts use two-byte codes
+	widths   map[int]float64
+	fallback float64
+}
+
+func newPDFFont(v pdf.Value) *pdfFont {
+	font := &pdfFont{encoding: pdf.Font{V: v}.Encoder(), widths: map[int]float64{}, fallback: pdfDefaultWidth}
+
+	if v.Key("Subtype").Name() == "Type0" {
+		// CID widths: [first [w1 w2 ...]] or [first last w]
+		font.twoByte = true
+		descendant := v.Key("DescendantFonts").Index(0)
+		if dw := descendant.Key("DW"); dw.Kind() == pdf.Integer || dw.Kind() == pdf.Real {
+			font.fallback = dw.Float64()
+		} else {
+			font.fallback = 1000
+		}
+		w := descendant.Key("W")
+		for i := 0; i+1 < w.Len(); {
+			first := int(w.Index(i).Float64())
+			if list := w.Index(i + 1); list.Kind() == pdf.Array {
+				for j := 0; j < list.Len(); j++ {
+					font.widths[first+j] = list.Index(j).Float64()
+				}
+				i += 2
+				continue
+			}
+			if i+2 >= w.Len() {
+				break
+			}
+			last, width := int(w.Index(i+1).Float64()), w.Index(i+2).Float64()
+			for code := first; code <= last && code-first < 65536; code++ {
+				font.widths[code] = width
+			}
+			i += 3
+		}
+		return font
+	}
+
+	first := int(v.Key("FirstChar").Int64())
+	widths := v.Key("Widths")
+	for i := 0; i < widths.Len(); i++ {
+		font.widths[first+i] = widths.Index(i).Float64()
+	}
+	return font
+}
+
+// width returns a glyph's width in 1/1000 em
+func (f *pdfFont) width(code int) float64 {
+	if w, ok := f.widths[code]; ok && w > 0 {
+		return w
+	}
+	return f.fallback
+}
+
+// pdfInterpreter places the glyphs of content streams
+type pdfInterpreter struct {
+	content *pdfPageContent
+	state   pdfTextState
+}
+
+// run interprets a content stream (or array of streams) with its resources
+func (p *pdfInterpreter) run(stream, resources pdf.Value, depth int) {
+	fonts := map[string]*pdfFont{}
+	var stack []pdfTextState
+
+	pdf.Interpret(stream, func(stk *pdf.Stack, op string) {
+		args := make([]pdf.Value, stk.Len())
+		for i := len(args) - 1; i >= 0; i-- {
+			args[i] = stk.Pop()
+		}
+		num := func(i int) float64 {
+			if i < len(args) {
+				return args[i].Float64()
+			}
+			return 0
+		}
+		matrix := func() pdfMatrix {
+			return pdfMatrix{num(0), num(1), num(2), num(3), num(4), num(5)}
+		}
+		s := &p.state
+
+		switch op {
+		case "q":
+			stack = append(stack, *s)
+		case "Q":
+			if n := len(stack); n > 0 {
+				*s = stack[n-1]
+				stack = stack[:n-1]
+			}
+		case "cm":
+			s.ctm = matrix().mul(s.ctm)
+		case "BT":
+			s.tm, s.tlm = pdfIdentity, pdfIdentity
+		case "Tc":
+			s.charSpace = num(0)
+		case "Tw":
+			s.wordSpace = num(0)
+		case "Tz":
+			s.scale = num(0) / 100
+		case "TL":
+			s.leading = num(0)
+		case "Ts":
+			s.rise = num(0)
+		case "Tf":
+			if len(args) == 2 {
+				name := args[0].Name()
+				if fonts[name] == nil {
+					fonts[name] = newPDFFont(resources.Key("Font").Key(name))
+				}
+				s.font = fonts[name]
+				s.size = num(1)
+			}
+		case "TD":
+			s.leading = -num(1)
+			p.moveLine(num(0), num(1))
+		case "Td":
+			p.moveLine(num(0), num(1))
+		case "Tm":
+			s.tm, s.tlm = matrix(), matrix()
+		case "T*":
+			p.moveLine(0, -s.leading)
+		case "'":
+			if len(args) == 1 {
+				p.moveLine(0, -s.leading)
+				p.show(args[0].RawString())
+			}
+		case "\"":
+			if len(args) == 3 {
+				s.wordSpace, s.charSpace = num(0), num(1)
+				p.moveLine(0, -s.leading)
+				p.show(args[2].RawString())
+			}
+		case "Tj":
+			if len(args) == 1 {
+				p.show(args[0].RawString())
+			}
+		case "TJ":
+			if len(args) != 1 {
+				break
+			}
+			for i := 0; i < args[0].Len(); i++ {
+				item := args[0].Index(i)
+				if item.Kind() == pdf.String {
+					p.show(item.RawString())
+				} else {
+					p.advance(-item.Float64() / 1000 * s.size * s.scale)
+				}
+			}
+		case "Do":
+			if len(args) != 1 {
+				break
+			}
+			xobject := resources.Key("XObject").Key(args[0].Name())
+			switch xobject.Key("Subtype").Name() {
+			case "Image":
+				// The unit square scaled by the CTM
+				area := math.Abs(s.ctm[0]*s.ctm[3] - s.ctm[1]*s.ctm[2])
+				p.content.imageArea = math.Max(p.content.imageArea, area)
+			case "Form":
+				if depth >= pdfMaxFormDepth {
+					break
+				}
+				saved := *s
+				if m := xobject.Key("Matrix"); m.Len() == 6 {
+					s.ctm = pdfMatrix{m.Index(0).Float64(), m.Index(1).Float64(), m.Index(2).Float64(),
+						m.Index(3).Float64(), m.Index(4).Float64(), m.Index(5).Float64()}.mul(s.ctm)
+				}
+				formResources := xobject.Key("Resources")
+				if formResources.IsNull() {
+					formResources = resources
+				}
+				p.run(xobject, formResources, depth+1)
+				*s = saved
+			}
+		}
+	})
+}
+
+// moveLine starts a new line offset from the start of the current one
+func (p *pdfInterpreter) moveLine(tx, ty float64) {
+	p.state.tlm = pdfMatrix{1, 0, 0, 1, tx, ty}.mul(p.state.tlm)
+	p.state.tm = p.state.tlm
+}
+
+// advance moves the text position horizontally, in unscaled text space
+func (p *pdfInterpreter) advance(tx float64) {
+	p.state.tm = pdfMatrix{1, 0, 0, 1, tx, 0}.mul(p.state.tm)
+}
+
+// show places the glyphs of a text string
+func (p *pdfInterpreter) show(raw string) {
+	s := &p.state
+	if s.font == nil {
+		return
+	}
+
+	step := 1
+	if s.font.twoByte {
+		step = 2
+	}
+	for i := 0; i+step <= len(raw); i += step {
+		code := int(raw[i])
+		if step == 2 {
+			code = code<<8 | int(raw[i+1])
+		}
+		width := s.font.width(code) / 1000
+
+		trm := pdfMatrix{s.size * s.scale, 0, 0, s.size, 0, s.rise}.mul(s.tm).mul(s.ctm)
+		p.content.glyphs = append(p.content.glyphs, pdfGlyph{
+			text:  s.font.encoding.Decode(raw[i : i+step]),
+			x:     trm[4],
+			y:     trm[5],
+			width: width * math.Hypot(trm[0], trm[1]),
+			size:  math.Hypot(trm[2], trm[3]),
+		})
+
+		tx := width*s.size + s.charSpace
+		if step == 1 && code == ' ' {
+			tx += s.wordSpace
+		}
+		p.advance(tx * s.scale)
+	}
+}
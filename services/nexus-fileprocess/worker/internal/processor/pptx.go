@@ -0,0 +1,412 @@
+/**
+ * PPTX Extraction
+ *
+ * Reads the slides of an OOXML PresentationML deck in presentation order.
+ * Every slide becomes one page (PageNumber = slide number), so GraphRAG page
+ * boundaries point at slides. Per slide, in reading order:
+ * - Title placeholders -> heading (subtitle = level 2)
+ * - Text boxes -> paragraphs; bulleted paragraphs (body placeholders, or an
+ *   explicit bullet) -> list items nested by their outline level
+ * - Tables -> Table with gridSpan/rowSpan mapped to ColSpan/RowSpan
+ * - Pictures -> image regions carrying their alt text
+ * - Speaker notes (the notes slide's body) -> speaker_notes region, last
+ *
+ * Reading order is titles first, then shapes top-to-bottom, left-to-right
+ * when every shape has a position (shapes inheriting their position from the
+ * slide layout keep document order). Bounding boxes are in pixels at 96 DPI.
+ */
+
+package processor
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MimeTypePPTX is the MIME type of PowerPoint decks
+const MimeTypePPTX = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+
+// OCRTierNativePPTX is reported as the tier of natively parsed PowerPoint decks
+const OCRTierNativePPTX = "native_pptx"
+
+// emuPerPixel converts DrawingML EMUs to pixels at 96 DPI
+const emuPerPixel = 9525
+
+// ExtractPPTX parses a PowerPoint deck into one page per slide
+func ExtractPPTX(data []byte) (*OCRResult, *LayoutResult, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid PPTX package: %w", err)
+	}
+
+	slides, err := readPPTXSlideList(zr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc := newNativeDocument(OCRTierNativePPTX)
+	for i, part := range slides {
+		f := findZipFile(zr, part)
+		if f == nil {
+			doc.endPage()
+			continue
+		}
+
+		shapes, err := readPPTXShapes(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read slide %d: %w", i+1, err)
+		}
+		for _, shape := range orderPPTXShapes(shapes) {
+			shape.addTo(doc)
+		}
+
+		if notes := readPPTXNotes(zr, part); notes != "" {
+			doc.addText(RegionNotes, 0, notes)
+		}
+		doc.endPage()
+	}
+
+	ocrResult, layoutResult := doc.result()
+	return ocrResult, layoutResult, nil
+}
+
+// readPPTXSlideList returns the slide parts in presentation order
+func readPPTXSlideList(zr *zip.Reader) ([]string, error) {
+	f := findZipFile(zr, "ppt/presentation.xml")
+	if f == nil {
+		return nil, fmt.Errorf("invalid PPTX package: ppt/presentation.xml not found")
+	}
+
+	targets, err := readRelationships(zr, "ppt/presentation.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read presentation relationships: %w", err)
+	}
+
+	var slides []string
+	err = walkXML(f, func(t xml.StartElement) {
+		if t.Name.Local == "sldId" {
+			if part, ok := targets[relationshipID(t)]; ok {
+				slides = append(slides, part.target)
+			}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read presentation: %w", err)
+	}
+	return slides, nil
+}
+
+// readPPTXNotes returns the speaker notes of a slide, or ""
+func readPPTXNotes(zr *zip.Reader, slidePart string) string {
+	rels, err := readRelationships(zr, slidePart)
+	if err != nil {
+		return ""
+	}
+
+	for _, rel := range rels {
+		if rel.kind != "notesSlide" {
+			continue
+		}
+		f := findZipFile(zr, rel.target)
+		if f == nil {
+			return ""
+		}
+		shapes, err := readPPTXShapes(f)
+		if err != nil {
+			return ""
+		}
+
+		// The notes body placeholder; the slide image and slide number are not notes
+		var lines []string
+		for _, shape := range shapes {
+			if shape.placeholder == "body" {
+				for _, para := range shape.paragraphs {
+					if text := strings.TrimSpace(para.text); text != "" {
+						lines = append(lines, text)
+					}
+				}
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	return ""
+}
+
+// pptxShape is a shape of a slide: a text box, table or picture
+type pptxShape struct {
+	kind        string // "text", "table", "image" or "graphic" (charts, diagrams)
+	placeholder string // Placeholder type ("title", "body", ...), "" for free shapes
+	description string // Alt text
+	box         BoundingBox
+	hasBox      bool
+	paragraphs  []pptxParagraph
+	rows        []TableRow
+}
+
+type pptxParagraph struct {
+	text   string
+	level  int // Outline level (0 = top)
+	bullet int // 1 bulleted, -1 bullets off, 0 inherited
+}
+
+// isTitle reports title placeholders
+func (s *pptxShape) isTitle() bool {
+	return s.placeholder == "title" || s.placeholder == "ctrTitle" || s.placeholder == "subTitle"
+}
+
+// addTo adds the shape's regions to the document
+func (s *pptxShape) addTo(doc *nativeDocument) {
+	var region *LayoutRegion
+
+	switch s.kind {
+	case "image":
+		region = doc.addImage(s.description)
+	case "table":
+		region = doc.addTable(Table{BoundingBox: s.box, Rows: s.rows})
+	case "text":
+		if s.isTitle() {
+			level := 1
+			if s.placeholder == "subTitle" {
+				level = 2
+			}
+			texts := make([]string, 0, len(s.paragraphs))
+			for _, para := range s.paragraphs {
+				texts = append(texts, para.text)
+			}
+			region = doc.addText(RegionHeading, level, strings.Join(strings.Fields(strings.Join(texts, " ")), " "))
+			break
+		}
+
+		regionType := RegionParagraph
+		switch s.placeholder {
+		case "dt", "sldNum":
+			return // Date and slide number boilerplate
+		case "ftr":
+			regionType = "footer"
+		case "hdr":
+			regionType = "header"
+		}
+
+		// Paragraphs of one text box share its bounding box
+		for _, para := range s.paragraphs {
+			bulleted := para.bullet == 1 || (para.bullet == 0 && s.placeholder == "body")
+			if bulleted && regionType == RegionParagraph {
+				region = doc.addText(RegionListItem, para.level+1, para.text)
+			} else {
+				region = doc.addText(regionType, 0, para.text)
+			}
+			if region != nil {
+				region.BoundingBox = s.box
+			}
+		}
+		return
+	}
+
+	if region != nil {
+		region.BoundingBox = s.box
+	}
+}
+
+// orderPPTXShapes puts titles first, then the remaining shapes top-to-bottom,
+// left-to-right if all of them have a position
+func orderPPTXShapes(shapes []*pptxShape) []*pptxShape {
+	var titles, others []*pptxShape
+	positioned := true
+	for _, shape := range shapes {
+		if shape.isTitle() {
+			titles = append(titles, shape)
+			continue
+		}
+		others = append(others, shape)
+		positioned = positioned && shape.hasBox
+	}
+
+	if positioned {
+		sort.SliceStable(others, func(i, j int) bool {
+			a, b := others[i].box, others[j].box
+			if a.Y != b.Y {
+				return a.Y < b.Y
+			}
+			return a.X < b.X
+		})
+	}
+	return append(titles, others...)
+}
+
+// pptxTable is a table being read from a graphic frame
+type pptxTable struct {
+	rows []TableRow
+	col  int
+	cell *TableCell // nil while reading a cell covered by a merge
+	text []string
+}
+
+// readPPTXShapes reads the shapes of a slide (or notes slide) in document order.
+// Group shapes are flattened.
+func readPPTXShapes(f *zip.File) ([]*pptxShape, error) {
+	rc, err := openZipPart(f)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var shapes []*pptxShape
+	var shape *pptxShape
+	var para *pptxParagraph
+	var table *pptxTable
+	var text strings.Builder
+	inText := false
+
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return shapes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "Fallback":
+				if err := decoder.Skip(); err != nil {
+					return nil, err
+				}
+			case "sp", "cxnSp":
+				shape = &pptxShape{kind: "text"}
+			case "pic":
+				shape = &pptxShape{kind: "image"}
+			case "graphicFrame":
+				shape = &pptxShape{kind: "graphic"}
+			case "ph":
+				if shape != nil {
+					shape.placeholder = attr(t, "type")
+					if shape.placeholder == "" {
+						shape.placeholder = "body" // Content placeholders default to body
+					}
+				}
+			case "cNvPr":
+				if shape != nil {
+					shape.description = attr(t, "descr")
+				}
+			case "off":
+				if shape != nil && !shape.hasBox {
+					shape.box.X = emuToPixels(attr(t, "x"))
+					shape.box.Y = emuToPixels(attr(t, "y"))
+					shape.hasBox = true
+				}
+			case "ext":
+				if shape != nil && shape.hasBox && shape.box.Width == 0 && attr(t, "cx") != "" {
+					shape.box.Width = emuToPixels(attr(t, "cx"))
+					shape.box.Height = emuToPixels(attr(t, "cy"))
+				}
+			case "tbl":
+				if shape != nil {
+					shape.kind = "table"
+					table = &pptxTable{}
+				}
+			case "tr":
+				if table != nil {
+					table.rows = append(table.rows, TableRow{RowNumber: len(table.rows)})
+					table.col = 0
+				}
+			case "tc":
+				if table != nil {
+					table.text = nil
+					table.cell = nil
+					if attr(t, "hMerge") != "1" && attr(t, "hMerge") != "true" && attr(t, "vMerge") != "1" && attr(t, "vMerge") != "true" {
+						table.cell = &TableCell{ColumnNumber: table.col, Confidence: 1.0, RowSpan: 1, ColSpan: 1}
+						if n, err := strconv.Atoi(attr(t, "gridSpan")); err == nil && n > 1 {
+							table.cell.ColSpan = n
+						}
+						if n, err := strconv.Atoi(attr(t, "rowSpan")); err == nil && n > 1 {
+							table.cell.RowSpan = n
+						}
+					}
+				}
+			case "p":
+				para = &pptxParagraph{}
+				text.Reset()
+			case "pPr":
+				if para != nil {
+					if level, err := strconv.Atoi(attr(t, "lvl")); err == nil {
+						para.level = level
+					}
+				}
+			case "buNone":
+				if para != nil {
+					para.bullet = -1
+				}
+			case "buChar", "buAutoNum", "buBlip":
+				if para != nil {
+					para.bullet = 1
+				}
+			case "t":
+				inText = para != nil
+			case "br":
+				if para != nil {
+					text.WriteString("\n")
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if para == nil {
+					break
+				}
+				para.text = strings.TrimSpace(text.String())
+				switch {
+				case table != nil:
+					if para.text != "" {
+						table.text = append(table.text, para.text)
+					}
+				case shape != nil && para.text != "":
+					shape.paragraphs = append(shape.paragraphs, *para)
+				}
+				para = nil
+			case "tc":
+				if table != nil {
+					if table.cell != nil {
+						table.cell.Content = strings.Join(table.text, "\n")
+						row := &table.rows[len(table.rows)-1]
+						row.Cells = append(row.Cells, *table.cell)
+					}
+					table.col++
+				}
+			case "tbl":
+				if table != nil && shape != nil {
+					shape.rows = table.rows
+				}
+				table = nil
+			case "sp", "cxnSp", "pic", "graphicFrame":
+				if shape != nil && (shape.kind != "text" || len(shape.paragraphs) > 0) && shape.kind != "graphic" {
+					shapes = append(shapes, shape)
+				}
+				shape = nil
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+}
+
+// emuToPixels converts an EMU coordinate attribute to pixels at 96 DPI
+func emuToPixels(value string) int {
+	emu, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return int(emu / emuPerPixel)
+}
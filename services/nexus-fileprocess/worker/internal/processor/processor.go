@@ -7,6 +7,10 @@
  * - Table extraction with 97.9% accuracy target
  * - Document DNA generation (semantic + structural + original)
  * - VoyageAI embeddings for semantic search
+ *
+ * In local-only mode (air-gapped deployments) no external AI service is
+ * called: OCR is Tesseract only, layout comes from the text heuristic,
+ * embeddings from a local server, and GraphRAG and artifact storage are skipped.
  */
 
 package processor
@@ -14,6 +18,7 @@ package processor
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -23,6 +28,7 @@ import (
 	"time"
 
 	"github.com/adverant/nexus/fileprocess-worker/internal/clients"
+	processingerrors "github.com/adverant/nexus/fileprocess-worker/internal/errors"
 	"github.com/adverant/nexus/fileprocess-worker/internal/storage"
 )
 
@@ -30,11 +36,26 @@ import (
 type DocumentProcessorInterface interface {
 	ProcessDocument(ctx context.Context, req *ProcessRequest) (*ProcessResult, error)
 	UpdateJobStatus(ctx context.Context, jobID string, status string, progress int, metadata map[string]interface{}) error
+	UpdateJobProgress(ctx context.Context, update *ProgressUpdate) error
 }
 
+// Processing modes
+const (
+	ProcessingModeCloud     = "cloud"      // MageAgent OCR and layout, GraphRAG, artifact storage, VoyageAI embeddings
+	ProcessingModeLocalOnly = "local_only" // Tesseract, heuristic layout, native extractors and local embeddings only
+)
+
+// errMageAgentUnavailable marks work that needs MageAgent on a local-only worker
+var errMageAgentUnavailable = errors.New("MageAgent is not available in local-only mode")
+
+// errVisionOCRNotAllowed marks a PDF that would need MageAgent when the job's OCR policy allows no vision tier
+var errVisionOCRNotAllowed = errors.New("OCR policy allows no vision tier")
+
 // ProcessorConfig holds processor configuration
 type ProcessorConfig struct {
-	VoyageAPIKey       string
+	ProcessingMode     string // ProcessingModeCloud (default) or ProcessingModeLocalOnly
+	VoyageAPIKey       string   // For the default VoyageAI embedder
+	Embedder           Embedder // Embedding provider (nil: VoyageAI voyage-3; required in local-only mode)
 	TesseractPath      string
 	TempDir            string
 	MaxFileSize        int64
@@ -42,6 +63,15 @@ type ProcessorConfig struct {
 	GraphRAGURL        string
 	MageAgentURL       string // MageAgent service URL for OCR operations
 	FileProcessAPIURL  string // FileProcess API URL for artifact storage
+	DedupEnabled       bool   // Link re-uploaded content to an existing Document DNA
+	DedupScope         string // DedupScopeUser (default) or DedupScopeGlobal
+	BlobStore          storage.BlobStore // Store for files referenced by BlobRef (nil: BlobRef unsupported)
+	ArchiveLimits      ArchiveLimits     // Bounds on archive expansion (zero values use defaults)
+	PDFRasterizerPath  string            // pdftoppm binary for rendering scanned PDF pages ("" disables)
+	OCRLanguages       string            // Languages tried when detection is inconclusive ("en,de"; default "en")
+	OCRPolicy          OCRPolicy         // OCR cascade tiers and thresholds (zero value: DefaultOCRPolicy)
+	OCRPrices          OCRPriceTable     // Per-page prices of paid tiers MageAgent reports no cost for (nil: DefaultOCRPrices)
+	Chunking           ChunkOptions      // Chunks embedded next to the document vector (zero value: DefaultChunkOptions)
 }
 
 // ProcessRequest represents a document processing request
@@ -52,8 +82,16 @@ type ProcessRequest struct {
 	MimeType   string
 	FileSize   int64
 	FileURL    string
+	BlobRef    string // Key of the file in the blob store
 	FileBuffer []byte
 	Metadata   map[string]interface{}
+
+	// OCR languages (ISO 639-1): from metadata.language, else detected on the first page
+	Languages      []string
+	LanguageSource string // LanguageSourceMetadata, LanguageSourceDetected or LanguageSourceDefault
+
+	OCRPolicy *OCRPolicy // Config policy with the job's metadata.ocrPolicy applied (nil: config policy)
+	OCRBudget *OCRBudget // Spend and limit of paid OCR tiers (nil: no limit)
 }
 
 // ProcessResult represents the processing result
@@ -65,18 +103,30 @@ type ProcessResult struct {
 	RegionsExtracted   int
 	EmbeddingGenerated bool
 	ProcessingTimeMs   int64
+	PageCount          int
+	ContentHash        string // SHA-256 of the processed file
+	ReusedFromJobID    string // Set when the Document DNA of an earlier job was linked instead of recomputed
+	OCRCostUSD         float64 // OCR spend of the job, including earlier attempts
+	ChunksEmbedded     int     // Chunk points stored next to the document vector
+
+	// Archive expansion: the job produced child documents instead of a Document DNA
+	Children       []*ChildDocument `json:"-"` // Enqueued as child jobs, never persisted with the result
+	ChildJobIDs    []string         // Set once the children are enqueued
+	SkippedEntries int
 }
 
 // DocumentProcessor handles document processing
 type DocumentProcessor struct {
 	config          *ProcessorConfig
 	storage         *storage.StorageManager
-	embeddingClient *EmbeddingClient
+	embedder        Embedder
 	mageAgentClient *clients.MageAgentClient // NEW: Delegate OCR to MageAgent
 	graphragClient  *clients.GraphRAGClient  // GraphRAG client for document storage and search
 	artifactClient  *clients.ArtifactClient  // Artifact client for permanent file storage
 	tesseractOCR    *TesseractOCR            // Fallback OCR for offline/fast processing
+	pdfRasterizer   *PDFRasterizer           // Renders scanned PDF pages for the per-page cascade (nil: not installed)
 	layoutAnalyzer  *LayoutAnalyzer
+	defaultLanguages []string                // OCR languages tried when detection is inconclusive
 }
 
 // NewDocumentProcessor creates a new document processor
@@ -89,31 +139,80 @@ func NewDocumentProcessor(cfg *ProcessorConfig) (*DocumentProcessor, error) {
 		return nil, fmt.Errorf("storage manager is required")
 	}
 
-	if cfg.MageAgentURL == "" {
+	switch cfg.ProcessingMode {
+	case "":
+		cfg.ProcessingMode = ProcessingModeCloud
+	case ProcessingModeCloud, ProcessingModeLocalOnly:
+	default:
+		return nil, fmt.Errorf("unknown processing mode %q", cfg.ProcessingMode)
+	}
+	localOnly := cfg.ProcessingMode == ProcessingModeLocalOnly
+
+	if cfg.MageAgentURL == "" && !localOnly {
 		return nil, fmt.Errorf("MageAgent URL is required for OCR operations")
 	}
 
-	// Create embedding client
-	embeddingClient, err := NewEmbeddingClient(cfg.VoyageAPIKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create embedding client: %w", err)
+	if len(cfg.OCRPolicy.Tiers) == 0 {
+		cfg.OCRPolicy = DefaultOCRPolicy()
+	}
+	if err := cfg.OCRPolicy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid OCR policy: %w", err)
+	}
+	if localOnly {
+		cfg.OCRPolicy = cfg.OCRPolicy.LocalOnly()
+	}
+	if cfg.OCRPrices == nil {
+		cfg.OCRPrices = DefaultOCRPrices()
+	}
+	if cfg.Chunking == (ChunkOptions{}) {
+		cfg.Chunking = DefaultChunkOptions()
+	}
+	if cfg.Chunking.MaxChars <= 0 || cfg.Chunking.MaxChunks <= 0 ||
+		cfg.Chunking.OverlapChars < 0 || cfg.Chunking.OverlapChars >= cfg.Chunking.MaxChars {
+		return nil, fmt.Errorf("invalid chunk options: size %d, overlap %d, max chunks %d",
+			cfg.Chunking.MaxChars, cfg.Chunking.OverlapChars, cfg.Chunking.MaxChunks)
 	}
 
-	// Create MageAgent client for dynamic vision/OCR model selection
-	mageAgentClient := clients.NewMageAgentClient(cfg.MageAgentURL)
+	// Create embedder (VoyageAI unless configured; a local server in local-only mode)
+	embedder := cfg.Embedder
+	if embedder == nil {
+		if localOnly {
+			return nil, fmt.Errorf("an embedder is required in local-only mode")
+		}
+		voyage, err := NewVoyageEmbedder(EmbeddingConfig{APIKey: cfg.VoyageAPIKey})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create embedding client: %w", err)
+		}
+		embedder = voyage
+	}
+	if dims := cfg.StorageManager.EmbeddingDimensions(); embedder.Dimensions() != dims {
+		return nil, fmt.Errorf("embedding model %s has %d dimensions, storage expects %d",
+			embedder.Model(), embedder.Dimensions(), dims)
+	}
+	log.Printf("Embeddings: model=%s, dimensions=%d", embedder.Model(), embedder.Dimensions())
 
-	// Test MageAgent connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := mageAgentClient.HealthCheck(ctx); err != nil {
-		log.Printf("WARNING: MageAgent health check failed: %v. Will fall back to Tesseract.", err)
+	// Create MageAgent client for dynamic vision/OCR model selection
+	var mageAgentClient *clients.MageAgentClient
+	if localOnly {
+		log.Printf("Local-only mode: OCR uses Tesseract only, layout the text heuristic")
 	} else {
-		log.Printf("MageAgent connection verified: %s", cfg.MageAgentURL)
+		mageAgentClient = clients.NewMageAgentClient(cfg.MageAgentURL)
+
+		// Test MageAgent connection
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := mageAgentClient.HealthCheck(ctx); err != nil {
+			log.Printf("WARNING: MageAgent health check failed: %v. Will fall back to Tesseract.", err)
+		} else {
+			log.Printf("MageAgent connection verified: %s", cfg.MageAgentURL)
+		}
 	}
 
 	// Create GraphRAG client for document storage and search
 	var graphragClient *clients.GraphRAGClient
-	if cfg.GraphRAGURL != "" {
+	if localOnly {
+		log.Printf("Local-only mode: GraphRAG disabled. Documents are searchable through Qdrant only.")
+	} else if cfg.GraphRAGURL != "" {
 		graphragClient = clients.NewGraphRAGClient(cfg.GraphRAGURL)
 		// Test GraphRAG connection (non-fatal if unavailable)
 		ctx2, cancel2 := context.WithTimeout(context.Background(), 5*time.Second)
@@ -129,7 +228,9 @@ func NewDocumentProcessor(cfg *ProcessorConfig) (*DocumentProcessor, error) {
 
 	// Create Artifact client for permanent file storage
 	var artifactClient *clients.ArtifactClient
-	if cfg.FileProcessAPIURL != "" {
+	if localOnly {
+		log.Printf("Local-only mode: artifact storage disabled. Original files are kept in the Document DNA only.")
+	} else if cfg.FileProcessAPIURL != "" {
 		artifactClient = clients.NewArtifactClient(cfg.FileProcessAPIURL)
 		// Test artifact storage connection (non-fatal if unavailable)
 		ctx3, cancel3 := context.WithTimeout(context.Background(), 5*time.Second)
@@ -148,22 +249,40 @@ func NewDocumentProcessor(cfg *ProcessorConfig) (*DocumentProcessor, error) {
 		TesseractPath: cfg.TesseractPath,
 	})
 	if err != nil {
+		if localOnly {
+			return nil, fmt.Errorf("Tesseract is required in local-only mode: %w", err)
+		}
 		log.Printf("WARNING: Failed to initialize Tesseract: %v. OCR will rely solely on MageAgent.", err)
 	}
 
+	// Scanned PDF pages are rendered locally for the per-page OCR cascade when poppler is installed
+	pdfRasterizer := NewPDFRasterizer(cfg.PDFRasterizerPath, cfg.TempDir)
+	if pdfRasterizer == nil && localOnly {
+		log.Printf("WARNING: pdftoppm not available at %q. Scanned PDF pages will keep only their text layer.", cfg.PDFRasterizerPath)
+	} else if pdfRasterizer == nil {
+		log.Printf("WARNING: pdftoppm not available at %q. Scanned PDFs will be converted by MageAgent /file-process.", cfg.PDFRasterizerPath)
+	}
+
+	defaultLanguages := ParseLanguages(cfg.OCRLanguages)
+	if len(defaultLanguages) == 0 {
+		defaultLanguages = []string{"en"}
+	}
+
 	// Create layout analyzer with MageAgent integration for vision-based analysis
-	// Enable vision mode for higher accuracy (99.2% vs 70% heuristic)
-	layoutAnalyzer := NewLayoutAnalyzer(mageAgentClient, true)
+	// Enable vision mode for higher accuracy (99.2% vs 70% heuristic); local-only uses the heuristic
+	layoutAnalyzer := NewLayoutAnalyzer(mageAgentClient, !localOnly)
 
 	return &DocumentProcessor{
 		config:          cfg,
 		storage:         cfg.StorageManager,
-		embeddingClient: embeddingClient,
+		embedder:        embedder,
 		mageAgentClient: mageAgentClient,
 		graphragClient:  graphragClient,
 		artifactClient:  artifactClient,
 		tesseractOCR:    tesseractOCR,
+		pdfRasterizer:   pdfRasterizer,
 		layoutAnalyzer:  layoutAnalyzer,
+		defaultLanguages: defaultLanguages,
 	}, nil
 }
 
@@ -171,33 +290,100 @@ func NewDocumentProcessor(cfg *ProcessorConfig) (*DocumentProcessor, error) {
 func (p *DocumentProcessor) ProcessDocument(ctx context.Context, req *ProcessRequest) (*ProcessResult, error) {
 	log.Printf("[Job %s] Starting document processing pipeline", req.JobID)
 
+	// Step 0: Duplicate delivery - the DNA was stored by an earlier attempt of this job
+	if existing, err := p.storage.FindDocumentDNAByJob(ctx, req.JobID); err != nil {
+		log.Printf("[Job %s] WARNING: Could not check for existing Document DNA: %v", req.JobID, err)
+	} else if existing != nil {
+		log.Printf("[Job %s] Document DNA already stored (dnaId=%s), skipping pipeline", req.JobID, existing.ID)
+		return resultFromDocumentDNA(existing), nil
+	}
+
 	// Step 1: Download/load file
 	log.Printf("[Job %s] Step 1: Loading file (%d bytes)", req.JobID, req.FileSize)
+	reportProgress(ctx, req.JobID, StageDownload, 0, "Loading file", nil)
 	fileData, err := p.loadFile(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load file: %w", err)
 	}
+	reportProgress(ctx, req.JobID, StageDownload, 1, "File loaded", map[string]interface{}{
+		"bytesRead": len(fileData),
+	})
+
+	// Step 1.2: Same content already processed by this pipeline - link its DNA instead of recomputing
+	contentHash := ContentHash(fileData)
+	if existing := p.findReusableDNA(ctx, req, contentHash); existing != nil {
+		log.Printf("[Job %s] Content already processed by job %s (sha256=%s), linking dnaId=%s",
+			req.JobID, existing.JobID, contentHash, existing.ID)
+		reportProgress(ctx, req.JobID, StageStorage, 1, "Linked existing Document DNA", map[string]interface{}{
+			"documentDnaId":   existing.ID,
+			"reusedFromJobId": existing.JobID,
+		})
+		result := resultFromDocumentDNA(existing)
+		result.ContentHash = contentHash
+		result.ReusedFromJobID = existing.JobID
+		return result, nil
+	}
 
 	// Step 1.5: Detect actual MIME type from magic bytes
 	// Essential for files from Google Drive which often return application/octet-stream
+	// Office documents are also commonly uploaded as plain application/zip
 	detectedMime := detectMimeTypeFromMagicBytes(fileData)
-	if detectedMime != "" && (req.MimeType == "" || req.MimeType == "application/octet-stream") {
+	if detectedMime != "" && (req.MimeType == "" || req.MimeType == "application/octet-stream" ||
+		(req.MimeType == "application/zip" && detectedMime != "application/zip")) {
 		log.Printf("[Job %s] Corrected MIME type from '%s' to '%s' (magic byte detection)",
 			req.JobID, req.MimeType, detectedMime)
 		req.MimeType = detectedMime
 	}
+	if corrected := DelimitedMimeType(req.MimeType, req.Filename); corrected != req.MimeType {
+		log.Printf("[Job %s] Corrected MIME type from '%s' to '%s' (file extension)", req.JobID, req.MimeType, corrected)
+		req.MimeType = corrected
+	}
+
+	if err := checkCancelled(ctx, req.JobID, "file analysis"); err != nil {
+		return nil, err
+	}
+
+	// Step 1.6: Archives are expanded into child documents instead of being OCRed
+	if format := DetectArchive(req.MimeType, req.Filename, fileData); format != "" {
+		return p.expandArchive(ctx, req, fileData, format, contentHash)
+	}
 
 	// Step 2: Determine processing strategy based on file type
 	log.Printf("[Job %s] Step 2: Analyzing file type (mime: %s)", req.JobID, req.MimeType)
 	needsOCR := p.requiresOCR(req.MimeType)
+	reportProgress(ctx, req.JobID, StageAnalysis, 1, "File type analyzed", map[string]interface{}{
+		"mimeType": req.MimeType,
+		"needsOCR": needsOCR,
+	})
+
+	// OCR languages named by the job; otherwise detected during extraction
+	if len(req.Languages) == 0 {
+		if languages := ParseLanguages(req.Metadata["language"]); len(languages) > 0 {
+			req.Languages, req.LanguageSource = languages, LanguageSourceMetadata
+			log.Printf("[Job %s] OCR languages from metadata: %v", req.JobID, languages)
+		}
+	}
 
 	var ocrResult *OCRResult
+	var layoutResult *LayoutResult
 	var extractedText string
 	var ocrTier string
 
 	// Check for EPUB first (before needsOCR check) - EPUB files are detected as ZIP but need MageAgent processing
 	isEPUB := req.MimeType == "application/epub+zip" || strings.HasSuffix(strings.ToLower(req.Filename), ".epub")
-	if isEPUB {
+	reportProgress(ctx, req.JobID, StageOCR, 0, "Extracting text", nil)
+	if extract := nativeExtractorFor(req.MimeType); extract != nil {
+		// Office documents: text and structure are parsed from the file itself (no OCR, no LLM)
+		log.Printf("[Job %s] Step 3: Parsing %s natively", req.JobID, req.MimeType)
+		ocrResult, layoutResult, err = extract(fileData)
+		if err != nil {
+			return nil, fmt.Errorf("native extraction failed: %w", err)
+		}
+		log.Printf("[Job %s] Native extraction complete: pages=%d, regions=%d, tables=%d",
+			req.JobID, len(ocrResult.Pages), len(layoutResult.Regions), len(layoutResult.Tables))
+		extractedText = ocrResult.Text
+		ocrTier = ocrResult.TierUsed
+	} else if isEPUB {
 		log.Printf("[Job %s] Step 3: Detected EPUB file, routing to MageAgent /file-process", req.JobID)
 		ocrResult, err = p.processDocumentViaMageAgent(ctx, req, fileData, "application/epub+zip")
 		if err != nil {
@@ -210,12 +396,13 @@ func (p *DocumentProcessor) ProcessDocument(ctx context.Context, req *ProcessReq
 	} else if needsOCR {
 		// Image/PDF files: Use MageAgent for intelligent OCR with dynamic model selection
 		log.Printf("[Job %s] Step 3: Determining OCR strategy for image/PDF", req.JobID)
+		p.resolveOCRBudget(ctx, req)
 
-		// For PDFs: Route to /file-process endpoint which handles PDF → image conversion
+		// For PDFs: Embedded text layer first; pages without one go to /file-process (PDF → image conversion)
 		// For Images: Use standard OCR cascade (Tesseract → GPT-4o → Claude Opus)
 		if req.MimeType == "application/pdf" || strings.HasSuffix(strings.ToLower(req.Filename), ".pdf") {
-			log.Printf("[Job %s] Step 4: Routing PDF to MageAgent /file-process (PDF-native processing)", req.JobID)
-			ocrResult, err = p.processPDFViaMageAgent(ctx, req, fileData)
+			p.resolveOCRPolicy(req, false)
+			ocrResult, err = p.processPDF(ctx, req, fileData)
 			if err != nil {
 				return nil, fmt.Errorf("PDF processing failed: %w", err)
 			}
@@ -224,9 +411,10 @@ func (p *DocumentProcessor) ProcessDocument(ctx context.Context, req *ProcessReq
 			preferAccuracy := p.shouldPreferAccuracy(req)
 			log.Printf("[Job %s] OCR strategy: preferAccuracy=%v (based on file size=%d, mime=%s)",
 				req.JobID, preferAccuracy, req.FileSize, req.MimeType)
+			p.resolveOCRPolicy(req, preferAccuracy)
 
 			log.Printf("[Job %s] Step 4: Delegating OCR to MageAgent (zero hardcoded models)", req.JobID)
-			ocrResult, err = p.performOCRWithMageAgent(ctx, req, fileData, preferAccuracy)
+			ocrResult, err = p.performOCRWithMageAgent(ctx, req, fileData)
 			if err != nil {
 				return nil, fmt.Errorf("OCR processing failed: %w", err)
 			}
@@ -258,9 +446,31 @@ func (p *DocumentProcessor) ProcessDocument(ctx context.Context, req *ProcessReq
 		log.Printf("[Job %s] Text extracted: %d characters", req.JobID, len(extractedText))
 	}
 
-	// Step 5: Layout analysis (only for image/PDF files)
-	var layoutResult *LayoutResult
-	if needsOCR {
+	// Text that needed no OCR has its language detected for the metadata and layout analysis
+	if len(req.Languages) == 0 {
+		if languages := DetectLanguages(extractedText); len(languages) > 0 {
+			req.Languages, req.LanguageSource = languages, LanguageSourceDetected
+		}
+	}
+	ocrResult.Languages = req.Languages
+
+	reportProgress(ctx, req.JobID, StageOCR, 1, fmt.Sprintf("Text extracted from %d/%d pages", len(ocrResult.Pages), len(ocrResult.Pages)), map[string]interface{}{
+		"pagesCompleted": len(ocrResult.Pages),
+		"pageCount":      len(ocrResult.Pages),
+		"confidence":     ocrResult.Confidence,
+		"ocrTier":        ocrTier,
+		"characters":     len(extractedText),
+	})
+
+	if err := checkCancelled(ctx, req.JobID, "layout analysis"); err != nil {
+		return nil, err
+	}
+	reportProgress(ctx, req.JobID, StageLayout, 0, "Analyzing layout", nil)
+
+	// Step 5: Layout analysis (only for image/PDF files; native extraction already has the layout)
+	if layoutResult != nil {
+		log.Printf("[Job %s] Layout taken from document structure", req.JobID)
+	} else if needsOCR {
 		log.Printf("[Job %s] Step 5: Analyzing document layout", req.JobID)
 		layoutResult, err = p.layoutAnalyzer.Analyze(ctx, ocrResult)
 		if err != nil {
@@ -287,14 +497,34 @@ func (p *DocumentProcessor) ProcessDocument(ctx context.Context, req *ProcessReq
 		log.Printf("[Job %s] Layout bypassed for text file", req.JobID)
 	}
 
-	// Step 7: Generate VoyageAI embedding (1024 dimensions)
-	log.Printf("[Job %s] Step 7: Generating semantic embedding", req.JobID)
-	embedding, err := p.embeddingClient.GenerateEmbedding(ctx, extractedText)
+	reportProgress(ctx, req.JobID, StageLayout, 1, "Layout analyzed", map[string]interface{}{
+		"regions": len(layoutResult.Regions),
+		"tables":  len(layoutResult.Tables),
+	})
+
+	if err := checkCancelled(ctx, req.JobID, "embedding generation"); err != nil {
+		return nil, err
+	}
+	reportProgress(ctx, req.JobID, StageEmbedding, 0, "Generating embedding", nil)
+
+	// Step 7: Generate semantic embedding (configured model and dimensions)
+	log.Printf("[Job %s] Step 7: Generating semantic embedding (model=%s)", req.JobID, p.embedder.Model())
+	embedding, err := p.embedder.GenerateEmbedding(ctx, extractedText)
 	if err != nil {
 		return nil, fmt.Errorf("embedding generation failed: %w", err)
 	}
 	log.Printf("[Job %s] Embedding generated: dimensions=%d", req.JobID, len(embedding))
 
+	// Step 7.5: Embed the text chunk by chunk, aligned with its regions and pages
+	chunks, err := p.embedChunks(ctx, req.JobID, extractedText, embedding, ocrResult, layoutResult)
+	if err != nil {
+		return nil, fmt.Errorf("chunk embedding failed: %w", err)
+	}
+	reportProgress(ctx, req.JobID, StageEmbedding, 1, fmt.Sprintf("Embedding generated with %d chunks", len(chunks)), map[string]interface{}{
+		"dimensions": len(embedding),
+		"chunks":     len(chunks),
+	})
+
 	// Step 8: Build structural data
 	structuralData := map[string]interface{}{
 		"layout": map[string]interface{}{
@@ -311,30 +541,60 @@ func (p *DocumentProcessor) ProcessDocument(ctx context.Context, req *ProcessReq
 			"ocrConfidence": ocrResult.Confidence,
 			"pageCount":    len(ocrResult.Pages),
 			"extractedAt":  "now",
+			"chunkCount":   len(chunks),
 		},
 	}
+	metadata := structuralData["metadata"].(map[string]interface{})
+	if len(req.Languages) > 0 {
+		metadata["language"] = req.Languages[0]
+		metadata["languages"] = req.Languages
+		metadata["languageSource"] = req.LanguageSource
+	}
+	if len(ocrResult.Decisions) > 0 {
+		metadata["ocrDecisions"] = ocrResult.Decisions
+	}
+	if req.OCRBudget != nil {
+		metadata["ocrCostUsd"] = req.OCRBudget.SpentUSD
+	}
+
+	if len(layoutResult.Sheets) > 0 {
+		structuralData["sheets"] = layoutResult.Sheets
+	}
+
+	// Last chance to stop: nothing has been persisted yet
+	if err := checkCancelled(ctx, req.JobID, "Document DNA storage"); err != nil {
+		return nil, err
+	}
 
 	// Step 9: Store Document DNA atomically across PostgreSQL + Qdrant
 	log.Printf("[Job %s] Step 9: Storing Document DNA", req.JobID)
+	reportProgress(ctx, req.JobID, StageStorage, 0, "Storing Document DNA", nil)
 	dnaResult, err := p.storage.StoreDocumentDNA(ctx, &storage.DocumentDNAInput{
 		JobID:             req.JobID,
 		SemanticEmbedding: embedding,
 		StructuralData:    structuralData,
 		OriginalContent:   fileData,
+		ContentHash:       contentHash,
+		PipelineVersion:   p.pipelineVersion(req),
+		Chunks:            chunks,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to store Document DNA: %w", err)
 	}
-	log.Printf("[Job %s] Document DNA stored: dnaId=%s, qdrantPointId=%s",
-		req.JobID, dnaResult.ID, dnaResult.QdrantPointID)
+	log.Printf("[Job %s] Document DNA stored: dnaId=%s, qdrantPointId=%s, chunks=%d",
+		req.JobID, dnaResult.ID, dnaResult.QdrantPointID, len(chunks))
 
 	dnaID := dnaResult.ID
+	reportProgress(ctx, req.JobID, StageStorage, 1, "Document DNA stored", map[string]interface{}{
+		"documentDnaId": dnaID,
+	})
 
 	// Step 9.5: Store original file as permanent artifact for later retrieval
 	// This enables page-specific PDF viewing via URLs like: https://drive.google.com/file/d/xxx/view#page=53
 	var artifactID, artifactURL, storageBackend string
 	if p.artifactClient != nil && len(fileData) > 0 {
 		log.Printf("[Job %s] Step 9.5: Uploading original file to permanent storage (%d bytes)", req.JobID, len(fileData))
+		reportProgress(ctx, req.JobID, StageArtifact, 0, "Storing original file", nil)
 
 		artifactResp, err := p.artifactClient.UploadArtifact(ctx, &clients.ArtifactUploadRequest{
 			FileBuffer:    fileData,
@@ -351,6 +611,10 @@ func (p *DocumentProcessor) ProcessDocument(ctx context.Context, req *ProcessReq
 			},
 		})
 
+		reportProgress(ctx, req.JobID, StageArtifact, 1, "Original file stored", map[string]interface{}{
+			"stored": err == nil && artifactResp != nil && artifactResp.Success,
+		})
+
 		if err != nil {
 			// Non-fatal: processing continues, but original file won't be accessible for viewing
 			log.Printf("[Job %s] WARNING: Failed to store artifact: %v. Original file will not be accessible for page viewing.", req.JobID, err)
@@ -370,6 +634,7 @@ func (p *DocumentProcessor) ProcessDocument(ctx context.Context, req *ProcessReq
 	// Include artifact URL so recall results can link to viewable PDF pages
 	if p.graphragClient != nil && len(extractedText) > 0 {
 		log.Printf("[Job %s] Step 10: Storing document in GraphRAG for chunking/search", req.JobID)
+		reportProgress(ctx, req.JobID, StageGraphRAG, 0, "Indexing document for search", nil)
 
 		// Calculate page boundaries for multi-page documents (PDFs)
 		// This allows GraphRAG to preserve page numbers in chunks for page-specific queries
@@ -410,6 +675,11 @@ func (p *DocumentProcessor) ProcessDocument(ctx context.Context, req *ProcessReq
 		}
 
 		graphragResp, err := p.graphragClient.StoreDocument(ctx, graphragReq)
+		graphragStats := map[string]interface{}{"stored": err == nil && graphragResp != nil && graphragResp.Success}
+		if err == nil && graphragResp != nil {
+			graphragStats["chunks"] = graphragResp.ChunkCount
+		}
+		reportProgress(ctx, req.JobID, StageGraphRAG, 1, "Document indexed", graphragStats)
 		if err != nil {
 			// Non-fatal error - document DNA is still stored, just not searchable via memory recall
 			log.Printf("[Job %s] WARNING: Failed to store in GraphRAG: %v. Document will not be searchable via memory recall.", req.JobID, err)
@@ -431,6 +701,12 @@ func (p *DocumentProcessor) ProcessDocument(ctx context.Context, req *ProcessReq
 		TablesExtracted:    len(layoutResult.Tables),
 		RegionsExtracted:   len(layoutResult.Regions),
 		EmbeddingGenerated: true,
+		PageCount:          len(ocrResult.Pages),
+		ContentHash:        contentHash,
+		ChunksEmbedded:     len(chunks),
+	}
+	if req.OCRBudget != nil {
+		result.OCRCostUSD = req.OCRBudget.SpentUSD
 	}
 
 	log.Printf("[Job %s] Processing pipeline complete: dnaId=%s, confidence=%.2f",
@@ -439,6 +715,62 @@ func (p *DocumentProcessor) ProcessDocument(ctx context.Context, req *ProcessReq
 	return result, nil
 }
 
+// embedChunks embeds the chunks of a document's text. A single chunk holding
+// the whole text reuses the document embedding.
+func (p *DocumentProcessor) embedChunks(ctx context.Context, jobID, text string, documentEmbedding []float32, ocrResult *OCRResult, layoutResult *LayoutResult) ([]storage.ChunkEmbedding, error) {
+	chunks := ChunkText(text, ocrResult.Pages, layoutResult, p.config.Chunking)
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+	if len(chunks) == p.config.Chunking.MaxChunks && chunks[len(chunks)-1].EndChar < len(strings.TrimRight(text, " \t\r\n")) {
+		log.Printf("[Job %s] WARNING: Chunk limit of %d reached at byte %d of %d. The rest of the text is covered by the document embedding only.",
+			jobID, p.config.Chunking.MaxChunks, chunks[len(chunks)-1].EndChar, len(text))
+	}
+
+	var vectors [][]float32
+	if len(chunks) == 1 && chunks[0].Text == strings.TrimSpace(text) {
+		vectors = [][]float32{documentEmbedding}
+	} else {
+		texts := make([]string, len(chunks))
+		for i, chunk := range chunks {
+			texts[i] = chunk.Text
+		}
+		var err error
+		vectors, err = p.embedder.GenerateEmbeddingBatch(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) != len(chunks) {
+			return nil, fmt.Errorf("expected %d chunk embeddings, got %d", len(chunks), len(vectors))
+		}
+	}
+	log.Printf("[Job %s] Chunks embedded: %d", jobID, len(chunks))
+
+	embeddings := make([]storage.ChunkEmbedding, len(chunks))
+	for i, chunk := range chunks {
+		embeddings[i] = storage.ChunkEmbedding{
+			Index:      chunk.Index,
+			Vector:     vectors[i],
+			Text:       chunk.Text,
+			PageNumber: chunk.PageNumber,
+			RegionID:   chunk.RegionID,
+			StartChar:  chunk.StartChar,
+			EndChar:    chunk.EndChar,
+		}
+	}
+	return embeddings, nil
+}
+
+// checkCancelled stops the pipeline between stages once the job context is done
+// (cancelled through the control channel or timed out)
+func checkCancelled(ctx context.Context, jobID, stage string) error {
+	if err := ctx.Err(); err != nil {
+		log.Printf("[Job %s] Skipping %s and remaining stages: %v", jobID, stage, context.Cause(ctx))
+		return fmt.Errorf("stopped before %s: %w", stage, err)
+	}
+	return nil
+}
+
 // UpdateJobStatus updates job status in database
 func (p *DocumentProcessor) UpdateJobStatus(ctx context.Context, jobID string, status string, progress int, metadata map[string]interface{}) error {
 	update := &storage.JobUpdate{
@@ -461,15 +793,44 @@ func (p *DocumentProcessor) UpdateJobStatus(ctx context.Context, jobID string, s
 		if ocrTierUsed, ok := metadata["ocrTierUsed"].(string); ok {
 			update.OCRTierUsed = ocrTierUsed
 		}
+		if contentHash, ok := metadata["contentHash"].(string); ok {
+			update.ContentHash = contentHash
+		}
+		if batchID, ok := metadata["batchId"].(string); ok {
+			update.BatchID = batchID
+		}
 		if errorMsg, ok := metadata["error"].(string); ok {
 			update.ErrorCode = "PROCESSING_ERROR"
 			update.ErrorMessage = errorMsg
 		}
+		if errorCode, ok := metadata["errorCode"].(string); ok && errorCode != "" {
+			update.ErrorCode = errorCode
+		}
 	}
 
 	return p.storage.UpdateJobStatus(ctx, update)
 }
 
+// UpdateJobProgress records the latest progress update in database
+func (p *DocumentProcessor) UpdateJobProgress(ctx context.Context, update *ProgressUpdate) error {
+	details := map[string]interface{}{
+		"stage":         update.Stage,
+		"stageProgress": update.StageProgress,
+		"message":       update.Message,
+		"timestamp":     update.Timestamp.Format(time.RFC3339),
+	}
+	if len(update.Stats) > 0 {
+		details["stats"] = update.Stats
+	}
+
+	return p.storage.UpdateJobProgress(ctx, &storage.JobProgress{
+		JobID:    update.JobID,
+		Progress: update.Progress,
+		Stage:    update.Stage,
+		Details:  details,
+	})
+}
+
 // loadFile loads file from URL or buffer
 func (p *DocumentProcessor) loadFile(ctx context.Context, req *ProcessRequest) ([]byte, error) {
 	// If buffer is provided, use it directly
@@ -478,6 +839,16 @@ func (p *DocumentProcessor) loadFile(ctx context.Context, req *ProcessRequest) (
 		return req.FileBuffer, nil
 	}
 
+	// If a blob reference is provided, stream it from the blob store
+	if req.BlobRef != "" {
+		fileData, err := p.readBlob(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read blob: %w", err)
+		}
+		log.Printf("[Job %s] Blob read successfully (%d bytes)", req.JobID, len(fileData))
+		return fileData, nil
+	}
+
 	// If URL is provided, download it
 	if req.FileURL != "" {
 		log.Printf("[Job %s] Downloading file from URL: %s (fileSize=%d)", req.JobID, req.FileURL, req.FileSize)
@@ -489,7 +860,53 @@ func (p *DocumentProcessor) loadFile(ctx context.Context, req *ProcessRequest) (
 		return fileData, nil
 	}
 
-	return nil, fmt.Errorf("no file source provided (buffer or URL)")
+	return nil, fmt.Errorf("no file source provided (buffer, blob or URL)")
+}
+
+// readBlob streams a file from the blob store, enforcing MaxFileSize
+func (p *DocumentProcessor) readBlob(ctx context.Context, req *ProcessRequest) ([]byte, error) {
+	if p.config.BlobStore == nil {
+		return nil, fmt.Errorf("job references blob %q but no blob store is configured", req.BlobRef)
+	}
+
+	log.Printf("[Job %s] Reading file from blob store: %s (fileSize=%d)", req.JobID, req.BlobRef, req.FileSize)
+	blob, size, err := p.config.BlobStore.Open(ctx, req.BlobRef)
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Close()
+
+	if p.config.MaxFileSize > 0 && size > p.config.MaxFileSize {
+		return nil, fmt.Errorf("file size exceeds maximum: %d > %d bytes", size, p.config.MaxFileSize)
+	}
+
+	totalBytes := size
+	if totalBytes <= 0 {
+		totalBytes = req.FileSize
+	}
+
+	// Read one byte past the limit so oversized blobs of unknown size are detected
+	maxReadBytes := p.config.MaxFileSize
+	if maxReadBytes == 0 {
+		maxReadBytes = 10 * 1024 * 1024 * 1024 // 10GB safety limit
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, max(totalBytes, 0)))
+	_, err = buf.ReadFrom(io.LimitReader(&progressReader{
+		reader:      blob,
+		ctx:         ctx,
+		jobID:       req.JobID,
+		total:       totalBytes,
+		reportEvery: 10 * 1024 * 1024,
+	}, maxReadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(buf.Len()) > maxReadBytes {
+		return nil, fmt.Errorf("file size exceeds maximum: more than %d bytes", maxReadBytes)
+	}
+
+	return buf.Bytes(), nil
 }
 
 // downloadFileFromURL downloads a file from a URL with retry logic and memory efficiency
@@ -577,7 +994,18 @@ func (p *DocumentProcessor) downloadFileFromURL(ctx context.Context, jobID strin
 			maxReadBytes = 10 * 1024 * 1024 * 1024 // 10GB safety limit
 		}
 
-		fileData, err := io.ReadAll(io.LimitReader(resp.Body, maxReadBytes))
+		totalBytes := contentLength
+		if totalBytes <= 0 {
+			totalBytes = expectedSize
+		}
+		body := &progressReader{
+			reader:      resp.Body,
+			ctx:         ctx,
+			jobID:       jobID,
+			total:       totalBytes,
+			reportEvery: chunkSizeBytes,
+		}
+		fileData, err := io.ReadAll(io.LimitReader(body, maxReadBytes))
 		resp.Body.Close()
 
 		if err != nil {
@@ -608,128 +1036,216 @@ func (p *DocumentProcessor) downloadFileFromURL(ctx context.Context, jobID strin
 	return nil, fmt.Errorf("failed to download file after %d attempts: %w", maxRetries, lastErr)
 }
 
-// performOCRWithMageAgent implements 3-tier OCR cascade for cost optimization
+// performOCRWithMageAgent runs the OCR cascade page by page. Pages of a
+// multi-page TIFF escalate independently on their own confidence; other
+// images are a single page.
+func (p *DocumentProcessor) performOCRWithMageAgent(ctx context.Context, req *ProcessRequest, fileData []byte) (*OCRResult, error) {
+	if pages := TIFFPageCount(fileData); pages > 1 {
+		log.Printf("[Job %s] Multi-page TIFF: running the OCR cascade on each of %d pages", req.JobID, pages)
+		return p.ocrPages(ctx, req, pages, func(fn func(page int, image []byte) error) error {
+			return EachTIFFPage(fileData, fn)
+		})
+	}
+	return p.ocrPageCascade(ctx, req, 1, fileData)
+}
+
+// ocrPageCascade runs the tiers of the job's OCR policy on one page for cost optimization
 // Tier 1: Tesseract (fast, free, 82% accuracy)
-// Tier 2: MageAgent GPT-4o (confidence < 0.85, balanced, $0.01-0.03/page)
-// Tier 3: MageAgent Claude Opus (confidence < 0.90, highest accuracy, $0.05-0.10/page)
-func (p *DocumentProcessor) performOCRWithMageAgent(ctx context.Context, req *ProcessRequest, fileData []byte, preferAccuracy bool) (*OCRResult, error) {
+// Tier 2: MageAgent GPT-4o (balanced, $0.01-0.03/page)
+// Tier 3: MageAgent Claude Opus (highest accuracy, $0.05-0.10/page)
+// Each tier's result is accepted at the policy's threshold, otherwise the page
+// escalates; when no tier reaches its threshold the most confident result is used.
+func (p *DocumentProcessor) ocrPageCascade(ctx context.Context, req *ProcessRequest, pageNumber int, image []byte) (*OCRResult, error) {
 	startTime := time.Now()
+	policy := p.ocrPolicy(req)
+	languages, firstPass := p.ocrLanguages(ctx, req, pageNumber, image)
+
+	var decisions []OCRDecision
+	var best *OCRResult
+	var failures []string
+	ran := false    // Whether a tier ran at all, rather than being skipped
+	pageCost := 0.0 // Every tier run is paid for, accepted or not
+	for _, tier := range policy.Tiers {
+		decision := OCRDecision{Page: pageNumber, Tier: tier, Threshold: policy.Thresholds[tier]}
+		if !policy.allows(tier) {
+			decision.Outcome, decision.Reason = OCRDecisionSkipped, fmt.Sprintf("above max tier %s", policy.MaxTier)
+			decisions = append(decisions, decision)
+			continue
+		}
+		if tier == OCRTierTesseract && p.tesseractOCR == nil {
+			log.Printf("[Job %s page %d] %s skipped: Tesseract not available", req.JobID, pageNumber, tier)
+			decision.Outcome, decision.Reason = OCRDecisionSkipped, "Tesseract not available"
+			decisions = append(decisions, decision)
+			continue
+		}
+		estimate := p.config.OCRPrices.PageCost(tier, "")
+		if !req.OCRBudget.Allows(estimate) {
+			log.Printf("[Job %s page %d] %s skipped: $%.4f/page would exceed the OCR budget, %s",
+				req.JobID, pageNumber, tier, estimate, req.OCRBudget)
+			decision.Outcome = OCRDecisionSkipped
+			decision.Reason = fmt.Sprintf("$%.4f/page would exceed the OCR budget: %s", estimate, req.OCRBudget)
+			decisions = append(decisions, decision)
+			failures = append(failures, fmt.Sprintf("%s=%v", tier, errOCRBudgetExceeded))
+			continue
+		}
+		reserved, ok := p.reserveOCR(ctx, req, estimate)
+		if !ok {
+			log.Printf("[Job %s page %d] %s skipped: $%.4f/page would exceed the user's OCR budget",
+				req.JobID, pageNumber, tier, estimate)
+			decision.Outcome = OCRDecisionSkipped
+			decision.Reason = fmt.Sprintf("$%.4f/page would exceed the user's OCR budget", estimate)
+			decisions = append(decisions, decision)
+			failures = append(failures, fmt.Sprintf("%s=%v", tier, errOCRBudgetExceeded))
+			continue
+		}
 
-	// TIER 1: Try Tesseract first (fast, free, offline)
-	if p.tesseractOCR != nil {
-		log.Printf("[Job %s] Tier 1: Attempting Tesseract OCR (fast, free)", req.JobID)
-		tesseractResult, err := p.tesseractOCR.Process(ctx, fileData)
-
-		if err == nil {
-			log.Printf("[Job %s] Tier 1 complete: confidence=%.2f", req.JobID, tesseractResult.Confidence)
-
-			// SUCCESS: Tesseract confidence >= 0.85 (high quality)
-			if tesseractResult.Confidence >= 0.85 {
-				log.Printf("[Job %s] ✓ Tesseract quality sufficient (%.2f >= 0.85), using result",
-					req.JobID, tesseractResult.Confidence)
-				tesseractResult.Duration = time.Since(startTime)
-				return tesseractResult, nil
-			}
+		log.Printf("[Job %s page %d] Attempting %s OCR (languages=%v)", req.JobID, pageNumber, tier, languages)
+		result, err := p.runOCRTier(ctx, tier, image, languages, firstPass)
+		ran = ran || !errors.Is(err, errMageAgentUnavailable)
+		if err != nil {
+			p.chargeOCR(ctx, req, 0, reserved)
+			log.Printf("[Job %s page %d] ✗ %s failed: %v", req.JobID, pageNumber, tier, err)
+			decision.Outcome, decision.Reason = OCRDecisionFailed, err.Error()
+			decisions = append(decisions, decision)
+			failures = append(failures, fmt.Sprintf("%s=%v", tier, err))
+			continue
+		}
+		decision.Model, decision.Confidence, decision.Cost = result.Model, result.Confidence, result.Cost
+		p.chargeOCR(ctx, req, result.Cost, reserved)
+		pageCost += result.Cost
+
+		if result.Confidence >= decision.Threshold {
+			log.Printf("[Job %s page %d] ✓ %s quality sufficient (%.2f >= %.2f), using result",
+				req.JobID, pageNumber, result.TierUsed, result.Confidence, decision.Threshold)
+			decision.Outcome = OCRDecisionAccepted
+			decision.Reason = fmt.Sprintf("confidence %.2f >= %.2f", result.Confidence, decision.Threshold)
+			return finishOCRPage(result, pageNumber, pageCost, startTime, append(decisions, decision)), nil
+		}
 
-			// LOW CONFIDENCE: Escalate to Tier 2
-			log.Printf("[Job %s] ✗ Tesseract confidence low (%.2f < 0.85), escalating to Tier 2 (GPT-4o)",
-				req.JobID, tesseractResult.Confidence)
-		} else {
-			log.Printf("[Job %s] Tier 1 failed: %v, escalating to Tier 2", req.JobID, err)
+		log.Printf("[Job %s page %d] ✗ %s confidence low (%.2f < %.2f), escalating",
+			req.JobID, pageNumber, result.TierUsed, result.Confidence, decision.Threshold)
+		decision.Outcome = OCRDecisionEscalated
+		decision.Reason = fmt.Sprintf("confidence %.2f < %.2f", result.Confidence, decision.Threshold)
+		decisions = append(decisions, decision)
+		if best == nil || result.Confidence > best.Confidence {
+			best = result
 		}
-	} else {
-		log.Printf("[Job %s] Tier 1 skipped: Tesseract not available, starting at Tier 2", req.JobID)
 	}
 
-	// TIER 2: MageAgent with preferAccuracy=false (GPT-4o, balanced speed/accuracy)
-	log.Printf("[Job %s] Tier 2: Attempting MageAgent OCR (preferAccuracy=false → GPT-4o)", req.JobID)
-
-	tier2Result, tier2Err := p.mageAgentClient.ExtractTextFromBytes(
-		ctx,
-		fileData,
-		false, // preferAccuracy=false → GPT-4o (balanced)
-		"en",
-	)
-
-	if tier2Err == nil {
-		log.Printf("[Job %s] Tier 2 complete: model=%s, confidence=%.2f",
-			req.JobID, tier2Result.Data.ModelUsed, tier2Result.Data.Confidence)
-
-		// SUCCESS: GPT-4o confidence >= 0.90 (high quality)
-		if tier2Result.Data.Confidence >= 0.90 {
-			log.Printf("[Job %s] ✓ GPT-4o quality sufficient (%.2f >= 0.90), using result",
-				req.JobID, tier2Result.Data.Confidence)
-
-			result := &OCRResult{
-				Text:       tier2Result.Data.Text,
-				Confidence: tier2Result.Data.Confidence,
-				TierUsed:   fmt.Sprintf("tier2_%s", tier2Result.Data.ModelUsed),
-				Model:      tier2Result.Data.ModelUsed,
-				Cost:       0.0, // Cost tracking in MageAgent
-				Duration:   time.Since(startTime),
-			ImageData:  fileData, // Store for layout analysis
-				Pages: []OCRPage{
-					{
-						PageNumber: 1,
-						Text:       tier2Result.Data.Text,
-						Confidence: tier2Result.Data.Confidence,
-						Words:      []OCRWord{},
-					},
-				},
-			}
-			return result, nil
+	if best == nil {
+		log.Printf("[Job %s page %d] ✗ All OCR tiers failed (policy %s): %s",
+			req.JobID, pageNumber, policy, strings.Join(failures, ", "))
+		err := fmt.Errorf("all OCR tiers failed: %s", strings.Join(failures, ", "))
+		if !ran && p.mageAgentClient == nil {
+			// Local-only: nothing could run, and a retry will not change that
+			return nil, processingerrors.NewMageAgentUnavailableError(req.JobID, "OCR without Tesseract", err)
 		}
-
-		// LOW CONFIDENCE: Escalate to Tier 3
-		log.Printf("[Job %s] ✗ GPT-4o confidence low (%.2f < 0.90), escalating to Tier 3 (Claude Opus)",
-			req.JobID, tier2Result.Data.Confidence)
-	} else {
-		log.Printf("[Job %s] Tier 2 failed: %v, escalating to Tier 3", req.JobID, tier2Err)
+		return nil, err
 	}
+	// Refused or failed escalations leave the most confident result so far
+
+	log.Printf("[Job %s page %d] No tier reached its threshold, using the most confident result: %s (%.2f)",
+		req.JobID, pageNumber, best.TierUsed, best.Confidence)
+	decisions = append(decisions, OCRDecision{
+		Page:       pageNumber,
+		Tier:       ocrPolicyTier(best.TierUsed),
+		Model:      best.Model,
+		Outcome:    OCRDecisionAccepted,
+		Confidence: best.Confidence,
+		Reason:     "no tier reached its threshold; most confident result",
+	})
+	return finishOCRPage(best, pageNumber, pageCost, startTime, decisions), nil
+}
 
-	// TIER 3: MageAgent with preferAccuracy=true (Claude Opus, highest accuracy)
-	log.Printf("[Job %s] Tier 3: Attempting MageAgent OCR (preferAccuracy=true → Claude Opus)", req.JobID)
-
-	tier3Result, tier3Err := p.mageAgentClient.ExtractTextFromBytes(
-		ctx,
-		fileData,
-		true, // preferAccuracy=true → Claude Opus (highest accuracy)
-		"en",
-	)
-
-	if tier3Err != nil {
-		// ALL TIERS FAILED
-		log.Printf("[Job %s] ✗ All OCR tiers failed. Tier1=%v, Tier2=%v, Tier3=%v",
-			req.JobID, "attempted", tier2Err, tier3Err)
-		return nil, fmt.Errorf("all OCR tiers failed: tier2=%w, tier3=%v", tier2Err, tier3Err)
+// runOCRTier runs one tier of the cascade on a page image. The Tesseract
+// pass of language detection is reused when given.
+func (p *DocumentProcessor) runOCRTier(ctx context.Context, tier string, image []byte, languages []string, firstPass *OCRResult) (*OCRResult, error) {
+	if tier == OCRTierTesseract {
+		if firstPass != nil {
+			return firstPass, nil
+		}
+		return p.tesseractOCR.Process(ctx, image, languages)
 	}
 
-	// SUCCESS: Claude Opus result (highest accuracy, accept any confidence)
-	log.Printf("[Job %s] ✓ Tier 3 complete: model=%s, confidence=%.2f (highest accuracy tier)",
-		req.JobID, tier3Result.Data.ModelUsed, tier3Result.Data.Confidence)
+	if p.mageAgentClient == nil {
+		return nil, errMageAgentUnavailable
+	}
 
-	result := &OCRResult{
-		Text:       tier3Result.Data.Text,
-		Confidence: tier3Result.Data.Confidence,
-		TierUsed:   fmt.Sprintf("tier3_%s", tier3Result.Data.ModelUsed),
-		Model:      tier3Result.Data.ModelUsed,
-		Cost:       0.0, // Cost tracking in MageAgent
-		Duration:   time.Since(startTime),
-			ImageData:  fileData, // Store for layout analysis
+	// preferAccuracy=false → GPT-4o (balanced), preferAccuracy=true → Claude Opus (highest accuracy)
+	resp, err := p.mageAgentClient.ExtractTextFromBytes(ctx, image, tier == OCRTierAccurate, strings.Join(languages, ","))
+	if err != nil {
+		return nil, err
+	}
+	cost := resp.Data.Cost
+	if cost <= 0 {
+		cost = p.config.OCRPrices.PageCost(tier, resp.Data.ModelUsed)
+	}
+	return &OCRResult{
+		Text:       resp.Data.Text,
+		Confidence: resp.Data.Confidence,
+		TierUsed:   fmt.Sprintf("%s_%s", tier, resp.Data.ModelUsed),
+		Model:      resp.Data.ModelUsed,
+		Cost:       cost,  // Reported by MageAgent, else priced per page
+		ImageData:  image, // Store for layout analysis
 		Pages: []OCRPage{
 			{
 				PageNumber: 1,
-				Text:       tier3Result.Data.Text,
-				Confidence: tier3Result.Data.Confidence,
+				Text:       resp.Data.Text,
+				Confidence: resp.Data.Confidence,
 				Words:      []OCRWord{},
 			},
 		},
+	}, nil
+}
+
+// finishOCRPage numbers the pages of a tier's result and attaches the cascade's
+// decisions and the cost of every tier run on the page
+func finishOCRPage(result *OCRResult, pageNumber int, cost float64, startTime time.Time, decisions []OCRDecision) *OCRResult {
+	for i := range result.Pages {
+		result.Pages[i].PageNumber = pageNumber
+		result.Pages[i].TierUsed = result.TierUsed
 	}
+	result.Cost = cost
+	result.Duration = time.Since(startTime)
+	result.Decisions = decisions
+	return result
+}
 
-	log.Printf("[Job %s] OCR cascade complete: tier=3, model=%s, confidence=%.2f, duration=%v",
-		req.JobID, result.Model, result.Confidence, result.Duration)
+// ocrPolicyTier maps a result's tier ("tier2_gpt-4o") back to its policy tier ("tier2")
+func ocrPolicyTier(tierUsed string) string {
+	tier, _, _ := strings.Cut(tierUsed, "_")
+	return tier
+}
 
-	return result, nil
+// ocrPolicy is the job's resolved OCR policy, else the configured one
+func (p *DocumentProcessor) ocrPolicy(req *ProcessRequest) OCRPolicy {
+	if req.OCRPolicy != nil {
+		return *req.OCRPolicy
+	}
+	return p.config.OCRPolicy
+}
+
+// resolveOCRPolicy applies the job's metadata.ocrPolicy to the configured
+// policy; an invalid override is logged and ignored. preferAccuracy skips the
+// balanced vision tier unless the job chose its tiers.
+func (p *DocumentProcessor) resolveOCRPolicy(req *ProcessRequest, preferAccuracy bool) {
+	policy := p.config.OCRPolicy
+	if override, ok := req.Metadata["ocrPolicy"]; ok {
+		overridden, err := policy.WithOverrides(override)
+		if err != nil {
+			log.Printf("[Job %s] WARNING: Ignoring metadata.ocrPolicy: %v", req.JobID, err)
+		} else {
+			policy = overridden
+		}
+	}
+	if preferAccuracy {
+		policy = policy.PreferringAccuracy()
+	}
+	if p.mageAgentClient == nil {
+		policy = policy.LocalOnly()
+	}
+	req.OCRPolicy = &policy
+	log.Printf("[Job %s] OCR policy: %s", req.JobID, policy)
 }
 
 // shouldPreferAccuracy determines if high accuracy OCR is needed based on file characteristics
@@ -758,6 +1274,11 @@ func (p *DocumentProcessor) shouldPreferAccuracy(req *ProcessRequest) bool {
 // requiresOCR determines if a file type requires OCR processing
 // Returns true for images and PDFs, false for text-based formats
 func (p *DocumentProcessor) requiresOCR(mimeType string) bool {
+	// Office documents with a native extractor are parsed, not OCRed
+	if nativeExtractorFor(mimeType) != nil {
+		return false
+	}
+
 	// Text-based formats that don't need OCR
 	textFormats := map[string]bool{
 		"text/plain":                  true,
@@ -780,7 +1301,6 @@ func (p *DocumentProcessor) requiresOCR(mimeType string) bool {
 		"text/x-rust":                 true,
 		"text/x-shellscript":          true,
 		"application/vnd.ms-excel":    false, // Excel needs special handling, but not OCR
-		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": false,
 	}
 
 	// Check if it's a text format
@@ -821,6 +1341,11 @@ func detectMimeTypeFromMagicBytes(data []byte) string {
 		return ""
 	}
 
+	// TAR: "ustar" at offset 257 (checked first: the header starts with an arbitrary file name)
+	if isTar(data) {
+		return "application/x-tar"
+	}
+
 	// PDF: %PDF-
 	if bytes.HasPrefix(data, []byte("%PDF")) {
 		return "application/pdf"
@@ -867,11 +1392,18 @@ func detectMimeTypeFromMagicBytes(data []byte) string {
 				return "application/epub+zip"
 			}
 		}
-		// Could be DOCX, XLSX, PPTX, or just ZIP
-		// For now, return zip; caller can check filename extension
+		// Office Open XML documents are identified by their main part
+		if ooxml := detectOOXMLMimeType(data); ooxml != "" {
+			return ooxml
+		}
 		return "application/zip"
 	}
 
+	// GZIP: 0x1F 0x8B (tarballs and single compressed files)
+	if bytes.HasPrefix(data, []byte{0x1F, 0x8B}) {
+		return "application/gzip"
+	}
+
 	// MS Office legacy (DOC, XLS, PPT): 0xD0 0xCF 0x11 0xE0 0xA1 0xB1 0x1A 0xE1
 	if len(data) >= 8 && bytes.HasPrefix(data, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}) {
 		return "application/msword" // Could be DOC, XLS, PPT - generic MS Office
@@ -880,11 +1412,105 @@ func detectMimeTypeFromMagicBytes(data []byte) string {
 	return ""
 }
 
+// processPDF reads a PDF's embedded text layer and sends it to OCR only when
+// some pages have no usable text; those pages take the OCR text. A PDF whose
+// text layer cannot be parsed is OCRed page by page, then via MageAgent.
+func (p *DocumentProcessor) processPDF(ctx context.Context, req *ProcessRequest, fileData []byte) (*OCRResult, error) {
+	startTime := time.Now()
+	layer, err := ExtractPDFTextLayer(fileData)
+	if err != nil {
+		// pdftoppm may still render what the text layer parser cannot read
+		if p.pdfRasterizer != nil {
+			log.Printf("[Job %s] Step 4: PDF text layer unreadable (%v), running the OCR cascade on every page", req.JobID, err)
+			ocrResult, ocrErr := p.ocrPages(ctx, req, 0, func(fn func(page int, image []byte) error) error {
+				return p.pdfRasterizer.Render(ctx, fileData, nil, fn)
+			})
+			if ocrErr == nil {
+				return ocrResult, nil
+			}
+			if ctx.Err() != nil {
+				return nil, ocrErr
+			}
+			err = fmt.Errorf("%v; per-page OCR failed: %v", err, ocrErr)
+		}
+
+		log.Printf("[Job %s] Step 4: PDF text layer unreadable (%v), routing to MageAgent /file-process", req.JobID, err)
+		ocrResult, ocrErr := p.processPDFViaMageAgent(ctx, req, fileData, PDFPageCount(fileData))
+		if errors.Is(ocrErr, errVisionOCRNotAllowed) || errors.Is(ocrErr, errMageAgentUnavailable) {
+			return nil, fmt.Errorf("PDF text layer unreadable (%v) and it cannot be OCRed: %w", err, ocrErr)
+		}
+		return ocrResult, ocrErr
+	}
+
+	if len(layer.NeedsOCR) == 0 {
+		log.Printf("[Job %s] Step 4: Text layer covers all %d pages, skipping OCR", req.JobID, len(layer.Pages))
+		result := layer.Result(nil)
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
+
+	// Pages that do have text tell the language of the scanned ones
+	if len(req.Languages) == 0 {
+		if languages := DetectLanguages(layer.Text()); len(languages) > 0 {
+			req.Languages, req.LanguageSource = languages, LanguageSourceDetected
+			log.Printf("[Job %s] OCR languages detected from the text layer: %v", req.JobID, languages)
+		}
+	}
+
+	// Rasterize just those pages for the per-page cascade; without pdftoppm MageAgent converts the PDF
+	if p.pdfRasterizer != nil {
+		log.Printf("[Job %s] Step 4: %d/%d pages lack a usable text layer (%v), running the OCR cascade on each",
+			req.JobID, len(layer.NeedsOCR), len(layer.Pages), layer.NeedsOCR)
+		ocrResult, err := p.ocrPages(ctx, req, len(layer.NeedsOCR), func(fn func(page int, image []byte) error) error {
+			return p.pdfRasterizer.Render(ctx, fileData, layer.NeedsOCR, fn)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return layer.Result(ocrResult), nil
+	}
+
+	log.Printf("[Job %s] Step 4: %d/%d pages lack a usable text layer (%v), routing PDF to MageAgent /file-process",
+		req.JobID, len(layer.NeedsOCR), len(layer.Pages), layer.NeedsOCR)
+	ocrResult, err := p.processPDFViaMageAgent(ctx, req, fileData, len(layer.Pages))
+	if errors.Is(err, errOCRBudgetExceeded) || errors.Is(err, errMageAgentUnavailable) || errors.Is(err, errVisionOCRNotAllowed) {
+		log.Printf("[Job %s] %v, keeping the text layer only", req.JobID, err)
+		return layer.Result(nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return layer.Result(ocrResult), nil
+}
+
 // processPDFViaMageAgent routes PDF files to MageAgent's /file-process endpoint
 // This endpoint handles PDF → image conversion internally, unlike /vision/extract-text
-func (p *DocumentProcessor) processPDFViaMageAgent(ctx context.Context, req *ProcessRequest, fileData []byte) (*OCRResult, error) {
+// pages is the PDF's page count, used to check the budget: the whole file is sent and every
+// page is charged. A count of 0 means unknown. It runs vision models, so the job's OCR policy
+// must allow a vision tier.
+func (p *DocumentProcessor) processPDFViaMageAgent(ctx context.Context, req *ProcessRequest, fileData []byte, pages int) (*OCRResult, error) {
 	log.Printf("[Job %s] Processing PDF via MageAgent /file-process endpoint", req.JobID)
 
+	if p.mageAgentClient == nil {
+		return nil, processingerrors.NewMageAgentUnavailableError(req.JobID, "PDF OCR via /file-process", errMageAgentUnavailable)
+	}
+
+	if policy := p.ocrPolicy(req); !policy.AllowsVision() {
+		return nil, processingerrors.NewOCRPolicyRejectedError(req.JobID, policy.String(), errVisionOCRNotAllowed)
+	}
+
+	estimate, err := p.config.OCRPrices.FileProcessEstimate(pages, req.OCRBudget)
+	if err != nil {
+		return nil, err
+	}
+	if !req.OCRBudget.Allows(estimate) {
+		return nil, fmt.Errorf("%w: /file-process estimated at $%.4f, %s", errOCRBudgetExceeded, estimate, req.OCRBudget)
+	}
+	reserved, ok := p.reserveOCR(ctx, req, estimate)
+	if !ok {
+		return nil, fmt.Errorf("%w: /file-process estimated at $%.4f would exceed the user's OCR budget", errOCRBudgetExceeded, estimate)
+	}
+
 	startTime := time.Now()
 
 	// Call MageAgent /file-process endpoint which handles PDF conversion internally
@@ -896,10 +1522,12 @@ func (p *DocumentProcessor) processPDFViaMageAgent(ctx context.Context, req *Pro
 		Options: clients.FileProcessOptions{
 			EnableOCR:     true,
 			ExtractTables: true,
+			Languages:     req.Languages,
 		},
 	})
 
 	if err != nil {
+		p.chargeOCR(ctx, req, 0, reserved)
 		return nil, fmt.Errorf("MageAgent /file-process failed: %w", err)
 	}
 
@@ -907,33 +1535,42 @@ func (p *DocumentProcessor) processPDFViaMageAgent(ctx context.Context, req *Pro
 		req.JobID, fileProcessResult.Data.PageCount, fileProcessResult.Data.Confidence)
 
 	// Convert FileProcessResult to OCRResult for pipeline compatibility
-	pages := make([]OCRPage, 0)
+	ocrPages := make([]OCRPage, 0)
 	for i, pageContent := range fileProcessResult.Data.Pages {
-		pages = append(pages, OCRPage{
+		ocrPages = append(ocrPages, OCRPage{
 			PageNumber: i + 1,
 			Text:       pageContent.Text,
 			Confidence: pageContent.Confidence,
 			Words:      []OCRWord{},
+			TierUsed:   "mageagent_file_process",
 		})
 	}
 
 	// If no pages returned, create single page from full text
-	if len(pages) == 0 {
-		pages = append(pages, OCRPage{
+	if len(ocrPages) == 0 {
+		ocrPages = append(ocrPages, OCRPage{
 			PageNumber: 1,
 			Text:       fileProcessResult.Data.Text,
 			Confidence: fileProcessResult.Data.Confidence,
 			Words:      []OCRWord{},
+			TierUsed:   "mageagent_file_process",
 		})
 	}
 
+	cost := fileProcessResult.Data.Cost
+	if cost <= 0 {
+		cost = p.config.OCRPrices.PageCost(OCRTierFileProcess, fileProcessResult.Data.ModelUsed) * float64(len(ocrPages))
+	}
+	p.chargeOCR(ctx, req, cost, reserved)
+
 	result := &OCRResult{
 		Text:       fileProcessResult.Data.Text,
 		Confidence: fileProcessResult.Data.Confidence,
 		TierUsed:   "mageagent_file_process",
 		Model:      fileProcessResult.Data.ModelUsed,
+		Cost:       cost,
 		Duration:   time.Since(startTime),
-		Pages:      pages,
+		Pages:      ocrPages,
 	}
 
 	return result, nil
@@ -944,6 +1581,10 @@ func (p *DocumentProcessor) processPDFViaMageAgent(ctx context.Context, req *Pro
 func (p *DocumentProcessor) processDocumentViaMageAgent(ctx context.Context, req *ProcessRequest, fileData []byte, mimeType string) (*OCRResult, error) {
 	log.Printf("[Job %s] Processing document via MageAgent /file-process endpoint (mime: %s)", req.JobID, mimeType)
 
+	if p.mageAgentClient == nil {
+		return nil, processingerrors.NewMageAgentUnavailableError(req.JobID, mimeType+" conversion", errMageAgentUnavailable)
+	}
+
 	startTime := time.Now()
 
 	// Call MageAgent /file-process endpoint which handles document conversion internally
@@ -0,0 +1,130 @@
+/**
+ * Pipeline Progress Reporting
+ *
+ * ProcessDocument reports stage-level progress through a ProgressReporter
+ * carried on the context (see WithProgressReporter). Each stage owns a slice
+ * of the overall 0-100 range so the UI can render a single progress bar:
+ *
+ *   download 0-15 | analysis 15-20 | ocr 20-60 | layout 60-70 | embedding 70-80
+ *   storage 80-88 | artifact 88-94 | graphrag 94-99
+ *
+ * Without a reporter on the context, reporting is a no-op.
+ */
+
+package processor
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Pipeline stages reported in ProgressUpdate.Stage
+const (
+	StageDownload  = "download"
+	StageAnalysis  = "analysis"
+	StageOCR       = "ocr"
+	StageLayout    = "layout"
+	StageEmbedding = "embedding"
+	StageStorage   = "storage"
+	StageArtifact  = "artifact"
+	StageGraphRAG  = "graphrag"
+)
+
+// stageRanges maps each stage to its [start, end) share of overall progress
+var stageRanges = map[string][2]int{
+	StageDownload:  {0, 15},
+	StageAnalysis:  {15, 20},
+	StageOCR:       {20, 60},
+	StageLayout:    {60, 70},
+	StageEmbedding: {70, 80},
+	StageStorage:   {80, 88},
+	StageArtifact:  {88, 94},
+	StageGraphRAG:  {94, 99},
+}
+
+// ProgressUpdate is a single progress report from the pipeline
+type ProgressUpdate struct {
+	JobID         string                 `json:"jobId"`
+	Stage         string                 `json:"stage"`
+	StageProgress float64                `json:"stageProgress"` // 0.0-1.0 within the stage
+	Progress      int                    `json:"progress"`      // 0-100 overall
+	Message       string                 `json:"message,omitempty"`
+	Stats         map[string]interface{} `json:"stats,omitempty"` // Partial stats (bytes, pages, regions...)
+	Timestamp     time.Time              `json:"timestamp"`
+}
+
+// ProgressReporter receives progress updates for a job
+type ProgressReporter interface {
+	ReportProgress(ctx context.Context, update *ProgressUpdate)
+}
+
+type progressReporterKey struct{}
+
+// WithProgressReporter returns a context that carries the reporter into ProcessDocument
+func WithProgressReporter(ctx context.Context, reporter ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressReporterKey{}, reporter)
+}
+
+// OverallProgress converts progress within a stage to overall 0-100 progress
+func OverallProgress(stage string, stageProgress float64) int {
+	bounds, ok := stageRanges[stage]
+	if !ok {
+		return 0
+	}
+	if stageProgress < 0 {
+		stageProgress = 0
+	}
+	if stageProgress > 1 {
+		stageProgress = 1
+	}
+	return bounds[0] + int(stageProgress*float64(bounds[1]-bounds[0]))
+}
+
+// reportProgress sends a progress update to the reporter on the context, if any
+func reportProgress(ctx context.Context, jobID, stage string, stageProgress float64, message string, stats map[string]interface{}) {
+	reporter, ok := ctx.Value(progressReporterKey{}).(ProgressReporter)
+	if !ok || reporter == nil {
+		return
+	}
+
+	reporter.ReportProgress(ctx, &ProgressUpdate{
+		JobID:         jobID,
+		Stage:         stage,
+		StageProgress: stageProgress,
+		Progress:      OverallProgress(stage, stageProgress),
+		Message:       message,
+		Stats:         stats,
+		Timestamp:     time.Now(),
+	})
+}
+
+// progressReader reports download progress every reportEvery bytes read
+type progressReader struct {
+	reader       io.Reader
+	ctx          context.Context
+	jobID        string
+	total        int64 // Expected size, 0 if unknown
+	read         int64
+	reportEvery  int64
+	lastReported int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+
+	if r.read-r.lastReported >= r.reportEvery {
+		r.lastReported = r.read
+		fraction := 0.0
+		if r.total > 0 {
+			fraction = float64(r.read) / float64(r.total)
+		}
+		reportProgress(r.ctx, r.jobID, StageDownload, fraction, "Downloading file", map[string]interface{}{
+			"bytesRead":  r.read,
+			"totalBytes": r.total,
+		})
+	}
+
+	return n, err
+}
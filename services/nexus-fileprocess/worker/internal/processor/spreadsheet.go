@@ -0,0 +1,348 @@
+/**
+ * Spreadsheet Extraction
+ *
+ * CSV/TSV files are parsed with RFC 4180 quoting (quoted delimiters, escaped
+ * quotes and embedded newlines) and become one table. XLSX workbooks are read
+ * sheet by sheet (see xlsx.go); a sheet holding several blocks of data
+ * separated by empty rows yields one table per block.
+ *
+ * Every table gets a detected header row and an inferred type per column:
+ * number, date, currency or text.
+ */
+
+package processor
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Spreadsheet MIME types
+const (
+	MimeTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	MimeTypeCSV  = "text/csv"
+	MimeTypeTSV  = "text/tab-separated-values"
+)
+
+// Tiers reported for natively parsed spreadsheets
+const (
+	OCRTierNativeXLSX = "native_xlsx"
+	OCRTierNativeCSV  = "native_csv"
+)
+
+// Column types inferred for spreadsheet tables
+const (
+	ColumnTypeNumber   = "number"
+	ColumnTypeDate     = "date"
+	ColumnTypeCurrency = "currency"
+	ColumnTypeText     = "text"
+)
+
+// currencyCodes are the ISO 4217 codes recognized next to amounts ("USD 1,200")
+const currencyCodes = `(USD|EUR|GBP|JPY|CHF|CAD|AUD|NZD|CNY|HKD|SGD|INR|SEK|NOK|DKK)`
+
+var (
+	numberPattern   = regexp.MustCompile(`^[-+]?((\d{1,3}(,\d{3})+|\d+)(\.\d+)?|\.\d+)([eE][-+]?\d+)?%?$`)
+	currencyPattern = regexp.MustCompile(`^[-+]?\(?[-+]?\s*([$€£¥₹]|` + currencyCodes + `\s)\s*[\d,.]+\)?$|^[-+]?\(?[\d,.]+\s*([$€£¥₹]|\s` + currencyCodes + `)\)?$`)
+	dateLayouts     = []string{
+		"2006-01-02", "2006-01-02 15:04:05", "2006-01-02T15:04:05Z07:00", "2006-01-02T15:04:05",
+		"2006/01/02", "01/02/2006", "1/2/2006", "01/02/06", "1/2/06", "02.01.2006", "2.1.2006",
+		"2 Jan 2006", "02 Jan 2006", "Jan 2, 2006", "January 2, 2006", "2 January 2006", "02-Jan-2006",
+	}
+)
+
+// DelimitedMimeType corrects the MIME type of CSV/TSV uploads from their extension.
+// They have no magic bytes, and browsers label them text/plain or application/vnd.ms-excel.
+func DelimitedMimeType(mimeType, filename string) string {
+	switch mimeType {
+	case "", "application/octet-stream", "text/plain", "application/vnd.ms-excel", "application/csv", "text/x-csv":
+	default:
+		return mimeType
+	}
+
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return MimeTypeCSV
+	case ".tsv", ".tab":
+		return MimeTypeTSV
+	}
+	if mimeType == "application/csv" || mimeType == "text/x-csv" {
+		return MimeTypeCSV
+	}
+	return mimeType
+}
+
+// ExtractCSV parses a CSV file into one typed table. The delimiter (comma,
+// semicolon or tab) is detected from the first line.
+func ExtractCSV(data []byte) (*OCRResult, *LayoutResult, error) {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	return extractDelimited(data, sniffDelimiter(data), OCRTierNativeCSV)
+}
+
+// ExtractTSV parses a tab-separated file into one typed table
+func ExtractTSV(data []byte) (*OCRResult, *LayoutResult, error) {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	return extractDelimited(data, '\t', OCRTierNativeCSV)
+}
+
+func extractDelimited(data []byte, delimiter rune, tier string) (*OCRResult, *LayoutResult, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1 // Ragged rows are common in exported files
+	reader.LazyQuotes = true
+
+	// The reader skips empty lines; line numbers keep them as table separators
+	var rows []sheetRow
+	index, lastLine := -1, 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse delimited file: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		index++
+		if lastLine > 0 && line > lastLine+1 {
+			index++
+		}
+		last := len(record) - 1
+		lastLine, _ = reader.FieldPos(last)
+		lastLine += strings.Count(record[last], "\n")
+
+		row := sheetRow{index: index}
+		for col, field := range record {
+			if field = strings.TrimSpace(field); field != "" {
+				row.cells = append(row.cells, sheetCell{col: col, text: field, rowSpan: 1, colSpan: 1})
+			}
+		}
+		if len(row.cells) > 0 {
+			rows = append(rows, row)
+		}
+	}
+
+	doc := newNativeDocument(tier)
+	for _, table := range buildSheetTables("", rows) {
+		doc.addTable(table)
+	}
+	ocrResult, layoutResult := doc.result()
+	return ocrResult, layoutResult, nil
+}
+
+// sniffDelimiter picks the most frequent of comma, semicolon and tab outside quotes on the first line
+func sniffDelimiter(data []byte) rune {
+	counts := map[rune]int{}
+	inQuotes := false
+	for _, r := range string(data[:min(len(data), 64*1024)]) {
+		if r == '"' {
+			inQuotes = !inQuotes
+			continue
+		}
+		if inQuotes {
+			continue
+		}
+		if r == '\n' {
+			break
+		}
+		if r == ',' || r == ';' || r == '\t' {
+			counts[r]++
+		}
+	}
+
+	best := ','
+	for _, r := range []rune{';', '\t'} {
+		if counts[r] > counts[best] {
+			best = r
+		}
+	}
+	return best
+}
+
+// sheetRow is a spreadsheet row with its non-empty cells in column order
+type sheetRow struct {
+	index int
+	cells []sheetCell
+}
+
+// sheetCell is a non-empty spreadsheet cell
+type sheetCell struct {
+	col     int
+	text    string
+	kind    string // Type known from the file (XLSX numbers and dates); "" to infer from the text
+	rowSpan int
+	colSpan int
+}
+
+// valueType returns the cell's column type, inferring it from the text when the file does not say
+func (c sheetCell) valueType() string {
+	if c.kind != "" {
+		return c.kind
+	}
+	return inferValueType(c.text)
+}
+
+// inferValueType classifies a text value as number, currency, date or text
+func inferValueType(text string) string {
+	value := strings.TrimSpace(text)
+	if value == "" {
+		return ""
+	}
+
+	// Accounting negatives: (1,234.00)
+	unwrapped := value
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		unwrapped = value[1 : len(value)-1]
+	}
+	if numberPattern.MatchString(unwrapped) {
+		return ColumnTypeNumber
+	}
+	if currencyPattern.MatchString(value) && strings.ContainsAny(value, "0123456789") {
+		return ColumnTypeCurrency
+	}
+	for _, layout := range dateLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return ColumnTypeDate
+		}
+	}
+	return ColumnTypeText
+}
+
+// buildSheetTables splits rows into blocks separated by empty rows and builds a table per block
+func buildSheetTables(name string, rows []sheetRow) []Table {
+	var tables []Table
+	start := 0
+	for i := 1; i <= len(rows); i++ {
+		if i == len(rows) || rows[i].index != rows[i-1].index+1 {
+			tables = append(tables, buildSheetTable(name, rows[start:i]))
+			start = i
+		}
+	}
+	return tables
+}
+
+// buildSheetTable turns a block of rows into a table with header detection and column types
+func buildSheetTable(name string, rows []sheetRow) Table {
+	minCol, maxCol := -1, 0
+	for _, row := range rows {
+		for _, cell := range row.cells {
+			if minCol < 0 || cell.col < minCol {
+				minCol = cell.col
+			}
+			maxCol = max(maxCol, cell.col+cell.colSpan)
+		}
+	}
+	width := maxCol - minCol
+
+	// Bucket the cells below the first row by column once, so header detection
+	// and column typing scan each column instead of every cell per column
+	below := make([][]sheetCell, width)
+	for _, row := range rows[1:] {
+		for _, cell := range row.cells {
+			below[cell.col-minCol] = append(below[cell.col-minCol], cell)
+		}
+	}
+
+	table := Table{Name: name, HasHeader: detectHeaderRow(rows, below, minCol)}
+
+	for i, row := range rows {
+		tableRow := TableRow{RowNumber: i, Cells: make([]TableCell, 0, len(row.cells))}
+		for _, cell := range row.cells {
+			tableRow.Cells = append(tableRow.Cells, TableCell{
+				ColumnNumber: cell.col - minCol,
+				Content:      cell.text,
+				Confidence:   1.0,
+				RowSpan:      cell.rowSpan,
+				ColSpan:      cell.colSpan,
+			})
+		}
+		table.Rows = append(table.Rows, tableRow)
+	}
+
+	if !table.HasHeader {
+		// The first row is data too
+		for _, cell := range rows[0].cells {
+			below[cell.col-minCol] = append(below[cell.col-minCol], cell)
+		}
+	}
+	table.Columns = make([]TableColumn, width)
+	for c := range table.Columns {
+		table.Columns[c] = TableColumn{Index: c, Type: columnType(below[c])}
+	}
+	if table.HasHeader {
+		for _, cell := range rows[0].cells {
+			table.Columns[cell.col-minCol].Name = cell.text
+		}
+	}
+	return table
+}
+
+// columnType combines the types of a column's values: a single type wins,
+// numbers mixed with currency amounts are currency, anything else is text
+func columnType(cells []sheetCell) string {
+	seen := map[string]bool{}
+	for _, cell := range cells {
+		seen[cell.valueType()] = true
+	}
+
+	switch {
+	case len(seen) == 1 && !seen[ColumnTypeText]:
+		for kind := range seen {
+			return kind
+		}
+	case len(seen) == 2 && seen[ColumnTypeNumber] && seen[ColumnTypeCurrency]:
+		return ColumnTypeCurrency
+	}
+	return ColumnTypeText
+}
+
+// detectHeaderRow decides whether the first row names the columns: it must be
+// text only, cover at least half of the columns, and either sit above typed
+// data or hold values that do not reappear in their columns. below holds the
+// cells of the other rows by column, starting at minCol.
+func detectHeaderRow(rows []sheetRow, below [][]sheetCell, minCol int) bool {
+	width := len(below)
+	if len(rows) < 2 || width == 0 {
+		return false
+	}
+
+	first := rows[0].cells
+	if len(first)*2 < width {
+		return false
+	}
+	for _, cell := range first {
+		if cell.valueType() != ColumnTypeText {
+			return false
+		}
+	}
+
+	names := map[string]bool{}
+	for _, cell := range first {
+		if names[cell.text] {
+			return false
+		}
+		names[cell.text] = true
+	}
+	for _, cell := range first {
+		if columnType(below[cell.col-minCol]) != ColumnTypeText {
+			return true
+		}
+	}
+
+	// All-text table: a header is a row whose values are not repeated below
+	for _, header := range first {
+		for _, cell := range below[header.col-minCol] {
+			if cell.text == header.text {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -3,6 +3,8 @@
  *
  * Simple, free, offline OCR using Tesseract.
  * Used as fallback when MageAgent is unavailable or for speed-optimized processing.
+ * Word boxes and confidences are parsed from hOCR output (see hocr.go).
+ * Languages are ISO 639-1 codes mapped to traineddata names (see languages.go).
  */
 
 package processor
@@ -37,86 +39,49 @@ func NewTesseractOCR(cfg *TesseractConfig) (*TesseractOCR, error) {
 	}, nil
 }
 
-// Process performs OCR using Tesseract
-func (t *TesseractOCR) Process(ctx context.Context, fileData []byte) (*OCRResult, error) {
+// Process performs OCR using Tesseract in the given languages (Tesseract's
+// default when none). Words, lines and confidences come from Tesseract's
+// hOCR output.
+func (t *TesseractOCR) Process(ctx context.Context, fileData []byte, languages []string) (*OCRResult, error) {
 	startTime := time.Now()
 
 	// Create Tesseract client
 	client := gosseract.NewClient()
 	defer client.Close()
 
+	if language := TesseractLanguage(languages); language != "" {
+		if err := client.SetLanguage(strings.Split(language, "+")...); err != nil {
+			return nil, fmt.Errorf("failed to set languages %q: %w", language, err)
+		}
+	}
+
 	// Set image from bytes
 	if err := client.SetImageFromBytes(fileData); err != nil {
 		return nil, fmt.Errorf("failed to set image: %w", err)
 	}
 
-	// Extract text
-	text, err := client.Text()
+	// Recognize with word geometry
+	hocr, err := client.HOCRText()
 	if err != nil {
 		return nil, fmt.Errorf("tesseract OCR failed: %w", err)
 	}
-
-	// Calculate confidence based on text quality indicators
-	confidence := calculateTesseractConfidence(text)
+	page, err := ParseHOCR(hocr)
+	if err != nil {
+		return nil, fmt.Errorf("tesseract OCR failed: %w", err)
+	}
+	page.TierUsed = "tesseract"
 
 	// Build result
 	result := &OCRResult{
-		Text:       text,
-		Confidence: confidence,
+		Text:       page.Text,
+		Confidence: page.Confidence, // Mean word confidence
 		TierUsed:   "tesseract",
 		Model:      "tesseract-local",
 		Cost:       0.0, // Tesseract is free
 		Duration:   time.Since(startTime),
-		Pages: []OCRPage{
-			{
-				PageNumber: 1,
-				Text:       text,
-				Confidence: confidence,
-				Words:      []OCRWord{}, // Word-level extraction requires HOCR parsing
-			},
-		},
+		Pages:      []OCRPage{*page},
+		Languages:  languages,
 	}
 
 	return result, nil
 }
-
-// calculateTesseractConfidence estimates confidence based on text quality
-func calculateTesseractConfidence(text string) float64 {
-	// Dynamic confidence calculation based on text quality
-	confidence := 0.5 // Base confidence
-
-	// Check text length
-	if len(text) > 1000 {
-		confidence += 0.1
-	}
-	if len(text) > 5000 {
-		confidence += 0.1
-	}
-
-	// Check for coherent words (simple heuristic)
-	words := strings.Fields(text)
-	if len(words) > 100 {
-		confidence += 0.1
-	}
-
-	// Check for reasonable character distribution
-	alphaCount := 0
-	for _, r := range text {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
-			alphaCount++
-		}
-	}
-	if len(text) > 0 {
-		alphaRatio := float64(alphaCount) / float64(len(text))
-		if alphaRatio > 0.5 && alphaRatio < 0.9 {
-			confidence += 0.1
-		}
-	}
-
-	// Cap at reasonable maximum for Tesseract
-	if confidence > 0.85 {
-		confidence = 0.85
-	}
-
-	return confidence
-}
@@ -0,0 +1,507 @@
+/**
+ * XLSX Extraction
+ *
+ * Reads every worksheet of an OOXML SpreadsheetML workbook in workbook order.
+ * Cell values come from the sheet XML, shared strings and inline strings;
+ * number formats from styles.xml decide whether a numeric cell is a date
+ * (converted from its serial number, honouring the 1904 date system), a
+ * currency amount or a plain number. Merged ranges map to RowSpan/ColSpan.
+ * References outside Excel's grid (column XFD, row 1048576) are ignored.
+ *
+ * Each sheet becomes one page: a heading with the sheet name followed by its
+ * tables. Formulas are not evaluated; the cached result Excel stored is used.
+ */
+
+package processor
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Excel's sheet size limits
+const (
+	xlsxMaxColumns = 16384   // Column XFD
+	xlsxMaxRows    = 1048576 // Row 1048576
+)
+
+// Built-in number formats (ECMA-376 18.8.30) that display dates/times or currency
+var (
+	builtinDateFormats     = map[int]bool{14: true, 15: true, 16: true, 17: true, 18: true, 19: true, 20: true, 21: true, 22: true, 45: true, 46: true, 47: true}
+	builtinCurrencyFormats = map[int]bool{5: true, 6: true, 7: true, 8: true, 42: true, 44: true}
+)
+
+// ExtractXLSX parses every sheet of an Excel workbook into typed tables
+func ExtractXLSX(data []byte) (*OCRResult, *LayoutResult, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid XLSX package: %w", err)
+	}
+
+	workbook, err := readXLSXWorkbook(zr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var sharedStrings []string
+	if f := findZipFile(zr, "xl/sharedStrings.xml"); f != nil {
+		if sharedStrings, err = readXLSXSharedStrings(f); err != nil {
+			return nil, nil, fmt.Errorf("failed to read shared strings: %w", err)
+		}
+	}
+
+	// Without styles every number is a plain number
+	var formats []string
+	if f := findZipFile(zr, "xl/styles.xml"); f != nil {
+		if parsed, err := readXLSXCellFormats(f); err == nil {
+			formats = parsed
+		}
+	}
+
+	doc := newNativeDocument(OCRTierNativeXLSX)
+	for _, sheet := range workbook.sheets {
+		f := findZipFile(zr, sheet.part)
+		if f == nil {
+			continue // Chart sheets and dangling relationships have no cells
+		}
+
+		reader := &xlsxSheetReader{sharedStrings: sharedStrings, formats: formats, date1904: workbook.date1904}
+		rows, err := reader.read(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read sheet %q: %w", sheet.name, err)
+		}
+
+		doc.sheets = append(doc.sheets, sheet.name)
+		doc.addText(RegionHeading, 1, sheet.name)
+		for _, table := range buildSheetTables(sheet.name, rows) {
+			doc.addTable(table)
+		}
+		doc.breakPage()
+	}
+
+	ocrResult, layoutResult := doc.result()
+	return ocrResult, layoutResult, nil
+}
+
+type xlsxSheet struct {
+	name string
+	part string // Path of the worksheet part inside the package
+}
+
+type xlsxWorkbook struct {
+	sheets   []xlsxSheet
+	date1904 bool
+}
+
+// readXLSXWorkbook lists the sheets of xl/workbook.xml and resolves their parts through the relationships
+func readXLSXWorkbook(zr *zip.Reader) (*xlsxWorkbook, error) {
+	f := findZipFile(zr, "xl/workbook.xml")
+	if f == nil {
+		return nil, fmt.Errorf("invalid XLSX package: xl/workbook.xml not found")
+	}
+
+	rels, err := readRelationships(zr, "xl/workbook.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workbook relationships: %w", err)
+	}
+
+	workbook := &xlsxWorkbook{}
+	err = walkXML(f, func(t xml.StartElement) {
+		switch t.Name.Local {
+		case "workbookPr":
+			date1904 := attr(t, "date1904")
+			workbook.date1904 = date1904 == "1" || date1904 == "true"
+		case "sheet":
+			part := fmt.Sprintf("xl/worksheets/sheet%d.xml", len(workbook.sheets)+1) // Excel's default naming
+			if rel, ok := rels[relationshipID(t)]; ok {
+				part = rel.target
+			}
+			workbook.sheets = append(workbook.sheets, xlsxSheet{name: attr(t, "name"), part: part})
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workbook: %w", err)
+	}
+	return workbook, nil
+}
+
+// readXLSXSharedStrings reads the shared string table. Phonetic runs (rPh) are not part of the value.
+func readXLSXSharedStrings(f *zip.File) ([]string, error) {
+	rc, err := openZipPart(f)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var strs []string
+	var current strings.Builder
+	inText := false
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return strs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				current.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				if err := decoder.Skip(); err != nil {
+					return nil, err
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				strs = append(strs, current.String())
+			case "t":
+				inText = false
+			}
+		case xml.CharData:
+			if inText {
+				current.Write(t)
+			}
+		}
+	}
+}
+
+// readXLSXCellFormats returns the value type (date, currency or "") of each cell format (cellXfs index)
+func readXLSXCellFormats(f *zip.File) ([]string, error) {
+	customFormats := map[int]string{}
+	var xfFormats []int
+	inCellXfs := false
+
+	rc, err := openZipPart(f)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "numFmt":
+				if id, err := strconv.Atoi(attr(t, "numFmtId")); err == nil {
+					customFormats[id] = attr(t, "formatCode")
+				}
+			case "cellXfs":
+				inCellXfs = true
+			case "xf":
+				if inCellXfs {
+					id, _ := strconv.Atoi(attr(t, "numFmtId"))
+					xfFormats = append(xfFormats, id)
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == "cellXfs" {
+				inCellXfs = false
+			}
+		}
+	}
+
+	kinds := make([]string, len(xfFormats))
+	for i, id := range xfFormats {
+		code, custom := customFormats[id]
+		switch {
+		case custom && isDateFormatCode(code), !custom && builtinDateFormats[id]:
+			kinds[i] = ColumnTypeDate
+		case custom && isCurrencyFormatCode(code), !custom && builtinCurrencyFormats[id]:
+			kinds[i] = ColumnTypeCurrency
+		}
+	}
+	return kinds, nil
+}
+
+// isDateFormatCode reports number formats that display a date or time: a y, m, d, h or s
+// outside quoted literals, escapes and [..] sections (colors, conditions, locales)
+func isDateFormatCode(code string) bool {
+	inQuotes := false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case inQuotes:
+			inQuotes = c != '"'
+		case c == '"':
+			inQuotes = true
+		case c == '[':
+			end := strings.IndexByte(code[i:], ']')
+			if end < 0 {
+				return false
+			}
+			// Elapsed time ([h]:mm) still displays a time; colors and locales do not
+			section := strings.ToLower(code[i+1 : i+end])
+			if section != "" && strings.Trim(section, "hms") == "" {
+				return true
+			}
+			i += end
+		case c == '\\' || c == '_' || c == '*':
+			i++ // Escaped literal, padding or fill character
+		default:
+			switch c | 0x20 { // Lower case
+			case 'y', 'm', 'd', 'h', 's':
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isCurrencyFormatCode reports number formats showing a currency symbol or locale currency ([$€-407])
+func isCurrencyFormatCode(code string) bool {
+	return strings.ContainsAny(code, "$€£¥₹") || strings.Contains(code, "[$")
+}
+
+// xlsxSheetReader reads the cells and merged ranges of one worksheet
+type xlsxSheetReader struct {
+	sharedStrings []string
+	formats       []string
+	date1904      bool
+}
+
+// xlsxCellRef locates a cell by zero-based row and column
+type xlsxCellRef struct {
+	row, col int
+}
+
+func (x *xlsxSheetReader) read(f *zip.File) ([]sheetRow, error) {
+	rc, err := openZipPart(f)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var rows []sheetRow
+	var merges [][2]xlsxCellRef
+	var cellType, cellStyle string
+	var value strings.Builder
+	cellRef := xlsxCellRef{row: -1}
+	nextCol := 0
+	inValue := false
+
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				index := cellRef.row + 1
+				if r, err := strconv.Atoi(attr(t, "r")); err == nil && r > 0 && r <= xlsxMaxRows {
+					index = r - 1
+				}
+				rows = append(rows, sheetRow{index: index})
+				cellRef, nextCol = xlsxCellRef{row: index}, 0
+			case "c":
+				cellType, cellStyle = attr(t, "t"), attr(t, "s")
+				cellRef.col = nextCol
+				if ref, ok := parseCellRef(attr(t, "r")); ok {
+					cellRef.col = ref.col
+				}
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			case "rPh":
+				if err := decoder.Skip(); err != nil {
+					return nil, err
+				}
+			case "mergeCell":
+				if from, to, ok := parseRangeRef(attr(t, "ref")); ok {
+					merges = append(merges, [2]xlsxCellRef{from, to})
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				nextCol = cellRef.col + 1
+				if len(rows) == 0 || cellRef.col >= xlsxMaxColumns {
+					continue
+				}
+				cell, ok := x.cell(cellType, cellStyle, value.String())
+				if ok {
+					cell.col = cellRef.col
+					row := &rows[len(rows)-1]
+					row.cells = append(row.cells, cell)
+				}
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+
+	return applyMerges(rows, merges), nil
+}
+
+// cell converts a raw cell value to text and its known type. Empty cells are dropped.
+func (x *xlsxSheetReader) cell(cellType, style, raw string) (sheetCell, bool) {
+	cell := sheetCell{rowSpan: 1, colSpan: 1}
+
+	switch cellType {
+	case "s":
+		index, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || index < 0 || index >= len(x.sharedStrings) {
+			return cell, false
+		}
+		cell.text = x.sharedStrings[index]
+	case "inlineStr", "str":
+		cell.text = raw
+	case "b":
+		cell.text, cell.kind = "FALSE", ColumnTypeText
+		if strings.TrimSpace(raw) == "1" {
+			cell.text = "TRUE"
+		}
+	case "e":
+		cell.text, cell.kind = raw, ColumnTypeText
+	case "d":
+		cell.text, cell.kind = raw, ColumnTypeDate
+	default:
+		raw = strings.TrimSpace(raw)
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			cell.text = raw
+			break
+		}
+		cell.text, cell.kind = raw, ColumnTypeNumber
+		if index, err := strconv.Atoi(style); err == nil && index >= 0 && index < len(x.formats) {
+			switch x.formats[index] {
+			case ColumnTypeDate:
+				cell.text, cell.kind = excelSerialToDate(number, x.date1904), ColumnTypeDate
+			case ColumnTypeCurrency:
+				cell.kind = ColumnTypeCurrency
+			}
+		}
+	}
+
+	cell.text = strings.TrimSpace(cell.text)
+	return cell, cell.text != ""
+}
+
+// excelSerialToDate formats a serial date number: days since 1899-12-30 (which absorbs
+// Excel's fictitious 29 Feb 1900) or since 1904-01-01 in the 1904 date system
+func excelSerialToDate(serial float64, date1904 bool) string {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	t := epoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+
+	switch {
+	case days == 0 && seconds > 0 && !date1904:
+		return t.Format("15:04:05") // Time of day only
+	case seconds == 0:
+		return t.Format("2006-01-02")
+	default:
+		return t.Format("2006-01-02 15:04:05")
+	}
+}
+
+// parseCellRef parses an A1-style reference ("AB12") into zero-based row and column.
+// References beyond column XFD or row 1048576 are invalid.
+func parseCellRef(ref string) (xlsxCellRef, bool) {
+	ref = strings.ReplaceAll(ref, "$", "")
+	i, col := 0, 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+		if col > xlsxMaxColumns {
+			return xlsxCellRef{}, false
+		}
+	}
+	row, err := strconv.Atoi(ref[i:])
+	if i == 0 || err != nil || row < 1 || row > xlsxMaxRows {
+		return xlsxCellRef{}, false
+	}
+	return xlsxCellRef{row: row - 1, col: col - 1}, true
+}
+
+// parseRangeRef parses "A1:C3" into its top-left and bottom-right corners
+func parseRangeRef(ref string) (xlsxCellRef, xlsxCellRef, bool) {
+	from, to, ok := strings.Cut(ref, ":")
+	if !ok {
+		return xlsxCellRef{}, xlsxCellRef{}, false
+	}
+	start, ok1 := parseCellRef(from)
+	end, ok2 := parseCellRef(to)
+	topLeft := xlsxCellRef{row: min(start.row, end.row), col: min(start.col, end.col)}
+	bottomRight := xlsxCellRef{row: max(start.row, end.row), col: max(start.col, end.col)}
+	return topLeft, bottomRight, ok1 && ok2
+}
+
+// applyMerges sets the spans of merge origins and drops the cells they cover.
+// Rows left without cells are removed so empty rows keep separating tables.
+func applyMerges(rows []sheetRow, merges [][2]xlsxCellRef) []sheetRow {
+	if len(merges) > 0 {
+		origins := map[xlsxCellRef][2]int{}
+		for _, merge := range merges {
+			origins[merge[0]] = [2]int{merge[1].row - merge[0].row + 1, merge[1].col - merge[0].col + 1}
+		}
+
+		for r := range rows {
+			kept := rows[r].cells[:0]
+			for _, cell := range rows[r].cells {
+				ref := xlsxCellRef{row: rows[r].index, col: cell.col}
+				if span, ok := origins[ref]; ok {
+					cell.rowSpan, cell.colSpan = span[0], span[1]
+				} else if coveredByMerge(ref, merges) {
+					continue
+				}
+				kept = append(kept, cell)
+			}
+			rows[r].cells = kept
+		}
+	}
+
+	nonEmpty := rows[:0]
+	for _, row := range rows {
+		if len(row.cells) > 0 {
+			nonEmpty = append(nonEmpty, row)
+		}
+	}
+	return nonEmpty
+}
+
+func coveredByMerge(ref xlsxCellRef, merges [][2]xlsxCellRef) bool {
+	for _, merge := range merges {
+		if ref.row >= merge[0].row && ref.row <= merge[1].row && ref.col >= merge[0].col && ref.col <= merge[1].col {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+/**
+ * Pluggable Queue Backends
+ *
+ * The worker consumes jobs through the Backend interface so the transport can
+ * be chosen from configuration (QUEUE_BACKEND):
+ * - list     Redis LIST protocol shared with the TypeScript RedisQueue (default)
+ * - streams  Redis Streams with consumer groups and XAUTOCLAIM recovery
+ * - asynq    Asynq task queue
+ *
+ * Job data, status sets, results, events and the dead-letter queue use the same
+ * <queue>:* keys on every backend; only message delivery and acknowledgement differ.
+ */
+
+package queue
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Supported backend types
+const (
+	BackendList    = "list"
+	BackendStreams = "streams"
+	BackendAsynq   = "asynq"
+)
+
+// Backend is a queue transport the worker consumes jobs from
+type Backend interface {
+	// Start begins consuming jobs in the background
+	Start() error
+	// Stop waits for in-progress jobs and releases the backend's resources
+	Stop() error
+	// Ack settles a successfully processed delivery
+	Ack(ctx context.Context, delivery *Delivery) error
+	// Nack settles a failed delivery, retrying or dead-lettering it
+	Nack(ctx context.Context, delivery *Delivery, cause error) error
+	// GetStats returns queue statistics
+	GetStats() (map[string]int64, error)
+}
+
+// Delivery is a single job handed to the worker by a backend
+type Delivery struct {
+	ID         string        // Backend message ID (LIST job ID, stream entry ID or Asynq task ID)
+	QueueJobID string        // Key of the job in <queue>:data
+	Job        *RedisJobData // Decoded job, set once the delivery has been loaded
+}
+
+// ackFunc queues the backend-specific acknowledgement of a delivery on a transaction,
+// so retries, dead-lettering and quarantine settle the message atomically
+type ackFunc func(ctx context.Context, pipe redis.Pipeliner)
+
+// NewBackend creates the queue backend of the given type
+func NewBackend(backendType string, cfg *RedisConsumerConfig) (Backend, error) {
+	switch backendType {
+	case "", BackendList:
+		return NewRedisConsumer(cfg)
+	case BackendStreams:
+		return NewStreamConsumer(cfg)
+	case BackendAsynq:
+		return NewConsumer(&ConsumerConfig{
+			RedisURL:          cfg.RedisURL,
+			QueueName:         cfg.QueueName,
+			Concurrency:       cfg.Concurrency,
+			Processor:         cfg.Processor,
+			ProcessingTimeout: cfg.ProcessingTimeout,
+			WorkerID:          cfg.WorkerID,
+			RetryPolicy:       cfg.RetryPolicy,
+			Notifier:          cfg.Notifier,
+			Batches:           cfg.Batches,
+		})
+	default:
+		return nil, fmt.Errorf("unknown queue backend %q (expected %s, %s or %s)",
+			backendType, BackendList, BackendStreams, BackendAsynq)
+	}
+}
+
+// streamKey is the Redis stream used by the streams backend
+func streamKey(queueName string) string {
+	return fmt.Sprintf("%s:stream", queueName)
+}
+
+// enqueue queues a job ID on the transport of the given backend
+func enqueue(ctx context.Context, pipe redis.Pipeliner, backendType, queueName, queueJobID string) {
+	if backendType == BackendStreams {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: streamKey(queueName),
+			Values: map[string]interface{}{"id": queueJobID},
+		})
+		return
+	}
+	pipe.LPush(ctx, queueName, queueJobID)
+}
+
+// applyRedisDefaults validates a Redis backend configuration and fills in defaults
+func applyRedisDefaults(cfg *RedisConsumerConfig) error {
+	if cfg.RedisURL == "" {
+		return fmt.Errorf("RedisURL is required")
+	}
+
+	if cfg.QueueName == "" {
+		cfg.QueueName = "fileprocess:jobs"
+	}
+
+	if cfg.Processor == nil {
+		return fmt.Errorf("Processor is required")
+	}
+
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 10
+	}
+
+	if cfg.WorkerID == "" {
+		cfg.WorkerID = defaultWorkerID()
+	}
+
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = defaultVisibilityTimeoutMs
+	}
+
+	if cfg.HeartbeatInterval <= 0 {
+		// Several heartbeats must fit inside one visibility timeout
+		cfg.HeartbeatInterval = defaultHeartbeatIntervalMs
+		if cfg.HeartbeatInterval > cfg.VisibilityTimeout/4 {
+			cfg.HeartbeatInterval = cfg.VisibilityTimeout / 4
+		}
+	}
+
+	if cfg.HeartbeatInterval >= cfg.VisibilityTimeout {
+		return fmt.Errorf("HeartbeatInterval (%dms) must be shorter than VisibilityTimeout (%dms)",
+			cfg.HeartbeatInterval, cfg.VisibilityTimeout)
+	}
+
+	if cfg.ReaperInterval <= 0 {
+		cfg.ReaperInterval = defaultReaperIntervalMs
+	}
+
+	if cfg.RetryPolicy == nil {
+		cfg.RetryPolicy = DefaultRetryPolicy()
+	}
+
+	if cfg.SchedulerInterval <= 0 {
+		cfg.SchedulerInterval = defaultSchedulerIntervalMs
+	}
+
+	if len(cfg.LaneWeights) == 0 {
+		cfg.LaneWeights = DefaultLaneWeights()
+	}
+
+	if cfg.DefaultLane == "" {
+		cfg.DefaultLane = LaneInteractive
+	}
+
+	if _, ok := cfg.LaneWeights[cfg.DefaultLane]; !ok {
+		return fmt.Errorf("DefaultLane %q has no weight in LaneWeights", cfg.DefaultLane)
+	}
+
+	if cfg.ConsumerGroup == "" {
+		cfg.ConsumerGroup = defaultConsumerGroup
+	}
+
+	return nil
+}
+
+// connectRedis parses the Redis URL and verifies the connection
+func connectRedis(redisURL string) (*redis.Client, error) {
+	opt, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	client := redis.NewClient(opt)
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return client, nil
+}
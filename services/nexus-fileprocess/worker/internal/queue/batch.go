@@ -0,0 +1,96 @@
+/**
+ * Batch Accounting
+ *
+ * Jobs enqueued together carry a batchId (JobPayload.BatchID or
+ * metadata.batchId). As each child reaches a final state the runner records
+ * it against the batch; the child that finishes the batch publishes a
+ * batch:completed event on <queue>:events with the aggregate summary.
+ */
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+	"github.com/adverant/nexus/fileprocess-worker/internal/storage"
+)
+
+// BatchRecorder records the final outcome of batch children (implemented by storage.StorageManager)
+type BatchRecorder interface {
+	RecordBatchJob(ctx context.Context, outcome *storage.BatchJobOutcome) (*storage.BatchSummary, error)
+}
+
+// batchID returns the batch a job belongs to: BatchID, then metadata.batchId
+func (p *JobPayload) batchID() string {
+	if p.BatchID != "" {
+		return p.BatchID
+	}
+	batchID, _ := p.Metadata["batchId"].(string)
+	return batchID
+}
+
+// recordBatchOutcome updates the job's batch and announces the batch once its last child finishes
+func (r *jobRunner) recordBatchOutcome(job *RedisJobData, status string, result interface{}, cause error) {
+	batchID := job.Payload.batchID()
+	if batchID == "" || r.config.Batches == nil {
+		return
+	}
+
+	outcome := &storage.BatchJobOutcome{
+		BatchID:   batchID,
+		UserID:    job.Payload.UserID,
+		BatchSize: job.Payload.BatchSize,
+		JobID:     job.Payload.JobID,
+		Filename:  job.Payload.Filename,
+		Status:    status,
+	}
+	if processResult, ok := result.(*processor.ProcessResult); ok {
+		outcome.OCRTierUsed = processResult.OCRTierUsed
+		outcome.PageCount = processResult.PageCount
+	}
+	if cause != nil {
+		outcome.ErrorMessage = cause.Error()
+	}
+
+	summary, err := r.config.Batches.RecordBatchJob(r.ctx, outcome)
+	if err != nil {
+		log.Printf("[Job %s] Warning: Failed to record outcome for batch %s: %v", job.Payload.JobID, batchID, err)
+		return
+	}
+
+	log.Printf("[Batch %s] %d/%d jobs finished (%d failed, %d cancelled)",
+		batchID, summary.CompletedJobs+summary.FailedJobs+summary.CancelledJobs, summary.TotalJobs,
+		summary.FailedJobs, summary.CancelledJobs)
+
+	if summary.JustFinished {
+		r.publishBatchCompleted(summary)
+	}
+}
+
+// publishBatchCompleted emits batch:completed for WebSocket streaming
+func (r *jobRunner) publishBatchCompleted(summary *storage.BatchSummary) {
+	event := map[string]interface{}{
+		"event":         "batch:completed",
+		"batchId":       summary.ID,
+		"userId":        summary.UserID,
+		"status":        summary.Status,
+		"totalJobs":     summary.TotalJobs,
+		"completedJobs": summary.CompletedJobs,
+		"failedJobs":    summary.FailedJobs,
+		"cancelledJobs": summary.CancelledJobs,
+		"totalPages":    summary.TotalPages,
+		"tierUsage":     summary.TierUsage,
+		"failures":      summary.Failures,
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}
+	eventData, _ := json.Marshal(event)
+	r.client.Publish(r.ctx, fmt.Sprintf("%s:events", r.config.QueueName), eventData)
+
+	log.Printf("[Batch %s] Finished: status=%s, completed=%d, failed=%d, cancelled=%d, pages=%d",
+		summary.ID, summary.Status, summary.CompletedJobs, summary.FailedJobs, summary.CancelledJobs, summary.TotalPages)
+}
@@ -0,0 +1,161 @@
+/**
+ * Job Cancellation
+ *
+ * A cancellation request for a job (by its PostgreSQL job ID) is two things:
+ * - <queue>:cancel:<jobId>   marker key (24h TTL) so jobs still waiting, delayed
+ *                            or redelivered are skipped when they are picked up
+ * - <queue>:control          pub/sub message {"action":"cancel","jobId":...} so a
+ *                            worker already processing the job aborts it
+ *
+ * Every backend watches the control channel and cancels the per-job context
+ * passed to ProcessDocument. Cancelled jobs are acked, marked cancelled in Redis
+ * and PostgreSQL, and a job:cancelled event is published.
+ */
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const cancelRequestTTL = 24 * time.Hour
+
+// errJobCancelled is the cancellation cause of a job context cancelled by request
+var errJobCancelled = errors.New("job cancelled")
+
+// errDeliveryLost is the cause of a job context aborted because another worker
+// took over its delivery. The job is left to that worker, unsettled.
+var errDeliveryLost = errors.New("delivery taken over by another worker")
+
+// ControlMessage is a message on the <queue>:control channel
+type ControlMessage struct {
+	Action string `json:"action"` // "cancel"
+	JobID  string `json:"jobId"`
+}
+
+// cancelKey is the cancellation marker for a job
+func cancelKey(queueName, jobID string) string {
+	return fmt.Sprintf("%s:cancel:%s", queueName, jobID)
+}
+
+// controlChannel is the pub/sub channel workers watch for control messages
+func controlChannel(queueName string) string {
+	return fmt.Sprintf("%s:control", queueName)
+}
+
+// RequestCancel marks a job as cancelled and notifies the worker processing it, if any
+func RequestCancel(ctx context.Context, client *redis.Client, queueName, jobID string) error {
+	if jobID == "" {
+		return fmt.Errorf("job ID is required")
+	}
+
+	message, err := json.Marshal(ControlMessage{Action: "cancel", JobID: jobID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal control message: %w", err)
+	}
+
+	pipe := client.TxPipeline()
+	pipe.Set(ctx, cancelKey(queueName, jobID), time.Now().Unix(), cancelRequestTTL)
+	pipe.Publish(ctx, controlChannel(queueName), message)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to request cancellation of job %s: %w", jobID, err)
+	}
+
+	return nil
+}
+
+// cancelRegistry tracks the cancel functions of jobs being processed by this worker
+type cancelRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]context.CancelCauseFunc
+}
+
+func newCancelRegistry() *cancelRegistry {
+	return &cancelRegistry{jobs: make(map[string]context.CancelCauseFunc)}
+}
+
+// register records a job's cancel function and returns a func that removes it
+func (r *cancelRegistry) register(jobID string, cancel context.CancelCauseFunc) func() {
+	r.mu.Lock()
+	r.jobs[jobID] = cancel
+	r.mu.Unlock()
+
+	return func() {
+		r.mu.Lock()
+		delete(r.jobs, jobID)
+		r.mu.Unlock()
+	}
+}
+
+// cancel aborts a job if this worker is processing it
+func (r *cancelRegistry) cancel(jobID string) bool {
+	return r.abort(jobID, errJobCancelled)
+}
+
+// abort cancels a job's context with the given cause if this worker is processing it
+func (r *cancelRegistry) abort(jobID string, cause error) bool {
+	r.mu.Lock()
+	cancel, ok := r.jobs[jobID]
+	r.mu.Unlock()
+
+	if ok {
+		cancel(cause)
+	}
+	return ok
+}
+
+// cancelRequested reports whether cancellation of a job has been requested
+func (r *jobRunner) cancelRequested(ctx context.Context, jobID string) bool {
+	exists, err := r.client.Exists(ctx, cancelKey(r.config.QueueName, jobID)).Result()
+	if err != nil {
+		log.Printf("[Queue] WARNING: Failed to check cancellation of job %s: %v", jobID, err)
+		return false
+	}
+	return exists > 0
+}
+
+// controlLoop applies control messages to jobs in progress until the backend stops
+func (r *jobRunner) controlLoop(wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	pubsub := r.client.Subscribe(r.ctx, controlChannel(r.config.QueueName))
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			var control ControlMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &control); err != nil {
+				log.Printf("[Queue] WARNING: Ignoring malformed control message: %v", err)
+				continue
+			}
+
+			if control.Action == "cancel" && r.cancels.cancel(control.JobID) {
+				log.Printf("[Job %s] Cancellation requested, aborting processing", control.JobID)
+			}
+		}
+	}
+}
+
+// markCancelled records a cancelled job, which is final for its batch. The marker is
+// left to expire so a redelivery is skipped too.
+func (r *jobRunner) markCancelled(job *RedisJobData) {
+	r.updateJobStatus(job.Payload.JobID, "cancelled", nil)
+	r.recordBatchOutcome(job, JobStatusCancelled, nil, nil)
+	log.Printf("Job %s cancelled", job.Payload.JobID)
+}
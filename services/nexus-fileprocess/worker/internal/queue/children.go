@@ -0,0 +1,127 @@
+/**
+ * Archive Child Jobs
+ *
+ * When the processor expands an archive, every extracted entry is enqueued as
+ * a job of its own. Children point back at the parent through
+ * metadata.parentJobId and form a batch whose ID is the parent job ID, so
+ * batch:completed announces that the whole archive has been processed.
+ *
+ * Child job IDs are derived from the parent job ID and the entry path, so
+ * expanding the same archive again (a redelivered parent) enqueues nothing new.
+ * The parent's callbackUrl is not inherited; the parent's own completion
+ * notification lists the child job IDs.
+ */
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// childNamespace seeds the name-based UUIDs of child jobs
+var childNamespace = uuid.MustParse("42087b7c-fe72-43b0-8b82-6b0f456bfe2f")
+
+// enqueueChildScript stores a child job and queues it unless a job with the same ID exists.
+// KEYS[1] = <queue>:data, KEYS[2] = intake list or stream; ARGV = id, job JSON, "list"|"stream"
+var enqueueChildScript = redis.NewScript(`
+if redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+if ARGV[3] == 'stream' then
+	redis.call('XADD', KEYS[2], '*', 'id', ARGV[1])
+else
+	redis.call('LPUSH', KEYS[2], ARGV[1])
+end
+return 1
+`)
+
+// childJobID is the stable job ID of an archive entry
+func childJobID(parentJobID, entryPath string) string {
+	return uuid.NewSHA1(childNamespace, []byte(parentJobID+"\x00"+entryPath)).String()
+}
+
+// enqueueChildren enqueues the documents extracted from an archive and records their IDs on the result
+func (r *jobRunner) enqueueChildren(ctx context.Context, parent *RedisJobData, result *processor.ProcessResult) error {
+	children := result.Children
+	ids := make([]string, 0, len(children))
+
+	for _, child := range children {
+		job := childJob(parent, child, len(children))
+		if err := r.submitChild(ctx, job); err != nil {
+			return fmt.Errorf("failed to enqueue child %s (%s): %w", job.Payload.JobID, child.Path, err)
+		}
+		ids = append(ids, job.Payload.JobID)
+	}
+
+	result.Children = nil
+	result.ChildJobIDs = ids
+	log.Printf("[Job %s] Enqueued %d child jobs from archive", parent.Payload.JobID, len(ids))
+	return nil
+}
+
+// childJob builds the queue job for an extracted archive entry
+func childJob(parent *RedisJobData, child *processor.ChildDocument, siblings int) *RedisJobData {
+	metadata := make(map[string]interface{}, len(parent.Payload.Metadata)+3)
+	for key, value := range parent.Payload.Metadata {
+		if key == "callbackUrl" || key == "batchId" {
+			continue
+		}
+		metadata[key] = value
+	}
+	metadata["parentJobId"] = parent.Payload.JobID
+	metadata["archivePath"] = child.Path
+	metadata["archiveDepth"] = child.Depth
+
+	id := childJobID(parent.Payload.JobID, child.Path)
+	return &RedisJobData{
+		ID:         id,
+		Type:       parent.Type,
+		CreatedAt:  time.Now(),
+		MaxRetries: parent.MaxRetries,
+		Payload: JobPayload{
+			JobID:      id,
+			UserID:     parent.Payload.UserID,
+			Filename:   child.Filename,
+			MimeType:   child.MimeType,
+			FileSize:   int64(len(child.Data)),
+			Priority:   parent.Payload.Priority,
+			BatchID:    parent.Payload.JobID,
+			BatchSize:  siblings,
+			FileBuffer: child.Data,
+			Metadata:   metadata,
+		},
+	}
+}
+
+// submitChild queues a child job on this runner's backend. Existing jobs are left alone.
+func (r *jobRunner) submitChild(ctx context.Context, job *RedisJobData) error {
+	if r.submit != nil {
+		r.externalizePayload(ctx, job)
+		return r.submit(ctx, job)
+	}
+
+	dataKey := fmt.Sprintf("%s:data", r.config.QueueName)
+	if exists, err := r.client.HExists(ctx, dataKey, job.ID).Result(); err == nil && exists {
+		return nil
+	}
+
+	r.externalizePayload(ctx, job)
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal child job: %w", err)
+	}
+
+	target, mode := r.config.QueueName, "list"
+	if r.backend == BackendStreams {
+		target, mode = streamKey(r.config.QueueName), "stream"
+	}
+	return enqueueChildScript.Run(ctx, r.client, []string{dataKey, target}, job.ID, jobData, mode).Err()
+}
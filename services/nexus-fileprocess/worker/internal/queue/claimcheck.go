@@ -0,0 +1,59 @@
+/**
+ * Claim-Check Payloads
+ *
+ * Jobs may carry their file either inline (fileBuffer) or as a reference to
+ * the blob store (blobRef). Producers should send files above the inline limit
+ * (INLINE_PAYLOAD_MAX_BYTES) as blobs; the worker enforces the same limit
+ * whenever it writes a job back to <queue>:data (retries, dead-lettering), so
+ * an oversized inline buffer is stored in Redis at most once.
+ *
+ * Blobs the worker externalizes itself live under jobs/<jobId>/payload and are
+ * deleted once the job completes. Producer-owned blobs are never deleted here.
+ */
+
+package queue
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+)
+
+// workerBlobKey is the blob store key for a payload externalized by the worker
+func workerBlobKey(job *RedisJobData) string {
+	return fmt.Sprintf("jobs/%s/payload", job.Payload.JobID)
+}
+
+// externalizePayload moves an inline file buffer above the inline limit into the blob store.
+// Without a blob store, or if the upload fails, the buffer stays inline.
+func (r *jobRunner) externalizePayload(ctx context.Context, job *RedisJobData) {
+	store := r.config.BlobStore
+	if store == nil || r.config.InlineBufferLimit <= 0 || int64(len(job.Payload.FileBuffer)) <= r.config.InlineBufferLimit {
+		return
+	}
+
+	key := workerBlobKey(job)
+	size := int64(len(job.Payload.FileBuffer))
+	if err := store.Put(ctx, key, bytes.NewReader(job.Payload.FileBuffer), size); err != nil {
+		log.Printf("[Job %s] Warning: Failed to externalize %d byte payload, keeping it inline: %v",
+			job.Payload.JobID, size, err)
+		return
+	}
+
+	job.Payload.BlobRef = key
+	job.Payload.FileBuffer = nil
+	log.Printf("[Job %s] Externalized %d byte payload to blob %s", job.Payload.JobID, size, key)
+}
+
+// releasePayload deletes a blob the worker externalized once the job no longer needs it
+func (r *jobRunner) releasePayload(ctx context.Context, job *RedisJobData) {
+	store := r.config.BlobStore
+	if store == nil || job.Payload.BlobRef == "" || job.Payload.BlobRef != workerBlobKey(job) {
+		return
+	}
+
+	if err := store.Delete(ctx, job.Payload.BlobRef); err != nil {
+		log.Printf("[Job %s] Warning: Failed to delete payload blob %s: %v", job.Payload.JobID, job.Payload.BlobRef, err)
+	}
+}
@@ -3,6 +3,10 @@
  *
  * Consumes jobs from BullMQ/Redis queue and processes documents.
  * Uses Asynq (Go BullMQ-compatible library) for queue management.
+ *
+ * This is the "asynq" Backend. Asynq owns delivery, retries and archiving of
+ * tasks; status tracking in Redis and PostgreSQL is shared with the other
+ * backends through jobRunner.
  */
 
 package queue
@@ -10,33 +14,27 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
-	"github.com/adverant/nexus/fileprocess-worker/internal/errors"
+	processingerrors "github.com/adverant/nexus/fileprocess-worker/internal/errors"
 	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
 	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
 )
 
-// JobData represents the structure of job data from BullMQ
-type JobData struct {
-	JobID      string                 `json:"jobId"`
-	UserID     string                 `json:"userId"`
-	Filename   string                 `json:"filename"`
-	MimeType   string                 `json:"mimeType,omitempty"`
-	FileSize   int64                  `json:"fileSize,omitempty"`
-	FileURL    string                 `json:"fileUrl,omitempty"`
-	FileBuffer []byte                 `json:"fileBuffer,omitempty"`
-	Metadata   map[string]interface{} `json:"metadata,omitempty"`
-}
-
 // Consumer handles job consumption from Redis queue
 type Consumer struct {
 	client    *asynq.Client
 	server    *asynq.Server
+	inspector *asynq.Inspector
 	mux       *asynq.ServeMux
-	processor processor.DocumentProcessorInterface
+	runner    jobRunner
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
 	config    *ConsumerConfig
 }
 
@@ -46,7 +44,11 @@ type ConsumerConfig struct {
 	QueueName         string
 	Concurrency       int
 	Processor         processor.DocumentProcessorInterface
-	ProcessingTimeout int64 // Processing timeout in milliseconds (default: 300000 = 5 minutes)
+	ProcessingTimeout int64         // Processing timeout in milliseconds (default: 300000 = 5 minutes)
+	WorkerID          string        // Recorded on quarantined payloads (default: hostname-pid)
+	RetryPolicy       *RetryPolicy  // Backoff between attempts (default: DefaultRetryPolicy())
+	Notifier          JobNotifier   // Told when jobs complete or finally fail (nil: disabled)
+	Batches           BatchRecorder // Records outcomes of jobs with a batchId (nil: batches not tracked)
 }
 
 // NewConsumer creates a new queue consumer
@@ -63,12 +65,26 @@ func NewConsumer(cfg *ConsumerConfig) (*Consumer, error) {
 		return nil, fmt.Errorf("Processor is required")
 	}
 
+	if cfg.WorkerID == "" {
+		cfg.WorkerID = defaultWorkerID()
+	}
+
+	if cfg.RetryPolicy == nil {
+		cfg.RetryPolicy = DefaultRetryPolicy()
+	}
+
 	// Parse Redis connection options
 	redisOpt, err := asynq.ParseRedisURI(cfg.RedisURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
 	}
 
+	// Status keys, events and quarantine use a plain Redis client
+	statusClient, err := connectRedis(cfg.RedisURL)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create Asynq client for task submission (if needed)
 	client := asynq.NewClient(redisOpt)
 
@@ -81,19 +97,13 @@ func NewConsumer(cfg *ConsumerConfig) (*Consumer, error) {
 				cfg.QueueName: 10, // Priority 10 for main queue
 				"default":     1,  // Priority 1 for fallback
 			},
-			// Retry configuration
+			// Retry configuration - same backoff as the Redis backends
 			RetryDelayFunc: func(n int, err error, task *asynq.Task) time.Duration {
-				// Exponential backoff: 5s, 10s, 20s
-				delay := time.Duration(5*(1<<uint(n))) * time.Second
-				if delay > 60*time.Second {
-					delay = 60 * time.Second
-				}
-				return delay
+				return cfg.RetryPolicy.Delay(n + 1)
 			},
 			// Error handling
 			ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
-				log.Printf("Task processing error: type=%s, payload=%s, error=%v",
-					task.Type(), string(task.Payload()), err)
+				log.Printf("Task processing error: type=%s, error=%v", task.Type(), err)
 			}),
 			// Logging - asynq provides a default logger if not specified
 			// The standard log package doesn't implement asynq.Logger interface
@@ -104,14 +114,35 @@ func NewConsumer(cfg *ConsumerConfig) (*Consumer, error) {
 	// Create multiplexer for task routing
 	mux := asynq.NewServeMux()
 
+	consumerCtx, cancel := context.WithCancel(context.Background())
+
 	consumer := &Consumer{
 		client:    client,
 		server:    server,
+		inspector: asynq.NewInspector(redisOpt),
 		mux:       mux,
-		processor: cfg.Processor,
-		config:    cfg,
+		runner: jobRunner{
+			client:    statusClient,
+			processor: cfg.Processor,
+			config: &RedisConsumerConfig{
+				QueueName:         cfg.QueueName,
+				Processor:         cfg.Processor,
+				ProcessingTimeout: cfg.ProcessingTimeout,
+				WorkerID:          cfg.WorkerID,
+				RetryPolicy:       cfg.RetryPolicy,
+				Notifier:          cfg.Notifier,
+				Batches:           cfg.Batches,
+			},
+			ctx:     consumerCtx,
+			backend: BackendAsynq,
+			cancels: newCancelRegistry(),
+		},
+		cancel: cancel,
+		config: cfg,
 	}
 
+	consumer.runner.submit = consumer.submitChild
+
 	// Register task handler
 	mux.HandleFunc("process-document", consumer.handleProcessDocument)
 
@@ -119,26 +150,32 @@ func NewConsumer(cfg *ConsumerConfig) (*Consumer, error) {
 }
 
 // Start starts the queue consumer
-func (c *Consumer) Start(ctx context.Context) error {
+func (c *Consumer) Start() error {
 	log.Printf("Starting queue consumer (concurrency=%d, queue=%s)...",
 		c.config.Concurrency, c.config.QueueName)
 
-	// Start server in a goroutine
-	go func() {
-		if err := c.server.Run(c.mux); err != nil {
-			log.Printf("Queue consumer error: %v", err)
-		}
-	}()
+	if err := c.server.Start(c.mux); err != nil {
+		return fmt.Errorf("failed to start Asynq server: %w", err)
+	}
+
+	// Watch the control channel for cancellation requests
+	c.wg.Add(1)
+	go c.runner.controlLoop(&c.wg)
 
 	return nil
 }
 
 // Stop stops the queue consumer gracefully
-func (c *Consumer) Stop(ctx context.Context) error {
+func (c *Consumer) Stop() error {
 	log.Printf("Stopping queue consumer...")
 
 	// Shutdown server gracefully
 	c.server.Shutdown()
+	c.cancel()
+	c.wg.Wait()
+
+	c.inspector.Close()
+	c.runner.client.Close()
 
 	// Close client
 	if err := c.client.Close(); err != nil {
@@ -151,100 +188,127 @@ func (c *Consumer) Stop(ctx context.Context) error {
 
 // handleProcessDocument processes a document processing job
 func (c *Consumer) handleProcessDocument(ctx context.Context, task *asynq.Task) error {
-	startTime := time.Now()
+	taskID, _ := asynq.GetTaskID(ctx)
+	retryCount, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
 
 	// Parse job data
-	var jobData JobData
-	if err := json.Unmarshal(task.Payload(), &jobData); err != nil {
-		return fmt.Errorf("failed to unmarshal job data: %w", err)
+	var payload JobPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		// Asynq acks the task itself; nothing backend-specific to settle
+		c.runner.quarantine(context.Background(), taskID, task.Payload(), err, func(context.Context, redis.Pipeliner) {})
+		return fmt.Errorf("failed to unmarshal job data: %v: %w", err, asynq.SkipRetry)
 	}
 
 	log.Printf("[Job %s] Processing document: filename=%s, size=%d bytes, user=%s",
-		jobData.JobID, jobData.Filename, jobData.FileSize, jobData.UserID)
-
-	// Update job status to processing
-	if err := c.processor.UpdateJobStatus(ctx, jobData.JobID, "processing", 0, nil); err != nil {
-		log.Printf("[Job %s] Warning: Failed to update status to processing: %v", jobData.JobID, err)
+		payload.JobID, payload.Filename, payload.FileSize, payload.UserID)
+
+	delivery := &Delivery{
+		ID:         taskID,
+		QueueJobID: taskID,
+		Job: &RedisJobData{
+			ID:         taskID,
+			Type:       task.Type(),
+			Payload:    payload,
+			Attempts:   retryCount,
+			MaxRetries: maxRetry + 1, // Asynq counts retries, not attempts
+		},
 	}
 
-	// =========================================================================
-	// CRITICAL FIX: Create timeout context to prevent 30-60 second hangs
-	// =========================================================================
-	// Default timeout: 5 minutes (300000ms)
-	// Configurable via ConsumerConfig.ProcessingTimeout
-	// =========================================================================
-	timeout := time.Duration(300000) * time.Millisecond
-	if c.config.ProcessingTimeout > 0 {
-		timeout = time.Duration(c.config.ProcessingTimeout) * time.Millisecond
+	// Returning the error lets Asynq retry or archive the task. The job runs under
+	// the handler's context so Asynq's deadline and shutdown cancel it.
+	err := c.runner.run(ctx, c, delivery)
+	if processingerrors.IsPermanent(err) {
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
 	}
+	return err
+}
 
-	log.Printf("[Job %s] Processing timeout set to: %v", jobData.JobID, timeout)
-
-	// Create timeout context
-	processCtx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	// Process document with timeout
-	result, err := c.processor.ProcessDocument(processCtx, &processor.ProcessRequest{
-		JobID:      jobData.JobID,
-		UserID:     jobData.UserID,
-		Filename:   jobData.Filename,
-		MimeType:   jobData.MimeType,
-		FileSize:   jobData.FileSize,
-		FileURL:    jobData.FileURL,
-		FileBuffer: jobData.FileBuffer,
-		Metadata:   jobData.Metadata,
-	})
-
-	duration := time.Since(startTime)
+// submitChild enqueues a job created by the worker (an archive entry) as an Asynq task
+func (c *Consumer) submitChild(ctx context.Context, job *RedisJobData) error {
+	return enqueueAsynqTask(ctx, c.client, c.config.QueueName, job)
+}
 
+// enqueueAsynqTask enqueues a job as an Asynq task whose ID is the job's queue ID.
+// A task with that ID already queued counts as enqueued.
+func enqueueAsynqTask(ctx context.Context, client *asynq.Client, queueName string, job *RedisJobData) error {
+	payload, err := json.Marshal(job.Payload)
 	if err != nil {
-		// Check if error was due to timeout
-		if processCtx.Err() == context.DeadlineExceeded {
-			log.Printf("[Job %s] Processing timed out after %v (timeout: %v)", jobData.JobID, duration, timeout)
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
 
-			// Create structured timeout error
-			timeoutErr := errors.NewProcessingTimeoutError(jobData.JobID, timeout, err)
-			errorMap := timeoutErr.ToMap()
+	taskType := job.Type
+	if taskType == "" {
+		taskType = "process-document"
+	}
 
-			// Update job status to failed with timeout error
-			if updateErr := c.processor.UpdateJobStatus(ctx, jobData.JobID, "failed", 100, errorMap); updateErr != nil {
-				log.Printf("[Job %s] Warning: Failed to update status to failed: %v", jobData.JobID, updateErr)
-			}
+	maxRetry := job.MaxRetries - 1 // Asynq counts retries, not attempts
+	if maxRetry < 0 {
+		maxRetry = 0
+	}
 
-			return fmt.Errorf("processing timeout: %w", timeoutErr)
-		}
+	_, err = client.EnqueueContext(ctx, asynq.NewTask(taskType, payload),
+		asynq.Queue(queueName), asynq.TaskID(job.ID), asynq.MaxRetry(maxRetry))
+	if errors.Is(err, asynq.ErrTaskIDConflict) || errors.Is(err, asynq.ErrDuplicateTask) {
+		return nil
+	}
+	return err
+}
 
-		log.Printf("[Job %s] Processing failed after %v: %v", jobData.JobID, duration, err)
+// Ack is a no-op: Asynq completes the task when the handler returns nil
+func (c *Consumer) Ack(ctx context.Context, delivery *Delivery) error {
+	return nil
+}
 
-		// Update job status to failed
-		if updateErr := c.processor.UpdateJobStatus(ctx, jobData.JobID, "failed", 100, map[string]interface{}{
-			"error":           err.Error(),
-			"processingTime": duration.Milliseconds(),
-		}); updateErr != nil {
-			log.Printf("[Job %s] Warning: Failed to update status to failed: %v", jobData.JobID, updateErr)
-		}
+// Nack marks the job failed and dead-letters it once Asynq has no retries left or the
+// error is permanent; earlier failures are retried by Asynq with the configured backoff.
+// Asynq also archives the exhausted task; replaying the dead-letter entry re-enqueues it.
+func (c *Consumer) Nack(ctx context.Context, delivery *Delivery, cause error) error {
+	job := delivery.Job
+	job.Attempts++
+
+	if job.Attempts < job.MaxRetries && !processingerrors.IsPermanent(cause) {
+		log.Printf("[Job %s] Attempt %d/%d failed, Asynq will retry: %v",
+			job.Payload.JobID, job.Attempts, job.MaxRetries, cause)
+		return nil
+	}
 
-		return fmt.Errorf("document processing failed: %w", err)
+	// Asynq keeps no attempt history, so the entry records the final attempt
+	job.Errors = append(job.Errors, AttemptError{
+		Attempt:  job.Attempts,
+		Error:    cause.Error(),
+		FailedAt: time.Now(),
+		WorkerID: c.config.WorkerID,
+	})
+	job.LastError = cause.Error()
+	c.runner.updateJobStatus(job.Payload.JobID, "failed", map[string]interface{}{
+		"error":     cause.Error(),
+		"errorCode": string(processingerrors.CodeOf(cause)),
+		"attempts":  job.Attempts,
+	})
+	// Asynq acks the task itself; nothing backend-specific to settle
+	if err := c.runner.deadLetter(ctx, job, func(context.Context, redis.Pipeliner) {}); err != nil {
+		log.Printf("[Job %s] WARNING: %v", job.Payload.JobID, err)
 	}
+	c.runner.finished(job, JobStatusFailed, nil, cause)
+	return nil
+}
 
-	log.Printf("[Job %s] Processing completed successfully in %v: confidence=%.2f, tier=%s, dnaId=%s",
-		jobData.JobID, duration, result.Confidence, result.OCRTierUsed, result.DocumentDNAID)
-
-	// Update job status to completed
-	if err := c.processor.UpdateJobStatus(ctx, jobData.JobID, "completed", 100, map[string]interface{}{
-		"confidence":         result.Confidence,
-		"processingTime":     duration.Milliseconds(),
-		"documentDnaId":      result.DocumentDNAID,
-		"ocrTierUsed":        result.OCRTierUsed,
-		"tablesExtracted":    result.TablesExtracted,
-		"regionsExtracted":   result.RegionsExtracted,
-		"embeddingGenerated": result.EmbeddingGenerated,
-	}); err != nil {
-		log.Printf("[Job %s] Warning: Failed to update status to completed: %v", jobData.JobID, err)
+// GetStats returns queue statistics
+func (c *Consumer) GetStats() (map[string]int64, error) {
+	stats := c.runner.sharedStats(context.Background())
+
+	info, err := c.inspector.GetQueueInfo(c.config.QueueName)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get Asynq queue info: %w", err)
 	}
 
-	return nil
+	stats["waiting"] = int64(info.Pending)
+	stats["processing"] = int64(info.Active)
+	stats["delayed"] = int64(info.Scheduled + info.Retry)
+	stats["dead"] = int64(info.Archived)
+
+	return stats, nil
 }
 
 // GetStatistics returns consumer statistics
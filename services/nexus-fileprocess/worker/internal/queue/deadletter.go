@@ -0,0 +1,332 @@
+/**
+ * Dead-Letter Queue
+ *
+ * Jobs that exhaust MaxRetries on any backend are stored with their full
+ * RedisJobData and attempt history so they can be re-driven after an upstream
+ * fix (Asynq jobs are replayed as new Asynq tasks):
+ * - <queue>:dead         hash of job ID -> DeadLetterEntry JSON
+ * - <queue>:dead:index   sorted set of job IDs scored by dead-letter time
+ *
+ * The legacy :failed set and :errors hash are still maintained for the
+ * TypeScript RedisQueue, which reads them for job status.
+ */
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// AttemptError records a single failed processing attempt
+type AttemptError struct {
+	Attempt  int       `json:"attempt"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failedAt"`
+	WorkerID string    `json:"workerId,omitempty"`
+}
+
+// DeadLetterEntry is a job that exhausted its retries
+type DeadLetterEntry struct {
+	Job            RedisJobData   `json:"job"`
+	Errors         []AttemptError `json:"errors"`
+	DeadLetteredAt time.Time      `json:"deadLetteredAt"`
+	Backend        string         `json:"backend,omitempty"` // Backend the job is replayed onto (default: list)
+}
+
+// DeadLetterFilter selects dead-lettered jobs for listing, replay or purge.
+// Zero-valued fields match everything.
+type DeadLetterFilter struct {
+	UserID        string    // Exact match on Payload.UserID
+	MimeType      string    // Exact match on Payload.MimeType
+	ErrorContains string    // Case-insensitive substring of the last attempt error
+	Before        time.Time // Dead-lettered before this time
+	After         time.Time // Dead-lettered after this time
+}
+
+// Matches reports whether the entry satisfies the filter
+func (f *DeadLetterFilter) Matches(entry *DeadLetterEntry) bool {
+	if f == nil {
+		return true
+	}
+	if f.UserID != "" && entry.Job.Payload.UserID != f.UserID {
+		return false
+	}
+	if f.MimeType != "" && entry.Job.Payload.MimeType != f.MimeType {
+		return false
+	}
+	if f.ErrorContains != "" {
+		lastError := ""
+		if len(entry.Errors) > 0 {
+			lastError = entry.Errors[len(entry.Errors)-1].Error
+		}
+		if !strings.Contains(strings.ToLower(lastError), strings.ToLower(f.ErrorContains)) {
+			return false
+		}
+	}
+	if !f.Before.IsZero() && !entry.DeadLetteredAt.Before(f.Before) {
+		return false
+	}
+	if !f.After.IsZero() && !entry.DeadLetteredAt.After(f.After) {
+		return false
+	}
+	return true
+}
+
+// DeadLetterQueue provides admin operations over dead-lettered jobs
+type DeadLetterQueue struct {
+	client    *redis.Client
+	queueName string
+}
+
+// NewDeadLetterQueue creates a dead-letter admin client for the given queue
+func NewDeadLetterQueue(client *redis.Client, queueName string) (*DeadLetterQueue, error) {
+	if client == nil {
+		return nil, fmt.Errorf("Redis client is required")
+	}
+
+	if queueName == "" {
+		queueName = "fileprocess:jobs"
+	}
+
+	return &DeadLetterQueue{
+		client:    client,
+		queueName: queueName,
+	}, nil
+}
+
+func deadKey(queueName string) string {
+	return fmt.Sprintf("%s:dead", queueName)
+}
+
+func deadIndexKey(queueName string) string {
+	return fmt.Sprintf("%s:dead:index", queueName)
+}
+
+// Count returns the number of dead-lettered jobs
+func (d *DeadLetterQueue) Count(ctx context.Context) (int64, error) {
+	return d.client.ZCard(ctx, deadIndexKey(d.queueName)).Result()
+}
+
+// List returns dead-lettered jobs matching the filter, newest first.
+// A limit of 0 returns every match.
+func (d *DeadLetterQueue) List(ctx context.Context, filter *DeadLetterFilter, offset, limit int) ([]*DeadLetterEntry, error) {
+	ids, err := d.client.ZRevRange(ctx, deadIndexKey(d.queueName), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead-letter index: %w", err)
+	}
+
+	entries := make([]*DeadLetterEntry, 0)
+	skipped := 0
+	for _, id := range ids {
+		entry, err := d.Get(ctx, id)
+		if err != nil {
+			log.Printf("[DLQ] WARNING: Skipping unreadable entry %s: %v", id, err)
+			continue
+		}
+		if !filter.Matches(entry) {
+			continue
+		}
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		entries = append(entries, entry)
+		if limit > 0 && len(entries) >= limit {
+			break
+		}
+	}
+
+	return entries, nil
+}
+
+// Get returns a single dead-lettered job
+func (d *DeadLetterQueue) Get(ctx context.Context, jobID string) (*DeadLetterEntry, error) {
+	data, err := d.client.HGet(ctx, deadKey(d.queueName), jobID).Result()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("dead-lettered job not found: %s", jobID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead-lettered job: %w", err)
+	}
+
+	var entry DeadLetterEntry
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead-lettered job %s: %w", jobID, err)
+	}
+
+	return &entry, nil
+}
+
+// Replay puts a dead-lettered job back on the queue with its attempt counter reset
+func (d *DeadLetterQueue) Replay(ctx context.Context, jobID string) error {
+	entry, err := d.Get(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	job := entry.Job
+	job.Attempts = 0
+	job.NextAttemptAt = nil
+	job.LastError = ""
+
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal replayed job: %w", err)
+	}
+
+	if entry.Backend == BackendAsynq {
+		if err := d.replayAsynqTask(ctx, &job); err != nil {
+			return fmt.Errorf("failed to replay job %s: %w", jobID, err)
+		}
+	}
+
+	pipe := d.client.TxPipeline()
+	pipe.HSet(ctx, fmt.Sprintf("%s:data", d.queueName), job.ID, jobData)
+	pipe.HDel(ctx, deadKey(d.queueName), jobID)
+	pipe.ZRem(ctx, deadIndexKey(d.queueName), jobID)
+	pipe.SRem(ctx, fmt.Sprintf("%s:failed", d.queueName), job.Payload.JobID)
+	pipe.HDel(ctx, fmt.Sprintf("%s:errors", d.queueName), job.Payload.JobID)
+	if entry.Backend != BackendAsynq {
+		enqueue(ctx, pipe, entry.Backend, d.queueName, job.ID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to replay job %s: %w", jobID, err)
+	}
+
+	d.publishEvent(ctx, "job:replayed", job.Payload.JobID)
+	log.Printf("[DLQ] Replayed job %s (queue id %s, %d previous attempt errors)",
+		job.Payload.JobID, job.ID, len(entry.Errors))
+	return nil
+}
+
+// sharedRedisConn lets Asynq use the dead-letter queue's Redis client. Asynq
+// clients built on it must not be closed, as that closes the shared client.
+type sharedRedisConn struct {
+	client *redis.Client
+}
+
+func (c sharedRedisConn) MakeRedisClient() interface{} {
+	return c.client
+}
+
+// replayAsynqTask replaces the task Asynq archived for a dead-lettered job with a new one
+func (d *DeadLetterQueue) replayAsynqTask(ctx context.Context, job *RedisJobData) error {
+	conn := sharedRedisConn{client: d.client}
+	err := asynq.NewInspector(conn).DeleteTask(d.queueName, job.ID)
+	if err != nil && !errors.Is(err, asynq.ErrTaskNotFound) && !errors.Is(err, asynq.ErrQueueNotFound) {
+		return fmt.Errorf("failed to delete archived task: %w", err)
+	}
+	return enqueueAsynqTask(ctx, asynq.NewClient(conn), d.queueName, job)
+}
+
+// ReplayFiltered replays every dead-lettered job matching the filter (nil replays all)
+func (d *DeadLetterQueue) ReplayFiltered(ctx context.Context, filter *DeadLetterFilter) (int, error) {
+	entries, err := d.List(ctx, filter, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for _, entry := range entries {
+		if err := d.Replay(ctx, entry.Job.ID); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+// Purge permanently deletes a dead-lettered job and its queue data
+func (d *DeadLetterQueue) Purge(ctx context.Context, jobID string) error {
+	entry, err := d.Get(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	pipe := d.client.TxPipeline()
+	pipe.HDel(ctx, deadKey(d.queueName), jobID)
+	pipe.ZRem(ctx, deadIndexKey(d.queueName), jobID)
+	pipe.HDel(ctx, fmt.Sprintf("%s:data", d.queueName), entry.Job.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to purge job %s: %w", jobID, err)
+	}
+
+	log.Printf("[DLQ] Purged job %s (queue id %s)", entry.Job.Payload.JobID, entry.Job.ID)
+	return nil
+}
+
+// PurgeFiltered purges every dead-lettered job matching the filter (nil purges all)
+func (d *DeadLetterQueue) PurgeFiltered(ctx context.Context, filter *DeadLetterFilter) (int, error) {
+	entries, err := d.List(ctx, filter, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, entry := range entries {
+		if err := d.Purge(ctx, entry.Job.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// publishEvent publishes a job event on the queue's events channel
+func (d *DeadLetterQueue) publishEvent(ctx context.Context, eventName string, jobID string) {
+	event := map[string]interface{}{
+		"event":     eventName,
+		"jobId":     jobID,
+		"timestamp": time.Now().Format(time.RFC3339),
+	}
+	eventData, _ := json.Marshal(event)
+	d.client.Publish(ctx, fmt.Sprintf("%s:events", d.queueName), eventData)
+}
+
+// deadLetter moves a job that exhausted its retries into the dead-letter queue and acks it
+func (r *jobRunner) deadLetter(ctx context.Context, job *RedisJobData, ack ackFunc) error {
+	r.externalizePayload(ctx, job)
+
+	entry := DeadLetterEntry{
+		Job:            *job,
+		Errors:         job.Errors,
+		DeadLetteredAt: time.Now(),
+		Backend:        r.backend,
+	}
+
+	entryData, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead-letter entry: %w", err)
+	}
+
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, fmt.Sprintf("%s:data", r.config.QueueName), job.ID, jobData)
+	pipe.HSet(ctx, deadKey(r.config.QueueName), job.ID, entryData)
+	pipe.ZAdd(ctx, deadIndexKey(r.config.QueueName), redis.Z{
+		Score:  float64(entry.DeadLetteredAt.UnixMilli()),
+		Member: job.ID,
+	})
+	ack(ctx, pipe)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to dead-letter job: %w", err)
+	}
+
+	log.Printf("Job %s moved to dead-letter queue after %d attempts", job.Payload.JobID, job.Attempts)
+	return nil
+}
@@ -0,0 +1,210 @@
+/**
+ * In-Flight Tracking for the Redis LIST Consumer
+ *
+ * Gives RedisConsumer at-least-once delivery semantics:
+ * - Job IDs are moved atomically (BLMOVE) from the queue into a per-worker
+ *   in-flight list instead of being popped and forgotten
+ * - Each worker refreshes a heartbeat key while it is alive
+ * - A reaper returns jobs from workers whose heartbeat has expired to the
+ *   head of the queue once the visibility timeout has passed
+ */
+
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultVisibilityTimeoutMs = 60000 // 1 minute without a heartbeat before jobs are reclaimed
+	defaultHeartbeatIntervalMs = 15000 // 15 seconds
+	defaultReaperIntervalMs    = 30000 // 30 seconds
+)
+
+// defaultWorkerID builds a worker identity from the pod hostname and process ID
+func defaultWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "worker"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// workersKey is the set of worker IDs that own (or owned) an in-flight list
+func (c *RedisConsumer) workersKey() string {
+	return fmt.Sprintf("%s:workers", c.config.QueueName)
+}
+
+// inflightKey is the in-flight list for the given worker
+func (c *RedisConsumer) inflightKey(workerID string) string {
+	return fmt.Sprintf("%s:inflight:%s", c.config.QueueName, workerID)
+}
+
+// heartbeatKey is the liveness key for the given worker (expires after the visibility timeout)
+func (c *RedisConsumer) heartbeatKey(workerID string) string {
+	return fmt.Sprintf("%s:heartbeat:%s", c.config.QueueName, workerID)
+}
+
+// visibilityTimeout returns the configured visibility timeout
+func (c *RedisConsumer) visibilityTimeout() time.Duration {
+	return time.Duration(c.config.VisibilityTimeout) * time.Millisecond
+}
+
+// registerWorker publishes the first heartbeat and records this worker in the workers set
+func (c *RedisConsumer) registerWorker(ctx context.Context) error {
+	pipe := c.client.TxPipeline()
+	pipe.SAdd(ctx, c.workersKey(), c.config.WorkerID)
+	pipe.Set(ctx, c.heartbeatKey(c.config.WorkerID), time.Now().Unix(), c.visibilityTimeout())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to register worker %s: %w", c.config.WorkerID, err)
+	}
+	return nil
+}
+
+// deregisterWorker removes this worker's heartbeat once its in-flight list has drained
+func (c *RedisConsumer) deregisterWorker(ctx context.Context) {
+	remaining, err := c.client.LLen(ctx, c.inflightKey(c.config.WorkerID)).Result()
+	if err != nil {
+		log.Printf("[Queue] WARNING: Failed to check in-flight list on shutdown: %v", err)
+		return
+	}
+
+	if remaining > 0 {
+		// Leave the worker registered so the reaper can recover what is left
+		log.Printf("[Queue] WARNING: %d job(s) still in flight for worker %s, leaving them for the reaper",
+			remaining, c.config.WorkerID)
+		return
+	}
+
+	pipe := c.client.TxPipeline()
+	pipe.Del(ctx, c.heartbeatKey(c.config.WorkerID))
+	pipe.SRem(ctx, c.workersKey(), c.config.WorkerID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("[Queue] WARNING: Failed to deregister worker %s: %v", c.config.WorkerID, err)
+	}
+}
+
+// recoverOwnInflight requeues anything left in this worker's in-flight list by a previous
+// process with the same worker ID. Nothing can be in flight before Start, so it is all orphaned.
+func (c *RedisConsumer) recoverOwnInflight(ctx context.Context) error {
+	recovered, err := c.requeueInflight(ctx, c.config.WorkerID)
+	if err != nil {
+		return err
+	}
+	if recovered > 0 {
+		log.Printf("[Queue] Recovered %d orphaned job(s) from previous run of worker %s",
+			recovered, c.config.WorkerID)
+	}
+	return nil
+}
+
+// heartbeatLoop refreshes this worker's heartbeat until the consumer stops
+func (c *RedisConsumer) heartbeatLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(c.config.HeartbeatInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.client.Set(c.ctx, c.heartbeatKey(c.config.WorkerID), time.Now().Unix(), c.visibilityTimeout()).Err(); err != nil {
+				log.Printf("[Queue] WARNING: Heartbeat failed for worker %s: %v", c.config.WorkerID, err)
+			}
+		}
+	}
+}
+
+// reaperLoop periodically reclaims jobs held by workers whose heartbeat has expired
+func (c *RedisConsumer) reaperLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(c.config.ReaperInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.reapDeadWorkers(c.ctx); err != nil && c.ctx.Err() == nil {
+				log.Printf("[Queue] WARNING: Reaper pass failed: %v", err)
+			}
+		}
+	}
+}
+
+// reapDeadWorkers requeues the in-flight jobs of every registered worker without a live heartbeat
+func (c *RedisConsumer) reapDeadWorkers(ctx context.Context) error {
+	workerIDs, err := c.client.SMembers(ctx, c.workersKey()).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list workers: %w", err)
+	}
+
+	for _, workerID := range workerIDs {
+		if workerID == c.config.WorkerID {
+			continue
+		}
+
+		alive, err := c.client.Exists(ctx, c.heartbeatKey(workerID)).Result()
+		if err != nil {
+			return fmt.Errorf("failed to check heartbeat for worker %s: %w", workerID, err)
+		}
+		if alive > 0 {
+			continue
+		}
+
+		recovered, err := c.requeueInflight(ctx, workerID)
+		if err != nil {
+			return err
+		}
+
+		// Only forget the worker once its list is empty; a concurrent reaper may still be draining it
+		remaining, err := c.client.LLen(ctx, c.inflightKey(workerID)).Result()
+		if err == nil && remaining == 0 {
+			c.client.SRem(ctx, c.workersKey(), workerID)
+		}
+
+		if recovered > 0 {
+			log.Printf("[Queue] Reaper requeued %d job(s) from dead worker %s (no heartbeat for %v)",
+				recovered, workerID, c.visibilityTimeout())
+		}
+	}
+
+	return nil
+}
+
+// requeueInflight moves every job ID in a worker's in-flight list back to the head of the queue.
+// LMOVE is atomic per element, so competing reapers never duplicate or lose a job.
+func (c *RedisConsumer) requeueInflight(ctx context.Context, workerID string) (int, error) {
+	recovered := 0
+	for {
+		// Consumers pop from the RIGHT, so pushing on the RIGHT puts recovered jobs next in line
+		_, err := c.client.LMove(ctx, c.inflightKey(workerID), c.config.QueueName, "LEFT", "RIGHT").Result()
+		if err == redis.Nil {
+			return recovered, nil
+		}
+		if err != nil {
+			return recovered, fmt.Errorf("failed to requeue in-flight jobs for worker %s: %w", workerID, err)
+		}
+		recovered++
+	}
+}
+
+// releaseJob hands an in-flight job back to the head of the queue without processing it
+func (c *RedisConsumer) releaseJob(ctx context.Context, jobID string) {
+	pipe := c.client.TxPipeline()
+	pipe.LRem(ctx, c.inflightKey(c.config.WorkerID), 1, jobID)
+	pipe.RPush(ctx, c.config.QueueName, jobID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("[Queue] WARNING: Failed to release job %s, leaving it for the reaper: %v", jobID, err)
+	}
+}
@@ -0,0 +1,299 @@
+/**
+ * Priority Lanes and Fair Scheduling for the Redis LIST Consumer
+ *
+ * Producers keep pushing job IDs onto the single <queue> list, which is now an
+ * intake. A router on each worker moves every job from the intake into a
+ * per-user sub-queue of its priority lane:
+ * - <queue>:lane:<lane>:users         ring of user IDs with queued jobs
+ * - <queue>:lane:<lane>:user:<userId> job IDs of one user in that lane
+ * - <queue>:lanes:wake                one token per routed job; idle workers block on it
+ *
+ * Workers pick a lane by smooth weighted round-robin (interactive=6, bulk=3,
+ * reprocess=1 by default) and rotate through the lane's users, so one tenant's
+ * bulk import cannot starve everyone else. Routing and picking both go through
+ * the worker's in-flight list, so crash recovery is unchanged.
+ *
+ * Lanes require a single Redis node (standalone or Sentinel), not Redis Cluster:
+ * the pick script reads a user from the lane's ring and builds that user's
+ * sub-queue key itself, so not every key it touches can be declared in KEYS.
+ */
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Built-in priority lanes
+const (
+	LaneInteractive = "interactive"
+	LaneBulk        = "bulk"
+	LaneReprocess   = "reprocess"
+
+	anonymousUser = "_" // Sub-queue for jobs without a user ID
+)
+
+// DefaultLaneWeights returns the lane weights used when none are configured
+func DefaultLaneWeights() map[string]int {
+	return map[string]int{
+		LaneInteractive: 6,
+		LaneBulk:        3,
+		LaneReprocess:   1,
+	}
+}
+
+// maxWakeTokens caps the wake list, so tokens left over while every worker was busy
+// cause at most a few spurious wake-ups
+const maxWakeTokens = 100
+
+// wakeTimeout is how long an idle worker blocks on the wake list before looking again
+const wakeTimeout = 5 * time.Second
+
+// routeScript moves a job ID from the in-flight list to its user's sub-queue, adds the
+// user to the lane's ring if they are not already in it and wakes one idle worker
+var routeScript = redis.NewScript(`
+redis.call('LREM', KEYS[1], 1, ARGV[1])
+redis.call('LPUSH', KEYS[2], ARGV[1])
+if not redis.call('LPOS', KEYS[3], ARGV[2]) then
+	redis.call('RPUSH', KEYS[3], ARGV[2])
+end
+redis.call('LPUSH', KEYS[4], 1)
+redis.call('LTRIM', KEYS[4], 0, tonumber(ARGV[3]) - 1)
+return 1
+`)
+
+// pickScript rotates the lane's user ring and moves the oldest job of the first user with
+// work into the in-flight list. Users whose sub-queue is empty drop out of the ring.
+// Sub-queue keys are ARGV[1] .. user, which is why lanes do not work on Redis Cluster.
+var pickScript = redis.NewScript(`
+local n = redis.call('LLEN', KEYS[1])
+for i = 1, n do
+	local user = redis.call('LMOVE', KEYS[1], KEYS[1], 'LEFT', 'RIGHT')
+	if not user then
+		return false
+	end
+	local id = redis.call('LMOVE', ARGV[1] .. user, KEYS[2], 'RIGHT', 'LEFT')
+	if id then
+		return id
+	end
+	redis.call('LREM', KEYS[1], 0, user)
+end
+return false
+`)
+
+// LaneScheduler orders lanes by smooth weighted round-robin, so over time each lane is
+// tried first in proportion to its weight without long runs of the same lane
+type LaneScheduler struct {
+	mu      sync.Mutex
+	lanes   []string
+	weights map[string]int
+	current map[string]int
+	total   int
+}
+
+// NewLaneScheduler creates a scheduler for the given lane weights
+func NewLaneScheduler(weights map[string]int) *LaneScheduler {
+	s := &LaneScheduler{
+		weights: make(map[string]int, len(weights)),
+		current: make(map[string]int, len(weights)),
+	}
+	for lane, weight := range weights {
+		if weight <= 0 {
+			continue
+		}
+		s.lanes = append(s.lanes, lane)
+		s.weights[lane] = weight
+		s.total += weight
+	}
+	// Deterministic tie-breaking
+	sort.Strings(s.lanes)
+	return s
+}
+
+// Next returns every lane in the order they should be tried for the next pull.
+// The first lane is the round-robin pick; the rest follow by weight as fallbacks.
+func (s *LaneScheduler) Next() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.lanes) == 0 {
+		return nil
+	}
+
+	best := ""
+	for _, lane := range s.lanes {
+		s.current[lane] += s.weights[lane]
+		if best == "" || s.current[lane] > s.current[best] {
+			best = lane
+		}
+	}
+	s.current[best] -= s.total
+
+	order := []string{best}
+	rest := make([]string, 0, len(s.lanes)-1)
+	for _, lane := range s.lanes {
+		if lane != best {
+			rest = append(rest, lane)
+		}
+	}
+	sort.SliceStable(rest, func(i, j int) bool {
+		return s.weights[rest[i]] > s.weights[rest[j]]
+	})
+	return append(order, rest...)
+}
+
+// laneUsersKey is the ring of users with queued jobs in a lane
+func (c *RedisConsumer) laneUsersKey(lane string) string {
+	return fmt.Sprintf("%s:lane:%s:users", c.config.QueueName, lane)
+}
+
+// laneUserPrefix prefixes the per-user sub-queues of a lane
+func (c *RedisConsumer) laneUserPrefix(lane string) string {
+	return fmt.Sprintf("%s:lane:%s:user:", c.config.QueueName, lane)
+}
+
+// wakeKey is the list routers push a token onto for every job they queue in a lane
+func (c *RedisConsumer) wakeKey() string {
+	return fmt.Sprintf("%s:lanes:wake", c.config.QueueName)
+}
+
+// laneFor returns the lane a job belongs to: JobPayload.Priority, then metadata.priority,
+// then the default lane. Unknown lanes fall back to the default.
+func (c *RedisConsumer) laneFor(priority string) string {
+	lane := strings.ToLower(strings.TrimSpace(priority))
+	if _, ok := c.config.LaneWeights[lane]; ok {
+		return lane
+	}
+	if lane != "" {
+		log.Printf("[Queue] WARNING: Unknown priority lane %q, using %s", priority, c.config.DefaultLane)
+	}
+	return c.config.DefaultLane
+}
+
+// routingInfo is the part of a job the router needs; fileBuffer is never decoded
+type routingInfo struct {
+	Payload struct {
+		UserID   string `json:"userId"`
+		Priority string `json:"priority"`
+		Metadata struct {
+			Priority string `json:"priority"`
+		} `json:"metadata"`
+	} `json:"payload"`
+}
+
+// routerLoop moves jobs from the intake list into their lanes until the consumer stops
+func (c *RedisConsumer) routerLoop() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		default:
+			if err := c.routeNextJob(); err != nil {
+				if err.Error() != "no jobs available" && c.ctx.Err() == nil {
+					log.Printf("[Queue] Router error: %v", err)
+					time.Sleep(1 * time.Second)
+				}
+			}
+		}
+	}
+}
+
+// routeNextJob takes the next job ID off the intake list and queues it in its lane
+func (c *RedisConsumer) routeNextJob() error {
+	// Stage the ID in our in-flight list so a crash mid-route returns it to the intake
+	jobID, err := c.client.BLMove(c.ctx, c.config.QueueName, c.inflightKey(c.config.WorkerID), "RIGHT", "LEFT", 5*time.Second).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return fmt.Errorf("no jobs available")
+		}
+		return fmt.Errorf("failed to fetch job: %w", err)
+	}
+
+	// Orphaned jobs are routed to the default lane and dropped by the worker that picks them
+	var info routingInfo
+	if jobData, err := c.client.HGet(c.ctx, fmt.Sprintf("%s:data", c.config.QueueName), jobID).Result(); err == nil {
+		if err := json.Unmarshal([]byte(jobData), &info); err != nil {
+			// Undecodable payloads can never be routed or processed - quarantine them
+			c.quarantine(context.Background(), jobID, []byte(jobData), err, c.inflightAck(jobID))
+			return fmt.Errorf("failed to unmarshal job %s (quarantined): %w", jobID, err)
+		}
+	} else if err != redis.Nil {
+		c.releaseJob(context.Background(), jobID)
+		return fmt.Errorf("failed to get job data: %w", err)
+	}
+
+	priority := info.Payload.Priority
+	if priority == "" {
+		priority = info.Payload.Metadata.Priority
+	}
+	lane := c.laneFor(priority)
+
+	userID := info.Payload.UserID
+	if userID == "" {
+		userID = anonymousUser
+	}
+
+	if err := routeScript.Run(context.Background(), c.client,
+		[]string{c.inflightKey(c.config.WorkerID), c.laneUserPrefix(lane) + userID, c.laneUsersKey(lane), c.wakeKey()},
+		jobID, userID, maxWakeTokens).Err(); err != nil {
+		// Still in flight; recovered on restart or by the reaper
+		return fmt.Errorf("failed to route job %s to lane %s: %w", jobID, lane, err)
+	}
+
+	return nil
+}
+
+// pickJob moves the next job chosen by the lane scheduler into the in-flight list
+func (c *RedisConsumer) pickJob() (string, error) {
+	for _, lane := range c.lanes.Next() {
+		jobID, err := pickScript.Run(c.ctx, c.client,
+			[]string{c.laneUsersKey(lane), c.inflightKey(c.config.WorkerID)},
+			c.laneUserPrefix(lane)).Text()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to pick job from lane %s: %w", lane, err)
+		}
+		return jobID, nil
+	}
+
+	return "", fmt.Errorf("no jobs available")
+}
+
+// waitForWork blocks until a router queues a job in a lane or wakeTimeout passes, so idle
+// workers neither spin on the lanes nor add a fixed delay to every new job
+func (c *RedisConsumer) waitForWork() {
+	err := c.client.BLPop(c.ctx, wakeTimeout, c.wakeKey()).Err()
+	if err != nil && err != redis.Nil && c.ctx.Err() == nil {
+		log.Printf("[Queue] WARNING: Failed to wait for jobs: %v", err)
+		time.Sleep(1 * time.Second)
+	}
+}
+
+// laneStats returns the queued job and tenant counts of every lane
+func (c *RedisConsumer) laneStats(ctx context.Context) map[string]int64 {
+	stats := make(map[string]int64)
+	for lane := range c.config.LaneWeights {
+		users, _ := c.client.LRange(ctx, c.laneUsersKey(lane), 0, -1).Result()
+		var waiting int64
+		for _, user := range users {
+			n, _ := c.client.LLen(ctx, c.laneUserPrefix(lane)+user).Result()
+			waiting += n
+		}
+		stats[fmt.Sprintf("lane:%s:waiting", lane)] = waiting
+		stats[fmt.Sprintf("lane:%s:tenants", lane)] = int64(len(users))
+	}
+	return stats
+}
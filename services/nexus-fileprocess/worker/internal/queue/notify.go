@@ -0,0 +1,81 @@
+/**
+ * Job Completion Notifications
+ *
+ * A JobNotifier is told once a job reaches a final state: completed, or failed
+ * after its last attempt. Retries and cancellations are not reported. The
+ * notifier must return quickly; webhook delivery persists the event and sends
+ * it from its own dispatcher.
+ */
+
+package queue
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+// Final job states reported to a JobNotifier
+const (
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled" // Counted in batches, not reported to the notifier
+)
+
+// JobOutcome describes a job that reached a final state
+type JobOutcome struct {
+	JobID      string
+	UserID     string
+	BatchID    string
+	Filename   string
+	MimeType   string
+	Status     string                   // completed or failed
+	Result     *processor.ProcessResult // Set for completed jobs
+	Error      string                   // Set for failed jobs
+	Attempts   int
+	Metadata   map[string]interface{}
+	FinishedAt time.Time
+}
+
+// JobNotifier is told about jobs that reached a final state
+type JobNotifier interface {
+	JobFinished(ctx context.Context, outcome *JobOutcome) error
+}
+
+// finished runs once a job reaches a final state: batch accounting, then notifications
+func (r *jobRunner) finished(job *RedisJobData, status string, result interface{}, cause error) {
+	r.recordBatchOutcome(job, status, result, cause)
+	r.notifyFinished(job, status, result, cause)
+}
+
+// notifyFinished reports a final job state to the configured notifier. Failures are
+// logged; they never change the outcome of the job.
+func (r *jobRunner) notifyFinished(job *RedisJobData, status string, result interface{}, cause error) {
+	if r.config.Notifier == nil {
+		return
+	}
+
+	outcome := &JobOutcome{
+		JobID:      job.Payload.JobID,
+		UserID:     job.Payload.UserID,
+		BatchID:    job.Payload.batchID(),
+		Filename:   job.Payload.Filename,
+		MimeType:   job.Payload.MimeType,
+		Status:     status,
+		Attempts:   job.Attempts,
+		Metadata:   job.Payload.Metadata,
+		FinishedAt: time.Now(),
+	}
+	if processResult, ok := result.(*processor.ProcessResult); ok {
+		outcome.Result = processResult
+	}
+	if cause != nil {
+		outcome.Error = cause.Error()
+	}
+
+	if err := r.config.Notifier.JobFinished(context.Background(), outcome); err != nil {
+		log.Printf("[Job %s] Warning: Failed to send %s notification: %v", job.Payload.JobID, status, err)
+	}
+}
@@ -0,0 +1,114 @@
+/**
+ * Poison-Message Quarantine for the Queue Backends
+ *
+ * Payloads that cannot be decoded into RedisJobData (bad JSON, or a fileBuffer
+ * rejected by JobPayload.UnmarshalJSON) are moved to <queue>:poison together
+ * with the raw bytes and the decode error instead of being dropped. The job is
+ * marked failed with MALFORMED_PAYLOAD and a job:rejected event is published so
+ * the API can tell the user their upload was rejected.
+ */
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/errors"
+)
+
+// PoisonEntry is an undecodable queue payload kept for inspection
+type PoisonEntry struct {
+	QueueJobID    string    `json:"queueJobId"`
+	JobID         string    `json:"jobId,omitempty"` // Recovered from the raw payload when possible
+	Raw           string    `json:"raw"`
+	DecodeError   string    `json:"decodeError"`
+	QuarantinedAt time.Time `json:"quarantinedAt"`
+	WorkerID      string    `json:"workerId"`
+}
+
+// poisonKey is the hash of quarantined payloads
+func (r *jobRunner) poisonKey() string {
+	return fmt.Sprintf("%s:poison", r.config.QueueName)
+}
+
+// recoverJobID pulls payload.jobId out of a payload that failed full decoding.
+// Only the fields needed to report the rejection are decoded, so a bad fileBuffer does not matter.
+func recoverJobID(raw []byte) string {
+	var partial struct {
+		Payload struct {
+			JobID string `json:"jobId"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(raw, &partial); err != nil {
+		return ""
+	}
+	return partial.Payload.JobID
+}
+
+// quarantine moves an undecodable payload to the poison hash, acks it and reports the rejection
+func (r *jobRunner) quarantine(ctx context.Context, queueJobID string, raw []byte, decodeErr error, ack ackFunc) {
+	entry := PoisonEntry{
+		QueueJobID:    queueJobID,
+		JobID:         recoverJobID(raw),
+		Raw:           string(raw),
+		DecodeError:   decodeErr.Error(),
+		QuarantinedAt: time.Now(),
+		WorkerID:      r.config.WorkerID,
+	}
+
+	entryData, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("[Queue] ERROR: Failed to marshal poison entry for %s: %v", queueJobID, err)
+		return
+	}
+
+	rejection := errors.NewMalformedPayloadError(entry.JobID, queueJobID, decodeErr)
+	errorData, _ := json.Marshal(rejection.ToMap())
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, r.poisonKey(), queueJobID, entryData)
+	pipe.HDel(ctx, fmt.Sprintf("%s:data", r.config.QueueName), queueJobID)
+	ack(ctx, pipe)
+	if entry.JobID != "" {
+		// Keep the TypeScript RedisQueue's view of the job consistent
+		pipe.SAdd(ctx, fmt.Sprintf("%s:failed", r.config.QueueName), entry.JobID)
+		pipe.HSet(ctx, fmt.Sprintf("%s:errors", r.config.QueueName), entry.JobID, errorData)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		// Leave it in flight; it will be recovered and quarantined on the next pass
+		log.Printf("[Queue] ERROR: Failed to quarantine malformed job %s: %v", queueJobID, err)
+		return
+	}
+
+	log.Printf("[Queue] Quarantined malformed job %s (jobId=%s): %v", queueJobID, entry.JobID, decodeErr)
+
+	// Mark the job failed in PostgreSQL so the API can surface the rejection
+	if entry.JobID != "" {
+		if err := r.processor.UpdateJobStatus(ctx, entry.JobID, "failed", 0, map[string]interface{}{
+			"error":      rejection.Error(),
+			"errorCode":  string(errors.ErrorMalformedPayload),
+			"queueJobId": queueJobID,
+		}); err != nil {
+			log.Printf("[PostgreSQL] WARNING: Failed to mark malformed job %s as failed: %v", entry.JobID, err)
+		}
+	}
+
+	eventJobID := entry.JobID
+	if eventJobID == "" {
+		eventJobID = queueJobID
+	}
+	event := map[string]interface{}{
+		"event":      "job:rejected",
+		"jobId":      eventJobID,
+		"queueJobId": queueJobID,
+		"errorCode":  string(errors.ErrorMalformedPayload),
+		"error":      decodeErr.Error(),
+		"timestamp":  time.Now().Format(time.RFC3339),
+	}
+	eventData, _ := json.Marshal(event)
+	r.client.Publish(ctx, fmt.Sprintf("%s:events", r.config.QueueName), eventData)
+}
@@ -0,0 +1,91 @@
+/**
+ * Job Progress Events
+ *
+ * jobProgress is the processor.ProgressReporter the queue attaches to every
+ * job context. Each update is:
+ * - published as a job:progress event on <queue>:events
+ * - kept in the <queue>:progress hash (job ID -> latest event) for polling
+ * - persisted to PostgreSQL (progress, progress_stage, progress_details)
+ *
+ * Stage boundaries are always delivered; updates within a stage are throttled
+ * so a fast download or page loop cannot flood Redis or PostgreSQL.
+ */
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+const (
+	progressEventInterval   = 500 * time.Millisecond // Minimum gap between events within a stage
+	progressPersistInterval = 2 * time.Second        // Minimum gap between PostgreSQL writes within a stage
+)
+
+// jobProgress publishes the pipeline progress of a single job
+type jobProgress struct {
+	runner      *jobRunner
+	mu          sync.Mutex
+	lastStage   string
+	lastEvent   time.Time
+	lastPersist time.Time
+}
+
+// progressKey is the hash of the latest progress event per job
+func (r *jobRunner) progressKey() string {
+	return fmt.Sprintf("%s:progress", r.config.QueueName)
+}
+
+// ReportProgress implements processor.ProgressReporter
+func (p *jobProgress) ReportProgress(ctx context.Context, update *processor.ProgressUpdate) {
+	now := time.Now()
+
+	p.mu.Lock()
+	boundary := update.Stage != p.lastStage || update.StageProgress <= 0 || update.StageProgress >= 1
+	publish := boundary || now.Sub(p.lastEvent) >= progressEventInterval
+	persist := boundary || now.Sub(p.lastPersist) >= progressPersistInterval
+	p.lastStage = update.Stage
+	if publish {
+		p.lastEvent = now
+	}
+	if persist {
+		p.lastPersist = now
+	}
+	p.mu.Unlock()
+
+	r := p.runner
+	if publish {
+		event := map[string]interface{}{
+			"event":         "job:progress",
+			"jobId":         update.JobID,
+			"stage":         update.Stage,
+			"progress":      update.Progress,
+			"stageProgress": update.StageProgress,
+			"message":       update.Message,
+			"stats":         update.Stats,
+			"timestamp":     update.Timestamp.Format(time.RFC3339Nano),
+		}
+		eventData, _ := json.Marshal(event)
+
+		pipe := r.client.Pipeline()
+		pipe.HSet(r.ctx, r.progressKey(), update.JobID, eventData)
+		pipe.Publish(r.ctx, fmt.Sprintf("%s:events", r.config.QueueName), eventData)
+		if _, err := pipe.Exec(r.ctx); err != nil {
+			log.Printf("[Job %s] Warning: Failed to publish progress: %v", update.JobID, err)
+		}
+	}
+
+	if persist {
+		// The job context may already be cancelled when the final stage reports
+		if err := r.processor.UpdateJobProgress(r.ctx, update); err != nil {
+			log.Printf("[PostgreSQL] WARNING: Failed to update progress of job %s: %v", update.JobID, err)
+		}
+	}
+}
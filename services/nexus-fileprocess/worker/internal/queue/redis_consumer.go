@@ -3,21 +3,28 @@
  *
  * Compatible with TypeScript RedisQueue implementation.
  * Uses simple Redis LIST operations for perfect compatibility.
+ *
+ * Delivery is at-least-once: job IDs are moved into a per-worker in-flight
+ * list while they are processed (see inflight.go).
+ *
+ * This is the "list" Backend; job handling shared with the other backends is in runner.go.
  */
 
 package queue
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/adverant/nexus/fileprocess-worker/internal/errors"
 	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+	"github.com/adverant/nexus/fileprocess-worker/internal/storage"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -29,6 +36,9 @@ type RedisJobData struct {
 	CreatedAt time.Time              `json:"createdAt"`
 	Attempts  int                    `json:"attempts"`
 	MaxRetries int                   `json:"maxRetries"`
+	NextAttemptAt *time.Time         `json:"nextAttemptAt,omitempty"` // When a delayed retry becomes due
+	LastError     string             `json:"lastError,omitempty"`     // Error from the most recent failed attempt
+	Errors        []AttemptError     `json:"errors,omitempty"`        // History of failed attempts
 }
 
 // JobPayload contains the actual job data
@@ -39,6 +49,10 @@ type JobPayload struct {
 	MimeType   string                 `json:"mimeType,omitempty"`
 	FileSize   int64                  `json:"fileSize,omitempty"`
 	FileURL    string                 `json:"fileUrl,omitempty"`
+	BlobRef    string                 `json:"blobRef,omitempty"`  // Key of the file in the blob store (claim check)
+	Priority   string                 `json:"priority,omitempty"` // Priority lane; falls back to metadata.priority
+	BatchID    string                 `json:"batchId,omitempty"`  // Batch this job belongs to; falls back to metadata.batchId
+	BatchSize  int                    `json:"batchSize,omitempty"` // Jobs in the batch, if the API has not registered it
 	FileBuffer []byte                 // Will be set by custom UnmarshalJSON
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
 }
@@ -49,7 +63,7 @@ func (p *JobPayload) UnmarshalJSON(data []byte) error {
 	// Create alias type to avoid recursion
 	type Alias JobPayload
 	aux := &struct {
-		FileBuffer interface{} `json:"fileBuffer,omitempty"`
+		FileBuffer json.RawMessage `json:"fileBuffer,omitempty"`
 		*Alias
 	}{
 		Alias: (*Alias)(p),
@@ -61,51 +75,126 @@ func (p *JobPayload) UnmarshalJSON(data []byte) error {
 	}
 
 	// Handle fileBuffer field with multiple format support
-	if aux.FileBuffer != nil {
-		switch v := aux.FileBuffer.(type) {
-		case string:
-			// Base64 string format (new format from TypeScript)
-			decoded, err := base64.StdEncoding.DecodeString(v)
-			if err != nil {
-				return fmt.Errorf("failed to decode base64 fileBuffer: %w", err)
+	raw := bytes.TrimSpace(aux.FileBuffer)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+
+	switch raw[0] {
+	case '"':
+		// Base64 string format (new format from TypeScript)
+		var encoded string
+		if err := json.Unmarshal(raw, &encoded); err != nil {
+			return fmt.Errorf("failed to decode fileBuffer string: %w", err)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("failed to decode base64 fileBuffer: %w", err)
+		}
+		p.FileBuffer = decoded
+
+	case '{':
+		// Node.js Buffer object format (legacy compatibility)
+		var buffer struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(raw, &buffer); err != nil {
+			return fmt.Errorf("failed to decode Buffer object: %w", err)
+		}
+		if buffer.Type != "Buffer" {
+			return fmt.Errorf("invalid Buffer object format (missing or incorrect 'type' field)")
+		}
+		if len(buffer.Data) == 0 {
+			return fmt.Errorf("Buffer object missing 'data' array")
+		}
+		decoded, err := decodeByteArray(buffer.Data)
+		if err != nil {
+			return err
+		}
+		p.FileBuffer = decoded
+
+	default:
+		return fmt.Errorf("fileBuffer must be either base64 string or Buffer object, got %s", jsonKind(raw[0]))
+	}
+
+	return nil
+}
+
+// decodeByteArray parses a JSON array of integers 0-255 directly into bytes. Node Buffers
+// serialize this way; decoding through []interface{} would box every byte as a float64.
+func decodeByteArray(raw json.RawMessage) ([]byte, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) < 2 || raw[0] != '[' || raw[len(raw)-1] != ']' {
+		return nil, fmt.Errorf("Buffer object missing 'data' array")
+	}
+
+	// Every element takes at least two characters ("0,"), which bounds the length
+	out := make([]byte, 0, len(raw)/2)
+	body := raw[1 : len(raw)-1]
+	value, digits, spaced := 0, 0, false
+
+	flush := func() error {
+		if digits == 0 || value > 255 {
+			return fmt.Errorf("invalid byte value in Buffer data array at index %d", len(out))
+		}
+		out = append(out, byte(value))
+		value, digits, spaced = 0, 0, false
+		return nil
+	}
+
+	for _, c := range body {
+		switch {
+		case c >= '0' && c <= '9':
+			if spaced {
+				return nil, fmt.Errorf("invalid byte value in Buffer data array at index %d", len(out))
 			}
-			p.FileBuffer = decoded
-
-		case map[string]interface{}:
-			// Node.js Buffer object format (legacy compatibility)
-			if bufferType, ok := v["type"].(string); ok && bufferType == "Buffer" {
-				if dataArray, ok := v["data"].([]interface{}); ok {
-					p.FileBuffer = make([]byte, len(dataArray))
-					for i, val := range dataArray {
-						if byteVal, ok := val.(float64); ok {
-							p.FileBuffer[i] = byte(byteVal)
-						} else {
-							return fmt.Errorf("invalid byte value in Buffer data array at index %d", i)
-						}
-					}
-				} else {
-					return fmt.Errorf("Buffer object missing 'data' array")
-				}
-			} else {
-				return fmt.Errorf("invalid Buffer object format (missing or incorrect 'type' field)")
+			value = value*10 + int(c-'0')
+			digits++
+			if digits > 3 {
+				return nil, fmt.Errorf("invalid byte value in Buffer data array at index %d", len(out))
 			}
-
+		case c == ',':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case c == ' ' || c == '\n' || c == '\r' || c == '\t':
+			spaced = digits > 0
 		default:
-			return fmt.Errorf("fileBuffer must be either base64 string or Buffer object, got %T", v)
+			return nil, fmt.Errorf("invalid byte value in Buffer data array at index %d", len(out))
 		}
 	}
 
-	return nil
+	if digits > 0 {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+	} else if len(bytes.TrimSpace(body)) > 0 {
+		// Trailing comma
+		return nil, fmt.Errorf("invalid byte value in Buffer data array at index %d", len(out))
+	}
+
+	return out, nil
+}
+
+// jsonKind names the JSON type starting with c, for error messages
+func jsonKind(c byte) string {
+	switch {
+	case c == '[':
+		return "array"
+	case c == 't' || c == 'f':
+		return "boolean"
+	default:
+		return "number"
+	}
 }
 
 // RedisConsumer handles job consumption from Redis queue
 type RedisConsumer struct {
-	client      *redis.Client
-	processor   processor.DocumentProcessorInterface
-	config      *RedisConsumerConfig
-	ctx         context.Context
-	cancel      context.CancelFunc
-	wg          sync.WaitGroup
+	jobRunner
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	lanes  *LaneScheduler
 }
 
 // RedisConsumerConfig holds consumer configuration
@@ -115,56 +204,82 @@ type RedisConsumerConfig struct {
 	Concurrency       int
 	Processor         processor.DocumentProcessorInterface
 	ProcessingTimeout int64 // Processing timeout in milliseconds (default: 300000 = 5 minutes)
-}
 
-// NewRedisConsumer creates a new Redis-based queue consumer
-func NewRedisConsumer(cfg *RedisConsumerConfig) (*RedisConsumer, error) {
-	if cfg.RedisURL == "" {
-		return nil, fmt.Errorf("RedisURL is required")
-	}
+	// At-least-once delivery
+	WorkerID          string // Identity of this worker's in-flight list or stream consumer (default: hostname-pid)
+	VisibilityTimeout int64  // Milliseconds without a heartbeat before in-flight jobs are reclaimed (default: 60000)
+	HeartbeatInterval int64  // Heartbeat refresh interval in milliseconds (default: 15000, or VisibilityTimeout/4 if shorter)
+	ReaperInterval    int64  // How often to look for dead workers in milliseconds (default: 30000)
 
-	if cfg.QueueName == "" {
-		cfg.QueueName = "fileprocess:jobs"
-	}
+	// Delayed retries
+	RetryPolicy       *RetryPolicy // Backoff between attempts (default: DefaultRetryPolicy())
+	SchedulerInterval int64        // How often due retries are promoted in milliseconds (default: 1000)
 
-	if cfg.Processor == nil {
-		return nil, fmt.Errorf("Processor is required")
-	}
+	// Priority lanes (LIST backend)
+	LaneWeights map[string]int // Relative share of pulls per lane (default: DefaultLaneWeights())
+	DefaultLane string         // Lane for jobs without a known priority (default: interactive)
 
-	if cfg.Concurrency <= 0 {
-		cfg.Concurrency = 10
-	}
+	// Streams backend
+	ConsumerGroup string // Consumer group shared by all workers (default: fileprocess-workers)
 
-	// Parse Redis URL
-	opt, err := redis.ParseURL(cfg.RedisURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
-	}
+	// Claim-check payloads
+	BlobStore         storage.BlobStore // Where oversized inline buffers are moved (nil: keep inline)
+	InlineBufferLimit int64             // Largest file buffer written back to Redis inline, in bytes
 
-	// Create Redis client
-	client := redis.NewClient(opt)
+	// Completion notifications
+	Notifier JobNotifier // Told when jobs complete or finally fail (nil: disabled)
 
-	// Test connection
-	ctx := context.Background()
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	// Batch tracking
+	Batches BatchRecorder // Records outcomes of jobs with a batchId (nil: batches not tracked)
+}
+
+// NewRedisConsumer creates a new Redis-based queue consumer
+func NewRedisConsumer(cfg *RedisConsumerConfig) (*RedisConsumer, error) {
+	if err := applyRedisDefaults(cfg); err != nil {
+		return nil, err
+	}
+
+	client, err := connectRedis(cfg.RedisURL)
+	if err != nil {
+		return nil, err
 	}
 
 	consumerCtx, cancel := context.WithCancel(context.Background())
 
 	return &RedisConsumer{
-		client:    client,
-		processor: cfg.Processor,
-		config:    cfg,
-		ctx:       consumerCtx,
-		cancel:    cancel,
+		jobRunner: jobRunner{
+			client:    client,
+			processor: cfg.Processor,
+			config:    cfg,
+			ctx:       consumerCtx,
+			backend:   BackendList,
+			cancels:   newCancelRegistry(),
+		},
+		cancel: cancel,
+		lanes:  NewLaneScheduler(cfg.LaneWeights),
 	}, nil
 }
 
 // Start begins processing jobs from the queue
 func (c *RedisConsumer) Start() error {
-	log.Printf("Starting Redis queue consumer (concurrency=%d, queue=%s)...",
-		c.config.Concurrency, c.config.QueueName)
+	log.Printf("Starting Redis queue consumer (concurrency=%d, queue=%s, workerId=%s)...",
+		c.config.Concurrency, c.config.QueueName, c.config.WorkerID)
+
+	// Register this worker and recover anything a previous run left in flight
+	if err := c.registerWorker(c.ctx); err != nil {
+		return err
+	}
+	if err := c.recoverOwnInflight(c.ctx); err != nil {
+		return err
+	}
+
+	// Start heartbeat, reaper, retry scheduler, lane router and control goroutines
+	c.wg.Add(5)
+	go c.heartbeatLoop()
+	go c.reaperLoop()
+	go c.schedulerLoop(&c.wg)
+	go c.routerLoop()
+	go c.controlLoop(&c.wg)
 
 	// Start worker goroutines
 	for i := 0; i < c.config.Concurrency; i++ {
@@ -181,6 +296,7 @@ func (c *RedisConsumer) Stop() error {
 	log.Println("Stopping queue consumer...")
 	c.cancel()
 	c.wg.Wait()
+	c.deregisterWorker(context.Background())
 	return c.client.Close()
 }
 
@@ -197,9 +313,12 @@ func (c *RedisConsumer) worker(id int) {
 		default:
 			// Try to get a job from the queue
 			if err := c.processNextJob(); err != nil {
-				if err.Error() != "no jobs available" {
-					log.Printf("Worker %d error: %v", id, err)
+				if err.Error() == "no jobs available" {
+					// Sleep until a router queues the next job
+					c.waitForWork()
+					continue
 				}
+				log.Printf("Worker %d error: %v", id, err)
 				// Small delay before trying again
 				time.Sleep(1 * time.Second)
 			}
@@ -209,231 +328,56 @@ func (c *RedisConsumer) worker(id int) {
 
 // processNextJob fetches and processes the next job from the queue
 func (c *RedisConsumer) processNextJob() error {
-	// Block for up to 5 seconds waiting for a job
-	result, err := c.client.BRPop(c.ctx, 5*time.Second, c.config.QueueName).Result()
-	if err != nil {
-		if err == redis.Nil {
-			return fmt.Errorf("no jobs available")
-		}
-		return fmt.Errorf("failed to fetch job: %w", err)
-	}
-
-	if len(result) < 2 {
-		return fmt.Errorf("invalid job result")
-	}
-
-	jobID := result[1]
-
-	// Get job data
-	jobData, err := c.client.HGet(c.ctx, fmt.Sprintf("%s:data", c.config.QueueName), jobID).Result()
+	// Pick the next job across the priority lanes, moving it atomically into our in-flight list
+	// so it survives a worker crash (the reaper returns it to the queue)
+	jobID, err := c.pickJob()
 	if err != nil {
-		return fmt.Errorf("failed to get job data: %w", err)
-	}
-
-	var job RedisJobData
-	if err := json.Unmarshal([]byte(jobData), &job); err != nil {
-		return fmt.Errorf("failed to unmarshal job: %w", err)
-	}
-
-	// Create/update job record in PostgreSQL (ensures job exists in database)
-	// This is idempotent - if job already exists, it will update status to processing
-	if err := c.processor.UpdateJobStatus(c.ctx, job.Payload.JobID, "processing", 0, map[string]interface{}{
-		"filename": job.Payload.Filename,
-		"mimeType": job.Payload.MimeType,
-		"fileSize": job.Payload.FileSize,
-		"userId":   job.Payload.UserID,
-	}); err != nil {
-		// Job record might not exist yet - this is OK, we'll create it on first update
-		log.Printf("Note: Could not update job status to processing (job may not exist in DB yet): %v", err)
+		return err
 	}
 
-	// Update job status to processing in Redis
-	c.updateJobStatus(job.Payload.JobID, "processing", nil)
-
-	// Process the job
-	log.Printf("Processing job %s: %s", job.Payload.JobID, job.Payload.Filename)
-
-	processResult, err := c.processJob(&job)
-	if err != nil {
-		log.Printf("Job %s failed: %v", job.Payload.JobID, err)
-
-		// Handle retry logic
-		job.Attempts++
-		if job.Attempts < job.MaxRetries {
-			// Re-queue for retry
-			updatedData, _ := json.Marshal(job)
-			c.client.HSet(c.ctx, fmt.Sprintf("%s:data", c.config.QueueName), job.ID, updatedData)
-			c.client.LPush(c.ctx, c.config.QueueName, job.ID)
-			log.Printf("Job %s re-queued for retry (attempt %d/%d)", job.Payload.JobID, job.Attempts, job.MaxRetries)
-		} else {
-			// Mark as failed
-			c.updateJobStatus(job.Payload.JobID, "failed", map[string]interface{}{
-				"error": err.Error(),
-				"attempts": job.Attempts,
-			})
+	delivery := &Delivery{ID: jobID, QueueJobID: jobID}
+	if retryable, err := c.loadJob(delivery, c.inflightAck(jobID)); err != nil {
+		if retryable {
+			// Transient Redis failure - hand the job back instead of dropping it
+			c.releaseJob(context.Background(), jobID)
 		}
-	} else {
-		// Mark as completed
-		c.updateJobStatus(job.Payload.JobID, "completed", processResult)
-		log.Printf("Job %s completed successfully", job.Payload.JobID)
+		return err
 	}
 
+	c.run(context.Background(), c, delivery)
 	return nil
 }
 
-// processJob handles the actual document processing
-func (c *RedisConsumer) processJob(job *RedisJobData) (interface{}, error) {
-	startTime := time.Now()
-
-	// Convert to processor format
-	request := &processor.ProcessRequest{
-		JobID:      job.Payload.JobID,
-		UserID:     job.Payload.UserID,
-		Filename:   job.Payload.Filename,
-		MimeType:   job.Payload.MimeType,
-		FileSize:   job.Payload.FileSize,
-		FileURL:    job.Payload.FileURL,
-		FileBuffer: job.Payload.FileBuffer,
-		Metadata:   job.Payload.Metadata,
+// inflightAck removes a job ID from this worker's in-flight list as part of a transaction
+func (c *RedisConsumer) inflightAck(jobID string) ackFunc {
+	return func(ctx context.Context, pipe redis.Pipeliner) {
+		pipe.LRem(ctx, c.inflightKey(c.config.WorkerID), 1, jobID)
 	}
-
-	// =========================================================================
-	// CRITICAL FIX: Create timeout context to prevent 30-60 second hangs
-	// =========================================================================
-	// Default timeout: 5 minutes (300000ms)
-	// Configurable via RedisConsumerConfig.ProcessingTimeout
-	// =========================================================================
-	timeout := time.Duration(300000) * time.Millisecond
-	if c.config.ProcessingTimeout > 0 {
-		timeout = time.Duration(c.config.ProcessingTimeout) * time.Millisecond
-	}
-
-	log.Printf("[Job %s] Processing timeout set to: %v", job.Payload.JobID, timeout)
-
-	// Create timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	// Process document with timeout
-	result, err := c.processor.ProcessDocument(ctx, request)
-
-	duration := time.Since(startTime)
-
-	if err != nil {
-		// Check if error was due to timeout
-		if ctx.Err() == context.DeadlineExceeded {
-			log.Printf("[Job %s] Processing timed out after %v (timeout: %v)", job.Payload.JobID, duration, timeout)
-
-			// Create structured timeout error
-			timeoutErr := errors.NewProcessingTimeoutError(job.Payload.JobID, timeout, err)
-			errorMap := timeoutErr.ToMap()
-
-			// Update job status to failed with timeout error
-			if updateErr := c.processor.UpdateJobStatus(c.ctx, job.Payload.JobID, "failed", 100, errorMap); updateErr != nil {
-				log.Printf("[Job %s] Warning: Failed to update status to failed: %v", job.Payload.JobID, updateErr)
-			}
-
-			return nil, fmt.Errorf("processing timeout: %w", timeoutErr)
-		}
-
-		return nil, err
-	}
-
-	log.Printf("[Job %s] Processing completed in %v", job.Payload.JobID, duration)
-	return result, nil
 }
 
-// updateJobStatus updates the status of a job in both Redis AND PostgreSQL
-func (c *RedisConsumer) updateJobStatus(jobID string, status string, result interface{}) {
-	// Update Redis for queue management
-	if status == "processing" {
-		c.client.SAdd(c.ctx, fmt.Sprintf("%s:processing", c.config.QueueName), jobID)
-	} else if status == "completed" {
-		c.client.SRem(c.ctx, fmt.Sprintf("%s:processing", c.config.QueueName), jobID)
-		c.client.SAdd(c.ctx, fmt.Sprintf("%s:completed", c.config.QueueName), jobID)
-		if result != nil {
-			resultData, _ := json.Marshal(result)
-			c.client.HSet(c.ctx, fmt.Sprintf("%s:results", c.config.QueueName), jobID, resultData)
-		}
-	} else if status == "failed" {
-		c.client.SRem(c.ctx, fmt.Sprintf("%s:processing", c.config.QueueName), jobID)
-		c.client.SAdd(c.ctx, fmt.Sprintf("%s:failed", c.config.QueueName), jobID)
-		if result != nil {
-			errorData, _ := json.Marshal(result)
-			c.client.HSet(c.ctx, fmt.Sprintf("%s:errors", c.config.QueueName), jobID, errorData)
-		}
-	}
-
-	// Update PostgreSQL for persistent job tracking
-	if status == "completed" {
-		// Convert processor.ProcessResult to storage update
-		if processResult, ok := result.(*processor.ProcessResult); ok {
-			log.Printf("[PostgreSQL] Updating job %s to completed with full details", jobID)
-			if err := c.processor.UpdateJobStatus(c.ctx, jobID, status, 100, map[string]interface{}{
-				"confidence":      processResult.Confidence,
-				"processingTime":  processResult.ProcessingTimeMs,
-				"documentDnaId":   processResult.DocumentDNAID,
-				"ocrTierUsed":     processResult.OCRTierUsed,
-				"embeddingGenerated": processResult.EmbeddingGenerated,
-				"tablesExtracted": processResult.TablesExtracted,
-				"regionsExtracted": processResult.RegionsExtracted,
-			}); err != nil {
-				log.Printf("[PostgreSQL] ERROR: Failed to update job status: %v", err)
-			} else {
-				log.Printf("[PostgreSQL] ✓ Job %s updated successfully (confidence=%.2f, tier=%s, dnaId=%s)",
-					jobID, processResult.Confidence, processResult.OCRTierUsed, processResult.DocumentDNAID)
-			}
-		} else {
-			// Fallback: Type assertion failed, but still try to mark as completed
-			log.Printf("[PostgreSQL] WARNING: ProcessResult type assertion failed. Marking as completed without details.")
-			if err := c.processor.UpdateJobStatus(c.ctx, jobID, status, 100, nil); err != nil {
-				log.Printf("[PostgreSQL] ERROR: Failed to update job status (fallback): %v", err)
-			}
-		}
-	} else if status == "failed" {
-		// Extract error message from result
-		errorMsg := "Unknown error"
-		if resultMap, ok := result.(map[string]interface{}); ok {
-			if errStr, ok := resultMap["error"].(string); ok {
-				errorMsg = errStr
-			}
-		}
-
-		if err := c.processor.UpdateJobStatus(c.ctx, jobID, status, 0, map[string]interface{}{
-			"error": errorMsg,
-		}); err != nil {
-			log.Printf("WARNING: Failed to update PostgreSQL job status for failed job: %v", err)
-		}
-	} else if status == "processing" {
-		// Mark job as processing in PostgreSQL
-		if err := c.processor.UpdateJobStatus(c.ctx, jobID, status, 0, nil); err != nil {
-			log.Printf("WARNING: Failed to update PostgreSQL job status to processing: %v", err)
-		}
-	}
+// Ack removes a processed job from this worker's in-flight list
+func (c *RedisConsumer) Ack(ctx context.Context, delivery *Delivery) error {
+	return c.settle(ctx, delivery, c.inflightAck(delivery.ID))
+}
 
-	// Publish event for WebSocket streaming
-	event := map[string]interface{}{
-		"event":     fmt.Sprintf("job:%s", status),
-		"jobId":     jobID,
-		"timestamp": time.Now().Format(time.RFC3339),
-	}
-	eventData, _ := json.Marshal(event)
-	c.client.Publish(c.ctx, fmt.Sprintf("%s:events", c.config.QueueName), eventData)
+// Nack schedules a failed job for retry or dead-letters it, removing it from the in-flight list.
+// On error the job stays in flight and is recovered by the reaper.
+func (c *RedisConsumer) Nack(ctx context.Context, delivery *Delivery, cause error) error {
+	return c.fail(ctx, delivery, cause, c.inflightAck(delivery.ID))
 }
 
 // GetStats returns queue statistics
 func (c *RedisConsumer) GetStats() (map[string]int64, error) {
 	ctx := context.Background()
 
-	waiting, _ := c.client.LLen(ctx, c.config.QueueName).Result()
-	processing, _ := c.client.SCard(ctx, fmt.Sprintf("%s:processing", c.config.QueueName)).Result()
-	completed, _ := c.client.SCard(ctx, fmt.Sprintf("%s:completed", c.config.QueueName)).Result()
-	failed, _ := c.client.SCard(ctx, fmt.Sprintf("%s:failed", c.config.QueueName)).Result()
+	stats := c.sharedStats(ctx)
+	stats["waiting"], _ = c.client.LLen(ctx, c.config.QueueName).Result()
+	for key, value := range c.laneStats(ctx) {
+		stats[key] = value
+		if strings.HasSuffix(key, ":waiting") {
+			stats["waiting"] += value
+		}
+	}
 
-	return map[string]int64{
-		"waiting":    waiting,
-		"processing": processing,
-		"completed":  completed,
-		"failed":     failed,
-	}, nil
-}
\ No newline at end of file
+	return stats, nil
+}
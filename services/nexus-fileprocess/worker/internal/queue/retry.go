@@ -0,0 +1,172 @@
+/**
+ * Delayed Retries for the Redis Backends
+ *
+ * Failed jobs are parked in a sorted set scored by their next-attempt time
+ * instead of going straight back onto the queue. A scheduler goroutine
+ * promotes due jobs back to the main list (or stream).
+ *
+ * Backoff matches the Asynq Consumer's RetryDelayFunc (5s, 10s, 20s ... capped
+ * at 60s) with optional jitter so retries from a shared outage spread out.
+ */
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultRetryBaseDelayMs = 5000  // 5 seconds
+	defaultRetryMaxDelayMs  = 60000 // 1 minute
+	defaultRetryJitter      = 0.2   // +/-20%
+
+	defaultSchedulerIntervalMs = 1000 // 1 second
+	schedulerBatchSize         = 100  // Max jobs promoted per scheduler tick
+)
+
+// RetryPolicy computes the delay before the next attempt of a failed job
+type RetryPolicy struct {
+	BaseDelay time.Duration // Delay before the first retry
+	MaxDelay  time.Duration // Upper bound on any single delay
+	Jitter    float64       // Fraction of the delay to randomize (0 = none, 0.2 = +/-20%)
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		BaseDelay: defaultRetryBaseDelayMs * time.Millisecond,
+		MaxDelay:  defaultRetryMaxDelayMs * time.Millisecond,
+		Jitter:    defaultRetryJitter,
+	}
+}
+
+// Delay returns the backoff for the given attempt (1 = first retry)
+func (p *RetryPolicy) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	// Exponential backoff: base * 2^(attempt-1), capped before it can overflow
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		spread := float64(delay) * p.Jitter
+		delay += time.Duration((rand.Float64()*2 - 1) * spread)
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+
+	return delay
+}
+
+// promoteDueScript atomically moves due job IDs from the delayed set to the queue.
+// Running it as a script keeps concurrent schedulers on other workers from double-promoting.
+var promoteDueScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, id in ipairs(due) do
+	redis.call('ZREM', KEYS[1], id)
+	redis.call('LPUSH', KEYS[2], id)
+end
+return #due
+`)
+
+// promoteDueStreamScript is promoteDueScript for the streams backend
+var promoteDueStreamScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, id in ipairs(due) do
+	redis.call('ZREM', KEYS[1], id)
+	redis.call('XADD', KEYS[2], '*', 'id', id)
+end
+return #due
+`)
+
+// delayedKey is the sorted set of job IDs waiting for their next attempt
+func (r *jobRunner) delayedKey() string {
+	return fmt.Sprintf("%s:delayed", r.config.QueueName)
+}
+
+// scheduleRetry parks a failed job in the delayed set and acks it in one transaction
+func (r *jobRunner) scheduleRetry(ctx context.Context, job *RedisJobData, cause error, ack ackFunc) error {
+	delay := r.config.RetryPolicy.Delay(job.Attempts)
+	nextAttemptAt := time.Now().Add(delay)
+	job.NextAttemptAt = &nextAttemptAt
+	job.LastError = cause.Error()
+	r.externalizePayload(ctx, job)
+
+	updatedData, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job for retry: %w", err)
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, fmt.Sprintf("%s:data", r.config.QueueName), job.ID, updatedData)
+	pipe.ZAdd(ctx, r.delayedKey(), redis.Z{
+		Score:  float64(nextAttemptAt.UnixMilli()),
+		Member: job.ID,
+	})
+	ack(ctx, pipe)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to schedule retry: %w", err)
+	}
+
+	log.Printf("Job %s scheduled for retry in %v (attempt %d/%d)",
+		job.Payload.JobID, delay.Round(time.Millisecond), job.Attempts, job.MaxRetries)
+	return nil
+}
+
+// schedulerLoop promotes due retries back onto the queue until the backend stops
+func (r *jobRunner) schedulerLoop(wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(time.Duration(r.config.SchedulerInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.promoteDueJobs(r.ctx); err != nil && r.ctx.Err() == nil {
+				log.Printf("[Queue] WARNING: Retry scheduler pass failed: %v", err)
+			}
+		}
+	}
+}
+
+// promoteDueJobs moves every job whose next attempt is due back onto the queue
+func (r *jobRunner) promoteDueJobs(ctx context.Context) (int, error) {
+	script, target := promoteDueScript, r.config.QueueName
+	if r.backend == BackendStreams {
+		script, target = promoteDueStreamScript, streamKey(r.config.QueueName)
+	}
+
+	total := 0
+	for {
+		now := time.Now().UnixMilli()
+		promoted, err := script.Run(ctx, r.client,
+			[]string{r.delayedKey(), target}, now, schedulerBatchSize).Int()
+		if err != nil {
+			return total, fmt.Errorf("failed to promote delayed jobs: %w", err)
+		}
+		total += promoted
+		if promoted < schedulerBatchSize {
+			return total, nil
+		}
+	}
+}
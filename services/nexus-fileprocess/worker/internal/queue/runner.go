@@ -0,0 +1,399 @@
+/**
+ * Shared Job Handling for Queue Backends
+ *
+ * Everything that happens to a job once a backend has delivered it - status
+ * tracking in Redis and PostgreSQL, the processing timeout, delayed retries,
+ * dead-lettering and quarantine - lives in jobRunner, so backends only differ
+ * in how they fetch and acknowledge messages.
+ */
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/errors"
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+	"github.com/redis/go-redis/v9"
+)
+
+// jobRunner processes delivered jobs on behalf of a backend
+type jobRunner struct {
+	client    *redis.Client
+	processor processor.DocumentProcessorInterface
+	config    *RedisConsumerConfig
+	ctx       context.Context
+	backend   string // Backend type, decides where retried and replayed jobs are queued
+	cancels   *cancelRegistry
+	submit    func(ctx context.Context, job *RedisJobData) error // Queues a new job on backends Redis cannot write to directly
+}
+
+// loadJob reads and decodes the job a backend delivered into d.Job.
+// Orphaned IDs are acked and undecodable payloads quarantined. retryable reports a
+// transient Redis failure, after which the backend should hand the message back.
+func (r *jobRunner) loadJob(d *Delivery, ack ackFunc) (retryable bool, err error) {
+	jobData, err := r.client.HGet(r.ctx, fmt.Sprintf("%s:data", r.config.QueueName), d.QueueJobID).Result()
+	if err != nil {
+		if err == redis.Nil {
+			// Nothing to process - drop the orphaned ID
+			r.settle(context.Background(), d, ack)
+			return false, fmt.Errorf("job data not found for %s", d.QueueJobID)
+		}
+		return true, fmt.Errorf("failed to get job data: %w", err)
+	}
+
+	var job RedisJobData
+	if err := json.Unmarshal([]byte(jobData), &job); err != nil {
+		// Undecodable payloads can never succeed - quarantine them rather than retrying or dropping
+		r.quarantine(context.Background(), d.QueueJobID, []byte(jobData), err, ack)
+		return false, fmt.Errorf("failed to unmarshal job %s (quarantined): %w", d.QueueJobID, err)
+	}
+
+	d.Job = &job
+	return false, nil
+}
+
+// ackCancelled marks a cancelled job and acks its delivery. The payload is only
+// released once the ack has gone through, so a redelivery still finds it.
+func (r *jobRunner) ackCancelled(b Backend, d *Delivery) error {
+	r.markCancelled(d.Job)
+	if err := b.Ack(context.Background(), d); err != nil {
+		return err
+	}
+	r.releasePayload(context.Background(), d.Job)
+	return nil
+}
+
+// settle acknowledges a delivery without touching the job
+func (r *jobRunner) settle(ctx context.Context, d *Delivery, ack ackFunc) error {
+	pipe := r.client.TxPipeline()
+	ack(ctx, pipe)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to ack job %s: %w", d.QueueJobID, err)
+	}
+	return nil
+}
+
+// run processes a loaded delivery and settles it through the backend. The job context
+// derives from ctx, so backends with their own deadlines and cancellation can pass them on.
+// The processing error is returned so backends with their own retry handling can see it.
+func (r *jobRunner) run(ctx context.Context, b Backend, d *Delivery) error {
+	job := d.Job
+
+	// Skip jobs cancelled while they were waiting
+	if r.cancelRequested(r.ctx, job.Payload.JobID) {
+		return r.ackCancelled(b, d)
+	}
+
+	// Duplicate delivery of a job that already completed - nothing left to do
+	if r.alreadyCompleted(r.ctx, job.Payload.JobID) {
+		log.Printf("[Job %s] Already completed, acking duplicate delivery", job.Payload.JobID)
+		return b.Ack(context.Background(), d)
+	}
+
+	// Create/update job record in PostgreSQL (ensures job exists in database)
+	// This is idempotent - if job already exists, it will update status to processing
+	if err := r.processor.UpdateJobStatus(r.ctx, job.Payload.JobID, "processing", 0, map[string]interface{}{
+		"filename": job.Payload.Filename,
+		"mimeType": job.Payload.MimeType,
+		"fileSize": job.Payload.FileSize,
+		"userId":   job.Payload.UserID,
+		"batchId":  job.Payload.batchID(),
+	}); err != nil {
+		// Job record might not exist yet - this is OK, we'll create it on first update
+		log.Printf("Note: Could not update job status to processing (job may not exist in DB yet): %v", err)
+	}
+
+	// Update job status to processing in Redis
+	r.updateJobStatus(job.Payload.JobID, "processing", nil)
+
+	// Process the job
+	log.Printf("Processing job %s: %s", job.Payload.JobID, job.Payload.Filename)
+
+	processResult, err := r.processJob(ctx, job)
+	if err == errDeliveryLost {
+		// Another worker owns the delivery now and settles it
+		log.Printf("[Job %s] Given up without settling the delivery", job.Payload.JobID)
+		return err
+	}
+	if err == errJobCancelled {
+		return r.ackCancelled(b, d)
+	}
+	if err != nil {
+		log.Printf("Job %s failed: %v", job.Payload.JobID, err)
+		if nackErr := b.Nack(context.Background(), d, err); nackErr != nil {
+			log.Printf("Job %s could not be settled, leaving it for recovery: %v", job.Payload.JobID, nackErr)
+		}
+		return err
+	}
+
+	// Archives: queue the extracted entries before the parent is marked completed
+	if result, ok := processResult.(*processor.ProcessResult); ok && len(result.Children) > 0 {
+		if err := r.enqueueChildren(r.ctx, job, result); err != nil {
+			log.Printf("Job %s failed: %v", job.Payload.JobID, err)
+			if nackErr := b.Nack(context.Background(), d, err); nackErr != nil {
+				log.Printf("Job %s could not be settled, leaving it for recovery: %v", job.Payload.JobID, nackErr)
+			}
+			return err
+		}
+	}
+
+	// Mark as completed
+	r.updateJobStatus(job.Payload.JobID, "completed", processResult)
+	if err := b.Ack(context.Background(), d); err != nil {
+		log.Printf("[Queue] WARNING: %v", err)
+	}
+	r.releasePayload(context.Background(), job)
+	r.finished(job, JobStatusCompleted, processResult, nil)
+	log.Printf("Job %s completed successfully", job.Payload.JobID)
+	return nil
+}
+
+// alreadyCompleted reports whether a job is in the completed set. Redis errors count as
+// not completed; the processor still reuses any Document DNA the job already stored.
+func (r *jobRunner) alreadyCompleted(ctx context.Context, jobID string) bool {
+	completed, err := r.client.SIsMember(ctx, fmt.Sprintf("%s:completed", r.config.QueueName), jobID).Result()
+	if err != nil {
+		log.Printf("[Job %s] Warning: Failed to check completed set: %v", jobID, err)
+		return false
+	}
+	return completed
+}
+
+// fail records a failed attempt, then schedules a retry or dead-letters the job once
+// MaxRetries is exhausted or the error is permanent. The delivery is acked in the same
+// transaction.
+func (r *jobRunner) fail(ctx context.Context, d *Delivery, cause error, ack ackFunc) error {
+	job := d.Job
+	job.Attempts++
+	job.Errors = append(job.Errors, AttemptError{
+		Attempt:  job.Attempts,
+		Error:    cause.Error(),
+		FailedAt: time.Now(),
+		WorkerID: r.config.WorkerID,
+	})
+
+	if job.Attempts < job.MaxRetries && !errors.IsPermanent(cause) {
+		// Park in the delayed set; the scheduler re-queues it once the backoff has elapsed
+		return r.scheduleRetry(ctx, job, cause, ack)
+	}
+
+	// Mark as failed and keep the full job for inspection and replay
+	job.LastError = cause.Error()
+	r.updateJobStatus(job.Payload.JobID, "failed", map[string]interface{}{
+		"error":     cause.Error(),
+		"errorCode": string(errors.CodeOf(cause)),
+		"attempts":  job.Attempts,
+	})
+	if err := r.deadLetter(ctx, job, ack); err != nil {
+		return err
+	}
+	r.finished(job, JobStatusFailed, nil, cause)
+	return nil
+}
+
+// processJob handles the actual document processing
+func (r *jobRunner) processJob(parent context.Context, job *RedisJobData) (interface{}, error) {
+	startTime := time.Now()
+
+	// Convert to processor format
+	request := &processor.ProcessRequest{
+		JobID:      job.Payload.JobID,
+		UserID:     job.Payload.UserID,
+		Filename:   job.Payload.Filename,
+		MimeType:   job.Payload.MimeType,
+		FileSize:   job.Payload.FileSize,
+		FileURL:    job.Payload.FileURL,
+		BlobRef:    job.Payload.BlobRef,
+		FileBuffer: job.Payload.FileBuffer,
+		Metadata:   job.Payload.Metadata,
+	}
+
+	// =========================================================================
+	// CRITICAL FIX: Create timeout context to prevent 30-60 second hangs
+	// =========================================================================
+	// Default timeout: 5 minutes (300000ms)
+	// Configurable via RedisConsumerConfig.ProcessingTimeout
+	// =========================================================================
+	timeout := time.Duration(300000) * time.Millisecond
+	if r.config.ProcessingTimeout > 0 {
+		timeout = time.Duration(r.config.ProcessingTimeout) * time.Millisecond
+	}
+
+	log.Printf("[Job %s] Processing timeout set to: %v", job.Payload.JobID, timeout)
+
+	// Create timeout context
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	// Make the job cancellable through the control channel
+	ctx, cancelJob := context.WithCancelCause(ctx)
+	defer cancelJob(nil)
+	defer r.cancels.register(job.Payload.JobID, cancelJob)()
+	if r.cancelRequested(ctx, job.Payload.JobID) {
+		// Requested between the pre-check and registration
+		cancelJob(errJobCancelled)
+	}
+
+	// Stage-level progress goes to the events channel and PostgreSQL
+	ctx = processor.WithProgressReporter(ctx, &jobProgress{runner: r})
+
+	// Process document with timeout
+	result, err := r.processor.ProcessDocument(ctx, request)
+
+	duration := time.Since(startTime)
+
+	if err != nil {
+		if context.Cause(ctx) == errJobCancelled {
+			log.Printf("[Job %s] Processing cancelled after %v", job.Payload.JobID, duration)
+			return nil, errJobCancelled
+		}
+		if context.Cause(ctx) == errDeliveryLost {
+			log.Printf("[Job %s] Processing aborted after %v: %v", job.Payload.JobID, duration, errDeliveryLost)
+			return nil, errDeliveryLost
+		}
+
+		// Check if error was due to timeout
+		if ctx.Err() == context.DeadlineExceeded {
+			log.Printf("[Job %s] Processing timed out after %v (timeout: %v)", job.Payload.JobID, duration, timeout)
+
+			// Create structured timeout error
+			timeoutErr := errors.NewProcessingTimeoutError(job.Payload.JobID, timeout, err)
+			errorMap := timeoutErr.ToMap()
+
+			// Update job status to failed with timeout error
+			if updateErr := r.processor.UpdateJobStatus(r.ctx, job.Payload.JobID, "failed", 100, errorMap); updateErr != nil {
+				log.Printf("[Job %s] Warning: Failed to update status to failed: %v", job.Payload.JobID, updateErr)
+			}
+
+			return nil, fmt.Errorf("processing timeout: %w", timeoutErr)
+		}
+
+		return nil, err
+	}
+
+	log.Printf("[Job %s] Processing completed in %v", job.Payload.JobID, duration)
+	return result, nil
+}
+
+// updateJobStatus updates the status of a job in both Redis AND PostgreSQL
+func (r *jobRunner) updateJobStatus(jobID string, status string, result interface{}) {
+	// Update Redis for queue management
+	if status == "processing" {
+		r.client.SAdd(r.ctx, fmt.Sprintf("%s:processing", r.config.QueueName), jobID)
+	} else if status == "completed" {
+		r.client.SRem(r.ctx, fmt.Sprintf("%s:processing", r.config.QueueName), jobID)
+		r.client.SAdd(r.ctx, fmt.Sprintf("%s:completed", r.config.QueueName), jobID)
+		if result != nil {
+			resultData, _ := json.Marshal(result)
+			r.client.HSet(r.ctx, fmt.Sprintf("%s:results", r.config.QueueName), jobID, resultData)
+		}
+	} else if status == "cancelled" {
+		r.client.SRem(r.ctx, fmt.Sprintf("%s:processing", r.config.QueueName), jobID)
+		r.client.SAdd(r.ctx, fmt.Sprintf("%s:cancelled", r.config.QueueName), jobID)
+	} else if status == "failed" {
+		r.client.SRem(r.ctx, fmt.Sprintf("%s:processing", r.config.QueueName), jobID)
+		r.client.SAdd(r.ctx, fmt.Sprintf("%s:failed", r.config.QueueName), jobID)
+		if result != nil {
+			errorData, _ := json.Marshal(result)
+			r.client.HSet(r.ctx, fmt.Sprintf("%s:errors", r.config.QueueName), jobID, errorData)
+		}
+	}
+
+	// Progress is only meaningful while the job is processing
+	if status != "processing" {
+		r.client.HDel(r.ctx, r.progressKey(), jobID)
+	}
+
+	// Update PostgreSQL for persistent job tracking
+	if status == "completed" {
+		// Convert processor.ProcessResult to storage update
+		if processResult, ok := result.(*processor.ProcessResult); ok {
+			log.Printf("[PostgreSQL] Updating job %s to completed with full details", jobID)
+			if err := r.processor.UpdateJobStatus(r.ctx, jobID, status, 100, map[string]interface{}{
+				"confidence":         processResult.Confidence,
+				"processingTime":     processResult.ProcessingTimeMs,
+				"documentDnaId":      processResult.DocumentDNAID,
+				"ocrTierUsed":        processResult.OCRTierUsed,
+				"embeddingGenerated": processResult.EmbeddingGenerated,
+				"tablesExtracted":    processResult.TablesExtracted,
+				"regionsExtracted":   processResult.RegionsExtracted,
+				"contentHash":        processResult.ContentHash,
+				"reusedFromJobId":    processResult.ReusedFromJobID,
+				"ocrCostUsd":         processResult.OCRCostUSD,
+				"chunksEmbedded":     processResult.ChunksEmbedded,
+			}); err != nil {
+				log.Printf("[PostgreSQL] ERROR: Failed to update job status: %v", err)
+			} else {
+				log.Printf("[PostgreSQL] ✓ Job %s updated successfully (confidence=%.2f, tier=%s, dnaId=%s)",
+					jobID, processResult.Confidence, processResult.OCRTierUsed, processResult.DocumentDNAID)
+			}
+		} else {
+			// Fallback: Type assertion failed, but still try to mark as completed
+			log.Printf("[PostgreSQL] WARNING: ProcessResult type assertion failed. Marking as completed without details.")
+			if err := r.processor.UpdateJobStatus(r.ctx, jobID, status, 100, nil); err != nil {
+				log.Printf("[PostgreSQL] ERROR: Failed to update job status (fallback): %v", err)
+			}
+		}
+	} else if status == "failed" {
+		// Extract error message and code from result
+		errorMsg := "Unknown error"
+		errorCode := ""
+		if resultMap, ok := result.(map[string]interface{}); ok {
+			if errStr, ok := resultMap["error"].(string); ok {
+				errorMsg = errStr
+			}
+			errorCode, _ = resultMap["errorCode"].(string)
+		}
+
+		if err := r.processor.UpdateJobStatus(r.ctx, jobID, status, 0, map[string]interface{}{
+			"error":     errorMsg,
+			"errorCode": errorCode,
+		}); err != nil {
+			log.Printf("WARNING: Failed to update PostgreSQL job status for failed job: %v", err)
+		}
+	} else if status == "cancelled" {
+		if err := r.processor.UpdateJobStatus(r.ctx, jobID, status, 0, nil); err != nil {
+			log.Printf("WARNING: Failed to update PostgreSQL job status to cancelled: %v", err)
+		}
+	} else if status == "processing" {
+		// Mark job as processing in PostgreSQL
+		if err := r.processor.UpdateJobStatus(r.ctx, jobID, status, 0, nil); err != nil {
+			log.Printf("WARNING: Failed to update PostgreSQL job status to processing: %v", err)
+		}
+	}
+
+	// Publish event for WebSocket streaming
+	event := map[string]interface{}{
+		"event":     fmt.Sprintf("job:%s", status),
+		"jobId":     jobID,
+		"timestamp": time.Now().Format(time.RFC3339),
+	}
+	eventData, _ := json.Marshal(event)
+	r.client.Publish(r.ctx, fmt.Sprintf("%s:events", r.config.QueueName), eventData)
+}
+
+// sharedStats returns the statistics every backend reports from the shared <queue>:* keys
+func (r *jobRunner) sharedStats(ctx context.Context) map[string]int64 {
+	processing, _ := r.client.SCard(ctx, fmt.Sprintf("%s:processing", r.config.QueueName)).Result()
+	completed, _ := r.client.SCard(ctx, fmt.Sprintf("%s:completed", r.config.QueueName)).Result()
+	failed, _ := r.client.SCard(ctx, fmt.Sprintf("%s:failed", r.config.QueueName)).Result()
+	cancelled, _ := r.client.SCard(ctx, fmt.Sprintf("%s:cancelled", r.config.QueueName)).Result()
+	delayed, _ := r.client.ZCard(ctx, r.delayedKey()).Result()
+	dead, _ := r.client.ZCard(ctx, deadIndexKey(r.config.QueueName)).Result()
+	poison, _ := r.client.HLen(ctx, r.poisonKey()).Result()
+
+	return map[string]int64{
+		"processing": processing,
+		"completed":  completed,
+		"failed":     failed,
+		"cancelled":  cancelled,
+		"delayed":    delayed,
+		"dead":       dead,
+		"poison":     poison,
+	}
+}
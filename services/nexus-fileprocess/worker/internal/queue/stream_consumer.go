@@ -0,0 +1,416 @@
+/**
+ * Redis Streams Queue Backend
+ *
+ * Consumes job IDs from <queue>:stream (entries of the form {id: <queueJobId>})
+ * through a consumer group shared by every worker:
+ * - XREADGROUP delivers new entries; they stay in the group's pending entries
+ *   list (PEL) until XACK, so a crashed worker loses nothing
+ * - Long-running jobs are re-claimed by their own consumer every heartbeat
+ *   interval, which resets their idle time. A consumer that finds the entry
+ *   owned by another one gives the job up.
+ * - A claimer uses XAUTOCLAIM to take over entries that have been idle longer
+ *   than the visibility timeout, i.e. whose worker stopped heartbeating. It
+ *   claims no more entries than it can queue for its workers, and heartbeats
+ *   them while they wait.
+ *
+ * Job data and all status keys are shared with the LIST backend. While producers
+ * migrate, job IDs still pushed onto the legacy list are forwarded to the stream.
+ */
+
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultConsumerGroup = "fileprocess-workers"
+
+// forwardLegacyScript moves job IDs from the legacy LIST queue onto the stream
+var forwardLegacyScript = redis.NewScript(`
+local moved = 0
+for i = 1, tonumber(ARGV[1]) do
+	local id = redis.call('RPOP', KEYS[1])
+	if not id then
+		break
+	end
+	redis.call('XADD', KEYS[2], '*', 'id', id)
+	moved = moved + 1
+end
+return moved
+`)
+
+// touchEntryScript re-claims a pending entry, resetting its idle time, only while
+// the consumer still owns it. Returns 0 once another consumer has taken it over.
+var touchEntryScript = redis.NewScript(`
+local pending = redis.call('XPENDING', KEYS[1], ARGV[1], ARGV[3], ARGV[3], 1)
+if #pending == 0 or pending[1][2] ~= ARGV[2] then
+	return 0
+end
+redis.call('XCLAIM', KEYS[1], ARGV[1], ARGV[2], 0, ARGV[3], 'JUSTID')
+return 1
+`)
+
+// StreamConsumer handles job consumption from a Redis stream consumer group
+type StreamConsumer struct {
+	jobRunner
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	claimed chan claimedEntry // Entries taken over from dead consumers, drained before new ones
+}
+
+// claimedEntry is a stale entry waiting for a worker, heartbeated until one takes it
+type claimedEntry struct {
+	msg       redis.XMessage
+	heartbeat chan struct{} // Closed when a worker takes the entry
+}
+
+// NewStreamConsumer creates a new Redis Streams queue consumer
+func NewStreamConsumer(cfg *RedisConsumerConfig) (*StreamConsumer, error) {
+	if err := applyRedisDefaults(cfg); err != nil {
+		return nil, err
+	}
+
+	client, err := connectRedis(cfg.RedisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	consumerCtx, cancel := context.WithCancel(context.Background())
+
+	return &StreamConsumer{
+		jobRunner: jobRunner{
+			client:    client,
+			processor: cfg.Processor,
+			config:    cfg,
+			ctx:       consumerCtx,
+			backend:   BackendStreams,
+			cancels:   newCancelRegistry(),
+		},
+		cancel:  cancel,
+		claimed: make(chan claimedEntry, cfg.Concurrency),
+	}, nil
+}
+
+// streamKey is this consumer's stream
+func (c *StreamConsumer) streamKey() string {
+	return streamKey(c.config.QueueName)
+}
+
+// Start creates the consumer group if needed and begins processing jobs
+func (c *StreamConsumer) Start() error {
+	log.Printf("Starting Redis Streams consumer (concurrency=%d, stream=%s, group=%s, consumer=%s)...",
+		c.config.Concurrency, c.streamKey(), c.config.ConsumerGroup, c.config.WorkerID)
+
+	err := c.client.XGroupCreateMkStream(c.ctx, c.streamKey(), c.config.ConsumerGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group %s: %w", c.config.ConsumerGroup, err)
+	}
+
+	// Start claimer, retry scheduler and control goroutines
+	c.wg.Add(3)
+	go c.claimLoop()
+	go c.schedulerLoop(&c.wg)
+	go c.controlLoop(&c.wg)
+
+	// Start worker goroutines
+	for i := 0; i < c.config.Concurrency; i++ {
+		c.wg.Add(1)
+		go c.worker(i)
+	}
+
+	log.Println("Queue consumer started successfully")
+	return nil
+}
+
+// Stop gracefully stops the consumer
+func (c *StreamConsumer) Stop() error {
+	log.Println("Stopping queue consumer...")
+	c.cancel()
+	c.wg.Wait()
+	c.removeConsumer(context.Background())
+	return c.client.Close()
+}
+
+// removeConsumer deletes this worker from the group once it has nothing pending
+func (c *StreamConsumer) removeConsumer(ctx context.Context) {
+	pending, err := c.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream:   c.streamKey(),
+		Group:    c.config.ConsumerGroup,
+		Start:    "-",
+		End:      "+",
+		Count:    1,
+		Consumer: c.config.WorkerID,
+	}).Result()
+	if err != nil {
+		log.Printf("[Queue] WARNING: Failed to check pending entries on shutdown: %v", err)
+		return
+	}
+
+	if len(pending) > 0 {
+		// Leave the consumer in place so XAUTOCLAIM can recover what is left
+		log.Printf("[Queue] WARNING: Consumer %s still has pending entries, leaving them for the claimer", c.config.WorkerID)
+		return
+	}
+
+	if err := c.client.XGroupDelConsumer(ctx, c.streamKey(), c.config.ConsumerGroup, c.config.WorkerID).Err(); err != nil {
+		log.Printf("[Queue] WARNING: Failed to remove consumer %s: %v", c.config.WorkerID, err)
+	}
+}
+
+// worker is a goroutine that processes stream entries
+func (c *StreamConsumer) worker(id int) {
+	defer c.wg.Done()
+	log.Printf("Worker %d started", id)
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			log.Printf("Worker %d stopping", id)
+			return
+		case entry := <-c.claimed:
+			close(entry.heartbeat)
+			c.handleClaimed(entry.msg)
+		default:
+			if err := c.processNextEntry(); err != nil {
+				if err.Error() == "no jobs available" {
+					// The read already blocked; look for claimed entries straight away
+					continue
+				}
+				log.Printf("Worker %d error: %v", id, err)
+				// Small delay before trying again
+				time.Sleep(1 * time.Second)
+			}
+		}
+	}
+}
+
+// processNextEntry reads and processes the next new entry for this consumer
+func (c *StreamConsumer) processNextEntry() error {
+	streams, err := c.client.XReadGroup(c.ctx, &redis.XReadGroupArgs{
+		Group:    c.config.ConsumerGroup,
+		Consumer: c.config.WorkerID,
+		Streams:  []string{c.streamKey(), ">"},
+		Count:    1,
+		Block:    5 * time.Second,
+	}).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return fmt.Errorf("no jobs available")
+		}
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			c.handleMessage(msg)
+		}
+	}
+	return nil
+}
+
+// handleClaimed runs a claimed entry unless another consumer took it over while it waited
+func (c *StreamConsumer) handleClaimed(msg redis.XMessage) {
+	owned, err := c.touchEntry(msg.ID)
+	if err != nil {
+		// Left pending; it is claimed again after the visibility timeout
+		log.Printf("[Queue] WARNING: Failed to check ownership of stream entry %s: %v", msg.ID, err)
+		return
+	}
+	if !owned {
+		log.Printf("[Queue] Stream entry %s was taken over by another consumer, skipping it", msg.ID)
+		return
+	}
+	c.handleMessage(msg)
+}
+
+// handleMessage loads and runs the job referenced by a stream entry
+func (c *StreamConsumer) handleMessage(msg redis.XMessage) {
+	queueJobID, _ := msg.Values["id"].(string)
+	delivery := &Delivery{ID: msg.ID, QueueJobID: queueJobID}
+
+	if queueJobID == "" {
+		log.Printf("[Queue] WARNING: Dropping stream entry %s without a job ID", msg.ID)
+		c.settle(context.Background(), delivery, c.streamAck(msg.ID))
+		return
+	}
+
+	if _, err := c.loadJob(delivery, c.streamAck(msg.ID)); err != nil {
+		// Transient failures leave the entry pending; it is claimed again after the visibility timeout
+		log.Printf("[Queue] %v", err)
+		return
+	}
+
+	// Keep the entry's idle time below the visibility timeout while we work on it,
+	// and stop working on it if another consumer takes it over
+	jobID := delivery.Job.Payload.JobID
+	done := make(chan struct{})
+	go c.heartbeat(msg.ID, done, func() {
+		log.Printf("[Job %s] Stream entry %s was taken over by another consumer, giving the job up", jobID, msg.ID)
+		c.cancels.abort(jobID, errDeliveryLost)
+	})
+	defer close(done)
+
+	c.run(context.Background(), c, delivery)
+}
+
+// heartbeat re-claims an entry for this consumer until done is closed, resetting its idle time.
+// lost is called, and the heartbeat stops, once another consumer owns the entry.
+func (c *StreamConsumer) heartbeat(entryID string, done <-chan struct{}, lost func()) {
+	ticker := time.NewTicker(time.Duration(c.config.HeartbeatInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			owned, err := c.touchEntry(entryID)
+			if err != nil {
+				if c.ctx.Err() == nil {
+					log.Printf("[Queue] WARNING: Heartbeat failed for stream entry %s: %v", entryID, err)
+				}
+				continue
+			}
+			if !owned {
+				if lost != nil {
+					lost()
+				}
+				return
+			}
+		}
+	}
+}
+
+// touchEntry resets the idle time of an entry this consumer owns. owned is false
+// once the entry has been acked or claimed by another consumer.
+func (c *StreamConsumer) touchEntry(entryID string) (owned bool, err error) {
+	result, err := touchEntryScript.Run(c.ctx, c.client, []string{c.streamKey()},
+		c.config.ConsumerGroup, c.config.WorkerID, entryID).Int()
+	if err != nil {
+		return false, err
+	}
+	return result == 1, nil
+}
+
+// claimLoop periodically takes over stale pending entries and forwards legacy LIST jobs
+func (c *StreamConsumer) claimLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(c.config.ReaperInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.forwardLegacyJobs(c.ctx); err != nil && c.ctx.Err() == nil {
+				log.Printf("[Queue] WARNING: Legacy queue forwarding failed: %v", err)
+			}
+			if err := c.claimStaleEntries(c.ctx); err != nil && c.ctx.Err() == nil {
+				log.Printf("[Queue] WARNING: Pending entry recovery failed: %v", err)
+			}
+		}
+	}
+}
+
+// claimStaleEntries uses XAUTOCLAIM to take over entries idle for longer than the
+// visibility timeout and queues them for this consumer's workers. Only as many
+// entries are claimed as the queue has room for, so the claimer never blocks.
+func (c *StreamConsumer) claimStaleEntries(ctx context.Context) error {
+	start := "0-0"
+	for {
+		room := cap(c.claimed) - len(c.claimed)
+		if room <= 0 {
+			return nil
+		}
+
+		messages, next, err := c.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   c.streamKey(),
+			Group:    c.config.ConsumerGroup,
+			Consumer: c.config.WorkerID,
+			MinIdle:  time.Duration(c.config.VisibilityTimeout) * time.Millisecond,
+			Start:    start,
+			Count:    int64(room),
+		}).Result()
+		if err != nil {
+			return fmt.Errorf("failed to auto-claim pending entries: %w", err)
+		}
+
+		if len(messages) > 0 {
+			log.Printf("[Queue] Claimed %d stale stream entr(ies) for consumer %s", len(messages), c.config.WorkerID)
+		}
+		for _, msg := range messages {
+			// Only this loop sends, so the queue has room for every claimed entry
+			entry := claimedEntry{msg: msg, heartbeat: make(chan struct{})}
+			go c.heartbeat(msg.ID, entry.heartbeat, nil)
+			c.claimed <- entry
+		}
+
+		if next == "0-0" {
+			return nil
+		}
+		start = next
+	}
+}
+
+// forwardLegacyJobs moves job IDs still pushed onto the LIST queue over to the stream
+func (c *StreamConsumer) forwardLegacyJobs(ctx context.Context) error {
+	for {
+		moved, err := forwardLegacyScript.Run(ctx, c.client,
+			[]string{c.config.QueueName, c.streamKey()}, schedulerBatchSize).Int()
+		if err != nil {
+			return err
+		}
+		if moved < schedulerBatchSize {
+			return nil
+		}
+	}
+}
+
+// streamAck acknowledges and deletes a stream entry as part of a transaction
+func (c *StreamConsumer) streamAck(entryID string) ackFunc {
+	return func(ctx context.Context, pipe redis.Pipeliner) {
+		pipe.XAck(ctx, c.streamKey(), c.config.ConsumerGroup, entryID)
+		pipe.XDel(ctx, c.streamKey(), entryID)
+	}
+}
+
+// Ack acknowledges a processed stream entry
+func (c *StreamConsumer) Ack(ctx context.Context, delivery *Delivery) error {
+	return c.settle(ctx, delivery, c.streamAck(delivery.ID))
+}
+
+// Nack schedules a failed job for retry or dead-letters it, acknowledging the stream entry.
+// On error the entry stays pending and is claimed again after the visibility timeout.
+func (c *StreamConsumer) Nack(ctx context.Context, delivery *Delivery, cause error) error {
+	return c.fail(ctx, delivery, cause, c.streamAck(delivery.ID))
+}
+
+// GetStats returns queue statistics
+func (c *StreamConsumer) GetStats() (map[string]int64, error) {
+	ctx := context.Background()
+
+	stats := c.sharedStats(ctx)
+
+	// Entries are deleted on ack, so the stream length is everything not yet settled
+	length, _ := c.client.XLen(ctx, c.streamKey()).Result()
+	pending, err := c.client.XPending(ctx, c.streamKey(), c.config.ConsumerGroup).Result()
+	var pendingCount int64
+	if err == nil {
+		pendingCount = pending.Count
+	}
+	stats["waiting"] = length - pendingCount
+	stats["pending"] = pendingCount
+
+	return stats, nil
+}
@@ -0,0 +1,269 @@
+/**
+ * Batch Tracking
+ *
+ * A batch groups jobs enqueued together (e.g. a Drive folder import). The
+ * worker records each child's final outcome (completed, failed or cancelled)
+ * in fileprocess.batch_jobs and
+ * adjusts the counters on fileprocess.batches under a row lock, so concurrent
+ * workers never lose an update and a redelivered child is not counted twice.
+ */
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// Batch statuses
+const (
+	BatchStatusProcessing          = "processing"
+	BatchStatusCompleted           = "completed"
+	BatchStatusCompletedWithErrors = "completed_with_errors"
+	BatchStatusFailed              = "failed"
+	BatchStatusCancelled           = "cancelled" // Every child was cancelled
+)
+
+// maxBatchFailures bounds the failures returned in a batch summary
+const maxBatchFailures = 100
+
+// BatchJobOutcome is the final outcome of one job in a batch
+type BatchJobOutcome struct {
+	BatchID      string
+	UserID       string // Owner recorded if the batch has not been created yet
+	BatchSize    int    // Expected number of jobs, used if the batch size is not known yet
+	JobID        string
+	Filename     string
+	Status       string // completed, failed or cancelled
+	OCRTierUsed  string
+	PageCount    int
+	ErrorMessage string
+}
+
+// BatchFailure is a failed job in a batch summary
+type BatchFailure struct {
+	JobID    string `json:"jobId"`
+	Filename string `json:"filename,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BatchSummary is the aggregate state of a batch
+type BatchSummary struct {
+	ID            string
+	UserID        string
+	Status        string
+	TotalJobs     int
+	CompletedJobs int
+	FailedJobs    int
+	CancelledJobs int
+	TotalPages    int
+	TierUsage     map[string]int
+	Failures      []BatchFailure // Only loaded once the batch is finished
+	JustFinished  bool           // This outcome was the one that finished the batch
+}
+
+// Finished reports whether every job of the batch has completed, failed or been cancelled
+func (s *BatchSummary) Finished() bool {
+	return s.Status != BatchStatusProcessing
+}
+
+// ApplyOutcome counts a job's outcome, replacing its previous outcome (nil if it has
+// none), and derives the batch status from the counters
+func (s *BatchSummary) ApplyOutcome(previous, outcome *BatchJobOutcome) {
+	if s.TierUsage == nil {
+		s.TierUsage = make(map[string]int)
+	}
+
+	// Undo the job's earlier outcome, if any
+	if previous != nil {
+		switch previous.Status {
+		case BatchStatusCompleted:
+			s.CompletedJobs--
+			s.TotalPages -= previous.PageCount
+			if previous.OCRTierUsed != "" {
+				s.TierUsage[previous.OCRTierUsed]--
+				if s.TierUsage[previous.OCRTierUsed] <= 0 {
+					delete(s.TierUsage, previous.OCRTierUsed)
+				}
+			}
+		case BatchStatusCancelled:
+			s.CancelledJobs--
+		default:
+			s.FailedJobs--
+		}
+	}
+
+	// Apply the new outcome
+	switch outcome.Status {
+	case BatchStatusCompleted:
+		s.CompletedJobs++
+		s.TotalPages += outcome.PageCount
+		if outcome.OCRTierUsed != "" {
+			s.TierUsage[outcome.OCRTierUsed]++
+		}
+	case BatchStatusCancelled:
+		s.CancelledJobs++
+	default:
+		s.FailedJobs++
+	}
+
+	s.Status = batchStatus(s)
+}
+
+// RecordBatchJob records a child's final outcome and returns the updated batch.
+// Recording the same job again replaces its earlier outcome.
+func (sm *StorageManager) RecordBatchJob(ctx context.Context, outcome *BatchJobOutcome) (*BatchSummary, error) {
+	if outcome.BatchID == "" || outcome.JobID == "" {
+		return nil, fmt.Errorf("batch ID and job ID are required")
+	}
+
+	tx, err := sm.postgres.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin batch transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Create the batch if the API did not, and fill in its size if still unknown
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO fileprocess.batches (id, user_id, total_jobs)
+		VALUES ($1, COALESCE(NULLIF($2, ''), 'anonymous'), $3)
+		ON CONFLICT (id) DO UPDATE SET
+			total_jobs = CASE
+				WHEN fileprocess.batches.total_jobs = 0 THEN EXCLUDED.total_jobs
+				ELSE fileprocess.batches.total_jobs
+			END
+	`, outcome.BatchID, outcome.UserID, outcome.BatchSize); err != nil {
+		return nil, fmt.Errorf("failed to create batch: %w", err)
+	}
+
+	summary := &BatchSummary{ID: outcome.BatchID}
+	var tierUsage []byte
+	if err := tx.QueryRowContext(ctx, `
+		SELECT user_id, status, total_jobs, completed_jobs, failed_jobs, cancelled_jobs, total_pages, tier_usage
+		FROM fileprocess.batches
+		WHERE id = $1
+		FOR UPDATE
+	`, outcome.BatchID).Scan(&summary.UserID, &summary.Status, &summary.TotalJobs,
+		&summary.CompletedJobs, &summary.FailedJobs, &summary.CancelledJobs, &summary.TotalPages, &tierUsage); err != nil {
+		return nil, fmt.Errorf("failed to lock batch: %w", err)
+	}
+	summary.TierUsage = make(map[string]int)
+	if err := json.Unmarshal(tierUsage, &summary.TierUsage); err != nil {
+		return nil, fmt.Errorf("failed to parse batch tier usage: %w", err)
+	}
+	previousStatus := summary.Status
+
+	// The job's earlier outcome, if any, is replaced
+	var previous *BatchJobOutcome
+	var prevTier sql.NullString
+	prev := BatchJobOutcome{}
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, ocr_tier_used, page_count
+		FROM fileprocess.batch_jobs
+		WHERE batch_id = $1 AND job_id = $2
+	`, outcome.BatchID, outcome.JobID).Scan(&prev.Status, &prevTier, &prev.PageCount)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return nil, fmt.Errorf("failed to read batch job: %w", err)
+	default:
+		prev.OCRTierUsed = prevTier.String
+		previous = &prev
+	}
+
+	summary.ApplyOutcome(previous, outcome)
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO fileprocess.batch_jobs (
+			batch_id, job_id, filename, status, ocr_tier_used, page_count, error_message, finished_at
+		) VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), $6, NULLIF($7, ''), NOW())
+		ON CONFLICT (batch_id, job_id) DO UPDATE SET
+			filename = EXCLUDED.filename,
+			status = EXCLUDED.status,
+			ocr_tier_used = EXCLUDED.ocr_tier_used,
+			page_count = EXCLUDED.page_count,
+			error_message = EXCLUDED.error_message,
+			finished_at = EXCLUDED.finished_at
+	`, outcome.BatchID, outcome.JobID, outcome.Filename, outcome.Status, outcome.OCRTierUsed,
+		outcome.PageCount, outcome.ErrorMessage); err != nil {
+		return nil, fmt.Errorf("failed to record batch job: %w", err)
+	}
+
+	summary.JustFinished = previousStatus == BatchStatusProcessing && summary.Finished()
+
+	tierUsage, err = json.Marshal(summary.TierUsage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch tier usage: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE fileprocess.batches
+		SET status = $2,
+			completed_jobs = $3,
+			failed_jobs = $4,
+			cancelled_jobs = $5,
+			total_pages = $6,
+			tier_usage = $7::jsonb,
+			completed_at = CASE WHEN $2 = 'processing' THEN NULL ELSE COALESCE(completed_at, NOW()) END
+		WHERE id = $1
+	`, outcome.BatchID, summary.Status, summary.CompletedJobs, summary.FailedJobs, summary.CancelledJobs,
+		summary.TotalPages, tierUsage); err != nil {
+		return nil, fmt.Errorf("failed to update batch: %w", err)
+	}
+
+	if summary.Finished() {
+		if summary.Failures, err = batchFailures(ctx, tx, outcome.BatchID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit batch update: %w", err)
+	}
+	return summary, nil
+}
+
+// batchStatus derives a batch's status from its counters. A batch of unknown size stays open.
+// Cancelled children count against the batch like failed ones.
+func batchStatus(s *BatchSummary) string {
+	if s.TotalJobs <= 0 || s.CompletedJobs+s.FailedJobs+s.CancelledJobs < s.TotalJobs {
+		return BatchStatusProcessing
+	}
+	switch {
+	case s.FailedJobs == 0 && s.CancelledJobs == 0:
+		return BatchStatusCompleted
+	case s.CompletedJobs == 0 && s.FailedJobs == 0:
+		return BatchStatusCancelled
+	case s.CompletedJobs == 0:
+		return BatchStatusFailed
+	default:
+		return BatchStatusCompletedWithErrors
+	}
+}
+
+// batchFailures lists the failed jobs of a batch
+func batchFailures(ctx context.Context, tx *sql.Tx, batchID string) ([]BatchFailure, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT job_id, COALESCE(filename, ''), COALESCE(error_message, '')
+		FROM fileprocess.batch_jobs
+		WHERE batch_id = $1 AND status = 'failed'
+		ORDER BY finished_at
+		LIMIT $2
+	`, batchID, maxBatchFailures)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batch failures: %w", err)
+	}
+	defer rows.Close()
+
+	failures := make([]BatchFailure, 0)
+	for rows.Next() {
+		var f BatchFailure
+		if err := rows.Scan(&f.JobID, &f.Filename, &f.Error); err != nil {
+			return nil, fmt.Errorf("failed to read batch failure: %w", err)
+		}
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
@@ -0,0 +1,195 @@
+/**
+ * Blob Store for Large File Payloads
+ *
+ * Claim-check storage for file contents that are too large to travel inline in
+ * the Redis job hash. A job carries a blobRef (a key in the configured store)
+ * instead of a fileBuffer, and the worker streams the blob in when processing.
+ *
+ * Backends (BLOB_STORE):
+ * - local  Directory on a filesystem shared with the API (BLOB_STORE_PATH)
+ * - s3     S3-compatible object storage (AWS S3, MinIO, Ceph RGW, ...)
+ */
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Supported blob store types
+const (
+	BlobStoreNone  = "none"
+	BlobStoreLocal = "local"
+	BlobStoreS3    = "s3"
+)
+
+// ErrBlobNotFound is returned when a blob reference does not exist in the store
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore stores file payloads referenced by jobs
+type BlobStore interface {
+	// Open streams a blob; the returned size is -1 when unknown
+	Open(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	// Put stores a blob of the given size under key, replacing any existing blob
+	Put(ctx context.Context, key string, body io.Reader, size int64) error
+	// Delete removes a blob; deleting a missing blob is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// BlobStoreConfig selects and configures a blob store backend
+type BlobStoreConfig struct {
+	Type string // none, local or s3
+
+	// local
+	Path string // Root directory of the store
+
+	// s3
+	S3Endpoint        string // e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3ForcePathStyle  bool // Address the bucket as /<bucket>/<key> (required by most non-AWS endpoints)
+}
+
+// NewBlobStore creates the configured blob store. It returns nil for type "none".
+func NewBlobStore(cfg *BlobStoreConfig) (BlobStore, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	switch cfg.Type {
+	case "", BlobStoreNone:
+		return nil, nil
+	case BlobStoreLocal:
+		store, err := NewLocalBlobStore(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case BlobStoreS3:
+		store, err := NewS3BlobStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown blob store %q (expected %s, %s or %s)",
+			cfg.Type, BlobStoreNone, BlobStoreLocal, BlobStoreS3)
+	}
+}
+
+// LocalBlobStore keeps blobs as files below a root directory
+type LocalBlobStore struct {
+	root string
+}
+
+// NewLocalBlobStore creates a filesystem blob store rooted at dir
+func NewLocalBlobStore(dir string) (*LocalBlobStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("blob store path is required")
+	}
+
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve blob store path: %w", err)
+	}
+
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create blob store directory: %w", err)
+	}
+
+	return &LocalBlobStore{root: root}, nil
+}
+
+// path resolves a key below the root, rejecting keys that would escape it
+func (s *LocalBlobStore) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + filepath.FromSlash(key))
+	if cleaned == string(filepath.Separator) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+
+	path := filepath.Join(s.root, cleaned)
+	if !strings.HasPrefix(path, s.root+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return path, nil
+}
+
+// Open implements BlobStore
+func (s *LocalBlobStore) Open(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, fmt.Errorf("%w: %s", ErrBlobNotFound, key)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open blob %s: %w", key, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("failed to stat blob %s: %w", key, err)
+	}
+
+	return file, info.Size(), nil
+}
+
+// Put implements BlobStore. The blob is written to a temporary file and renamed
+// into place so readers never see a partial blob.
+func (s *LocalBlobStore) Put(ctx context.Context, key string, body io.Reader, size int64) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".blob-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+	if size >= 0 && written != size {
+		return fmt.Errorf("failed to write blob %s: wrote %d of %d bytes", key, written, size)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store blob %s: %w", key, err)
+	}
+	return nil
+}
+
+// Delete implements BlobStore
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete blob %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+/**
+ * OCR Spend and Budgets
+ *
+ * The worker adds the cost of every paid OCR tier it runs to the job's
+ * processing_jobs.ocr_cost_usd as it goes, so failed and retried attempts
+ * are charged too. Per-user budgets in fileprocess.ocr_budgets cap the spend
+ * of the user's jobs created in the current UTC day or month.
+ *
+ * Before a paid tier runs, its estimated cost is reserved in
+ * processing_jobs.ocr_reserved_usd while the user's budget rows are locked,
+ * so concurrent jobs of one user cannot all pass the check against the same
+ * remaining budget. Reservations count as spend until they are settled.
+ */
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// OCR budget periods
+const (
+	BudgetPeriodDaily   = "daily"
+	BudgetPeriodMonthly = "monthly"
+)
+
+// OCRBudgetStatus is a user budget and the spend counted against it
+type OCRBudgetStatus struct {
+	Period   string // BudgetPeriodDaily or BudgetPeriodMonthly
+	LimitUSD float64
+	SpentUSD float64 // Spend of the user's jobs created in the current period
+}
+
+// RemainingUSD is the spend left in the period, never negative
+func (b OCRBudgetStatus) RemainingUSD() float64 {
+	return max(b.LimitUSD-b.SpentUSD, 0)
+}
+
+// OCRSpend is the OCR spend relevant to a job about to run OCR
+type OCRSpend struct {
+	JobSpentUSD float64           // Spent by earlier attempts of the job
+	Budgets     []OCRBudgetStatus // The user's budgets (empty: unlimited)
+}
+
+// GetOCRSpend returns a job's spend so far and its user's budgets with their current spend
+func (sm *StorageManager) GetOCRSpend(ctx context.Context, jobID, userID string) (*OCRSpend, error) {
+	spend := &OCRSpend{}
+
+	err := sm.postgres.db.QueryRowContext(ctx, `
+		SELECT COALESCE((SELECT ocr_cost_usd FROM fileprocess.processing_jobs WHERE id = $1::uuid), 0)
+	`, jobID).Scan(&spend.JobSpentUSD)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job OCR spend: %w", err)
+	}
+
+	if userID == "" {
+		return spend, nil
+	}
+
+	if spend.Budgets, err = ocrBudgets(ctx, sm.postgres.db, userID); err != nil {
+		return nil, err
+	}
+	return spend, nil
+}
+
+// ReserveOCRSpend reserves the estimated cost of a paid OCR call for a job. The user's
+// budget rows stay locked until the reservation is recorded, so concurrent jobs of the
+// user see each other's reservations. It returns false, reserving nothing, when the
+// cost would exceed one of the user's budgets.
+func (sm *StorageManager) ReserveOCRSpend(ctx context.Context, jobID, userID string, costUSD float64) (bool, error) {
+	if jobID == "" {
+		return false, fmt.Errorf("job ID is required")
+	}
+
+	tx, err := sm.postgres.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin OCR reservation: %w", err)
+	}
+	defer tx.Rollback()
+
+	if userID != "" {
+		if _, err := tx.ExecContext(ctx, `
+			SELECT 1 FROM fileprocess.ocr_budgets WHERE user_id = $1 FOR UPDATE
+		`, userID); err != nil {
+			return false, fmt.Errorf("failed to lock OCR budgets: %w", err)
+		}
+
+		budgets, err := ocrBudgets(ctx, tx, userID)
+		if err != nil {
+			return false, err
+		}
+		for _, budget := range budgets {
+			if budget.SpentUSD+costUSD > budget.LimitUSD+1e-9 {
+				return false, nil
+			}
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE fileprocess.processing_jobs
+		SET ocr_reserved_usd = ocr_reserved_usd + $2, updated_at = NOW()
+		WHERE id = $1::uuid
+	`, jobID, costUSD)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve OCR spend: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return false, fmt.Errorf("job not found: %s", jobID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit OCR reservation: %w", err)
+	}
+	return true, nil
+}
+
+// ReleaseOCRReservations drops a job's reservations left by an attempt that did not
+// settle them; a job's attempts run one at a time
+func (sm *StorageManager) ReleaseOCRReservations(ctx context.Context, jobID string) error {
+	if _, err := sm.postgres.db.ExecContext(ctx, `
+		UPDATE fileprocess.processing_jobs
+		SET ocr_reserved_usd = 0
+		WHERE id = $1::uuid AND ocr_reserved_usd <> 0
+	`, jobID); err != nil {
+		return fmt.Errorf("failed to release OCR reservations: %w", err)
+	}
+	return nil
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// ocrBudgets reads a user's budgets with the spend and reservations of their jobs in each period
+func ocrBudgets(ctx context.Context, q queryer, userID string) ([]OCRBudgetStatus, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT b.period, b.limit_usd, COALESCE((
+			SELECT SUM(j.ocr_cost_usd + j.ocr_reserved_usd)
+			FROM fileprocess.processing_jobs j
+			WHERE j.user_id = b.user_id
+			  AND j.created_at >= date_trunc(
+				CASE b.period WHEN 'daily' THEN 'day' ELSE 'month' END,
+				NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+		), 0)
+		FROM fileprocess.ocr_budgets b
+		WHERE b.user_id = $1
+		ORDER BY b.period
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OCR budgets: %w", err)
+	}
+	defer rows.Close()
+
+	var budgets []OCRBudgetStatus
+	for rows.Next() {
+		var budget OCRBudgetStatus
+		if err := rows.Scan(&budget.Period, &budget.LimitUSD, &budget.SpentUSD); err != nil {
+			return nil, fmt.Errorf("failed to scan OCR budget: %w", err)
+		}
+		budgets = append(budgets, budget)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read OCR budgets: %w", err)
+	}
+	return budgets, nil
+}
+
+// AddJobOCRCost adds OCR spend to a job and settles the reservation made for it
+func (sm *StorageManager) AddJobOCRCost(ctx context.Context, jobID string, costUSD, reservedUSD float64) error {
+	if jobID == "" {
+		return fmt.Errorf("job ID is required")
+	}
+
+	result, err := sm.postgres.db.ExecContext(ctx, `
+		UPDATE fileprocess.processing_jobs
+		SET ocr_cost_usd = ocr_cost_usd + $2,
+			ocr_reserved_usd = GREATEST(ocr_reserved_usd - $3, 0),
+			updated_at = NOW()
+		WHERE id = $1::uuid
+	`, jobID, costUSD, reservedUSD)
+	if err != nil {
+		return fmt.Errorf("failed to record OCR cost: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("job not found: %s", jobID)
+	}
+	return nil
+}
@@ -32,9 +32,19 @@ type JobUpdate struct {
 	ErrorCode         string
 	ErrorMessage      string
 	OCRTierUsed       string
+	ContentHash       string
+	BatchID           string
 	Metadata          map[string]interface{}
 }
 
+// JobProgress represents the latest pipeline progress of a job
+type JobProgress struct {
+	JobID    string
+	Progress int                    // Overall progress 0-100
+	Stage    string                 // Current pipeline stage
+	Details  map[string]interface{} // Full progress update (message, partial stats, timestamp)
+}
+
 // DocumentDNA represents the document DNA structure
 type DocumentDNA struct {
 	ID                string
@@ -122,7 +132,7 @@ func (p *PostgresClient) UpdateJobStatus(ctx context.Context, update *JobUpdate)
 			id, user_id, filename, mime_type, file_size,
 			status, confidence, processing_time_ms, document_dna_id,
 			error_code, error_message, ocr_tier_used, metadata,
-			created_at, updated_at
+			content_hash, batch_id, created_at, updated_at
 		) VALUES (
 			$1::uuid, COALESCE($13, 'anonymous'), COALESCE($10, 'unknown.txt'),
 			COALESCE($11, 'application/octet-stream'), COALESCE($12, 0),
@@ -130,7 +140,7 @@ func (p *PostgresClient) UpdateJobStatus(ctx context.Context, update *JobUpdate)
 			CASE WHEN $5 = '' THEN NULL ELSE $5::uuid END,
 			NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''),
 			COALESCE($9::jsonb, '{}'::jsonb),
-			NOW(), NOW()
+			NULLIF($14, ''), NULLIF($15, ''), NOW(), NOW()
 		)
 		ON CONFLICT (id) DO UPDATE SET
 			status = EXCLUDED.status,
@@ -148,6 +158,12 @@ func (p *PostgresClient) UpdateJobStatus(ctx context.Context, update *JobUpdate)
 			mime_type = COALESCE(EXCLUDED.mime_type, fileprocess.processing_jobs.mime_type),
 			file_size = COALESCE(NULLIF(EXCLUDED.file_size, 0), fileprocess.processing_jobs.file_size),
 			user_id = COALESCE(EXCLUDED.user_id, fileprocess.processing_jobs.user_id),
+			content_hash = COALESCE(EXCLUDED.content_hash, fileprocess.processing_jobs.content_hash),
+			batch_id = COALESCE(EXCLUDED.batch_id, fileprocess.processing_jobs.batch_id),
+			ocr_reserved_usd = CASE
+				WHEN EXCLUDED.status IN ('completed', 'failed', 'cancelled') THEN 0
+				ELSE fileprocess.processing_jobs.ocr_reserved_usd
+			END,
 			updated_at = NOW()
 		RETURNING id
 	`
@@ -189,6 +205,8 @@ func (p *PostgresClient) UpdateJobStatus(ctx context.Context, update *JobUpdate)
 		mimeType,               // $11 - mime_type
 		fileSize,               // $12 - file_size
 		userId,                 // $13 - user_id
+		update.ContentHash,     // $14 - content_hash
+		update.BatchID,         // $15 - batch_id
 	).Scan(&returnedID)
 
 	if err == sql.ErrNoRows {
@@ -297,6 +315,31 @@ func (p *PostgresClient) GetDocumentDNA(ctx context.Context, dnaID string) (*Doc
 	return &dna, nil
 }
 
+// UpdateJobProgress records the latest progress of a job that is still processing
+func (p *PostgresClient) UpdateJobProgress(ctx context.Context, progress *JobProgress) error {
+	detailsJSON, err := json.Marshal(progress.Details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress details: %w", err)
+	}
+
+	query := `
+		UPDATE fileprocess.processing_jobs
+		SET progress = $2,
+			progress_stage = $3,
+			progress_details = $4::jsonb,
+			progress_updated_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1::uuid
+			AND status = 'processing'
+	`
+
+	if _, err := p.db.ExecContext(ctx, query, progress.JobID, progress.Progress, progress.Stage, detailsJSON); err != nil {
+		return fmt.Errorf("failed to update job progress (job=%s, stage=%s): %w", progress.JobID, progress.Stage, err)
+	}
+
+	return nil
+}
+
 // GetJobByID retrieves a job by ID
 func (p *PostgresClient) GetJobByID(ctx context.Context, jobID string) (map[string]interface{}, error) {
 	if jobID == "" {
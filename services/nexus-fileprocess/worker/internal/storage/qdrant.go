@@ -23,6 +23,7 @@ type QdrantClient struct {
 	collectionClient qdrant.CollectionsClient
 	conn           *grpc.ClientConn
 	collectionName string
+	dimensions     int // Vector size of the collection (the embedding model's dimensions)
 }
 
 // VectorPoint represents a vector with metadata
@@ -33,8 +34,15 @@ type VectorPoint struct {
 	Timestamp int64
 }
 
-// NewQdrantClient creates a new Qdrant client
-func NewQdrantClient(address string, collectionName string) (*QdrantClient, error) {
+// Payload field telling document points from the chunk points of their text
+const (
+	PointTypeKey      = "point_type"
+	PointTypeDocument = "document"
+	PointTypeChunk    = "chunk"
+)
+
+// NewQdrantClient creates a new Qdrant client for a collection of vectors of the given size
+func NewQdrantClient(address string, collectionName string, dimensions int) (*QdrantClient, error) {
 	if address == "" {
 		return nil, fmt.Errorf("qdrant address is required")
 	}
@@ -43,6 +51,10 @@ func NewQdrantClient(address string, collectionName string) (*QdrantClient, erro
 		return nil, fmt.Errorf("collection name is required")
 	}
 
+	if dimensions <= 0 {
+		return nil, fmt.Errorf("vector dimensions must be positive, got %d", dimensions)
+	}
+
 	// Connect to Qdrant using gRPC
 	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
@@ -54,6 +66,7 @@ func NewQdrantClient(address string, collectionName string) (*QdrantClient, erro
 		collectionClient: qdrant.NewCollectionsClient(conn),
 		conn:           conn,
 		collectionName: collectionName,
+		dimensions:     dimensions,
 	}
 
 	// Ensure collection exists
@@ -65,7 +78,8 @@ func NewQdrantClient(address string, collectionName string) (*QdrantClient, erro
 	return qc, nil
 }
 
-// ensureCollection creates the collection if it doesn't exist
+// ensureCollection creates the collection if it doesn't exist and checks
+// that an existing one holds vectors of the configured size
 func (q *QdrantClient) ensureCollection(ctx context.Context) error {
 	// List collections to check if ours exists
 	listResp, err := q.collectionClient.List(ctx, &qdrant.ListCollectionsRequest{})
@@ -83,17 +97,16 @@ func (q *QdrantClient) ensureCollection(ctx context.Context) error {
 	}
 
 	if exists {
-		return nil
+		return q.checkCollectionDimensions(ctx)
 	}
 
-	// Create collection with VoyageAI embedding configuration
-	// 1024 dimensions, cosine similarity
+	// Create collection for the embedding model's dimensions, cosine similarity
 	_, err = q.collectionClient.Create(ctx, &qdrant.CreateCollection{
 		CollectionName: q.collectionName,
 		VectorsConfig: &qdrant.VectorsConfig{
 			Config: &qdrant.VectorsConfig_Params{
 				Params: &qdrant.VectorParams{
-					Size:     1024, // VoyageAI voyage-3 dimensions
+					Size:     uint64(q.dimensions),
 					Distance: qdrant.Distance_Cosine,
 				},
 			},
@@ -109,14 +122,74 @@ func (q *QdrantClient) ensureCollection(ctx context.Context) error {
 	return nil
 }
 
+// checkCollectionDimensions fails when the collection was created for a
+// different embedding size (switching models needs a new collection)
+func (q *QdrantClient) checkCollectionDimensions(ctx context.Context) error {
+	info, err := q.collectionClient.Get(ctx, &qdrant.GetCollectionInfoRequest{
+		CollectionName: q.collectionName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get collection info: %w", err)
+	}
+
+	params := info.GetResult().GetConfig().GetParams().GetVectorsConfig().GetParams()
+	if params == nil {
+		return fmt.Errorf("collection %s has no single unnamed vector", q.collectionName)
+	}
+	if size := int(params.GetSize()); size != q.dimensions {
+		return fmt.Errorf("collection %s holds %d-dimension vectors, embeddings have %d; use a new QDRANT_COLLECTION for this model",
+			q.collectionName, size, q.dimensions)
+	}
+
+	return nil
+}
+
+// Dimensions returns the vector size of the collection
+func (q *QdrantClient) Dimensions() int {
+	return q.dimensions
+}
+
 // UpsertVector stores or updates a vector point in Qdrant
 func (q *QdrantClient) UpsertVector(ctx context.Context, point *VectorPoint) error {
+	return q.UpsertVectors(ctx, []*VectorPoint{point})
+}
+
+// upsertBatchSize bounds the points sent in one upsert request
+const upsertBatchSize = 64
+
+// UpsertVectors stores or updates vector points in Qdrant, in batches
+func (q *QdrantClient) UpsertVectors(ctx context.Context, points []*VectorPoint) error {
+	pointStructs := make([]*qdrant.PointStruct, 0, len(points))
+	for _, point := range points {
+		pointStruct, err := q.toPointStruct(point)
+		if err != nil {
+			return err
+		}
+		pointStructs = append(pointStructs, pointStruct)
+	}
+
+	for start := 0; start < len(pointStructs); start += upsertBatchSize {
+		end := min(start+upsertBatchSize, len(pointStructs))
+		_, err := q.client.Upsert(ctx, &qdrant.UpsertPoints{
+			CollectionName: q.collectionName,
+			Points:         pointStructs[start:end],
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upsert vector: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// toPointStruct validates a vector point and converts it for Qdrant
+func (q *QdrantClient) toPointStruct(point *VectorPoint) (*qdrant.PointStruct, error) {
 	if point == nil {
-		return fmt.Errorf("point is required")
+		return nil, fmt.Errorf("point is required")
 	}
 
-	if len(point.Vector) != 1024 {
-		return fmt.Errorf("invalid vector dimensions: expected 1024, got %d", len(point.Vector))
+	if len(point.Vector) != q.dimensions {
+		return nil, fmt.Errorf("invalid vector dimensions: expected %d, got %d", q.dimensions, len(point.Vector))
 	}
 
 	// Generate UUID if not provided
@@ -160,8 +233,7 @@ func (q *QdrantClient) UpsertVector(ctx context.Context, point *VectorPoint) err
 		}
 	}
 
-	// Upsert point
-	pointStruct := &qdrant.PointStruct{
+	return &qdrant.PointStruct{
 		Id: &qdrant.PointId{
 			PointIdOptions: &qdrant.PointId_Uuid{
 				Uuid: point.ID,
@@ -175,24 +247,13 @@ func (q *QdrantClient) UpsertVector(ctx context.Context, point *VectorPoint) err
 			},
 		},
 		Payload: payload,
-	}
-
-	_, err := q.client.Upsert(ctx, &qdrant.UpsertPoints{
-		CollectionName: q.collectionName,
-		Points:         []*qdrant.PointStruct{pointStruct},
-	})
-
-	if err != nil {
-		return fmt.Errorf("failed to upsert vector: %w", err)
-	}
-
-	return nil
+	}, nil
 }
 
 // SearchVectors performs similarity search
 func (q *QdrantClient) SearchVectors(ctx context.Context, queryVector []float32, limit int) ([]*VectorPoint, error) {
-	if len(queryVector) != 1024 {
-		return nil, fmt.Errorf("invalid query vector dimensions: expected 1024, got %d", len(queryVector))
+	if len(queryVector) != q.dimensions {
+		return nil, fmt.Errorf("invalid query vector dimensions: expected %d, got %d", q.dimensions, len(queryVector))
 	}
 
 	if limit <= 0 {
@@ -204,6 +265,7 @@ func (q *QdrantClient) SearchVectors(ctx context.Context, queryVector []float32,
 		CollectionName: q.collectionName,
 		Vector:         queryVector,
 		Limit:          uint64(limit),
+		Filter:         documentPointsOnly(),
 		WithPayload:    &qdrant.WithPayloadSelector{
 			SelectorOptions: &qdrant.WithPayloadSelector_Enable{
 				Enable: true,
@@ -257,6 +319,25 @@ func (q *QdrantClient) SearchVectors(ctx context.Context, queryVector []float32,
 	return points, nil
 }
 
+// documentPointsOnly excludes chunk points, which share the collection
+// with the document points
+func documentPointsOnly() *qdrant.Filter {
+	return &qdrant.Filter{
+		MustNot: []*qdrant.Condition{
+			{
+				ConditionOneOf: &qdrant.Condition_Field{
+					Field: &qdrant.FieldCondition{
+						Key: PointTypeKey,
+						Match: &qdrant.Match{
+							MatchValue: &qdrant.Match_Keyword{Keyword: PointTypeChunk},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 // GetVector retrieves a vector by ID
 func (q *QdrantClient) GetVector(ctx context.Context, pointID string) (*VectorPoint, error) {
 	if pointID == "" {
@@ -333,20 +414,31 @@ func (q *QdrantClient) DeleteVector(ctx context.Context, pointID string) error {
 	if pointID == "" {
 		return fmt.Errorf("point ID is required")
 	}
+	return q.DeleteVectors(ctx, []string{pointID})
+}
+
+// DeleteVectors removes vectors by ID
+func (q *QdrantClient) DeleteVectors(ctx context.Context, pointIDs []string) error {
+	if len(pointIDs) == 0 {
+		return nil
+	}
 
-	// Delete point
+	ids := make([]*qdrant.PointId, 0, len(pointIDs))
+	for _, pointID := range pointIDs {
+		ids = append(ids, &qdrant.PointId{
+			PointIdOptions: &qdrant.PointId_Uuid{
+				Uuid: pointID,
+			},
+		})
+	}
+
+	// Delete points
 	deleteReq := &qdrant.DeletePoints{
 		CollectionName: q.collectionName,
 		Points: &qdrant.PointsSelector{
 			PointsSelectorOneOf: &qdrant.PointsSelector_Points{
 				Points: &qdrant.PointsIdsList{
-					Ids: []*qdrant.PointId{
-						{
-							PointIdOptions: &qdrant.PointId_Uuid{
-								Uuid: pointID,
-							},
-						},
-					},
+					Ids: ids,
 				},
 			},
 		},
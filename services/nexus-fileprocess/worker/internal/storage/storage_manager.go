@@ -30,6 +30,20 @@ type DocumentDNAInput struct {
 	SemanticEmbedding []float32
 	StructuralData    map[string]interface{}
 	OriginalContent   []byte
+	ContentHash       string           // SHA-256 of OriginalContent, used to deduplicate re-uploads
+	PipelineVersion   string           // Fingerprint of the pipeline that produced this DNA
+	Chunks            []ChunkEmbedding // Stored as chunk points next to the document point
+}
+
+// ChunkEmbedding is the embedding of a span of the document text
+type ChunkEmbedding struct {
+	Index      int
+	Vector     []float32
+	Text       string
+	PageNumber int // 0 when unknown
+	RegionID   int // Layout region the chunk starts in, -1 when none
+	StartChar  int // Byte offsets of Text in the document text
+	EndChar    int
 }
 
 // DocumentDNAOutput represents stored document DNA with all IDs
@@ -41,8 +55,9 @@ type DocumentDNAOutput struct {
 	CreatedAt     time.Time
 }
 
-// NewStorageManager creates a new storage manager
-func NewStorageManager(postgresURL string, qdrantAddress string, qdrantCollection string) (*StorageManager, error) {
+// NewStorageManager creates a new storage manager. embeddingDimensions is the
+// vector size of the Qdrant collection and of every embedding stored in it.
+func NewStorageManager(postgresURL string, qdrantAddress string, qdrantCollection string, embeddingDimensions int) (*StorageManager, error) {
 	// Initialize PostgreSQL client
 	postgres, err := NewPostgresClient(postgresURL)
 	if err != nil {
@@ -50,7 +65,7 @@ func NewStorageManager(postgresURL string, qdrantAddress string, qdrantCollectio
 	}
 
 	// Initialize Qdrant client
-	qdrant, err := NewQdrantClient(qdrantAddress, qdrantCollection)
+	qdrant, err := NewQdrantClient(qdrantAddress, qdrantCollection, embeddingDimensions)
 	if err != nil {
 		postgres.Close() // Cleanup on failure
 		return nil, fmt.Errorf("failed to initialize Qdrant client: %w", err)
@@ -62,6 +77,11 @@ func NewStorageManager(postgresURL string, qdrantAddress string, qdrantCollectio
 	}, nil
 }
 
+// EmbeddingDimensions returns the vector size every stored embedding must have
+func (sm *StorageManager) EmbeddingDimensions() int {
+	return sm.qdrant.Dimensions()
+}
+
 // StoreDocumentDNA atomically stores document DNA across PostgreSQL and Qdrant
 func (sm *StorageManager) StoreDocumentDNA(ctx context.Context, input *DocumentDNAInput) (*DocumentDNAOutput, error) {
 	if input == nil {
@@ -72,36 +92,69 @@ func (sm *StorageManager) StoreDocumentDNA(ctx context.Context, input *DocumentD
 		return nil, fmt.Errorf("job ID is required")
 	}
 
-	if len(input.SemanticEmbedding) != 1024 {
-		return nil, fmt.Errorf("invalid embedding dimensions: expected 1024, got %d", len(input.SemanticEmbedding))
+	if len(input.SemanticEmbedding) != sm.qdrant.Dimensions() {
+		return nil, fmt.Errorf("invalid embedding dimensions: expected %d, got %d", sm.qdrant.Dimensions(), len(input.SemanticEmbedding))
+	}
+	for _, chunk := range input.Chunks {
+		if len(chunk.Vector) != sm.qdrant.Dimensions() {
+			return nil, fmt.Errorf("invalid embedding dimensions for chunk %d: expected %d, got %d", chunk.Index, sm.qdrant.Dimensions(), len(chunk.Vector))
+		}
 	}
 
 	// Step 1: Generate UUIDs for both systems
 	dnaID := uuid.New().String()
 	qdrantPointID := uuid.New().String()
 
-	// Step 2: Store vector in Qdrant first (fails fast if vector invalid)
-	qdrantPoint := &VectorPoint{
+	// Step 2: Store vectors in Qdrant first (fails fast if vector invalid)
+	now := time.Now().Unix()
+	points := make([]*VectorPoint, 0, 1+len(input.Chunks))
+	points = append(points, &VectorPoint{
 		ID:     qdrantPointID,
 		Vector: input.SemanticEmbedding,
 		Metadata: map[string]interface{}{
 			"job_id":     input.JobID,
 			"dna_id":     dnaID,
-			"created_at": time.Now().Unix(),
+			PointTypeKey: PointTypeDocument,
+			"created_at": now,
 		},
-		Timestamp: time.Now().Unix(),
+		Timestamp: now,
+	})
+	for _, chunk := range input.Chunks {
+		points = append(points, &VectorPoint{
+			ID:     uuid.New().String(),
+			Vector: chunk.Vector,
+			Metadata: map[string]interface{}{
+				"job_id":      input.JobID,
+				"dna_id":      dnaID,
+				PointTypeKey:  PointTypeChunk,
+				"chunk_index": int64(chunk.Index),
+				"page_number": int64(chunk.PageNumber),
+				"region_id":   int64(chunk.RegionID),
+				"start_char":  int64(chunk.StartChar),
+				"end_char":    int64(chunk.EndChar),
+				"text":        chunk.Text,
+				"created_at":  now,
+			},
+			Timestamp: now,
+		})
+	}
+	pointIDs := make([]string, len(points))
+	for i, point := range points {
+		pointIDs[i] = point.ID
 	}
 
-	if err := sm.qdrant.UpsertVector(ctx, qdrantPoint); err != nil {
-		return nil, fmt.Errorf("failed to store vector in Qdrant: %w", err)
+	if err := sm.qdrant.UpsertVectors(ctx, points); err != nil {
+		// Rollback: Delete any batch already stored
+		sm.qdrant.DeleteVectors(ctx, pointIDs)
+		return nil, fmt.Errorf("failed to store vectors in Qdrant: %w", err)
 	}
 
 	// Step 3: Store metadata in PostgreSQL
 	// Convert StructuralData to JSONB
 	structuralJSON, err := json.Marshal(input.StructuralData)
 	if err != nil {
-		// Rollback: Delete Qdrant point
-		sm.qdrant.DeleteVector(ctx, qdrantPointID)
+		// Rollback: Delete Qdrant points
+		sm.qdrant.DeleteVectors(ctx, pointIDs)
 		return nil, fmt.Errorf("failed to marshal structural data: %w", err)
 	}
 
@@ -118,8 +171,10 @@ func (sm *StorageManager) StoreDocumentDNA(ctx context.Context, input *DocumentD
 			structural_data,
 			original_content,
 			embedding_dimensions,
+			content_hash,
+			pipeline_version,
 			created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), NOW())
 		RETURNING created_at
 	`
 
@@ -132,12 +187,14 @@ func (sm *StorageManager) StoreDocumentDNA(ctx context.Context, input *DocumentD
 		qdrantPointID,
 		structuralJSON,
 		input.OriginalContent,
-		1024,
+		len(input.SemanticEmbedding),
+		input.ContentHash,
+		input.PipelineVersion,
 	).Scan(&createdAt)
 
 	if err != nil {
-		// Rollback: Delete Qdrant point
-		sm.qdrant.DeleteVector(ctx, qdrantPointID)
+		// Rollback: Delete Qdrant points
+		sm.qdrant.DeleteVectors(ctx, pointIDs)
 		return nil, fmt.Errorf("failed to store metadata in PostgreSQL: %w", err)
 	}
 
@@ -151,6 +208,67 @@ func (sm *StorageManager) StoreDocumentDNA(ctx context.Context, input *DocumentD
 	}, nil
 }
 
+// FindDocumentDNAByJob returns the document DNA already stored for a job, or nil if there is none.
+// A redelivered job whose DNA was stored before the worker stopped can be completed from it.
+func (sm *StorageManager) FindDocumentDNAByJob(ctx context.Context, jobID string) (*DocumentDNAOutput, error) {
+	if jobID == "" {
+		return nil, fmt.Errorf("job ID is required")
+	}
+
+	query := `
+		SELECT id, job_id, qdrant_point_id, structural_data, created_at
+		FROM fileprocess.document_dna
+		WHERE job_id = $1::uuid
+	`
+
+	return sm.scanDocumentDNA(sm.postgres.db.QueryRowContext(ctx, query, jobID))
+}
+
+// FindDocumentDNAByContent returns the newest document DNA of a completed job with the same
+// content hash and pipeline version, or nil if there is none. A non-empty userID restricts
+// the match to that user's jobs.
+func (sm *StorageManager) FindDocumentDNAByContent(ctx context.Context, contentHash, pipelineVersion, userID string) (*DocumentDNAOutput, error) {
+	if contentHash == "" || pipelineVersion == "" {
+		return nil, fmt.Errorf("content hash and pipeline version are required")
+	}
+
+	query := `
+		SELECT d.id, d.job_id, d.qdrant_point_id, d.structural_data, d.created_at
+		FROM fileprocess.document_dna d
+		JOIN fileprocess.processing_jobs j ON j.id = d.job_id
+		WHERE d.content_hash = $1
+			AND d.pipeline_version = $2
+			AND j.status = 'completed'
+			AND ($3 = '' OR j.user_id = $3)
+		ORDER BY d.created_at DESC
+		LIMIT 1
+	`
+
+	return sm.scanDocumentDNA(sm.postgres.db.QueryRowContext(ctx, query, contentHash, pipelineVersion, userID))
+}
+
+// scanDocumentDNA reads a document DNA lookup row, returning nil when nothing matched
+func (sm *StorageManager) scanDocumentDNA(row *sql.Row) (*DocumentDNAOutput, error) {
+	var (
+		dna            DocumentDNAOutput
+		structuralJSON []byte
+	)
+
+	err := row.Scan(&dna.ID, &dna.JobID, &dna.QdrantPointID, &structuralJSON, &dna.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up document DNA: %w", err)
+	}
+
+	if err := json.Unmarshal(structuralJSON, &dna.StructuralData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal structural data: %w", err)
+	}
+
+	return &dna, nil
+}
+
 // GetDocumentDNA retrieves document DNA with vector from both systems
 func (sm *StorageManager) GetDocumentDNA(ctx context.Context, dnaID string) (*DocumentDNAFull, error) {
 	if dnaID == "" {
@@ -218,8 +336,8 @@ func (sm *StorageManager) GetDocumentDNA(ctx context.Context, dnaID string) (*Do
 
 // SearchSimilarDocuments performs semantic search across documents
 func (sm *StorageManager) SearchSimilarDocuments(ctx context.Context, queryVector []float32, limit int) ([]*DocumentDNASearchResult, error) {
-	if len(queryVector) != 1024 {
-		return nil, fmt.Errorf("invalid query vector dimensions: expected 1024, got %d", len(queryVector))
+	if len(queryVector) != sm.qdrant.Dimensions() {
+		return nil, fmt.Errorf("invalid query vector dimensions: expected %d, got %d", sm.qdrant.Dimensions(), len(queryVector))
 	}
 
 	// Search Qdrant for similar vectors
@@ -289,6 +407,11 @@ func (sm *StorageManager) UpdateJobStatus(ctx context.Context, update *JobUpdate
 	return sm.postgres.UpdateJobStatus(ctx, update)
 }
 
+// UpdateJobProgress records job progress in PostgreSQL
+func (sm *StorageManager) UpdateJobProgress(ctx context.Context, progress *JobProgress) error {
+	return sm.postgres.UpdateJobProgress(ctx, progress)
+}
+
 // GetJobByID retrieves job by ID
 func (sm *StorageManager) GetJobByID(ctx context.Context, jobID string) (map[string]interface{}, error) {
 	return sm.postgres.GetJobByID(ctx, jobID)
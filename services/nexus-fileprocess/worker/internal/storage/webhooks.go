@@ -0,0 +1,193 @@
+/**
+ * Webhook Delivery Storage
+ *
+ * PostgreSQL side of completion webhooks: the per-user registry, the durable
+ * delivery queue (fileprocess.webhook_deliveries) and the attempt audit log.
+ * Deliveries are claimed with FOR UPDATE SKIP LOCKED so several workers can
+ * dispatch from the same table without sending an event twice.
+ */
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookDelivery is a claimed delivery ready to be sent
+type WebhookDelivery struct {
+	ID        string
+	WebhookID string
+	JobID     string
+	Event     string
+	URL       string
+	Payload   json.RawMessage
+	Secret    string // Registry secret; empty for metadata callbacks
+	Attempts  int    // Attempts made before this one
+}
+
+// WebhookAttempt is the outcome of one delivery attempt
+type WebhookAttempt struct {
+	DeliveryID   string
+	Attempt      int
+	StatusCode   int // 0 when no response was received
+	Error        string
+	ResponseBody string
+	Duration     time.Duration
+	Delivered    bool
+	NextAttempt  *time.Time // nil once the delivery is delivered or given up
+}
+
+// EnqueueWebhookDeliveries stores one delivery for the job's metadata callback URL (if any)
+// plus one per active registry webhook of the user subscribed to the event. buildPayload
+// renders the body for a delivery ID. Returns the number of deliveries enqueued.
+func (sm *StorageManager) EnqueueWebhookDeliveries(ctx context.Context, jobID, userID, event, callbackURL string, buildPayload func(deliveryID string) (json.RawMessage, error)) (int, error) {
+	tx, err := sm.postgres.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin webhook transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	type target struct {
+		webhookID string
+		url       string
+	}
+	targets := make([]target, 0, 1)
+	if callbackURL != "" {
+		targets = append(targets, target{url: callbackURL})
+	}
+
+	if userID != "" {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, url
+			FROM fileprocess.webhooks
+			WHERE user_id = $1 AND active AND $2 = ANY(events)
+		`, userID, event)
+		if err != nil {
+			return 0, fmt.Errorf("failed to look up webhooks: %w", err)
+		}
+		for rows.Next() {
+			var t target
+			if err := rows.Scan(&t.webhookID, &t.url); err != nil {
+				rows.Close()
+				return 0, fmt.Errorf("failed to read webhook: %w", err)
+			}
+			targets = append(targets, t)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, fmt.Errorf("failed to read webhooks: %w", err)
+		}
+	}
+
+	for _, t := range targets {
+		deliveryID := uuid.New().String()
+		payload, err := buildPayload(deliveryID)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO fileprocess.webhook_deliveries (id, webhook_id, job_id, event, url, payload)
+			VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5, $6::jsonb)
+		`, deliveryID, t.webhookID, jobID, event, t.url, sanitizeJSONForPostgres(payload)); err != nil {
+			return 0, fmt.Errorf("failed to enqueue webhook delivery: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit webhook deliveries: %w", err)
+	}
+	return len(targets), nil
+}
+
+// ClaimWebhookDeliveries locks up to limit due deliveries for lease, so no other worker
+// sends them until the lease expires or the attempt is recorded
+func (sm *StorageManager) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*WebhookDelivery, error) {
+	query := `
+		UPDATE fileprocess.webhook_deliveries d
+		SET locked_until = NOW() + ($2 * INTERVAL '1 millisecond')
+		WHERE d.id IN (
+			SELECT id
+			FROM fileprocess.webhook_deliveries
+			WHERE status = 'pending'
+				AND next_attempt_at <= NOW()
+				AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, COALESCE(d.webhook_id::text, ''), d.job_id, d.event, d.url, d.payload, d.attempts,
+			COALESCE((SELECT w.secret FROM fileprocess.webhooks w WHERE w.id = d.webhook_id), '')
+	`
+
+	rows, err := sm.postgres.db.QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*WebhookDelivery, 0)
+	for rows.Next() {
+		var d WebhookDelivery
+		var payload []byte
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.JobID, &d.Event, &d.URL, &payload, &d.Attempts, &d.Secret); err != nil {
+			return nil, fmt.Errorf("failed to read webhook delivery: %w", err)
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, &d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// RecordWebhookAttempt logs an attempt and moves the delivery to delivered, pending
+// (retry at NextAttempt) or failed, releasing its claim
+func (sm *StorageManager) RecordWebhookAttempt(ctx context.Context, attempt *WebhookAttempt) error {
+	tx, err := sm.postgres.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin webhook transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO fileprocess.webhook_delivery_attempts (
+			delivery_id, attempt, status_code, error, response_body, duration_ms
+		) VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, ''), NULLIF($5, ''), $6)
+	`, attempt.DeliveryID, attempt.Attempt, attempt.StatusCode, attempt.Error, attempt.ResponseBody,
+		attempt.Duration.Milliseconds()); err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+
+	status := "failed"
+	var nextAttempt interface{}
+	switch {
+	case attempt.Delivered:
+		status = "delivered"
+	case attempt.NextAttempt != nil:
+		status = "pending"
+		nextAttempt = *attempt.NextAttempt
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE fileprocess.webhook_deliveries
+		SET status = $2,
+			attempts = $3,
+			next_attempt_at = COALESCE($4, next_attempt_at),
+			locked_until = NULL,
+			last_error = NULLIF($5, ''),
+			delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() ELSE NULL END
+		WHERE id = $1
+	`, attempt.DeliveryID, status, attempt.Attempt, nextAttempt, attempt.Error); err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit webhook attempt: %w", err)
+	}
+	return nil
+}
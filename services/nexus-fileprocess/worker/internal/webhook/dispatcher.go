@@ -0,0 +1,304 @@
+/**
+ * Webhook Dispatcher
+ *
+ * Finished jobs are written to fileprocess.webhook_deliveries and sent by a
+ * polling loop, so deliveries survive restarts and endpoint outages. Failed
+ * attempts are retried with exponential backoff until WEBHOOK_MAX_ATTEMPTS;
+ * every attempt is recorded in fileprocess.webhook_delivery_attempts.
+ * Deliveries only reach public addresses unless a network is allowed (guard.go).
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
+	"github.com/adverant/nexus/fileprocess-worker/internal/storage"
+)
+
+const (
+	defaultMaxAttempts  = 8
+	defaultPollInterval = 2 * time.Second
+	defaultTimeout      = 10 * time.Second
+	defaultBatchSize    = 20
+	maxResponseBody     = 1024 // Bytes of the response kept for the audit log
+	userAgent           = "Nexus-FileProcess-Webhooks/" + PayloadVersion
+)
+
+// Store persists deliveries and their attempts (implemented by storage.StorageManager)
+type Store interface {
+	EnqueueWebhookDeliveries(ctx context.Context, jobID, userID, event, callbackURL string, buildPayload func(deliveryID string) (json.RawMessage, error)) (int, error)
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*storage.WebhookDelivery, error)
+	RecordWebhookAttempt(ctx context.Context, attempt *storage.WebhookAttempt) error
+}
+
+// Config configures the dispatcher
+type Config struct {
+	SigningSecret string             // Signs metadata.callbackUrl deliveries; callbacks are skipped without it
+	MaxAttempts   int                // Attempts per delivery before it is marked failed (default: 8)
+	RetryPolicy   *queue.RetryPolicy // Backoff between attempts (default: 30s doubling up to 1h)
+	PollInterval  time.Duration      // How often due deliveries are looked up (default: 2s)
+	Timeout       time.Duration      // Per-request timeout (default: 10s)
+	BatchSize     int                // Deliveries claimed per poll (default: 20)
+
+	AllowedNetworks []string // CIDRs deliveries may reach although they are internal (default: none)
+}
+
+// Dispatcher records finished jobs as webhook deliveries and sends them
+type Dispatcher struct {
+	store  Store
+	config *Config
+	client *http.Client
+	wake   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher creates a webhook dispatcher
+func NewDispatcher(store Store, cfg *Config) (*Dispatcher, error) {
+	if store == nil {
+		return nil, fmt.Errorf("webhook store is required")
+	}
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.RetryPolicy == nil {
+		cfg.RetryPolicy = &queue.RetryPolicy{
+			BaseDelay: 30 * time.Second,
+			MaxDelay:  time.Hour,
+			Jitter:    0.2,
+		}
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+
+	guard, err := newEgressGuard(cfg.AllowedNetworks)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		store:  store,
+		config: cfg,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: guard.transport(),
+			// A redirect is reported as a failed attempt rather than followed
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		wake:   make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// JobFinished implements queue.JobNotifier by enqueueing a delivery per target
+func (d *Dispatcher) JobFinished(ctx context.Context, outcome *queue.JobOutcome) error {
+	callbackURL := d.callbackURL(outcome)
+	eventType := EventType(outcome.Status)
+
+	count, err := d.store.EnqueueWebhookDeliveries(ctx, outcome.JobID, outcome.UserID, eventType, callbackURL,
+		func(deliveryID string) (json.RawMessage, error) {
+			return marshalEvent(NewEvent(deliveryID, outcome))
+		})
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		log.Printf("[Webhook] Enqueued %d %s deliveries for job %s", count, eventType, outcome.JobID)
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// callbackURL returns the job's metadata.callbackUrl if it can be delivered
+func (d *Dispatcher) callbackURL(outcome *queue.JobOutcome) string {
+	raw, _ := outcome.Metadata["callbackUrl"].(string)
+	if raw == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		log.Printf("[Webhook] Ignoring invalid callbackUrl for job %s: %q", outcome.JobID, raw)
+		return ""
+	}
+
+	if d.config.SigningSecret == "" {
+		log.Printf("[Webhook] Ignoring callbackUrl for job %s: WEBHOOK_SIGNING_SECRET is not set", outcome.JobID)
+		return ""
+	}
+	return raw
+}
+
+// Start starts the delivery loop
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	go d.loop()
+	log.Printf("[Webhook] Dispatcher started (poll=%v, maxAttempts=%d)", d.config.PollInterval, d.config.MaxAttempts)
+}
+
+// Stop stops the delivery loop and waits for in-flight requests
+func (d *Dispatcher) Stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) loop() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+
+		d.dispatchDue()
+	}
+}
+
+// dispatchDue sends due deliveries until none are left
+func (d *Dispatcher) dispatchDue() {
+	// The lease outlives a request so a claim never expires mid-attempt
+	lease := d.config.Timeout + 30*time.Second
+
+	for d.ctx.Err() == nil {
+		deliveries, err := d.store.ClaimWebhookDeliveries(d.ctx, d.config.BatchSize, lease)
+		if err != nil {
+			log.Printf("[Webhook] Warning: %v", err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			go func(delivery *storage.WebhookDelivery) {
+				defer wg.Done()
+				d.deliver(delivery)
+			}(delivery)
+		}
+		wg.Wait()
+
+		if len(deliveries) < d.config.BatchSize {
+			return
+		}
+	}
+}
+
+// deliver makes one attempt and records its outcome
+func (d *Dispatcher) deliver(delivery *storage.WebhookDelivery) {
+	attempt := &storage.WebhookAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts + 1,
+	}
+
+	secret := delivery.Secret
+	if secret == "" {
+		secret = d.config.SigningSecret
+	}
+
+	start := time.Now()
+	retryable := true
+	if secret == "" {
+		attempt.Error = "no signing secret configured"
+		retryable = false
+	} else {
+		attempt.StatusCode, attempt.ResponseBody, attempt.Error, retryable = d.post(delivery, secret)
+		attempt.Delivered = attempt.Error == ""
+	}
+	attempt.Duration = time.Since(start)
+
+	if !attempt.Delivered && retryable && attempt.Attempt < d.config.MaxAttempts {
+		next := time.Now().Add(d.config.RetryPolicy.Delay(attempt.Attempt))
+		attempt.NextAttempt = &next
+	}
+
+	// Record even if the dispatcher is stopping; the attempt already happened
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := d.store.RecordWebhookAttempt(ctx, attempt); err != nil {
+		log.Printf("[Webhook] Warning: Failed to record attempt %d of delivery %s: %v", attempt.Attempt, delivery.ID, err)
+	}
+
+	switch {
+	case attempt.Delivered:
+		log.Printf("[Webhook] Delivered %s for job %s to %s (attempt %d)", delivery.Event, delivery.JobID, delivery.URL, attempt.Attempt)
+	case attempt.NextAttempt != nil:
+		log.Printf("[Webhook] Attempt %d of %s for job %s failed, retrying at %s: %s",
+			attempt.Attempt, delivery.Event, delivery.JobID, attempt.NextAttempt.Format(time.RFC3339), attempt.Error)
+	default:
+		log.Printf("[Webhook] Giving up on %s for job %s to %s after %d attempts: %s",
+			delivery.Event, delivery.JobID, delivery.URL, attempt.Attempt, attempt.Error)
+	}
+}
+
+// post sends a signed delivery. errMsg is empty when the receiver answered 2xx;
+// retryable is false when another attempt cannot succeed.
+func (d *Dispatcher) post(delivery *storage.WebhookDelivery, secret string) (statusCode int, responseBody, errMsg string, retryable bool) {
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", fmt.Sprintf("failed to create request: %v", err), false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderVersion, PayloadVersion)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		// Internal addresses stay blocked, however often the delivery is retried
+		return 0, "", err.Error(), !errors.Is(err, errBlockedAddress)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	responseBody = strings.ReplaceAll(strings.ToValidUTF8(strings.TrimSpace(string(body)), ""), "\x00", "")
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// 410 Gone: the receiver asks us to stop
+		return resp.StatusCode, responseBody, fmt.Sprintf("HTTP %d", resp.StatusCode), resp.StatusCode != http.StatusGone
+	}
+	return resp.StatusCode, responseBody, "", true
+}
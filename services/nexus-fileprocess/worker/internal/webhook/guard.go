@@ -0,0 +1,106 @@
+/**
+ * Webhook Egress Guard
+ *
+ * Webhook URLs come from users (metadata.callbackUrl and registered webhooks),
+ * so deliveries must not reach the worker's own network. Every connection is
+ * checked at dial time, after DNS resolution, which also covers hostnames that
+ * resolve to internal addresses:
+ * - loopback, private (RFC 1918, fc00::/7), link-local (including the cloud
+ *   metadata endpoint 169.254.169.254), shared/CGNAT 100.64.0.0/10 (common for
+ *   cluster networks), unspecified and multicast addresses are refused
+ * - WEBHOOK_ALLOWED_NETWORKS lists CIDRs that may be reached anyway
+ */
+
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errBlockedAddress marks a delivery refused because its host resolves to an internal address
+var errBlockedAddress = errors.New("webhook address is not publicly routable")
+
+// blockedPrefixes are refused on top of the ranges net/netip classifies
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "This network"
+	netip.MustParsePrefix("100.64.0.0/10"), // Shared address space (CGNAT, cluster networks)
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+}
+
+// egressGuard decides which addresses deliveries may connect to
+type egressGuard struct {
+	allowed []netip.Prefix
+}
+
+// newEgressGuard parses the CIDRs deliveries may reach despite being internal
+func newEgressGuard(allowedNetworks []string) (*egressGuard, error) {
+	guard := &egressGuard{}
+	for _, network := range allowedNetworks {
+		network = strings.TrimSpace(network)
+		if network == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed webhook network %q: %w", network, err)
+		}
+		guard.allowed = append(guard.allowed, prefix.Masked())
+	}
+	return guard, nil
+}
+
+// permits reports whether a connection to addr is allowed
+func (g *egressGuard) permits(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range g.allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// control is a net.Dialer Control function refusing connections to blocked addresses
+func (g *egressGuard) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errBlockedAddress, address)
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !g.permits(addr) {
+		return fmt.Errorf("%w: %s", errBlockedAddress, host)
+	}
+	return nil
+}
+
+// transport is an HTTP transport that dials through the guard. Proxies are not
+// used, as they would make the connection on the guard's behalf.
+func (g *egressGuard) transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   g.control,
+	}).DialContext
+	return transport
+}
@@ -0,0 +1,182 @@
+/**
+ * Completion Webhooks
+ *
+ * When a job completes or finally fails, a versioned JSON event is POSTed to
+ * the job's metadata.callbackUrl and to every matching webhook the user has
+ * registered in fileprocess.webhooks.
+ *
+ * Every request is signed so receivers can verify it came from this service:
+ *
+ *   X-FileProcess-Timestamp: <unix seconds>
+ *   X-FileProcess-Signature: sha256=<hex HMAC-SHA256(secret, "<timestamp>.<body>")>
+ *
+ * Registry webhooks are signed with their own secret, metadata callbacks with
+ * WEBHOOK_SIGNING_SECRET. Receivers should reject stale timestamps to prevent
+ * replays, and dedupe on X-FileProcess-Delivery since delivery is at-least-once.
+ */
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
+)
+
+// PayloadVersion is bumped on breaking changes to the event body
+const PayloadVersion = "1"
+
+// Event types
+const (
+	EventJobCompleted = "job.completed"
+	EventJobFailed    = "job.failed"
+)
+
+// Request headers
+const (
+	HeaderEvent     = "X-FileProcess-Event"
+	HeaderDelivery  = "X-FileProcess-Delivery"
+	HeaderTimestamp = "X-FileProcess-Timestamp"
+	HeaderSignature = "X-FileProcess-Signature"
+	HeaderVersion   = "X-FileProcess-Webhook-Version"
+
+	signaturePrefix = "sha256="
+)
+
+// Event is the body of a webhook request
+type Event struct {
+	Version   string    `json:"version"`
+	ID        string    `json:"id"` // Delivery ID, also sent as X-FileProcess-Delivery
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      EventData `json:"data"`
+}
+
+// EventData describes the finished job
+type EventData struct {
+	JobID         string                 `json:"jobId"`
+	UserID        string                 `json:"userId,omitempty"`
+	BatchID       string                 `json:"batchId,omitempty"`
+	Filename      string                 `json:"filename,omitempty"`
+	MimeType      string                 `json:"mimeType,omitempty"`
+	Status        string                 `json:"status"`
+	DocumentDNAID string                 `json:"documentDnaId,omitempty"`
+	Result        *EventResult           `json:"result,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+	Attempts      int                    `json:"attempts,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	FinishedAt    time.Time              `json:"finishedAt"`
+}
+
+// EventResult is the processing result of a completed job
+type EventResult struct {
+	Confidence         float64  `json:"confidence"`
+	OCRTierUsed        string   `json:"ocrTierUsed"`
+	TablesExtracted    int      `json:"tablesExtracted"`
+	RegionsExtracted   int      `json:"regionsExtracted"`
+	EmbeddingGenerated bool     `json:"embeddingGenerated"`
+	ProcessingTimeMs   int64    `json:"processingTimeMs"`
+	PageCount          int      `json:"pageCount"`
+	ContentHash        string   `json:"contentHash,omitempty"`
+	ReusedFromJobID    string   `json:"reusedFromJobId,omitempty"`
+	ChildJobIDs        []string `json:"childJobIds,omitempty"` // Jobs created from the entries of an archive
+}
+
+// EventType maps a final job status to its event type
+func EventType(status string) string {
+	if status == queue.JobStatusCompleted {
+		return EventJobCompleted
+	}
+	return EventJobFailed
+}
+
+// NewEvent builds the event for a finished job
+func NewEvent(deliveryID string, outcome *queue.JobOutcome) *Event {
+	data := EventData{
+		JobID:      outcome.JobID,
+		UserID:     outcome.UserID,
+		BatchID:    outcome.BatchID,
+		Filename:   outcome.Filename,
+		MimeType:   outcome.MimeType,
+		Status:     outcome.Status,
+		Error:      outcome.Error,
+		Attempts:   outcome.Attempts,
+		Metadata:   outcome.Metadata,
+		FinishedAt: outcome.FinishedAt.UTC(),
+	}
+
+	if result := outcome.Result; result != nil {
+		data.DocumentDNAID = result.DocumentDNAID
+		data.Result = &EventResult{
+			Confidence:         result.Confidence,
+			OCRTierUsed:        result.OCRTierUsed,
+			TablesExtracted:    result.TablesExtracted,
+			RegionsExtracted:   result.RegionsExtracted,
+			EmbeddingGenerated: result.EmbeddingGenerated,
+			ProcessingTimeMs:   result.ProcessingTimeMs,
+			PageCount:          result.PageCount,
+			ContentHash:        result.ContentHash,
+			ReusedFromJobID:    result.ReusedFromJobID,
+			ChildJobIDs:        result.ChildJobIDs,
+		}
+	}
+
+	return &Event{
+		Version:   PayloadVersion,
+		ID:        deliveryID,
+		Type:      EventType(outcome.Status),
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// Sign returns the X-FileProcess-Signature value for a body sent at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature and rejects timestamps further than tolerance from now.
+// Receivers written in Go can use it directly.
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid webhook timestamp %q", timestamp)
+	}
+
+	if tolerance > 0 {
+		age := time.Since(time.Unix(ts, 0))
+		if age > tolerance || age < -tolerance {
+			return fmt.Errorf("webhook timestamp outside tolerance of %v", tolerance)
+		}
+	}
+
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return fmt.Errorf("unsupported webhook signature scheme")
+	}
+
+	expected := Sign(secret, ts, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("webhook signature mismatch")
+	}
+	return nil
+}
+
+// marshalEvent renders an event body
+func marshalEvent(event *Event) (json.RawMessage, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+	return body, nil
+}
@@ -0,0 +1,220 @@
+/**
+ * Archive Detection and Expansion Tests
+ *
+ * Validates that uploaded archives are recognized, that ZIP-based documents
+ * are left for the normal pipeline, and that zip bomb limits and unsafe entry
+ * paths are enforced during expansion.
+ */
+
+package tests
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/errors"
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+func buildZip(t *testing.T, names ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		w.Write([]byte("content of " + name))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func buildTar(t *testing.T, names ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range names {
+		body := []byte("content of " + name)
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("tar header: %v", err)
+		}
+		tw.Write(body)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestDetectArchive tests format detection from content and filename
+func TestDetectArchive(t *testing.T) {
+	tarball := buildTar(t, "a.pdf", "docs/b.png")
+
+	tests := []struct {
+		name     string
+		mimeType string
+		filename string
+		data     []byte
+		expected string
+	}{
+		{"zip", "application/zip", "scans.zip", buildZip(t, "a.pdf", "b.png"), processor.ArchiveZip},
+		{"zip without mime type", "", "upload", buildZip(t, "a.pdf"), processor.ArchiveZip},
+		{"docx is a document", "application/zip", "report.docx", buildZip(t, "[Content_Types].xml", "word/document.xml"), ""},
+		{"odt is a document", "application/zip", "report.odt", buildZip(t, "mimetype", "content.xml"), ""},
+		{"tar", "application/x-tar", "scans.tar", tarball, processor.ArchiveTar},
+		{"tar.gz by content", "application/gzip", "scans.bin", gzipBytes(t, tarball), processor.ArchiveTarGz},
+		{"tgz by name", "application/gzip", "scans.tgz", gzipBytes(t, []byte("not a tar")), processor.ArchiveTarGz},
+		{"single gzip file", "application/gzip", "report.pdf.gz", gzipBytes(t, []byte("%PDF-1.4")), processor.ArchiveGzip},
+		{"pdf", "application/pdf", "report.pdf", []byte("%PDF-1.4 ..."), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := processor.DetectArchive(tt.mimeType, tt.filename, tt.data); got != tt.expected {
+				t.Errorf("DetectArchive() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// buildZipEntries builds a ZIP archive from name/content pairs
+func buildZipEntries(t *testing.T, entries map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		w.Write(body)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestExpandArchiveLimits tests that zip bombs are rejected permanently with ARCHIVE_REJECTED
+func TestExpandArchiveLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		format string
+		limits processor.ArchiveLimits
+		depth  int
+	}{
+		{"too many entries", buildZip(t, "a.pdf", "b.pdf", "c.pdf"), processor.ArchiveZip,
+			processor.ArchiveLimits{MaxEntries: 2}, 0},
+		{"total size", buildTar(t, "a.pdf", "b.pdf", "c.pdf"), processor.ArchiveTar,
+			processor.ArchiveLimits{MaxTotalSize: 40}, 0},
+		{"zip entry ratio", buildZipEntries(t, map[string][]byte{"zeros.txt": make([]byte, 4<<20)}), processor.ArchiveZip,
+			processor.ArchiveLimits{}, 0},
+		{"gzip ratio", gzipBytes(t, make([]byte, 4<<20)), processor.ArchiveGzip,
+			processor.ArchiveLimits{}, 0},
+		{"nesting depth", buildZip(t, "a.pdf"), processor.ArchiveZip,
+			processor.ArchiveLimits{MaxDepth: 2}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &processor.ProcessRequest{
+				JobID:    "job-archive",
+				Filename: "upload.bin",
+				Metadata: map[string]interface{}{"archiveDepth": float64(tt.depth)},
+			}
+			result, err := processor.ExpandArchive(context.Background(), req, tt.data, tt.format, tt.limits, 0)
+			if err == nil {
+				t.Fatalf("expected rejection, got %d children", len(result.Children))
+			}
+			if code := errors.CodeOf(err); code != errors.ErrorArchiveRejected {
+				t.Errorf("error code = %q, want %q (%v)", code, errors.ErrorArchiveRejected, err)
+			}
+			if !errors.IsPermanent(fmt.Errorf("processing failed: %w", err)) {
+				t.Errorf("archive rejection is not permanent: %v", err)
+			}
+		})
+	}
+}
+
+// TestExpandArchiveUnsafePaths tests that traversal and absolute entries are skipped
+func TestExpandArchiveUnsafePaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		format string
+	}{
+		{"zip", buildZip(t, "../evil.pdf", "/etc/passwd", "C:/boot.ini", "docs/../../up.pdf", "docs/ok.pdf"), processor.ArchiveZip},
+		{"tar", buildTar(t, "../evil.pdf", "/etc/passwd", "docs/../../up.pdf", "docs/ok.pdf"), processor.ArchiveTar},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &processor.ProcessRequest{JobID: "job-archive", Filename: "upload." + tt.format}
+			result, err := processor.ExpandArchive(context.Background(), req, tt.data, tt.format, processor.ArchiveLimits{}, 0)
+			if err != nil {
+				t.Fatalf("ExpandArchive failed: %v", err)
+			}
+			if len(result.Children) != 1 || result.Children[0].Path != "docs/ok.pdf" {
+				for _, child := range result.Children {
+					t.Errorf("extracted %q", child.Path)
+				}
+				t.Fatalf("expected only docs/ok.pdf, got %d children", len(result.Children))
+			}
+			if result.Children[0].Depth != 1 {
+				t.Errorf("child depth = %d, want 1", result.Children[0].Depth)
+			}
+			if result.SkippedEntries == 0 {
+				t.Error("unsafe entries were not reported as skipped")
+			}
+		})
+	}
+}
+
+// TestPermanentErrors tests which processing errors skip retries
+func TestPermanentErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		permanent bool
+		code      errors.ErrorCode
+	}{
+		{"archive rejected", errors.NewArchiveRejectedError("job", "too big"), true, errors.ErrorArchiveRejected},
+		{"wrapped", fmt.Errorf("processing: %w", errors.NewArchiveRejectedError("job", "too big")), true, errors.ErrorArchiveRejected},
+		{"mageagent unavailable", fmt.Errorf("EPUB processing failed: %w",
+			errors.NewMageAgentUnavailableError("job", "application/epub+zip conversion", nil)), true, errors.ErrorMageAgentUnavailable},
+		{"ocr policy rejected", errors.NewOCRPolicyRejectedError("job", "tesseract≥0.85", nil), true, errors.ErrorOCRPolicyRejected},
+		{"timeout", errors.NewProcessingTimeoutError("job", time.Minute, nil), false, errors.ErrorProcessingTimeout},
+		{"plain error", fmt.Errorf("connection refused"), false, ""},
+	}
+
+	for _, tt := range tests {
+		if got := errors.IsPermanent(tt.err); got != tt.permanent {
+			t.Errorf("%s: IsPermanent() = %v, want %v", tt.name, got, tt.permanent)
+		}
+		if got := errors.CodeOf(tt.err); got != tt.code {
+			t.Errorf("%s: CodeOf() = %q, want %q", tt.name, got, tt.code)
+		}
+	}
+}
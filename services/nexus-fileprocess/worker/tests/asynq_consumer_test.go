@@ -0,0 +1,105 @@
+/**
+ * Asynq Backend Tests
+ *
+ * Validates the asynq Backend against miniredis:
+ * - A task that fails its last attempt is dead-lettered and archived by Asynq
+ * - A permanent error skips the remaining retries
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/errors"
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
+	"github.com/hibiken/asynq"
+)
+
+// TestAsynqDeadLettersExhaustedTasks tests dead-lettering once Asynq has no retries left
+func TestAsynqDeadLettersExhaustedTasks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		maxRetry int
+		err      error
+	}{
+		{
+			name:     "retries exhausted",
+			maxRetry: 0,
+			err:      fmt.Errorf("upstream unavailable"),
+		},
+		{
+			name:     "permanent error",
+			maxRetry: 5,
+			err:      errors.NewArchiveRejectedError("job-asynq", "too many entries"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, client := startMiniredis(t)
+			proc := newFakeProcessor()
+			proc.process = func(ctx context.Context, req *processor.ProcessRequest) (*processor.ProcessResult, error) {
+				return nil, tc.err
+			}
+			ctx := context.Background()
+
+			consumer, err := queue.NewConsumer(&queue.ConsumerConfig{
+				RedisURL:    "redis://" + server.Addr(),
+				QueueName:   testQueue,
+				Concurrency: 1,
+				Processor:   proc,
+				WorkerID:    "worker-a",
+			})
+			if err != nil {
+				t.Fatalf("NewConsumer() failed: %v", err)
+			}
+			stop := startBackend(t, consumer)
+
+			redisOpt := asynq.RedisClientOpt{Addr: server.Addr()}
+			producer := asynq.NewClient(redisOpt)
+			defer producer.Close()
+			inspector := asynq.NewInspector(redisOpt)
+			defer inspector.Close()
+
+			payload, _ := json.Marshal(queue.JobPayload{JobID: "job-asynq", UserID: "user-1", Filename: "a.pdf"})
+			if _, err := producer.Enqueue(asynq.NewTask("process-document", payload),
+				asynq.Queue(testQueue), asynq.TaskID("task-1"), asynq.MaxRetry(tc.maxRetry)); err != nil {
+				t.Fatalf("Enqueue() failed: %v", err)
+			}
+
+			eventually(t, 10*time.Second, "Asynq to archive the task", func() bool {
+				info, err := inspector.GetTaskInfo(testQueue, "task-1")
+				return err == nil && info.State == asynq.TaskStateArchived
+			})
+			stop()
+
+			dlq, err := queue.NewDeadLetterQueue(client, testQueue)
+			if err != nil {
+				t.Fatalf("NewDeadLetterQueue() failed: %v", err)
+			}
+			entry, err := dlq.Get(ctx, "task-1")
+			if err != nil {
+				t.Fatalf("task was not dead-lettered: %v", err)
+			}
+			if entry.Backend != queue.BackendAsynq || entry.Job.Attempts != 1 || len(entry.Errors) != 1 {
+				t.Errorf("dead-letter entry has backend=%s attempts=%d errors=%d, want asynq, 1 and 1",
+					entry.Backend, entry.Job.Attempts, len(entry.Errors))
+			}
+			if got := proc.callCount("job-asynq"); got != 1 {
+				t.Errorf("ProcessDocument called %d times, want 1", got)
+			}
+			if !isMember(t, client, "failed", "job-asynq") {
+				t.Error("job is not in the failed set")
+			}
+			if got := proc.lastStatus("job-asynq"); got != "failed" {
+				t.Errorf("last status = %q, want failed", got)
+			}
+		})
+	}
+}
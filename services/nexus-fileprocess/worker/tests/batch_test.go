@@ -0,0 +1,137 @@
+/**
+ * Batch Accounting Tests
+ *
+ * Validates batch counters and statuses as children complete, fail or are
+ * cancelled, including redelivered children replacing their earlier outcome.
+ * TestRecordBatchJob requires DATABASE_URL (migrated) and QDRANT_URL.
+ */
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/storage"
+)
+
+// TestBatchStatus tests the status derived from a batch's outcomes
+func TestBatchStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int
+		outcomes []string
+		want     string
+	}{
+		{"open", 3, []string{"completed", "failed"}, storage.BatchStatusProcessing},
+		{"unknown size", 0, []string{"completed"}, storage.BatchStatusProcessing},
+		{"all completed", 2, []string{"completed", "completed"}, storage.BatchStatusCompleted},
+		{"all failed", 2, []string{"failed", "failed"}, storage.BatchStatusFailed},
+		{"all cancelled", 2, []string{"cancelled", "cancelled"}, storage.BatchStatusCancelled},
+		{"failed and cancelled", 2, []string{"failed", "cancelled"}, storage.BatchStatusFailed},
+		{"completed and failed", 2, []string{"completed", "failed"}, storage.BatchStatusCompletedWithErrors},
+		{"completed and cancelled", 2, []string{"completed", "cancelled"}, storage.BatchStatusCompletedWithErrors},
+	}
+
+	for _, tt := range tests {
+		summary := &storage.BatchSummary{TotalJobs: tt.total}
+		for _, status := range tt.outcomes {
+			summary.ApplyOutcome(nil, &storage.BatchJobOutcome{Status: status})
+		}
+		if summary.Status != tt.want {
+			t.Errorf("%s: status = %s, want %s", tt.name, summary.Status, tt.want)
+		}
+		if got := summary.Finished(); got != (tt.want != storage.BatchStatusProcessing) {
+			t.Errorf("%s: Finished() = %v", tt.name, got)
+		}
+	}
+}
+
+// TestBatchApplyOutcome tests counters, pages and tier usage, and replacing an earlier outcome
+func TestBatchApplyOutcome(t *testing.T) {
+	summary := &storage.BatchSummary{TotalJobs: 2}
+
+	completed := &storage.BatchJobOutcome{Status: "completed", OCRTierUsed: "tier2", PageCount: 4}
+	summary.ApplyOutcome(nil, completed)
+	if summary.CompletedJobs != 1 || summary.TotalPages != 4 || summary.TierUsage["tier2"] != 1 {
+		t.Fatalf("after completed: %+v", summary)
+	}
+
+	// A redelivered job replaces its earlier outcome instead of being counted twice
+	cancelled := &storage.BatchJobOutcome{Status: "cancelled"}
+	summary.ApplyOutcome(completed, cancelled)
+	if summary.CompletedJobs != 0 || summary.CancelledJobs != 1 || summary.TotalPages != 0 {
+		t.Errorf("after replacing completed with cancelled: %+v", summary)
+	}
+	if _, ok := summary.TierUsage["tier2"]; ok {
+		t.Errorf("tier usage of the replaced outcome kept: %v", summary.TierUsage)
+	}
+
+	summary.ApplyOutcome(cancelled, &storage.BatchJobOutcome{Status: "failed"})
+	if summary.CancelledJobs != 0 || summary.FailedJobs != 1 {
+		t.Errorf("after replacing cancelled with failed: %+v", summary)
+	}
+	if summary.Status != storage.BatchStatusProcessing {
+		t.Errorf("status = %s, want %s", summary.Status, storage.BatchStatusProcessing)
+	}
+
+	summary.ApplyOutcome(nil, &storage.BatchJobOutcome{Status: "cancelled"})
+	if summary.Status != storage.BatchStatusFailed {
+		t.Errorf("status = %s, want %s", summary.Status, storage.BatchStatusFailed)
+	}
+}
+
+// TestRecordBatchJob tests batch accounting against PostgreSQL
+func TestRecordBatchJob(t *testing.T) {
+	databaseURL := os.Getenv("DATABASE_URL")
+	qdrantURL := os.Getenv("QDRANT_URL")
+	if databaseURL == "" || qdrantURL == "" {
+		t.Skip("DATABASE_URL and QDRANT_URL not set, skipping batch accounting test")
+	}
+
+	sm, err := storage.NewStorageManager(databaseURL, qdrantURL, "fileprocess_test_batches", 1024)
+	if err != nil {
+		t.Fatalf("NewStorageManager failed: %v", err)
+	}
+	defer sm.Close()
+
+	ctx := context.Background()
+	batchID := fmt.Sprintf("batch-test-%d", time.Now().UnixNano())
+	record := func(jobID, status string) *storage.BatchSummary {
+		summary, err := sm.RecordBatchJob(ctx, &storage.BatchJobOutcome{
+			BatchID:   batchID,
+			UserID:    "test-user",
+			BatchSize: 3,
+			JobID:     jobID,
+			Filename:  jobID + ".pdf",
+			Status:    status,
+			PageCount: 2,
+		})
+		if err != nil {
+			t.Fatalf("RecordBatchJob(%s, %s) failed: %v", jobID, status, err)
+		}
+		return summary
+	}
+
+	record("job-1", "failed")
+	// Redelivery replaces the job's earlier outcome
+	summary := record("job-1", "completed")
+	if summary.CompletedJobs != 1 || summary.FailedJobs != 0 || summary.JustFinished {
+		t.Errorf("after redelivery: %+v", summary)
+	}
+
+	record("job-2", "failed")
+	summary = record("job-3", "cancelled")
+	if !summary.JustFinished || summary.Status != storage.BatchStatusCompletedWithErrors {
+		t.Errorf("batch not finished by its cancelled child: %+v", summary)
+	}
+	if summary.CancelledJobs != 1 || summary.TotalPages != 2 {
+		t.Errorf("counters = %+v", summary)
+	}
+	if len(summary.Failures) != 1 || summary.Failures[0].JobID != "job-2" {
+		t.Errorf("failures = %+v", summary.Failures)
+	}
+}
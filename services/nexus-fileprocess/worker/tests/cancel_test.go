@@ -0,0 +1,172 @@
+/**
+ * Job Cancellation Tests
+ *
+ * Validates cancellation on the LIST backend against miniredis:
+ * - RequestCancel sets the marker and rejects an empty job ID
+ * - A job cancelled while queued is skipped by the marker pre-check
+ * - A running job is aborted through the control channel
+ * - Both are acked and marked cancelled instead of failed or retried, and the
+ *   payload blob the worker externalized is released
+ */
+
+package tests
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
+	"github.com/adverant/nexus/fileprocess-worker/internal/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+// TestRequestCancel tests the marker key written for a cancellation request
+func TestRequestCancel(t *testing.T) {
+	server, client := startMiniredis(t)
+
+	if err := queue.RequestCancel(context.Background(), client, testQueue, ""); err == nil {
+		t.Error("RequestCancel() with an empty job ID succeeded, want an error")
+	}
+
+	if err := queue.RequestCancel(context.Background(), client, testQueue, "job-1"); err != nil {
+		t.Fatalf("RequestCancel() failed: %v", err)
+	}
+	if ttl := server.TTL(testQueue + ":cancel:job-1"); ttl != 24*time.Hour {
+		t.Errorf("cancel marker TTL = %v, want 24h", ttl)
+	}
+}
+
+// TestCancelQueuedJob tests that a job cancelled before it is picked up is never processed
+func TestCancelQueuedJob(t *testing.T) {
+	server, client := startMiniredis(t)
+	proc := newFakeProcessor()
+
+	job := newTestJob("job-queued", 3)
+	store := externalizedPayload(t, job)
+	pushJob(t, client, job)
+	if err := queue.RequestCancel(context.Background(), client, testQueue, job.Payload.JobID); err != nil {
+		t.Fatalf("RequestCancel() failed: %v", err)
+	}
+
+	cfg := redisConsumerConfig(server, proc, "worker-a")
+	cfg.BlobStore = store
+	consumer, err := queue.NewRedisConsumer(cfg)
+	if err != nil {
+		t.Fatalf("NewRedisConsumer() failed: %v", err)
+	}
+	stop := startBackend(t, consumer)
+
+	eventually(t, 5*time.Second, "the job to be marked cancelled", func() bool {
+		return proc.lastStatus(job.Payload.JobID) == queue.JobStatusCancelled
+	})
+	eventually(t, 5*time.Second, "the delivery to be acked", func() bool {
+		return client.LLen(context.Background(), testQueue+":inflight:worker-a").Val() == 0
+	})
+	stop()
+
+	assertCancelled(t, client, proc, job.Payload.JobID, 0)
+	assertReleased(t, store, job)
+}
+
+// TestCancelRunningJob tests that a job being processed is aborted by a cancellation request
+func TestCancelRunningJob(t *testing.T) {
+	server, client := startMiniredis(t)
+	proc := newFakeProcessor()
+
+	started := make(chan struct{})
+	var once sync.Once
+	proc.process = func(ctx context.Context, req *processor.ProcessRequest) (*processor.ProcessResult, error) {
+		once.Do(func() { close(started) })
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	job := newTestJob("job-running", 3)
+	store := externalizedPayload(t, job)
+	pushJob(t, client, job)
+
+	cfg := redisConsumerConfig(server, proc, "worker-a")
+	cfg.BlobStore = store
+	consumer, err := queue.NewRedisConsumer(cfg)
+	if err != nil {
+		t.Fatalf("NewRedisConsumer() failed: %v", err)
+	}
+	stop := startBackend(t, consumer)
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the job to start")
+	}
+
+	if err := queue.RequestCancel(context.Background(), client, testQueue, job.Payload.JobID); err != nil {
+		t.Fatalf("RequestCancel() failed: %v", err)
+	}
+
+	eventually(t, 5*time.Second, "the job to be marked cancelled", func() bool {
+		return proc.lastStatus(job.Payload.JobID) == queue.JobStatusCancelled
+	})
+	eventually(t, 5*time.Second, "the delivery to be acked", func() bool {
+		return client.LLen(context.Background(), testQueue+":inflight:worker-a").Val() == 0
+	})
+	stop()
+
+	assertCancelled(t, client, proc, job.Payload.JobID, 1)
+	assertReleased(t, store, job)
+}
+
+// assertCancelled checks that a job ended up cancelled, not failed, retried or completed
+func assertCancelled(t *testing.T, client *redis.Client, proc *fakeProcessor, jobID string, wantCalls int) {
+	t.Helper()
+	ctx := context.Background()
+
+	if got := proc.callCount(jobID); got != wantCalls {
+		t.Errorf("ProcessDocument called %d times, want %d", got, wantCalls)
+	}
+	if !isMember(t, client, "cancelled", jobID) {
+		t.Errorf("job %s is not in the cancelled set", jobID)
+	}
+	for _, set := range []string{"failed", "completed", "processing"} {
+		if isMember(t, client, set, jobID) {
+			t.Errorf("job %s is in the %s set", jobID, set)
+		}
+	}
+	if n := client.ZCard(ctx, testQueue+":delayed").Val(); n != 0 {
+		t.Errorf("%d job(s) scheduled for retry, want 0", n)
+	}
+	if n := client.ZCard(ctx, testQueue+":dead:index").Val(); n != 0 {
+		t.Errorf("%d job(s) dead-lettered, want 0", n)
+	}
+}
+
+// externalizedPayload stores a job's file under the worker's claim-check key, as a retry
+// of an oversized inline payload would have, and points the job at it
+func externalizedPayload(t *testing.T, job *queue.RedisJobData) *storage.LocalBlobStore {
+	t.Helper()
+
+	store, err := storage.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore() failed: %v", err)
+	}
+
+	key := "jobs/" + job.Payload.JobID + "/payload"
+	if err := store.Put(context.Background(), key, strings.NewReader("payload"), 7); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+	job.Payload.BlobRef = key
+	return store
+}
+
+// assertReleased checks that the worker deleted a job's externalized payload
+func assertReleased(t *testing.T, store storage.BlobStore, job *queue.RedisJobData) {
+	t.Helper()
+
+	if body, _, err := store.Open(context.Background(), job.Payload.BlobRef); err == nil {
+		body.Close()
+		t.Errorf("payload blob %s still exists after cancellation", job.Payload.BlobRef)
+	}
+}
@@ -0,0 +1,130 @@
+/**
+ * Text Chunking Tests
+ *
+ * Validates how document text is split for chunk embeddings: paragraph and
+ * region alignment, heading and page breaks, overlapping windows, UTF-8
+ * safety and the chunk limit.
+ */
+
+package tests
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+// checkChunkOffsets fails when a chunk's text is not the span its offsets point to
+func checkChunkOffsets(t *testing.T, text string, chunks []processor.TextChunk) {
+	t.Helper()
+	for i, chunk := range chunks {
+		if chunk.Index != i {
+			t.Errorf("chunk %d has index %d", i, chunk.Index)
+		}
+		if chunk.StartChar < 0 || chunk.EndChar > len(text) || text[chunk.StartChar:chunk.EndChar] != chunk.Text {
+			t.Errorf("chunk %d offsets [%d:%d] do not match its text %q", i, chunk.StartChar, chunk.EndChar, chunk.Text)
+		}
+		if !utf8.ValidString(chunk.Text) {
+			t.Errorf("chunk %d splits a UTF-8 sequence", i)
+		}
+	}
+}
+
+// TestChunkTextParagraphs tests that short paragraphs are packed into one chunk
+func TestChunkTextParagraphs(t *testing.T) {
+	text := "First paragraph.\n\nSecond paragraph.\n \nThird paragraph."
+	chunks := processor.ChunkText(text, nil, nil, processor.DefaultChunkOptions())
+	if len(chunks) != 1 {
+		t.Fatalf("chunks = %d, want 1", len(chunks))
+	}
+	if chunks[0].Text != text || chunks[0].RegionID != -1 || chunks[0].PageNumber != 0 {
+		t.Errorf("chunk = %+v, want the whole text without region or page", chunks[0])
+	}
+	checkChunkOffsets(t, text, chunks)
+
+	if chunks := processor.ChunkText(" \n\n ", nil, nil, processor.DefaultChunkOptions()); len(chunks) != 0 {
+		t.Errorf("blank text gave %d chunks, want none", len(chunks))
+	}
+}
+
+// TestChunkTextBreaks tests that headings and pages start new chunks
+func TestChunkTextBreaks(t *testing.T) {
+	page1 := "Introduction\n\nThe worker extracts text."
+	page2 := "Results\n\nEvery page is embedded."
+	text := page1 + "\n\n" + page2
+	pages := []processor.OCRPage{{PageNumber: 1, Text: page1}, {PageNumber: 2, Text: page2}}
+	layout := &processor.LayoutResult{
+		Regions: []processor.LayoutRegion{
+			{ID: 0, Type: "heading", Content: "Introduction", PageNumber: 1},
+			{ID: 1, Type: "paragraph", Content: "The worker extracts text.", PageNumber: 1},
+			{ID: 2, Type: "heading", Content: "Results", PageNumber: 2},
+			{ID: 3, Type: "paragraph", Content: "Every page is embedded.", PageNumber: 2},
+		},
+		ReadingOrder: []int{0, 1, 2, 3},
+	}
+
+	chunks := processor.ChunkText(text, pages, layout, processor.DefaultChunkOptions())
+	if len(chunks) != 2 {
+		t.Fatalf("chunks = %d, want 2: %+v", len(chunks), chunks)
+	}
+	if chunks[0].Text != page1 || chunks[0].PageNumber != 1 || chunks[0].RegionID != 0 {
+		t.Errorf("chunk 0 = %+v, want page 1 from region 0", chunks[0])
+	}
+	if chunks[1].Text != page2 || chunks[1].PageNumber != 2 || chunks[1].RegionID != 2 {
+		t.Errorf("chunk 1 = %+v, want page 2 from region 2", chunks[1])
+	}
+	checkChunkOffsets(t, text, chunks)
+
+	// Without regions, pages come from where their text is found
+	chunks = processor.ChunkText(text, pages, nil, processor.DefaultChunkOptions())
+	if len(chunks) != 2 || chunks[0].PageNumber != 1 || chunks[1].PageNumber != 2 {
+		t.Errorf("paragraph chunks = %+v, want one per page", chunks)
+	}
+}
+
+// TestChunkTextOverlap tests that long text is split into overlapping windows
+func TestChunkTextOverlap(t *testing.T) {
+	text := strings.TrimSpace(strings.Repeat("lorem ipsum dolor sit amet ", 200))
+	opts := processor.ChunkOptions{MaxChars: 500, OverlapChars: 100, MaxChunks: 100}
+	chunks := processor.ChunkText(text, nil, nil, opts)
+	if len(chunks) < 10 {
+		t.Fatalf("chunks = %d, want at least 10", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if len(chunk.Text) > opts.MaxChars {
+			t.Errorf("chunk %d is %d bytes, want at most %d", i, len(chunk.Text), opts.MaxChars)
+		}
+		if i > 0 && chunk.StartChar >= chunks[i-1].EndChar {
+			t.Errorf("chunk %d starts at %d, want it to overlap chunk %d ending at %d", i, chunk.StartChar, i-1, chunks[i-1].EndChar)
+		}
+		if strings.HasPrefix(chunk.Text, " ") || (chunk.StartChar > 0 && text[chunk.StartChar-1] != ' ') {
+			t.Errorf("chunk %d does not start at a word: %q", i, chunk.Text[:20])
+		}
+	}
+	if last := chunks[len(chunks)-1]; last.EndChar != len(text) {
+		t.Errorf("last chunk ends at %d, want %d", last.EndChar, len(text))
+	}
+	checkChunkOffsets(t, text, chunks)
+}
+
+// TestChunkTextUTF8 tests that windows never split a multibyte character
+func TestChunkTextUTF8(t *testing.T) {
+	text := strings.Repeat("日本語のテキスト", 100) + "\n\n" + strings.Repeat("Ünïcödé ", 100)
+	chunks := processor.ChunkText(text, nil, nil, processor.ChunkOptions{MaxChars: 250, OverlapChars: 50, MaxChunks: 100})
+	if len(chunks) < 2 {
+		t.Fatalf("chunks = %d, want several", len(chunks))
+	}
+	checkChunkOffsets(t, text, chunks)
+}
+
+// TestChunkTextMaxChunks tests the chunk limit per document
+func TestChunkTextMaxChunks(t *testing.T) {
+	text := strings.Repeat("A short paragraph that fills a chunk.\n\n", 50)
+	chunks := processor.ChunkText(text, nil, nil, processor.ChunkOptions{MaxChars: 200, MaxChunks: 3})
+	if len(chunks) != 3 {
+		t.Fatalf("chunks = %d, want 3", len(chunks))
+	}
+	checkChunkOffsets(t, text, chunks)
+}
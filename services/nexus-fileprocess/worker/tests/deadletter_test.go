@@ -0,0 +1,228 @@
+/**
+ * Dead-Letter Queue Tests
+ *
+ * Validates dead-lettering and the admin operations against miniredis:
+ * - Jobs that exhaust MaxRetries are stored with their attempt history
+ * - Replay and ReplayFiltered requeue jobs with their attempts reset
+ * - Purge and PurgeFiltered delete entries and their queue data
+ * - The :dead hash and the :dead:index sorted set always hold the same jobs
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
+	"github.com/redis/go-redis/v9"
+)
+
+// TestDeadLetterReplay tests that an exhausted job is dead-lettered and completes once replayed
+func TestDeadLetterReplay(t *testing.T) {
+	server, client := startMiniredis(t)
+	proc := newFakeProcessor()
+	ctx := context.Background()
+
+	var failing atomic.Bool
+	failing.Store(true)
+	proc.process = func(ctx context.Context, req *processor.ProcessRequest) (*processor.ProcessResult, error) {
+		if failing.Load() {
+			return nil, fmt.Errorf("upstream unavailable")
+		}
+		return &processor.ProcessResult{Confidence: 1}, nil
+	}
+
+	consumer, err := queue.NewRedisConsumer(redisConsumerConfig(server, proc, "worker-a"))
+	if err != nil {
+		t.Fatalf("NewRedisConsumer() failed: %v", err)
+	}
+	startBackend(t, consumer)
+
+	job := newTestJob("job-exhausted", 2)
+	pushJob(t, client, job)
+
+	dlq, err := queue.NewDeadLetterQueue(client, testQueue)
+	if err != nil {
+		t.Fatalf("NewDeadLetterQueue() failed: %v", err)
+	}
+
+	eventually(t, 5*time.Second, "the job to be dead-lettered", func() bool {
+		return isMember(t, client, "failed", job.Payload.JobID)
+	})
+	assertDeadLetters(t, client, job.ID)
+
+	entry, err := dlq.Get(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if entry.Job.Attempts != 2 || len(entry.Errors) != 2 {
+		t.Errorf("dead-lettered after %d attempts with %d errors, want 2 and 2", entry.Job.Attempts, len(entry.Errors))
+	}
+	if got := proc.callCount(job.Payload.JobID); got != 2 {
+		t.Errorf("ProcessDocument called %d times before dead-lettering, want 2", got)
+	}
+
+	failing.Store(false)
+	if err := dlq.Replay(ctx, job.ID); err != nil {
+		t.Fatalf("Replay() failed: %v", err)
+	}
+	assertDeadLetters(t, client)
+	if isMember(t, client, "failed", job.Payload.JobID) {
+		t.Error("replayed job is still in the failed set")
+	}
+
+	eventually(t, 5*time.Second, "the replayed job to complete", func() bool {
+		return isMember(t, client, "completed", job.Payload.JobID)
+	})
+
+	replayed := loadJob(t, client, job.ID)
+	if replayed.Attempts != 0 || replayed.LastError != "" || replayed.NextAttemptAt != nil {
+		t.Errorf("replayed job has attempts=%d lastError=%q nextAttemptAt=%v, want them reset",
+			replayed.Attempts, replayed.LastError, replayed.NextAttemptAt)
+	}
+
+	if err := dlq.Replay(ctx, job.ID); err == nil {
+		t.Error("Replay() of a job no longer dead-lettered succeeded, want an error")
+	}
+}
+
+// TestDeadLetterFilteredReplayAndPurge tests the filtered admin operations and Purge
+func TestDeadLetterFilteredReplayAndPurge(t *testing.T) {
+	_, client := startMiniredis(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	a := seedDeadLetter(t, client, "job-a", "user-1", "application/pdf", "MageAgent timeout", now.Add(-4*time.Minute))
+	b := seedDeadLetter(t, client, "job-b", "user-2", "application/pdf", "OCR failed", now.Add(-3*time.Minute))
+	c := seedDeadLetter(t, client, "job-c", "user-1", "text/plain", "processing TIMEOUT", now.Add(-2*time.Minute))
+	d := seedDeadLetter(t, client, "job-d", "user-2", "text/plain", "MageAgent timeout", now.Add(-1*time.Minute))
+	assertDeadLetters(t, client, a.ID, b.ID, c.ID, d.ID)
+
+	dlq, err := queue.NewDeadLetterQueue(client, testQueue)
+	if err != nil {
+		t.Fatalf("NewDeadLetterQueue() failed: %v", err)
+	}
+
+	// Replay user-1's timeouts
+	replayed, err := dlq.ReplayFiltered(ctx, &queue.DeadLetterFilter{UserID: "user-1", ErrorContains: "timeout"})
+	if err != nil {
+		t.Fatalf("ReplayFiltered() failed: %v", err)
+	}
+	if replayed != 2 {
+		t.Errorf("ReplayFiltered() = %d, want 2", replayed)
+	}
+	assertDeadLetters(t, client, b.ID, d.ID)
+
+	queued, _ := client.LRange(ctx, testQueue, 0, -1).Result()
+	sort.Strings(queued)
+	if want := []string{a.ID, c.ID}; !reflect.DeepEqual(queued, want) {
+		t.Errorf("queued after replay = %v, want %v", queued, want)
+	}
+	for _, id := range []string{a.ID, c.ID} {
+		if job := loadJob(t, client, id); job.Attempts != 0 || job.LastError != "" {
+			t.Errorf("replayed job %s has attempts=%d lastError=%q, want them reset", id, job.Attempts, job.LastError)
+		}
+	}
+
+	// Purge PDFs that failed OCR
+	purged, err := dlq.PurgeFiltered(ctx, &queue.DeadLetterFilter{MimeType: "application/pdf", ErrorContains: "ocr"})
+	if err != nil {
+		t.Fatalf("PurgeFiltered() failed: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("PurgeFiltered() = %d, want 1", purged)
+	}
+	assertDeadLetters(t, client, d.ID)
+	if client.HExists(ctx, testQueue+":data", b.ID).Val() {
+		t.Errorf("job data of purged job %s still exists", b.ID)
+	}
+
+	if err := dlq.Purge(ctx, d.ID); err != nil {
+		t.Fatalf("Purge() failed: %v", err)
+	}
+	assertDeadLetters(t, client)
+	if client.HExists(ctx, testQueue+":data", d.ID).Val() {
+		t.Errorf("job data of purged job %s still exists", d.ID)
+	}
+	if err := dlq.Purge(ctx, d.ID); err == nil {
+		t.Error("Purge() of a missing job succeeded, want an error")
+	}
+}
+
+// seedDeadLetter stores a job as the worker dead-letters it after three failed attempts
+func seedDeadLetter(t *testing.T, client *redis.Client, jobID, userID, mimeType, lastError string, at time.Time) *queue.RedisJobData {
+	t.Helper()
+	ctx := context.Background()
+
+	job := newTestJob(jobID, 3)
+	job.Payload.UserID = userID
+	job.Payload.MimeType = mimeType
+	for attempt := 1; attempt <= 3; attempt++ {
+		job.Errors = append(job.Errors, queue.AttemptError{Attempt: attempt, Error: lastError, FailedAt: at})
+	}
+	job.Attempts = 3
+	job.LastError = lastError
+
+	entry, err := json.Marshal(queue.DeadLetterEntry{Job: *job, Errors: job.Errors, DeadLetteredAt: at})
+	if err != nil {
+		t.Fatalf("failed to marshal dead-letter entry: %v", err)
+	}
+
+	storeJob(t, client, job)
+	client.HSet(ctx, testQueue+":dead", job.ID, entry)
+	client.ZAdd(ctx, testQueue+":dead:index", redis.Z{Score: float64(at.UnixMilli()), Member: job.ID})
+	client.SAdd(ctx, testQueue+":failed", job.Payload.JobID)
+	return job
+}
+
+// assertDeadLetters checks that the :dead hash and the :dead:index set both hold exactly the given jobs
+func assertDeadLetters(t *testing.T, client *redis.Client, want ...string) {
+	t.Helper()
+	ctx := context.Background()
+
+	entries, err := client.HKeys(ctx, testQueue+":dead").Result()
+	if err != nil {
+		t.Fatalf("HKEYS failed: %v", err)
+	}
+	indexed, err := client.ZRange(ctx, testQueue+":dead:index", 0, -1).Result()
+	if err != nil {
+		t.Fatalf("ZRANGE failed: %v", err)
+	}
+
+	sort.Strings(entries)
+	sort.Strings(indexed)
+	sort.Strings(want)
+	if len(want) == 0 {
+		want = []string{}
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf(":dead holds %v, want %v", entries, want)
+	}
+	if !reflect.DeepEqual(indexed, want) {
+		t.Errorf(":dead:index holds %v, want %v", indexed, want)
+	}
+}
+
+// loadJob reads a job back from <queue>:data
+func loadJob(t *testing.T, client *redis.Client, queueJobID string) *queue.RedisJobData {
+	t.Helper()
+
+	data, err := client.HGet(context.Background(), testQueue+":data", queueJobID).Result()
+	if err != nil {
+		t.Fatalf("failed to load job %s: %v", queueJobID, err)
+	}
+
+	var job queue.RedisJobData
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		t.Fatalf("failed to unmarshal job %s: %v", queueJobID, err)
+	}
+	return &job
+}
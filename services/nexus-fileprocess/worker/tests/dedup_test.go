@@ -0,0 +1,80 @@
+/**
+ * Content Deduplication Tests
+ *
+ * Validates the content hash and pipeline fingerprint used as the dedup key,
+ * including the OCR overrides of a job.
+ */
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+// TestContentHash tests that the hash is the stable hex SHA-256 of the bytes
+func TestContentHash(t *testing.T) {
+	// SHA-256 of the empty input
+	if got := processor.ContentHash(nil); got != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("ContentHash(nil) = %s", got)
+	}
+
+	a := processor.ContentHash([]byte("invoice-2024.pdf contents"))
+	if a != processor.ContentHash([]byte("invoice-2024.pdf contents")) {
+		t.Errorf("ContentHash is not deterministic")
+	}
+	if a == processor.ContentHash([]byte("invoice-2024.pdf contents.")) {
+		t.Errorf("ContentHash collided for different content")
+	}
+}
+
+// TestPipelineFingerprint tests that the embedding model and dimensions change the fingerprint
+func TestPipelineFingerprint(t *testing.T) {
+	base := processor.PipelineFingerprint("voyage-3", 1024)
+	if base != processor.PipelineFingerprint("voyage-3", 1024) {
+		t.Errorf("PipelineFingerprint is not deterministic")
+	}
+	if base == processor.PipelineFingerprint("voyage-3-large", 1024) {
+		t.Errorf("PipelineFingerprint ignores the embedding model")
+	}
+	if base == processor.PipelineFingerprint("voyage-3", 512) {
+		t.Errorf("PipelineFingerprint ignores the embedding dimensions")
+	}
+}
+
+// TestJobPipelineFingerprint tests that OCR policy and language overrides separate DNA
+func TestJobPipelineFingerprint(t *testing.T) {
+	base := processor.PipelineFingerprint("voyage-3", 1024)
+	if got := processor.JobPipelineFingerprint(base, map[string]interface{}{"forceReprocess": false}); got != base {
+		t.Errorf("fingerprint without OCR overrides = %s, want %s", got, base)
+	}
+
+	tesseractOnly := map[string]interface{}{"ocrPolicy": map[string]interface{}{"mode": "local_only", "maxTier": "tesseract"}}
+	german := map[string]interface{}{"language": "deu"}
+	both := map[string]interface{}{"ocrPolicy": tesseractOnly["ocrPolicy"], "language": "deu"}
+
+	seen := map[string]string{base: "no overrides"}
+	for name, metadata := range map[string]map[string]interface{}{
+		"ocrPolicy": tesseractOnly,
+		"language":  german,
+		"both":      both,
+	} {
+		got := processor.JobPipelineFingerprint(base, metadata)
+		if other, ok := seen[got]; ok {
+			t.Errorf("%s override shares fingerprint %s with %s", name, got, other)
+		}
+		seen[got] = name
+		if got != processor.JobPipelineFingerprint(base, metadata) {
+			t.Errorf("%s override fingerprint is not deterministic", name)
+		}
+		if len(got) > 100 {
+			t.Errorf("%s override fingerprint %s does not fit pipeline_version", name, got)
+		}
+	}
+
+	// The same languages written differently match
+	if processor.JobPipelineFingerprint(base, map[string]interface{}{"language": "de"}) != processor.JobPipelineFingerprint(base, german) {
+		t.Errorf("language overrides de and deu have different fingerprints")
+	}
+}
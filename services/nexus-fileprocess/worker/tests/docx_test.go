@@ -0,0 +1,217 @@
+/**
+ * DOCX Extraction Tests
+ *
+ * Validates native Word extraction: heading levels, list items, page breaks
+ * and tables with merged cells, including spans outside the table grid.
+ */
+
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+const docxStylesXML = `<?xml version="1.0" encoding="UTF-8"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/></w:style>
+  <w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/></w:style>
+  <w:style w:type="paragraph" w:styleId="ChapterTitle"><w:name w:val="Chapter Title"/><w:basedOn w:val="Heading1"/></w:style>
+  <w:style w:type="paragraph" w:styleId="ListBullet"><w:name w:val="List Bullet"/><w:pPr><w:numPr><w:numId w:val="3"/></w:numPr></w:pPr></w:style>
+</w:styles>`
+
+const docxDocumentXML = `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:body>
+  <w:p><w:pPr><w:pStyle w:val="ChapterTitle"/></w:pPr><w:r><w:t>Quarterly Report</w:t></w:r></w:p>
+  <w:p><w:r><w:t xml:space="preserve">Revenue grew </w:t></w:r><w:r><w:t>12%.</w:t></w:r><w:del><w:r><w:delText>Deleted text</w:delText></w:r></w:del></w:p>
+  <w:p><w:pPr><w:pStyle w:val="ListBullet"/></w:pPr><w:r><w:t>First point</w:t></w:r></w:p>
+  <w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="3"/></w:numPr></w:pPr><w:r><w:t>Nested point</w:t></w:r></w:p>
+  <w:p><w:r><w:br w:type="page"/></w:r></w:p>
+  <w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>Figures</w:t></w:r></w:p>
+  <w:tbl>
+    <w:tr>
+      <w:tc><w:tcPr><w:gridSpan w:val="2"/></w:tcPr><w:p><w:r><w:t>Region</w:t></w:r></w:p></w:tc>
+      <w:tc><w:p><w:r><w:t>Total</w:t></w:r></w:p></w:tc>
+    </w:tr>
+    <w:tr>
+      <w:tc><w:tcPr><w:vMerge w:val="restart"/></w:tcPr><w:p><w:r><w:t>EMEA</w:t></w:r></w:p></w:tc>
+      <w:tc><w:p><w:r><w:t>UK</w:t></w:r></w:p></w:tc>
+      <w:tc><w:p><w:r><w:t>10</w:t></w:r></w:p></w:tc>
+    </w:tr>
+    <w:tr>
+      <w:tc><w:tcPr><w:vMerge/></w:tcPr><w:p/></w:tc>
+      <w:tc><w:p><w:r><w:t>DE</w:t></w:r></w:p></w:tc>
+      <w:tc><w:p><w:r><w:t>20</w:t></w:r></w:p></w:tc>
+    </w:tr>
+  </w:tbl>
+</w:body>
+</w:document>`
+
+// docxMalformedTableXML has spans and skipped columns outside its two-column grid
+const docxMalformedTableXML = `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:body>
+  <w:tbl>
+    <w:tblGrid><w:gridCol w:w="2000"/><w:gridCol w:w="2000"/></w:tblGrid>
+    <w:tr>
+      <w:trPr><w:gridBefore w:val="-5"/></w:trPr>
+      <w:tc><w:tcPr><w:gridSpan w:val="2147483647"/></w:tcPr><w:p><w:r><w:t>Wide</w:t></w:r></w:p></w:tc>
+    </w:tr>
+    <w:tr>
+      <w:trPr><w:gridBefore w:val="2147483647"/></w:trPr>
+      <w:tc><w:p><w:r><w:t>Shifted</w:t></w:r></w:p></w:tc>
+    </w:tr>
+    <w:tr>
+      <w:tc><w:p><w:r><w:t>A</w:t></w:r></w:p></w:tc>
+      <w:tc>
+        <w:tbl>
+          <w:tr>
+            <w:trPr><w:gridBefore w:val="-3"/></w:trPr>
+            <w:tc><w:tcPr><w:gridSpan w:val="1000000"/></w:tcPr><w:p><w:r><w:t>Nested</w:t></w:r></w:p></w:tc>
+          </w:tr>
+        </w:tbl>
+      </w:tc>
+    </w:tr>
+  </w:tbl>
+</w:body>
+</w:document>`
+
+func buildDOCX(t *testing.T) []byte {
+	t.Helper()
+	return buildDOCXWith(t, docxDocumentXML)
+}
+
+func buildDOCXWith(t *testing.T, documentXML string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"[Content_Types].xml": `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`,
+		"word/document.xml":   documentXML,
+		"word/styles.xml":     docxStylesXML,
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestExtractDOCX tests region types, pages and confidence of a Word document
+func TestExtractDOCX(t *testing.T) {
+	ocrResult, layoutResult, err := processor.ExtractDOCX(buildDOCX(t))
+	if err != nil {
+		t.Fatalf("ExtractDOCX failed: %v", err)
+	}
+
+	if ocrResult.Confidence != 1.0 || ocrResult.TierUsed != processor.OCRTierNativeDOCX {
+		t.Errorf("confidence=%v tier=%s, want 1.0 %s", ocrResult.Confidence, ocrResult.TierUsed, processor.OCRTierNativeDOCX)
+	}
+	if len(ocrResult.Pages) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(ocrResult.Pages))
+	}
+	if strings.Contains(ocrResult.Text, "Deleted text") {
+		t.Errorf("deleted text was extracted")
+	}
+
+	expected := []struct {
+		regionType string
+		level      int
+		content    string
+		page       int
+	}{
+		{processor.RegionHeading, 1, "Quarterly Report", 1},
+		{processor.RegionParagraph, 0, "Revenue grew 12%.", 1},
+		{processor.RegionListItem, 1, "First point", 1},
+		{processor.RegionListItem, 2, "Nested point", 1},
+		{processor.RegionHeading, 2, "Figures", 2},
+		{processor.RegionTable, 0, "", 2},
+	}
+	if len(layoutResult.Regions) != len(expected) {
+		t.Fatalf("expected %d regions, got %d: %+v", len(expected), len(layoutResult.Regions), layoutResult.Regions)
+	}
+	for i, want := range expected {
+		got := layoutResult.Regions[i]
+		if got.Type != want.regionType || got.Level != want.level || got.PageNumber != want.page {
+			t.Errorf("region %d = %s/%d page %d, want %s/%d page %d", i, got.Type, got.Level, got.PageNumber, want.regionType, want.level, want.page)
+		}
+		if want.content != "" && got.Content != want.content {
+			t.Errorf("region %d content = %q, want %q", i, got.Content, want.content)
+		}
+	}
+}
+
+// TestExtractDOCXMergedCells tests gridSpan and vMerge mapping to ColSpan and RowSpan
+func TestExtractDOCXMergedCells(t *testing.T) {
+	_, layoutResult, err := processor.ExtractDOCX(buildDOCX(t))
+	if err != nil {
+		t.Fatalf("ExtractDOCX failed: %v", err)
+	}
+	if len(layoutResult.Tables) != 1 {
+		t.Fatalf("expected 1 table, got %d", len(layoutResult.Tables))
+	}
+
+	rows := layoutResult.Tables[0].Rows
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+
+	region := rows[0].Cells[0]
+	if region.Content != "Region" || region.ColSpan != 2 || rows[0].Cells[1].ColumnNumber != 2 {
+		t.Errorf("header row not spanned correctly: %+v", rows[0].Cells)
+	}
+
+	emea := rows[1].Cells[0]
+	if emea.Content != "EMEA" || emea.RowSpan != 2 {
+		t.Errorf("EMEA cell = %+v, want RowSpan 2", emea)
+	}
+
+	// The continued merge is not a cell of its own
+	if len(rows[2].Cells) != 2 || rows[2].Cells[0].Content != "DE" || rows[2].Cells[0].ColumnNumber != 1 {
+		t.Errorf("third row = %+v, want DE at column 1", rows[2].Cells)
+	}
+}
+
+// TestExtractDOCXMalformedTable tests that spans and skipped columns stay within the table grid
+func TestExtractDOCXMalformedTable(t *testing.T) {
+	ocrResult, layoutResult, err := processor.ExtractDOCX(buildDOCXWith(t, docxMalformedTableXML))
+	if err != nil {
+		t.Fatalf("ExtractDOCX failed: %v", err)
+	}
+	if len(layoutResult.Tables) != 1 {
+		t.Fatalf("expected 1 table, got %d", len(layoutResult.Tables))
+	}
+
+	rows := layoutResult.Tables[0].Rows
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+	for _, row := range rows {
+		for _, cell := range row.Cells {
+			if cell.ColumnNumber < 0 || cell.ColSpan < 1 || cell.ColSpan > 2 {
+				t.Errorf("cell %q outside the grid: column %d span %d", cell.Content, cell.ColumnNumber, cell.ColSpan)
+			}
+		}
+	}
+
+	wide := rows[0].Cells[0]
+	if wide.Content != "Wide" || wide.ColumnNumber != 0 || wide.ColSpan != 2 {
+		t.Errorf("wide cell = %+v, want column 0 span 2", wide)
+	}
+	if shifted := rows[1].Cells[0]; shifted.Content != "Shifted" || shifted.ColumnNumber != 2 {
+		t.Errorf("shifted cell = %+v, want column 2", shifted)
+	}
+	if !strings.Contains(ocrResult.Text, "Nested") {
+		t.Errorf("nested table text missing: %q", ocrResult.Text)
+	}
+}
@@ -0,0 +1,164 @@
+/**
+ * Embedding Provider Tests
+ *
+ * Validates the VoyageAI and OpenAI-compatible embedders against a fake
+ * /v1/embeddings server: request shape, auth and dimension checks.
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+// embeddingServer answers /v1/embeddings with vectors of the given size and records the last request
+func embeddingServer(t *testing.T, dimensions int) (*httptest.Server, *map[string]interface{}, *string) {
+	t.Helper()
+	var body map[string]interface{}
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		inputs := []interface{}{body["input"]}
+		if list, ok := body["input"].([]interface{}); ok {
+			inputs = list
+		}
+		data := make([]map[string]interface{}, len(inputs))
+		for i := range inputs {
+			data[i] = map[string]interface{}{"index": i, "embedding": make([]float32, dimensions)}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data, "model": body["model"]})
+	}))
+	t.Cleanup(server.Close)
+	return server, &body, &auth
+}
+
+// TestOpenAIEmbedder tests a local OpenAI-compatible server with a configured model and size
+func TestOpenAIEmbedder(t *testing.T) {
+	server, body, auth := embeddingServer(t, 768)
+	embedder, err := processor.NewEmbedder(processor.EmbeddingConfig{
+		Provider:   processor.EmbeddingProviderOpenAI,
+		URL:        server.URL + "/v1/embeddings",
+		Model:      "nomic-embed-text",
+		Dimensions: 768,
+	})
+	if err != nil {
+		t.Fatalf("NewEmbedder failed: %v", err)
+	}
+	if embedder.Model() != "nomic-embed-text" || embedder.Dimensions() != 768 {
+		t.Errorf("embedder = %s/%d, want nomic-embed-text/768", embedder.Model(), embedder.Dimensions())
+	}
+
+	embedding, err := embedder.GenerateEmbedding(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("GenerateEmbedding failed: %v", err)
+	}
+	if len(embedding) != 768 {
+		t.Errorf("dimensions = %d, want 768", len(embedding))
+	}
+	if (*body)["model"] != "nomic-embed-text" || *auth != "" {
+		t.Errorf("request model=%v auth=%q, want nomic-embed-text and no auth", (*body)["model"], *auth)
+	}
+	if _, ok := (*body)["output_dimension"]; ok {
+		t.Errorf("OpenAI-compatible request should not carry output_dimension")
+	}
+
+	batch, err := embedder.GenerateEmbeddingBatch(context.Background(), []string{"a", "b", "c"})
+	if err != nil || len(batch) != 3 {
+		t.Fatalf("GenerateEmbeddingBatch = %d embeddings, %v", len(batch), err)
+	}
+}
+
+// TestEmbedderDimensionMismatch tests that a model of the wrong size is rejected
+func TestEmbedderDimensionMismatch(t *testing.T) {
+	server, _, _ := embeddingServer(t, 384)
+	embedder, err := processor.NewOpenAIEmbedder(processor.EmbeddingConfig{
+		URL:   server.URL,
+		Model: "all-minilm",
+	})
+	if err != nil {
+		t.Fatalf("NewOpenAIEmbedder failed: %v", err)
+	}
+	if embedder.Dimensions() != processor.DefaultEmbeddingDimensions {
+		t.Errorf("default dimensions = %d, want %d", embedder.Dimensions(), processor.DefaultEmbeddingDimensions)
+	}
+	if _, err := embedder.GenerateEmbedding(context.Background(), "hello"); err == nil || !strings.Contains(err.Error(), "dimensions") {
+		t.Errorf("GenerateEmbedding error = %v, want a dimensions mismatch", err)
+	}
+}
+
+// TestVoyageEmbedder tests the VoyageAI defaults, auth and output_dimension
+func TestVoyageEmbedder(t *testing.T) {
+	if _, err := processor.NewVoyageEmbedder(processor.EmbeddingConfig{}); err == nil {
+		t.Errorf("NewVoyageEmbedder without an API key should fail")
+	}
+
+	server, body, auth := embeddingServer(t, 1024)
+	embedder, err := processor.NewVoyageEmbedder(processor.EmbeddingConfig{APIKey: "pa-test", URL: server.URL})
+	if err != nil {
+		t.Fatalf("NewVoyageEmbedder failed: %v", err)
+	}
+	if _, err := embedder.GenerateEmbedding(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateEmbedding failed: %v", err)
+	}
+	if (*body)["model"] != "voyage-3" || *auth != "Bearer pa-test" {
+		t.Errorf("request model=%v auth=%q, want voyage-3 with the API key", (*body)["model"], *auth)
+	}
+	if _, ok := (*body)["output_dimension"]; ok {
+		t.Errorf("default size should not send output_dimension")
+	}
+
+	server, body, _ = embeddingServer(t, 2048)
+	large, err := processor.NewVoyageEmbedder(processor.EmbeddingConfig{
+		APIKey: "pa-test", URL: server.URL, Model: "voyage-3-large", Dimensions: 2048,
+	})
+	if err != nil {
+		t.Fatalf("NewVoyageEmbedder failed: %v", err)
+	}
+	if _, err := large.GenerateEmbedding(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateEmbedding failed: %v", err)
+	}
+	if (*body)["output_dimension"] != float64(2048) {
+		t.Errorf("output_dimension = %v, want 2048", (*body)["output_dimension"])
+	}
+
+	// Sizes the model cannot produce are rejected up front
+	for _, cfg := range []processor.EmbeddingConfig{
+		{APIKey: "pa-test", Model: "voyage-3", Dimensions: 2048},
+		{APIKey: "pa-test", Dimensions: 512},
+		{APIKey: "pa-test", Model: "voyage-3-large", Dimensions: 768},
+	} {
+		if _, err := processor.NewVoyageEmbedder(cfg); err == nil {
+			t.Errorf("NewVoyageEmbedder(model=%q, dimensions=%d) should fail", cfg.Model, cfg.Dimensions)
+		}
+	}
+
+	server, body, _ = embeddingServer(t, 512)
+	lite, err := processor.NewVoyageEmbedder(processor.EmbeddingConfig{
+		APIKey: "pa-test", URL: server.URL, Model: "voyage-3-lite", Dimensions: 512,
+	})
+	if err != nil {
+		t.Fatalf("NewVoyageEmbedder failed: %v", err)
+	}
+	if _, err := lite.GenerateEmbedding(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateEmbedding failed: %v", err)
+	}
+	if _, ok := (*body)["output_dimension"]; ok {
+		t.Errorf("fixed-size model should not send output_dimension")
+	}
+
+	if _, err := processor.NewEmbedder(processor.EmbeddingConfig{Provider: "cohere"}); err == nil {
+		t.Errorf("NewEmbedder with an unknown provider should fail")
+	}
+}
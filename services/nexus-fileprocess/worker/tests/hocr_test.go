@@ -0,0 +1,112 @@
+/**
+ * hOCR Parsing Tests
+ *
+ * Validates word boxes, confidences and line/block grouping from Tesseract
+ * hOCR, and layout regions built from that geometry.
+ */
+
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+const tesseractHOCR = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="en" lang="en">
+ <head><title></title><meta name='ocr-system' content='tesseract 5.3.0' /></head>
+ <body>
+  <div class='ocr_page' id='page_1' title='image "unknown"; bbox 0 0 2480 3508; ppageno 0; scan_res 300 300'>
+   <div class='ocr_carea' id='block_1_1' title="bbox 200 150 900 260">
+    <p class='ocr_par' id='par_1_1' lang='eng' title="bbox 200 150 900 260">
+     <span class='ocr_header' id='line_1_1' title="bbox 200 150 900 200; baseline 0 -8; x_size 50">
+      <span class='ocrx_word' id='word_1_1' title='bbox 200 150 500 200; x_wconf 96'><strong>Invoice</strong></span>
+      <span class='ocrx_word' id='word_1_2' title='bbox 520 150 900 200; x_wconf 90'>#1042</span>
+     </span>
+    </p>
+   </div>
+   <div class='ocr_carea' id='block_1_2' title="bbox 200 400 1800 560">
+    <p class='ocr_par' id='par_1_2' lang='eng' title="bbox 200 400 1800 560">
+     <span class='ocr_line' id='line_1_2' title="bbox 200 400 1800 450; baseline 0 -10">
+      <span class='ocrx_word' id='word_1_3' title='bbox 200 400 400 450; x_wconf 80'>Total</span>
+      <span class='ocrx_word' id='word_1_4' title='bbox 420 400 600 450; x_wconf 70'>due:</span>
+     </span>
+     <span class='ocr_line' id='line_1_3' title="bbox 200 510 1500 560; baseline 0 -10">
+      <span class='ocrx_word' id='word_1_5' title='bbox 200 510 500 560; x_wconf 60'>R&amp;D</span>
+      <span class='ocrx_word' id='word_1_6' title='bbox 520 510 540 560; x_wconf 95'> </span>
+     </span>
+    </p>
+   </div>
+  </div>
+ </body>
+</html>`
+
+// TestParseHOCR tests words, boxes, confidences, lines and page text
+func TestParseHOCR(t *testing.T) {
+	page, err := processor.ParseHOCR(tesseractHOCR)
+	if err != nil {
+		t.Fatalf("ParseHOCR failed: %v", err)
+	}
+
+	if page.Width != 2480 || page.Height != 3508 {
+		t.Errorf("page size = %dx%d, want 2480x3508", page.Width, page.Height)
+	}
+	if page.Text != "Invoice #1042\n\nTotal due:\nR&D" {
+		t.Errorf("text = %q", page.Text)
+	}
+
+	// Empty words are dropped
+	if len(page.Words) != 5 {
+		t.Fatalf("expected 5 words, got %d: %+v", len(page.Words), page.Words)
+	}
+	word := page.Words[1]
+	if word.Text != "#1042" || word.Confidence != 0.9 {
+		t.Errorf("word = %+v", word)
+	}
+	if box := word.BoundingBox; box.X != 520 || box.Y != 150 || box.Width != 380 || box.Height != 50 {
+		t.Errorf("word box = %+v", box)
+	}
+
+	// Mean of 96, 90, 80, 70 and 60
+	if page.Confidence < 0.7919 || page.Confidence > 0.7921 {
+		t.Errorf("page confidence = %v, want 0.792", page.Confidence)
+	}
+
+	if len(page.Lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(page.Lines))
+	}
+	if page.Lines[0].Block != 1 || page.Lines[1].Block != 2 || page.Lines[2].Block != 2 {
+		t.Errorf("line blocks = %d, %d, %d", page.Lines[0].Block, page.Lines[1].Block, page.Lines[2].Block)
+	}
+	if page.Lines[1].Confidence < 0.7499 || page.Lines[1].Confidence > 0.7501 {
+		t.Errorf("line confidence = %v, want 0.75", page.Lines[1].Confidence)
+	}
+}
+
+// TestLayoutRegionsFromGeometry tests that heuristic layout uses OCR line geometry
+func TestLayoutRegionsFromGeometry(t *testing.T) {
+	page, err := processor.ParseHOCR(tesseractHOCR)
+	if err != nil {
+		t.Fatalf("ParseHOCR failed: %v", err)
+	}
+
+	analyzer := processor.NewLayoutAnalyzer(nil, false)
+	layout, err := analyzer.Analyze(context.Background(), &processor.OCRResult{Text: page.Text, Pages: []processor.OCRPage{*page}})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if len(layout.Regions) != 2 {
+		t.Fatalf("expected one region per block, got %d", len(layout.Regions))
+	}
+	region := layout.Regions[1]
+	if region.Content != "Total due:\nR&D" || region.PageNumber != 1 {
+		t.Errorf("region = %+v", region)
+	}
+	if box := region.BoundingBox; box.X != 200 || box.Y != 400 || box.Width != 1600 || box.Height != 160 {
+		t.Errorf("region box = %+v, want the union of its lines", box)
+	}
+}
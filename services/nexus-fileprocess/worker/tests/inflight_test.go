@@ -0,0 +1,104 @@
+/**
+ * In-Flight Recovery Tests
+ *
+ * Validates at-least-once delivery of the LIST backend against miniredis:
+ * - Jobs of a worker with a live heartbeat are left alone
+ * - Once its heartbeat is gone, competing reapers requeue them exactly once
+ * - A restarted worker recovers what its previous run left in flight
+ */
+
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
+)
+
+// TestReaperRequeuesDeadWorkerJobs tests that a dead worker's in-flight job is requeued exactly once
+func TestReaperRequeuesDeadWorkerJobs(t *testing.T) {
+	server, client := startMiniredis(t)
+	proc := newFakeProcessor()
+	ctx := context.Background()
+
+	// A worker that took a job and is still heartbeating
+	job := newTestJob("job-orphaned", 3)
+	storeJob(t, client, job)
+	client.SAdd(ctx, testQueue+":workers", "worker-dead")
+	client.Set(ctx, testQueue+":heartbeat:worker-dead", time.Now().Unix(), time.Minute)
+	client.RPush(ctx, testQueue+":inflight:worker-dead", job.ID)
+
+	// Two live workers reap concurrently
+	for _, workerID := range []string{"worker-a", "worker-b"} {
+		consumer, err := queue.NewRedisConsumer(redisConsumerConfig(server, proc, workerID))
+		if err != nil {
+			t.Fatalf("NewRedisConsumer(%s) failed: %v", workerID, err)
+		}
+		startBackend(t, consumer)
+	}
+
+	// Several reaper passes while the heartbeat is alive
+	time.Sleep(300 * time.Millisecond)
+	if got := proc.callCount(job.Payload.JobID); got != 0 {
+		t.Fatalf("job of a live worker processed %d times, want 0", got)
+	}
+	if n := client.LLen(ctx, testQueue+":inflight:worker-dead").Val(); n != 1 {
+		t.Fatalf("live worker has %d job(s) in flight, want 1", n)
+	}
+
+	// Kill the heartbeat
+	client.Del(ctx, testQueue+":heartbeat:worker-dead")
+
+	eventually(t, 5*time.Second, "the orphaned job to complete", func() bool {
+		return isMember(t, client, "completed", job.Payload.JobID)
+	})
+
+	// Further reaper passes must not bring it back
+	time.Sleep(300 * time.Millisecond)
+	if got := proc.callCount(job.Payload.JobID); got != 1 {
+		t.Errorf("orphaned job processed %d times, want 1", got)
+	}
+	if n := client.LLen(ctx, testQueue+":inflight:worker-dead").Val(); n != 0 {
+		t.Errorf("dead worker still has %d job(s) in flight, want 0", n)
+	}
+	if client.SIsMember(ctx, testQueue+":workers", "worker-dead").Val() {
+		t.Error("dead worker is still registered after its in-flight list was drained")
+	}
+}
+
+// TestRecoverOwnInflight tests that a restarted worker requeues what its previous run left in flight
+func TestRecoverOwnInflight(t *testing.T) {
+	server, client := startMiniredis(t)
+	proc := newFakeProcessor()
+	ctx := context.Background()
+
+	// The previous run of worker-a crashed mid-job; its heartbeat is long gone
+	job := newTestJob("job-crashed", 3)
+	storeJob(t, client, job)
+	client.SAdd(ctx, testQueue+":workers", "worker-a")
+	client.RPush(ctx, testQueue+":inflight:worker-a", job.ID)
+
+	consumer, err := queue.NewRedisConsumer(redisConsumerConfig(server, proc, "worker-a"))
+	if err != nil {
+		t.Fatalf("NewRedisConsumer() failed: %v", err)
+	}
+	stop := startBackend(t, consumer)
+
+	eventually(t, 5*time.Second, "the recovered job to complete", func() bool {
+		return isMember(t, client, "completed", job.Payload.JobID)
+	})
+	time.Sleep(300 * time.Millisecond)
+	stop()
+
+	if got := proc.callCount(job.Payload.JobID); got != 1 {
+		t.Errorf("recovered job processed %d times, want 1", got)
+	}
+	if n := client.LLen(ctx, testQueue+":inflight:worker-a").Val(); n != 0 {
+		t.Errorf("%d job(s) left in flight after a clean stop, want 0", n)
+	}
+	if client.SIsMember(ctx, testQueue+":workers", "worker-a").Val() {
+		t.Error("worker is still registered after a clean stop")
+	}
+}
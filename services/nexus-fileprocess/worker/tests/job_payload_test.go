@@ -0,0 +1,126 @@
+/**
+ * Job Payload Tests
+ *
+ * Validates decoding of inline file buffers (base64 and Node.js Buffer JSON)
+ * and blob references, plus the local blob store used for claim-check payloads.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
+	"github.com/adverant/nexus/fileprocess-worker/internal/storage"
+)
+
+// TestJobPayloadFileBuffer tests every supported fileBuffer encoding
+func TestJobPayloadFileBuffer(t *testing.T) {
+	testCases := []struct {
+		name    string
+		json    string
+		want    []byte
+		wantErr bool
+	}{
+		{"base64", `{"jobId":"j1","fileBuffer":"aGk="}`, []byte("hi"), false},
+		{"node buffer", `{"jobId":"j1","fileBuffer":{"type":"Buffer","data":[104, 105,0,255]}}`, []byte{104, 105, 0, 255}, false},
+		{"empty node buffer", `{"jobId":"j1","fileBuffer":{"type":"Buffer","data":[]}}`, []byte{}, false},
+		{"absent", `{"jobId":"j1"}`, nil, false},
+		{"byte out of range", `{"jobId":"j1","fileBuffer":{"type":"Buffer","data":[256]}}`, nil, true},
+		{"negative byte", `{"jobId":"j1","fileBuffer":{"type":"Buffer","data":[-1]}}`, nil, true},
+		{"split digits", `{"jobId":"j1","fileBuffer":{"type":"Buffer","data":[1 2]}}`, nil, true},
+		{"trailing comma", `{"jobId":"j1","fileBuffer":{"type":"Buffer","data":[1,]}}`, nil, true},
+		{"wrong type", `{"jobId":"j1","fileBuffer":{"type":"Blob","data":[1]}}`, nil, true},
+		{"number", `{"jobId":"j1","fileBuffer":12}`, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var payload queue.JobPayload
+			err := json.Unmarshal([]byte(tc.json), &payload)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got buffer %v", payload.FileBuffer)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(payload.FileBuffer, tc.want) {
+				t.Errorf("FileBuffer = %v, want %v", payload.FileBuffer, tc.want)
+			}
+		})
+	}
+}
+
+// TestJobPayloadBlobRef tests that blob references survive a re-save round trip
+func TestJobPayloadBlobRef(t *testing.T) {
+	job := queue.RedisJobData{ID: "q1", Payload: queue.JobPayload{JobID: "j1", BlobRef: "uploads/j1.pdf"}}
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	var decoded queue.RedisJobData
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if decoded.Payload.BlobRef != "uploads/j1.pdf" || len(decoded.Payload.FileBuffer) != 0 {
+		t.Errorf("round trip lost blob reference: %+v", decoded.Payload)
+	}
+}
+
+// TestLocalBlobStore tests put, open, delete and key confinement of the local store
+func TestLocalBlobStore(t *testing.T) {
+	ctx := context.Background()
+	root := filepath.Join(t.TempDir(), "blobs")
+	store, err := storage.NewLocalBlobStore(root)
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore failed: %v", err)
+	}
+
+	content := []byte("%PDF-1.7 claim check")
+	if err := store.Put(ctx, "jobs/j1/payload", bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	blob, size, err := store.Open(ctx, "jobs/j1/payload")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	got, _ := io.ReadAll(blob)
+	blob.Close()
+	if size != int64(len(content)) || !bytes.Equal(got, content) {
+		t.Errorf("Open returned %q (size %d), want %q", got, size, content)
+	}
+
+	// Keys are confined to the root: "../escape" is stored as <root>/escape
+	if err := store.Put(ctx, "../escape", bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Put of ../escape failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(root), "escape")); !os.IsNotExist(err) {
+		t.Errorf("Put escaped the store root")
+	}
+	if _, _, err := store.Open(ctx, "escape"); err != nil {
+		t.Errorf("Open(escape) = %v, want the confined blob", err)
+	}
+
+	if err := store.Delete(ctx, "jobs/j1/payload"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, _, err := store.Open(ctx, "jobs/j1/payload"); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("Open after Delete = %v, want ErrBlobNotFound", err)
+	}
+	if err := store.Delete(ctx, "jobs/j1/payload"); err != nil {
+		t.Errorf("Delete of missing blob = %v, want nil", err)
+	}
+}
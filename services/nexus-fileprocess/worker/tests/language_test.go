@@ -0,0 +1,68 @@
+/**
+ * OCR Language Tests
+ *
+ * Validates metadata.language parsing, Tesseract language names and
+ * script/stopword language detection.
+ */
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+// TestParseLanguages tests ISO 639-1, Tesseract and regional codes, lists and auto
+func TestParseLanguages(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{"de", "[de]"},
+		{"DE-at", "[de]"},
+		{"de,en", "[de en]"},
+		{"deu+eng", "[de en]"},
+		{[]interface{}{"ja", "jpn", "en", 42}, "[ja en]"},
+		{"auto", "[]"},
+		{"", "[]"},
+		{nil, "[]"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprint(processor.ParseLanguages(tt.value)); got != tt.want {
+			t.Errorf("ParseLanguages(%#v) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	if got := processor.TesseractLanguage([]string{"ja", "en", "zh"}); got != "jpn+eng+chi_sim" {
+		t.Errorf("TesseractLanguage = %q", got)
+	}
+}
+
+// TestDetectLanguages tests script detection, Latin stopwords and mixed-language text
+func TestDetectLanguages(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "The invoice is due on receipt and must be paid to the account of the supplier.", "[en]"},
+		{"german", "Die Rechnung ist bei Erhalt fällig und muss auf das Konto des Lieferanten überwiesen werden.", "[de]"},
+		{"french", "La facture est payable à réception et doit être versée sur le compte du fournisseur.", "[fr]"},
+		{"japanese", "請求書は受領時に支払われるものとし、仕入先の口座に振り込んでください。", "[ja]"},
+		{"chinese", "发票应在收到时支付，并须汇入供应商的账户。请在三十天内完成付款。", "[zh]"},
+		{"arabic", "يجب دفع الفاتورة عند الاستلام وتحويلها إلى حساب المورد", "[ar]"},
+		{"japanese with english", "請求書は受領時に支払われるものとし、仕入先の口座に振り込んでください。Invoice due on receipt.", "[ja en]"},
+		{"too short", "Total: 42", "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmt.Sprint(processor.DetectLanguages(tt.text)); got != tt.want {
+				t.Errorf("DetectLanguages = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
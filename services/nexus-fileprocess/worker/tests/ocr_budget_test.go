@@ -0,0 +1,225 @@
+/**
+ * OCR Budget Tests
+ *
+ * Validates per-page pricing of paid OCR tiers, budget limits and
+ * reservations of OCR spend by concurrent jobs.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+	"github.com/adverant/nexus/fileprocess-worker/internal/storage"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// TestOCRPriceTable tests model, model family and tier prices
+func TestOCRPriceTable(t *testing.T) {
+	prices := processor.OCRPriceTable{
+		"tier2":                   0.02,
+		"tier3":                   0.08,
+		"gpt-4o":                  0.015,
+		"gpt-4o-mini":             0.003,
+		"anthropic/claude-3-opus": 0.1,
+	}
+
+	tests := []struct {
+		tier, model string
+		want        float64
+	}{
+		{"tier2", "gpt-4o", 0.015},
+		{"tier2", "openai/gpt-4o-mini-2024-07-18", 0.003}, // Longest contained key
+		{"tier3", "anthropic/claude-3-opus", 0.1},
+		{"tier3", "claude-3.5-sonnet", 0.08}, // Unknown model: tier price
+		{"tier2", "", 0.02},
+		{"tesseract", "tesseract-local", 0},
+	}
+	for _, tt := range tests {
+		if got := prices.PageCost(tt.tier, tt.model); got != tt.want {
+			t.Errorf("PageCost(%s, %s) = %v, want %v", tt.tier, tt.model, got, tt.want)
+		}
+	}
+}
+
+// TestOCRBudget tests limits, spend from earlier attempts and budgets without a limit
+func TestOCRBudget(t *testing.T) {
+	var unlimited *processor.OCRBudget
+	if !unlimited.Allows(100) || !(&processor.OCRBudget{SpentUSD: 5}).Allows(100) {
+		t.Errorf("a budget without a limit should allow any spend")
+	}
+
+	budget := &processor.OCRBudget{LimitUSD: 0.10, Source: "maxCostUSD", SpentUSD: 0.02}
+	if !budget.Allows(0.08) {
+		t.Errorf("spend up to the limit should be allowed")
+	}
+	if budget.Allows(0.081) {
+		t.Errorf("spend past the limit should be refused")
+	}
+	if budget.String() != "$0.0200 of $0.1000 spent (maxCostUSD)" {
+		t.Errorf("String = %q", budget.String())
+	}
+
+	// A zero budget still allows free tiers
+	if zero := (&processor.OCRBudget{Source: "daily budget"}); !zero.Allows(0) || zero.Allows(0.001) {
+		t.Errorf("zero budget should allow only free OCR")
+	}
+
+	overspent := storage.OCRBudgetStatus{Period: storage.BudgetPeriodDaily, LimitUSD: 1, SpentUSD: 1.25}
+	if overspent.RemainingUSD() != 0 {
+		t.Errorf("RemainingUSD = %v, want 0", overspent.RemainingUSD())
+	}
+}
+
+// TestFileProcessEstimate tests that /file-process is priced for every page of the PDF it is sent
+func TestFileProcessEstimate(t *testing.T) {
+	prices := processor.DefaultOCRPrices()
+	budget := &processor.OCRBudget{LimitUSD: 0.05, Source: "maxCostUSD"}
+	perPage := prices.PageCost(processor.OCRTierFileProcess, "")
+
+	// Text layer parsed: one of three pages needs OCR, but all three are charged
+	partial := buildPDF(t, "BT /F1 12 Tf 72 720 Td (Invoice number 1042) Tj ET", "", "BT /F1 12 Tf 72 720 Td (Total due 310 EUR) Tj ET")
+	layer, err := processor.ExtractPDFTextLayer(partial)
+	if err != nil {
+		t.Fatalf("ExtractPDFTextLayer failed: %v", err)
+	}
+	if len(layer.NeedsOCR) != 1 {
+		t.Fatalf("NeedsOCR = %v, want one page", layer.NeedsOCR)
+	}
+	estimate, err := prices.FileProcessEstimate(len(layer.Pages), budget)
+	if err != nil || estimate != 3*perPage {
+		t.Errorf("partial text layer estimate = %v, %v; want %v", estimate, err, 3*perPage)
+	}
+	if budget.Allows(estimate) {
+		t.Errorf("budget %s should refuse %v for three pages", budget, estimate)
+	}
+
+	// Text layer unreadable: the page objects are still counted
+	unreadable := partial[:bytes.Index(partial, []byte("xref"))]
+	if _, err := processor.ExtractPDFTextLayer(unreadable); err == nil {
+		t.Fatalf("ExtractPDFTextLayer of a PDF without xref succeeded")
+	}
+	if pages := processor.PDFPageCount(unreadable); pages != 3 {
+		t.Errorf("PDFPageCount = %d, want 3", pages)
+	}
+	if pages := processor.PDFPageCount(partial); pages != 3 {
+		t.Errorf("PDFPageCount of a readable PDF = %d, want 3", pages)
+	}
+	estimate, err = prices.FileProcessEstimate(processor.PDFPageCount(unreadable), budget)
+	if err != nil || estimate != 3*perPage {
+		t.Errorf("unreadable text layer estimate = %v, %v; want %v", estimate, err, 3*perPage)
+	}
+
+	// No page count: refused under a budget, one page without
+	garbage := []byte("%PDF-1.4\ngarbage")
+	if pages := processor.PDFPageCount(garbage); pages != 0 {
+		t.Errorf("PDFPageCount of garbage = %d, want 0", pages)
+	}
+	if _, err := prices.FileProcessEstimate(0, budget); err == nil {
+		t.Errorf("estimate of an unknown page count under a budget succeeded, want an error")
+	}
+	if estimate, err := prices.FileProcessEstimate(0, nil); err != nil || estimate != perPage {
+		t.Errorf("estimate of an unknown page count without a budget = %v, %v; want %v", estimate, err, perPage)
+	}
+}
+
+// TestReserveOCRSpend tests that concurrent jobs of a user cannot reserve past its budget.
+// Requires DATABASE_URL (migrated) and QDRANT_URL.
+func TestReserveOCRSpend(t *testing.T) {
+	databaseURL := os.Getenv("DATABASE_URL")
+	qdrantURL := os.Getenv("QDRANT_URL")
+	if databaseURL == "" || qdrantURL == "" {
+		t.Skip("DATABASE_URL and QDRANT_URL not set, skipping OCR reservation test")
+	}
+
+	sm, err := storage.NewStorageManager(databaseURL, qdrantURL, "fileprocess_test_budgets", 1024)
+	if err != nil {
+		t.Fatalf("NewStorageManager failed: %v", err)
+	}
+	defer sm.Close()
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		t.Fatalf("sql.Open failed: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := "budget-test-" + uuid.NewString()
+	if _, err := db.ExecContext(ctx, `INSERT INTO fileprocess.ocr_budgets (user_id, period, limit_usd) VALUES ($1, 'daily', 0.10)`, userID); err != nil {
+		t.Fatalf("failed to create budget: %v", err)
+	}
+	defer db.ExecContext(ctx, `DELETE FROM fileprocess.processing_jobs WHERE user_id = $1`, userID)
+	defer db.ExecContext(ctx, `DELETE FROM fileprocess.ocr_budgets WHERE user_id = $1`, userID)
+
+	jobs := make([]string, 8)
+	for i := range jobs {
+		jobs[i] = uuid.NewString()
+		if err := sm.UpdateJobStatus(ctx, &storage.JobUpdate{
+			JobID:    jobs[i],
+			Status:   "processing",
+			Metadata: map[string]interface{}{"userId": userID},
+		}); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+	}
+
+	// $0.03 pages from 8 jobs against $0.10: only 3 fit
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var reserved []string
+	for _, jobID := range jobs {
+		wg.Add(1)
+		go func(jobID string) {
+			defer wg.Done()
+			ok, err := sm.ReserveOCRSpend(ctx, jobID, userID, 0.03)
+			if err != nil {
+				t.Errorf("ReserveOCRSpend failed: %v", err)
+				return
+			}
+			if ok {
+				mu.Lock()
+				reserved = append(reserved, jobID)
+				mu.Unlock()
+			}
+		}(jobID)
+	}
+	wg.Wait()
+	if len(reserved) != 3 {
+		t.Fatalf("%d reservations granted, want 3", len(reserved))
+	}
+
+	// Settling at a lower cost frees the difference
+	if err := sm.AddJobOCRCost(ctx, reserved[0], 0.01, 0.03); err != nil {
+		t.Fatalf("AddJobOCRCost failed: %v", err)
+	}
+	spend, err := sm.GetOCRSpend(ctx, reserved[0], userID)
+	if err != nil {
+		t.Fatalf("GetOCRSpend failed: %v", err)
+	}
+	if len(spend.Budgets) != 1 || spend.Budgets[0].SpentUSD < 0.0699 || spend.Budgets[0].SpentUSD > 0.0701 {
+		t.Errorf("budgets = %+v, want $0.07 spent or reserved", spend.Budgets)
+	}
+	if ok, err := sm.ReserveOCRSpend(ctx, jobs[0], userID, 0.03); err != nil || !ok {
+		t.Errorf("reservation after settling = %v, %v; want granted", ok, err)
+	}
+
+	// Budget spent or reserved in full
+	if ok, err := sm.ReserveOCRSpend(ctx, jobs[1], userID, 0.001); err != nil || ok {
+		t.Errorf("reservation past the budget = %v, %v; want refused", ok, err)
+	}
+
+	// Reservations an attempt never settled are dropped before the next one
+	if err := sm.ReleaseOCRReservations(ctx, reserved[1]); err != nil {
+		t.Fatalf("ReleaseOCRReservations failed: %v", err)
+	}
+	if ok, err := sm.ReserveOCRSpend(ctx, jobs[1], userID, 0.03); err != nil || !ok {
+		t.Errorf("reservation after release = %v, %v; want granted", ok, err)
+	}
+}
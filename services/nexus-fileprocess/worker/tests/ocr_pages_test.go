@@ -0,0 +1,191 @@
+/**
+ * OCR Pages Tests
+ *
+ * Validates multi-page TIFF splitting, PDF page rendering and aggregation of
+ * per-page cascade results.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+// buildTIFF writes an uncompressed 2x2 grayscale TIFF with one IFD per value.
+// A negative value adds a reduced-resolution (thumbnail) IFD instead of a page.
+func buildTIFF(t *testing.T, values ...int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	buf.WriteString("II*\x00")
+	binary.Write(&buf, binary.LittleEndian, uint32(0)) // Patched to the first IFD
+
+	nextPointer := 4
+	for _, value := range values {
+		subfile := uint32(0)
+		if value < 0 {
+			subfile, value = 1, -value
+		}
+
+		pixels := buf.Len()
+		buf.Write(bytes.Repeat([]byte{byte(value)}, 4))
+		if buf.Len()%2 == 1 {
+			buf.WriteByte(0)
+		}
+
+		ifd := buf.Len()
+		binary.LittleEndian.PutUint32(buf.Bytes()[nextPointer:], uint32(ifd))
+		entries := [][3]uint32{
+			{254, 4, subfile},        // NewSubfileType
+			{256, 3, 2},              // ImageWidth
+			{257, 3, 2},              // ImageLength
+			{258, 3, 8},              // BitsPerSample
+			{259, 3, 1},              // Compression: none
+			{262, 3, 1},              // PhotometricInterpretation: black is zero
+			{273, 4, uint32(pixels)}, // StripOffsets
+			{278, 3, 2},              // RowsPerStrip
+			{279, 4, 4},              // StripByteCounts
+		}
+		binary.Write(&buf, binary.LittleEndian, uint16(len(entries)))
+		for _, entry := range entries {
+			binary.Write(&buf, binary.LittleEndian, uint16(entry[0]))
+			binary.Write(&buf, binary.LittleEndian, uint16(entry[1]))
+			binary.Write(&buf, binary.LittleEndian, uint32(1))
+			binary.Write(&buf, binary.LittleEndian, entry[2])
+		}
+		nextPointer = buf.Len()
+		binary.Write(&buf, binary.LittleEndian, uint32(0))
+	}
+	return buf.Bytes()
+}
+
+// TestEachTIFFPage tests that every page is decoded on its own and thumbnails are skipped
+func TestEachTIFFPage(t *testing.T) {
+	data := buildTIFF(t, 10, -99, 20, 30)
+
+	if count := processor.TIFFPageCount(data); count != 3 {
+		t.Fatalf("TIFFPageCount = %d, want 3", count)
+	}
+	if count := processor.TIFFPageCount([]byte("\x89PNG\r\n\x1a\n")); count != 0 {
+		t.Errorf("TIFFPageCount(PNG) = %d, want 0", count)
+	}
+
+	var got []int
+	err := processor.EachTIFFPage(data, func(page int, image []byte) error {
+		img, err := png.Decode(bytes.NewReader(image))
+		if err != nil {
+			t.Fatalf("page %d is not a PNG: %v", page, err)
+		}
+		r, _, _, _ := img.At(1, 1).RGBA()
+		got = append(got, page*1000+int(r>>8))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachTIFFPage failed: %v", err)
+	}
+
+	want := []int{1010, 2020, 3030}
+	if len(got) != len(want) {
+		t.Fatalf("pages = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("page %d = %d, want %d", i+1, got[i], want[i])
+		}
+	}
+}
+
+// TestAggregateOCRPages tests per-page tiers, summed cost and the overall tier
+func TestAggregateOCRPages(t *testing.T) {
+	page := func(number int, tier string, confidence, cost float64) *processor.OCRResult {
+		return &processor.OCRResult{
+			TierUsed:   tier,
+			Confidence: confidence,
+			Cost:       cost,
+			Pages:      []processor.OCRPage{{PageNumber: number, Text: tier, Confidence: confidence}},
+		}
+	}
+
+	result := processor.AggregateOCRPages([]*processor.OCRResult{
+		page(1, "tesseract", 0.9, 0),
+		page(2, "tier3_claude", 0.6, 0.08),
+		page(3, "tier2_gpt-4o", 0.9, 0.02),
+	})
+
+	if len(result.Pages) != 3 || result.Pages[1].TierUsed != "tier3_claude" || result.Pages[0].TierUsed != "tesseract" {
+		t.Fatalf("pages = %+v", result.Pages)
+	}
+	if result.TierUsed != "tier3_claude" {
+		t.Errorf("tier = %q, want the most expensive page tier", result.TierUsed)
+	}
+	if result.Cost < 0.0999 || result.Cost > 0.1001 {
+		t.Errorf("cost = %v, want 0.10", result.Cost)
+	}
+	if result.Confidence < 0.7999 || result.Confidence > 0.8001 {
+		t.Errorf("confidence = %v, want 0.80", result.Confidence)
+	}
+	if result.Text != "tesseract\n\ntier3_claude\n\ntier2_gpt-4o" {
+		t.Errorf("text = %q", result.Text)
+	}
+}
+
+// fakePDFToPPM writes a pdftoppm stand-in that renders pages 1-11 as text "page N"
+// when called without -f, or the requested page otherwise
+const fakePDFToPPM = `#!/bin/sh
+first=""
+for arg; do
+	case "$prev" in -f) first="$arg" ;; esac
+	prev="$arg"
+	prefix="$arg"
+done
+if [ -n "$first" ]; then
+	printf "page %s" "$first" > "$prefix.png"
+	exit 0
+fi
+for n in 1 2 3 4 5 6 7 8 9 10 11; do
+	printf "page %s" "$n" > "$(printf "%s-%02d.png" "$prefix" "$n")"
+done
+`
+
+// TestPDFRasterizerRenderAll tests that nil pages renders every page in page order
+func TestPDFRasterizerRenderAll(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "pdftoppm")
+	if err := os.WriteFile(script, []byte(fakePDFToPPM), 0o755); err != nil {
+		t.Fatalf("failed to write fake pdftoppm: %v", err)
+	}
+	rasterizer := processor.NewPDFRasterizer(script, dir)
+	if rasterizer == nil {
+		t.Skip("cannot run the fake pdftoppm")
+	}
+
+	var rendered []string
+	collect := func(page int, image []byte) error {
+		rendered = append(rendered, fmt.Sprintf("%d=%s", page, image))
+		return nil
+	}
+
+	if err := rasterizer.Render(context.Background(), []byte("%PDF-1.4"), nil, collect); err != nil {
+		t.Fatalf("Render(all) failed: %v", err)
+	}
+	if len(rendered) != 11 || rendered[0] != "1=page 1" || rendered[9] != "10=page 10" || rendered[10] != "11=page 11" {
+		t.Errorf("rendered = %v, want pages 1-11 in order", rendered)
+	}
+
+	rendered = nil
+	if err := rasterizer.Render(context.Background(), []byte("%PDF-1.4"), []int{3}, collect); err != nil {
+		t.Fatalf("Render(page 3) failed: %v", err)
+	}
+	if len(rendered) != 1 || rendered[0] != "3=page 3" {
+		t.Errorf("rendered = %v, want page 3 only", rendered)
+	}
+}
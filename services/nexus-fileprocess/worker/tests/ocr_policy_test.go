@@ -0,0 +1,119 @@
+/**
+ * OCR Policy Tests
+ *
+ * Validates metadata.ocrPolicy overrides, preferAccuracy, policy validation
+ * and whether a policy permits vision OCR.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+// ocrPolicyOverride decodes a metadata.ocrPolicy value the way job payloads arrive
+func ocrPolicyOverride(t *testing.T, raw string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		t.Fatalf("invalid override %s: %v", raw, err)
+	}
+	return value
+}
+
+// TestOCRPolicyOverrides tests modes, tier lists, thresholds and the max tier from job metadata
+func TestOCRPolicyOverrides(t *testing.T) {
+	base := processor.DefaultOCRPolicy()
+	if base.String() != "tesseract≥0.85 → tier2≥0.90 → tier3" {
+		t.Errorf("default policy = %s", base)
+	}
+
+	tests := []struct {
+		name     string
+		override string
+		want     string
+	}{
+		{"local only", `{"mode": "local_only"}`, "tesseract≥0.85"},
+		{"vision only", `{"mode": "vision_only"}`, "tier2≥0.90 → tier3"},
+		{"tier order", `{"tiers": ["tier2", "tesseract"]}`, "tier2≥0.90 → tesseract≥0.85"},
+		{"thresholds", `{"thresholds": {"tesseract": 0.7, "tier2": 0.95}}`, "tesseract≥0.70 → tier2≥0.95 → tier3"},
+		{"max tier", `{"maxTier": "tier2"}`, "tesseract≥0.85 → tier2≥0.90"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := base.WithOverrides(ocrPolicyOverride(t, tt.override))
+			if err != nil {
+				t.Fatalf("WithOverrides failed: %v", err)
+			}
+			if policy.String() != tt.want {
+				t.Errorf("policy = %s, want %s", policy, tt.want)
+			}
+		})
+	}
+
+	// Overrides never change the configured policy
+	if base.Thresholds[processor.OCRTierTesseract] != 0.85 || len(base.Tiers) != 3 {
+		t.Errorf("base policy modified: %+v", base)
+	}
+
+	for _, invalid := range []string{
+		`{"mode": "cheap"}`,
+		`{"tiers": ["tier4"]}`,
+		`{"tiers": []}`,
+		`{"thresholds": {"tier2": 1.5}}`,
+		`{"maxTier": 3}`,
+		`"local_only"`,
+	} {
+		if _, err := base.WithOverrides(ocrPolicyOverride(t, invalid)); err == nil {
+			t.Errorf("WithOverrides(%s) should fail", invalid)
+		}
+	}
+}
+
+// TestOCRPolicyPreferringAccuracy tests that preferAccuracy skips the balanced tier unless the job chose its tiers
+func TestOCRPolicyPreferringAccuracy(t *testing.T) {
+	policy := processor.DefaultOCRPolicy().PreferringAccuracy()
+	if fmt.Sprint(policy.Tiers) != "[tesseract tier3]" {
+		t.Errorf("tiers = %v, want [tesseract tier3]", policy.Tiers)
+	}
+
+	chosen, err := processor.DefaultOCRPolicy().WithOverrides(ocrPolicyOverride(t, `{"mode": "vision_only"}`))
+	if err != nil {
+		t.Fatalf("WithOverrides failed: %v", err)
+	}
+	if tiers := chosen.PreferringAccuracy().Tiers; fmt.Sprint(tiers) != "[tier2 tier3]" {
+		t.Errorf("job-chosen tiers = %v, want [tier2 tier3]", tiers)
+	}
+}
+
+// TestOCRPolicyAllowsVision tests whether a policy permits MageAgent vision OCR (and /file-process)
+func TestOCRPolicyAllowsVision(t *testing.T) {
+	tests := []struct {
+		name     string
+		override string
+		want     bool
+	}{
+		{"default", `{}`, true},
+		{"local only", `{"mode": "local_only"}`, false},
+		{"vision only", `{"mode": "vision_only"}`, true},
+		{"max tier tesseract", `{"maxTier": "tesseract"}`, false},
+		{"max tier below listed tiers", `{"tiers": ["tier3"], "maxTier": "tier2"}`, false},
+	}
+	for _, tt := range tests {
+		policy, err := processor.DefaultOCRPolicy().WithOverrides(ocrPolicyOverride(t, tt.override))
+		if err != nil {
+			t.Fatalf("%s: WithOverrides failed: %v", tt.name, err)
+		}
+		if got := policy.AllowsVision(); got != tt.want {
+			t.Errorf("%s: AllowsVision() = %v, want %v (policy %s)", tt.name, got, tt.want, policy)
+		}
+	}
+
+	if processor.DefaultOCRPolicy().LocalOnly().AllowsVision() {
+		t.Error("local-only worker policy allows vision")
+	}
+}
@@ -0,0 +1,137 @@
+/**
+ * PDF Text Layer Tests
+ *
+ * Validates per-page text and word boxes from the embedded text layer, and
+ * escalation of empty or garbled pages to OCR.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+// buildPDF writes a PDF with one page per content stream. Pages use F1, a
+// Helvetica with widths, and F2, a composite font whose ToUnicode CMap only
+// maps code 1 to "A".
+func buildPDF(t *testing.T, contents ...string) []byte {
+	t.Helper()
+
+	widths := strings.TrimSpace(strings.Repeat("500 ", 95))
+	cmap := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+		"1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"1 beginbfchar <0001> <0041> endbfchar\n" +
+		"endcmap CMapName currentdict /CMap defineresource pop end end\n"
+
+	var kids []string
+	for i := range contents {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 7+2*i))
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 612 792] >>", strings.Join(kids, " "), len(contents)),
+		"<< /Font << /F1 4 0 R /F2 5 0 R >> >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding /FirstChar 32 /LastChar 126 /Widths [" + widths + "] >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /Encoding /Identity-H /DescendantFonts [<< /Type /Font /Subtype /CIDFontType2 /DW 600 >>] /ToUnicode 6 0 R >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(cmap), cmap),
+	}
+	for i, content := range contents {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Resources 3 0 R /Contents %d 0 R >>", 8+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// TestExtractPDFTextLayer tests page text, word boxes and pages escalated to OCR
+func TestExtractPDFTextLayer(t *testing.T) {
+	data := buildPDF(t,
+		// Two lines: a plain string, then kerned TJ runs whose gap is a word break
+		"BT /F1 10 Tf 72 720 Td (Hello World) Tj 0 -14 Td [(Tot) 20 (al) -1000 (due)] TJ ET",
+		// No text: a scanned page
+		"",
+		// Codes without a Unicode mapping
+		"BT /F2 12 Tf 72 720 Td <0001000200030004> Tj ET",
+	)
+
+	layer, err := processor.ExtractPDFTextLayer(data)
+	if err != nil {
+		t.Fatalf("ExtractPDFTextLayer failed: %v", err)
+	}
+	if len(layer.Pages) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(layer.Pages))
+	}
+	if fmt.Sprint(layer.NeedsOCR) != "[2 3]" {
+		t.Errorf("NeedsOCR = %v, want [2 3]", layer.NeedsOCR)
+	}
+
+	page := layer.Pages[0]
+	if page.Text != "Hello World\nTotal due" {
+		t.Errorf("page 1 text = %q", page.Text)
+	}
+	if page.TierUsed != processor.OCRTierPDFTextLayer {
+		t.Errorf("page 1 tier = %q", page.TierUsed)
+	}
+	if len(page.Words) != 4 {
+		t.Fatalf("expected 4 words, got %d: %+v", len(page.Words), page.Words)
+	}
+
+	// "Hello" at 72pt from the left, baseline 720pt from the bottom, 5 glyphs of 5pt, at 96 DPI
+	box := page.Words[0].BoundingBox
+	if box.X != 96 || box.Y != 85 || box.Width != 34 || box.Height != 14 {
+		t.Errorf("Hello box = %+v", box)
+	}
+	if page.Words[2].BoundingBox.Y <= box.Y {
+		t.Errorf("second line should be below the first: %+v", page.Words[2].BoundingBox)
+	}
+
+	// OCR fills only the pages without a text layer
+	ocr := &processor.OCRResult{
+		TierUsed:   "mageagent_file_process",
+		Confidence: 0.8,
+		Pages: []processor.OCRPage{
+			{PageNumber: 1, Text: "Hel1o Wor1d", Confidence: 0.8},
+			{PageNumber: 2, Text: "Scanned", Confidence: 0.8},
+			{PageNumber: 3, Text: "AAAA", Confidence: 0.8},
+		},
+	}
+	result := layer.Result(ocr)
+	if result.Text != "Hello World\nTotal due\n\nScanned\n\nAAAA" {
+		t.Errorf("merged text = %q", result.Text)
+	}
+	if result.Pages[1].TierUsed != "mageagent_file_process" || result.Pages[0].TierUsed != processor.OCRTierPDFTextLayer {
+		t.Errorf("page tiers = %q, %q", result.Pages[0].TierUsed, result.Pages[1].TierUsed)
+	}
+	if result.TierUsed != "pdf_text_layer+mageagent_file_process" {
+		t.Errorf("result tier = %q", result.TierUsed)
+	}
+
+	// The per-page cascade returns only the pages it was given
+	cascade := processor.AggregateOCRPages([]*processor.OCRResult{
+		{TierUsed: "tesseract", Pages: []processor.OCRPage{{PageNumber: 2, Text: "Scanned", Confidence: 0.9}}},
+		{TierUsed: "tier2_gpt-4o", Pages: []processor.OCRPage{{PageNumber: 3, Text: "AAAA", Confidence: 0.9}}},
+	})
+	result = layer.Result(cascade)
+	if len(result.Pages) != 3 || result.Pages[2].TierUsed != "tier2_gpt-4o" || result.Pages[1].Text != "Scanned" {
+		t.Errorf("merged cascade pages = %+v", result.Pages)
+	}
+}
@@ -0,0 +1,100 @@
+/**
+ * Poison-Message Quarantine Tests
+ *
+ * Validates that undecodable payloads on the LIST backend are quarantined
+ * instead of retried or dropped, whether routing or loading the job fails:
+ * - The raw payload and decode error land in <queue>:poison
+ * - The job is acked, removed from <queue>:data and marked failed with
+ *   MALFORMED_PAYLOAD in Redis and PostgreSQL
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
+)
+
+// TestQuarantineMalformedPayload tests that undecodable payloads end up in the poison hash
+func TestQuarantineMalformedPayload(t *testing.T) {
+	testCases := []struct {
+		name  string
+		jobID string
+		raw   string
+	}{
+		{
+			// Rejected by the router before the job reaches a lane
+			name:  "undecodable routing fields",
+			jobID: "job-bad-user",
+			raw:   `{"id":"q-job-bad-user","payload":{"jobId":"job-bad-user","userId":42,"filename":"a.pdf"}}`,
+		},
+		{
+			// Routed by user, then rejected when the worker decodes the whole job
+			name:  "undecodable file buffer",
+			jobID: "job-bad-buffer",
+			raw:   `{"id":"q-job-bad-buffer","payload":{"jobId":"job-bad-buffer","userId":"user-1","filename":"a.pdf","fileBuffer":true}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, client := startMiniredis(t)
+			proc := newFakeProcessor()
+			ctx := context.Background()
+
+			queueJobID := "q-" + tc.jobID
+			client.HSet(ctx, testQueue+":data", queueJobID, tc.raw)
+			client.LPush(ctx, testQueue, queueJobID)
+
+			consumer, err := queue.NewRedisConsumer(redisConsumerConfig(server, proc, "worker-a"))
+			if err != nil {
+				t.Fatalf("NewRedisConsumer() failed: %v", err)
+			}
+			stop := startBackend(t, consumer)
+
+			eventually(t, 5*time.Second, "the payload to be quarantined", func() bool {
+				return client.HExists(ctx, testQueue+":poison", queueJobID).Val()
+			})
+			eventually(t, 5*time.Second, "the job to be marked failed", func() bool {
+				return proc.lastStatus(tc.jobID) == "failed"
+			})
+			stop()
+
+			var entry queue.PoisonEntry
+			if err := json.Unmarshal([]byte(client.HGet(ctx, testQueue+":poison", queueJobID).Val()), &entry); err != nil {
+				t.Fatalf("failed to decode poison entry: %v", err)
+			}
+			if entry.JobID != tc.jobID || entry.Raw != tc.raw || entry.DecodeError == "" || entry.WorkerID != "worker-a" {
+				t.Errorf("poison entry = %+v, want jobId %s, the raw payload, a decode error and worker-a", entry, tc.jobID)
+			}
+
+			var rejection map[string]interface{}
+			if err := json.Unmarshal([]byte(client.HGet(ctx, testQueue+":errors", tc.jobID).Val()), &rejection); err != nil {
+				t.Fatalf("failed to decode job error: %v", err)
+			}
+			if rejection["error_code"] != "MALFORMED_PAYLOAD" {
+				t.Errorf("error_code = %v, want MALFORMED_PAYLOAD", rejection["error_code"])
+			}
+			if !isMember(t, client, "failed", tc.jobID) {
+				t.Errorf("job %s is not in the failed set", tc.jobID)
+			}
+
+			if client.HExists(ctx, testQueue+":data", queueJobID).Val() {
+				t.Error("quarantined payload is still in the job data")
+			}
+			if n := client.LLen(ctx, testQueue+":inflight:worker-a").Val(); n != 0 {
+				t.Errorf("%d job(s) left in flight, want 0", n)
+			}
+			if n := client.ZCard(ctx, testQueue+":delayed").Val(); n != 0 {
+				t.Errorf("%d job(s) scheduled for retry, want 0", n)
+			}
+			if got := proc.callCount(tc.jobID); got != 0 {
+				t.Errorf("ProcessDocument called %d times, want 0", got)
+			}
+		})
+	}
+}
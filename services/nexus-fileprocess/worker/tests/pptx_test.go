@@ -0,0 +1,125 @@
+/**
+ * PPTX Extraction Tests
+ *
+ * Validates one page per slide, reading order, tables, images and speaker notes.
+ */
+
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+const pptxNamespaces = `xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+
+func buildPPTX(t *testing.T) []byte {
+	t.Helper()
+	parts := map[string]string{
+		"ppt/presentation.xml": `<p:presentation ` + pptxNamespaces + `>
+  <p:sldIdLst><p:sldId id="256" r:id="rId2"/><p:sldId id="257" r:id="rId3"/><p:sldId id="258" r:id="rId4"/></p:sldIdLst>
+</p:presentation>`,
+		"ppt/_rels/presentation.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide1.xml"/>
+  <Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide2.xml"/>
+  <Relationship Id="rId4" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide3.xml"/>
+</Relationships>`,
+		// Shapes are stored bottom-up; reading order must put the title first and sort the rest by position
+		"ppt/slides/slide1.xml": `<p:sld ` + pptxNamespaces + `><p:cSld><p:spTree>
+  <p:pic><p:nvPicPr><p:cNvPr id="5" name="Picture 4" descr="Revenue chart"/></p:nvPicPr>
+    <p:spPr><a:xfrm><a:off x="952500" y="2857500"/><a:ext cx="1905000" cy="952500"/></a:xfrm></p:spPr></p:pic>
+  <p:sp><p:nvSpPr><p:cNvPr id="3" name="Content"/><p:nvPr><p:ph idx="1"/></p:nvPr></p:nvSpPr>
+    <p:spPr><a:xfrm><a:off x="952500" y="952500"/><a:ext cx="1905000" cy="952500"/></a:xfrm></p:spPr>
+    <p:txBody><a:p><a:r><a:t>Revenue up</a:t></a:r></a:p><a:p><a:pPr lvl="1"/><a:r><a:t>EMEA </a:t></a:r><a:r><a:t>+12%</a:t></a:r></a:p></p:txBody></p:sp>
+  <p:sp><p:nvSpPr><p:cNvPr id="2" name="Title"/><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr>
+    <p:txBody><a:p><a:r><a:t>Q1 Results</a:t></a:r></a:p></p:txBody></p:sp>
+</p:spTree></p:cSld></p:sld>`,
+		"ppt/slides/_rels/slide1.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId9" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide" Target="../notesSlides/notesSlide1.xml"/>
+</Relationships>`,
+		"ppt/notesSlides/notesSlide1.xml": `<p:notes ` + pptxNamespaces + `><p:cSld><p:spTree>
+  <p:sp><p:nvSpPr><p:cNvPr id="2" name="Slide Image"/><p:nvPr><p:ph type="sldImg"/></p:nvPr></p:nvSpPr></p:sp>
+  <p:sp><p:nvSpPr><p:cNvPr id="3" name="Notes"/><p:nvPr><p:ph type="body" idx="1"/></p:nvPr></p:nvSpPr>
+    <p:txBody><a:p><a:r><a:t>Mention the EMEA launch.</a:t></a:r></a:p></p:txBody></p:sp>
+</p:spTree></p:cSld></p:notes>`,
+		"ppt/slides/slide2.xml": `<p:sld ` + pptxNamespaces + `><p:cSld><p:spTree>
+  <p:graphicFrame><p:nvGraphicFramePr><p:cNvPr id="4" name="Table"/></p:nvGraphicFramePr>
+    <p:xfrm><a:off x="0" y="0"/><a:ext cx="9525000" cy="952500"/></p:xfrm>
+    <a:graphic><a:graphicData><a:tbl>
+      <a:tr><a:tc gridSpan="2"><a:txBody><a:p><a:r><a:t>Region</a:t></a:r></a:p></a:txBody></a:tc><a:tc hMerge="1"/><a:tc><a:txBody><a:p><a:r><a:t>Total</a:t></a:r></a:p></a:txBody></a:tc></a:tr>
+      <a:tr><a:tc><a:txBody><a:p><a:r><a:t>EMEA</a:t></a:r></a:p></a:txBody></a:tc><a:tc><a:txBody><a:p><a:r><a:t>UK</a:t></a:r></a:p></a:txBody></a:tc><a:tc><a:txBody><a:p><a:r><a:t>10</a:t></a:r></a:p></a:txBody></a:tc></a:tr>
+    </a:tbl></a:graphicData></a:graphic></p:graphicFrame>
+</p:spTree></p:cSld></p:sld>`,
+		"ppt/slides/slide3.xml": `<p:sld ` + pptxNamespaces + `><p:cSld><p:spTree/></p:cSld></p:sld>`,
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestExtractPPTX tests slide pages, region types and reading order
+func TestExtractPPTX(t *testing.T) {
+	ocrResult, layoutResult, err := processor.ExtractPPTX(buildPPTX(t))
+	if err != nil {
+		t.Fatalf("ExtractPPTX failed: %v", err)
+	}
+
+	// The empty third slide is still a page
+	if len(ocrResult.Pages) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(ocrResult.Pages))
+	}
+	for i, page := range ocrResult.Pages {
+		if page.PageNumber != i+1 {
+			t.Errorf("page %d has PageNumber %d", i, page.PageNumber)
+		}
+	}
+
+	expected := []struct {
+		regionType string
+		level      int
+		content    string
+		page       int
+	}{
+		{processor.RegionHeading, 1, "Q1 Results", 1},
+		{processor.RegionListItem, 1, "Revenue up", 1},
+		{processor.RegionListItem, 2, "EMEA +12%", 1},
+		{processor.RegionImage, 0, "Revenue chart", 1},
+		{processor.RegionNotes, 0, "Mention the EMEA launch.", 1},
+		{processor.RegionTable, 0, "", 2},
+	}
+	if len(layoutResult.Regions) != len(expected) {
+		t.Fatalf("expected %d regions, got %d: %+v", len(expected), len(layoutResult.Regions), layoutResult.Regions)
+	}
+	for i, want := range expected {
+		got := layoutResult.Regions[i]
+		if got.Type != want.regionType || got.Level != want.level || got.PageNumber != want.page {
+			t.Errorf("region %d = %s/%d page %d, want %s/%d page %d", i, got.Type, got.Level, got.PageNumber, want.regionType, want.level, want.page)
+		}
+		if want.content != "" && got.Content != want.content {
+			t.Errorf("region %d content = %q, want %q", i, got.Content, want.content)
+		}
+	}
+
+	if box := layoutResult.Regions[3].BoundingBox; box.X != 100 || box.Y != 300 || box.Width != 200 {
+		t.Errorf("image bounding box = %+v, want x=100 y=300 w=200 (96 DPI)", box)
+	}
+
+	table := layoutResult.Tables[0]
+	if len(table.Rows) != 2 || table.Rows[0].Cells[0].ColSpan != 2 || table.Rows[0].Cells[1].ColumnNumber != 2 {
+		t.Errorf("table = %+v", table.Rows)
+	}
+}
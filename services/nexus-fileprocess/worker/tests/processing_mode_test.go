@@ -0,0 +1,82 @@
+/**
+ * Processing Mode Tests
+ *
+ * Validates startup validation per PROCESSING_MODE and the Tesseract-only
+ * OCR policy of local-only workers.
+ */
+
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/config"
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+// TestLoadConfigProcessingMode tests which settings each mode requires
+func TestLoadConfigProcessingMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"cloud with keys", map[string]string{"VOYAGE_API_KEY": "pa-test", "OPENROUTER_API_KEY": "sk-test"}, ""},
+		{"cloud without keys", map[string]string{}, "VOYAGE_API_KEY"},
+		{"local only without keys", map[string]string{
+			"PROCESSING_MODE": "local_only",
+			"EMBEDDING_URL":   "http://ollama:11434/v1/embeddings",
+			"EMBEDDING_MODEL": "bge-m3",
+		}, ""},
+		{"local only without embedding server", map[string]string{"PROCESSING_MODE": "local_only"}, "EMBEDDING_URL"},
+		{"local only with voyage", map[string]string{
+			"PROCESSING_MODE":    "local_only",
+			"EMBEDDING_PROVIDER": "voyage",
+			"VOYAGE_API_KEY":     "pa-test",
+		}, "EMBEDDING_PROVIDER"},
+		{"cloud with openai embeddings", map[string]string{
+			"OPENROUTER_API_KEY": "sk-test",
+			"EMBEDDING_PROVIDER": "openai",
+			"EMBEDDING_URL":      "https://api.openai.com/v1/embeddings",
+			"EMBEDDING_MODEL":    "text-embedding-3-small",
+		}, ""},
+		{"unknown mode", map[string]string{"PROCESSING_MODE": "offline"}, "PROCESSING_MODE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"PROCESSING_MODE", "VOYAGE_API_KEY", "OPENROUTER_API_KEY",
+				"EMBEDDING_PROVIDER", "EMBEDDING_URL", "EMBEDDING_MODEL"} {
+				t.Setenv(key, tt.env[key])
+			}
+			t.Setenv("DATABASE_URL", "postgres://localhost/fileprocess")
+
+			cfg, err := config.LoadConfig()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("LoadConfig failed: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("LoadConfig error = %v, want one naming %s", err, tt.wantErr)
+			case err == nil && tt.env["PROCESSING_MODE"] == "" && cfg.ProcessingMode != processor.ProcessingModeCloud:
+				t.Errorf("default mode = %q, want cloud", cfg.ProcessingMode)
+			}
+		})
+	}
+}
+
+// TestOCRPolicyLocalOnly tests that local-only workers run Tesseract whatever the job asks for
+func TestOCRPolicyLocalOnly(t *testing.T) {
+	policy := processor.DefaultOCRPolicy().LocalOnly()
+	if policy.String() != "tesseract≥0.85" {
+		t.Errorf("policy = %s, want tesseract≥0.85", policy)
+	}
+
+	visionOnly, err := processor.DefaultOCRPolicy().WithOverrides(ocrPolicyOverride(t, `{"mode": "vision_only", "maxTier": "tier3"}`))
+	if err != nil {
+		t.Fatalf("WithOverrides failed: %v", err)
+	}
+	if tiers := visionOnly.LocalOnly().Tiers; fmt.Sprint(tiers) != "[tesseract]" {
+		t.Errorf("tiers = %v, want [tesseract]", tiers)
+	}
+}
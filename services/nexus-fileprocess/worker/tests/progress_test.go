@@ -0,0 +1,36 @@
+/**
+ * Progress Reporting Tests
+ *
+ * Validates the mapping from stage-local progress to overall 0-100 progress.
+ */
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+// TestOverallProgress tests stage ranges, clamping and unknown stages
+func TestOverallProgress(t *testing.T) {
+	testCases := []struct {
+		stage         string
+		stageProgress float64
+		want          int
+	}{
+		{processor.StageDownload, 0, 0},
+		{processor.StageDownload, 1, 15},
+		{processor.StageOCR, 0.5, 40},
+		{processor.StageOCR, 2, 60},
+		{processor.StageLayout, -1, 60},
+		{processor.StageGraphRAG, 1, 99},
+		{"unknown", 0.5, 0},
+	}
+
+	for _, tc := range testCases {
+		if got := processor.OverallProgress(tc.stage, tc.stageProgress); got != tc.want {
+			t.Errorf("OverallProgress(%s, %.1f) = %d, want %d", tc.stage, tc.stageProgress, got, tc.want)
+		}
+	}
+}
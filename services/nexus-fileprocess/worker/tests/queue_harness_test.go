@@ -0,0 +1,194 @@
+/**
+ * Queue Backend Test Harness
+ *
+ * Shared helpers for the Redis-backed queue tests:
+ * - An in-process miniredis server per test
+ * - A fake DocumentProcessorInterface that records calls and status updates
+ * - Helpers to queue jobs the way the TypeScript RedisQueue does and to wait
+ *   for the consumers' background goroutines
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const testQueue = "test:jobs"
+
+// fakeProcessor records what the queue asks of the processor. ProcessDocument runs
+// process when set and otherwise succeeds immediately.
+type fakeProcessor struct {
+	process func(ctx context.Context, req *processor.ProcessRequest) (*processor.ProcessResult, error)
+
+	mu       sync.Mutex
+	calls    map[string]int
+	statuses map[string][]string
+}
+
+func newFakeProcessor() *fakeProcessor {
+	return &fakeProcessor{
+		calls:    make(map[string]int),
+		statuses: make(map[string][]string),
+	}
+}
+
+func (p *fakeProcessor) ProcessDocument(ctx context.Context, req *processor.ProcessRequest) (*processor.ProcessResult, error) {
+	p.mu.Lock()
+	p.calls[req.JobID]++
+	p.mu.Unlock()
+
+	if p.process != nil {
+		return p.process(ctx, req)
+	}
+	return &processor.ProcessResult{Confidence: 1}, nil
+}
+
+func (p *fakeProcessor) UpdateJobStatus(ctx context.Context, jobID string, status string, progress int, metadata map[string]interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.statuses[jobID] = append(p.statuses[jobID], status)
+	return nil
+}
+
+func (p *fakeProcessor) UpdateJobProgress(ctx context.Context, update *processor.ProgressUpdate) error {
+	return nil
+}
+
+// callCount returns how often ProcessDocument ran for a job
+func (p *fakeProcessor) callCount(jobID string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls[jobID]
+}
+
+// lastStatus returns the last status the queue reported for a job
+func (p *fakeProcessor) lastStatus(jobID string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	statuses := p.statuses[jobID]
+	if len(statuses) == 0 {
+		return ""
+	}
+	return statuses[len(statuses)-1]
+}
+
+// startMiniredis starts an in-process Redis server and a client for it
+func startMiniredis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return server, client
+}
+
+// redisConsumerConfig returns a consumer configuration with intervals short enough for tests
+func redisConsumerConfig(server *miniredis.Miniredis, proc *fakeProcessor, workerID string) *queue.RedisConsumerConfig {
+	return &queue.RedisConsumerConfig{
+		RedisURL:          "redis://" + server.Addr(),
+		QueueName:         testQueue,
+		Concurrency:       2,
+		Processor:         proc,
+		WorkerID:          workerID,
+		VisibilityTimeout: 1000,
+		HeartbeatInterval: 100,
+		ReaperInterval:    50,
+		SchedulerInterval: 50,
+		RetryPolicy:       &queue.RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 10 * time.Millisecond},
+	}
+}
+
+// startBackend starts a backend and returns an idempotent stop function, also run on cleanup
+func startBackend(t *testing.T, backend queue.Backend) func() {
+	t.Helper()
+
+	if err := backend.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			if err := backend.Stop(); err != nil {
+				t.Logf("Stop() failed: %v", err)
+			}
+		})
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+// newTestJob builds a queued job whose queue ID is derived from its job ID
+func newTestJob(jobID string, maxRetries int) *queue.RedisJobData {
+	return &queue.RedisJobData{
+		ID:   "q-" + jobID,
+		Type: "process-document",
+		Payload: queue.JobPayload{
+			JobID:    jobID,
+			UserID:   "user-1",
+			Filename: jobID + ".txt",
+			MimeType: "text/plain",
+		},
+		CreatedAt:  time.Now(),
+		MaxRetries: maxRetries,
+	}
+}
+
+// storeJob writes a job to <queue>:data without queueing it
+func storeJob(t *testing.T, client *redis.Client, job *queue.RedisJobData) {
+	t.Helper()
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		t.Fatalf("failed to marshal job: %v", err)
+	}
+	if err := client.HSet(context.Background(), testQueue+":data", job.ID, data).Err(); err != nil {
+		t.Fatalf("failed to store job: %v", err)
+	}
+}
+
+// pushJob stores a job and pushes its queue ID onto the intake list, like the TypeScript RedisQueue
+func pushJob(t *testing.T, client *redis.Client, job *queue.RedisJobData) {
+	t.Helper()
+
+	storeJob(t, client, job)
+	if err := client.LPush(context.Background(), testQueue, job.ID).Err(); err != nil {
+		t.Fatalf("failed to queue job: %v", err)
+	}
+}
+
+// isMember reports whether a job is in one of the queue's status sets
+func isMember(t *testing.T, client *redis.Client, set, jobID string) bool {
+	t.Helper()
+
+	member, err := client.SIsMember(context.Background(), fmt.Sprintf("%s:%s", testQueue, set), jobID).Result()
+	if err != nil {
+		t.Fatalf("SISMEMBER %s failed: %v", set, err)
+	}
+	return member
+}
+
+// eventually polls cond until it holds, failing the test after timeout
+func eventually(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out after %v waiting for %s", timeout, what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
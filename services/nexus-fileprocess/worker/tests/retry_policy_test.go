@@ -0,0 +1,60 @@
+/**
+ * Retry Policy Tests
+ *
+ * Validates the exponential backoff used for delayed retries in the Redis queue:
+ * - Doubling from the base delay
+ * - Capping at the max delay
+ * - Jitter stays within the configured spread
+ */
+
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
+)
+
+// TestRetryPolicyExponentialBackoff tests the un-jittered backoff schedule
+func TestRetryPolicyExponentialBackoff(t *testing.T) {
+	policy := &queue.RetryPolicy{
+		BaseDelay: 5 * time.Second,
+		MaxDelay:  60 * time.Second,
+	}
+
+	testCases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: 5 * time.Second},
+		{attempt: 1, want: 5 * time.Second},
+		{attempt: 2, want: 10 * time.Second},
+		{attempt: 3, want: 20 * time.Second},
+		{attempt: 4, want: 40 * time.Second},
+		{attempt: 5, want: 60 * time.Second},
+		{attempt: 50, want: 60 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		if got := policy.Delay(tc.attempt); got != tc.want {
+			t.Errorf("Delay(%d) = %v, want %v", tc.attempt, got, tc.want)
+		}
+	}
+}
+
+// TestRetryPolicyJitter tests that jittered delays stay within the configured spread
+func TestRetryPolicyJitter(t *testing.T) {
+	policy := &queue.RetryPolicy{
+		BaseDelay: 10 * time.Second,
+		MaxDelay:  60 * time.Second,
+		Jitter:    0.2,
+	}
+
+	for i := 0; i < 1000; i++ {
+		got := policy.Delay(1)
+		if got < 8*time.Second || got > 12*time.Second {
+			t.Fatalf("Delay(1) = %v, want within 8s-12s", got)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+/**
+ * Spreadsheet Extraction Tests
+ *
+ * Validates CSV quoting, header detection, column type inference and XLSX
+ * sheets, shared strings, date formats and merged ranges.
+ */
+
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+)
+
+// TestExtractCSV tests RFC 4180 quoting, header detection and column types
+func TestExtractCSV(t *testing.T) {
+	csv := "\xEF\xBB\xBFInvoice,Customer,Issued,Amount,Qty\r\n" +
+		"INV-1,\"Acme, Inc.\",2024-01-15,\"$1,200.00\",3\r\n" +
+		"INV-2,\"Say \"\"Hi\"\" Ltd\",2024-02-01,$80.50,12\r\n" +
+		"INV-3,\"Multi\nLine Co\",2024-03-09,(45.00),1\r\n"
+
+	_, layoutResult, err := processor.ExtractCSV([]byte(csv))
+	if err != nil {
+		t.Fatalf("ExtractCSV failed: %v", err)
+	}
+	if len(layoutResult.Tables) != 1 {
+		t.Fatalf("expected 1 table, got %d", len(layoutResult.Tables))
+	}
+
+	table := layoutResult.Tables[0]
+	if !table.HasHeader {
+		t.Errorf("header row not detected")
+	}
+	if len(table.Rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(table.Rows))
+	}
+	if got := table.Rows[1].Cells[1].Content; got != "Acme, Inc." {
+		t.Errorf("quoted comma: got %q", got)
+	}
+	if got := table.Rows[2].Cells[1].Content; got != `Say "Hi" Ltd` {
+		t.Errorf("escaped quotes: got %q", got)
+	}
+	if got := table.Rows[3].Cells[1].Content; got != "Multi\nLine Co" {
+		t.Errorf("embedded newline: got %q", got)
+	}
+
+	expected := []struct{ name, kind string }{
+		{"Invoice", processor.ColumnTypeText},
+		{"Customer", processor.ColumnTypeText},
+		{"Issued", processor.ColumnTypeDate},
+		{"Amount", processor.ColumnTypeCurrency},
+		{"Qty", processor.ColumnTypeNumber},
+	}
+	if len(table.Columns) != len(expected) {
+		t.Fatalf("expected %d columns, got %d", len(expected), len(table.Columns))
+	}
+	for i, want := range expected {
+		if table.Columns[i].Name != want.name || table.Columns[i].Type != want.kind {
+			t.Errorf("column %d = %s/%s, want %s/%s", i, table.Columns[i].Name, table.Columns[i].Type, want.name, want.kind)
+		}
+	}
+}
+
+// TestExtractCSVSemicolon tests delimiter detection and tables split at empty rows
+func TestExtractCSVSemicolon(t *testing.T) {
+	csv := "a;b\n1;2\n\nx;y\n3;4\n"
+	_, layoutResult, err := processor.ExtractCSV([]byte(csv))
+	if err != nil {
+		t.Fatalf("ExtractCSV failed: %v", err)
+	}
+	if len(layoutResult.Tables) != 2 {
+		t.Fatalf("expected 2 tables, got %d", len(layoutResult.Tables))
+	}
+	if got := layoutResult.Tables[1].Rows[1].Cells[1].Content; got != "4" {
+		t.Errorf("second table cell = %q, want 4", got)
+	}
+}
+
+// TestDelimitedMimeType tests MIME correction from the file extension
+func TestDelimitedMimeType(t *testing.T) {
+	tests := []struct{ mime, filename, expected string }{
+		{"application/vnd.ms-excel", "export.csv", processor.MimeTypeCSV},
+		{"text/plain", "export.TSV", processor.MimeTypeTSV},
+		{"application/octet-stream", "notes.txt", "application/octet-stream"},
+		{"application/pdf", "report.csv", "application/pdf"},
+	}
+	for _, tt := range tests {
+		if got := processor.DelimitedMimeType(tt.mime, tt.filename); got != tt.expected {
+			t.Errorf("DelimitedMimeType(%q, %q) = %q, want %q", tt.mime, tt.filename, got, tt.expected)
+		}
+	}
+}
+
+func buildXLSX(t *testing.T) []byte {
+	t.Helper()
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <sheets><sheet name="Q1 Sales" sheetId="1" r:id="rId1"/><sheet name="Notes" sheetId="2" r:id="rId2"/></sheets>
+</workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+  <Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/notes.xml"/>
+</Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <si><t>Region</t></si><si><t>Date</t></si><si><t>Revenue</t></si><si><r><t>North</t></r><r><t>east</t></r></si><si><t>Draft figures</t></si>
+</sst>`,
+		"xl/styles.xml": `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <numFmts><numFmt numFmtId="164" formatCode="&quot;$&quot;#,##0.00"/><numFmt numFmtId="165" formatCode="[Red]0.00"/></numFmts>
+  <cellStyleXfs><xf numFmtId="14"/></cellStyleXfs>
+  <cellXfs><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/><xf numFmtId="165"/></cellXfs>
+</styleSheet>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+  <row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c></row>
+  <row r="2"><c r="A2" t="s"><v>3</v></c><c r="B2" s="1"><v>45306</v></c><c r="C2" s="2"><v>1200.5</v></c><c r="D2" s="3"><v>1.5</v></c></row>
+  <row r="3"><c r="B3" s="1"><v>45337</v></c><c r="C3" s="2"><f>C2*2</f><v>2401</v></c></row>
+</sheetData><mergeCells count="1"><mergeCell ref="A2:A3"/></mergeCells></worksheet>`,
+		"xl/worksheets/notes.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+  <row r="2"><c r="B2" t="s"><v>4</v></c></row>
+  <row r="3"><c r="B3" t="inlineStr"><is><t>Inline note</t></is></c><c r="AAAAAAAAAAAAAAAAAAAA3" t="inlineStr"><is><t>Far</t></is></c></row>
+  <row r="99999999999"><c t="inlineStr"><is><t>Past the last row</t></is></c></row>
+</sheetData><mergeCells count="3"><mergeCell ref="B2:XFE2"/><mergeCell ref="B3:B1048577"/><mergeCell ref="XFD3:C3"/></mergeCells></worksheet>`,
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestExtractXLSX tests sheets, shared and inline strings, date serials, formats and merges
+func TestExtractXLSX(t *testing.T) {
+	ocrResult, layoutResult, err := processor.ExtractXLSX(buildXLSX(t))
+	if err != nil {
+		t.Fatalf("ExtractXLSX failed: %v", err)
+	}
+
+	if len(layoutResult.Sheets) != 2 || layoutResult.Sheets[0] != "Q1 Sales" || layoutResult.Sheets[1] != "Notes" {
+		t.Errorf("sheets = %v", layoutResult.Sheets)
+	}
+	if len(ocrResult.Pages) != 2 {
+		t.Errorf("expected one page per sheet, got %d", len(ocrResult.Pages))
+	}
+	if len(layoutResult.Tables) != 2 {
+		t.Fatalf("expected 2 tables, got %d", len(layoutResult.Tables))
+	}
+
+	sales := layoutResult.Tables[0]
+	if sales.Name != "Q1 Sales" || !sales.HasHeader {
+		t.Errorf("sales table name=%q header=%v", sales.Name, sales.HasHeader)
+	}
+
+	region := sales.Rows[1].Cells[0]
+	if region.Content != "Northeast" || region.RowSpan != 2 {
+		t.Errorf("merged region cell = %+v", region)
+	}
+	if got := sales.Rows[1].Cells[1].Content; got != "2024-01-15" {
+		t.Errorf("date serial 45306 = %q, want 2024-01-15", got)
+	}
+	if got := sales.Rows[2].Cells[1].Content; got != "2401" {
+		t.Errorf("formula cached value = %q, want 2401", got)
+	}
+
+	expected := []string{processor.ColumnTypeText, processor.ColumnTypeDate, processor.ColumnTypeCurrency, processor.ColumnTypeNumber}
+	for i, want := range expected {
+		if sales.Columns[i].Type != want {
+			t.Errorf("column %d (%s) type = %s, want %s", i, sales.Columns[i].Name, sales.Columns[i].Type, want)
+		}
+	}
+
+	// References past XFD or row 1048576 are ignored; the merge ending at XFD is kept
+	notes := layoutResult.Tables[1]
+	if notes.Name != "Notes" || len(notes.Rows) != 3 || notes.Rows[1].Cells[0].Content != "Inline note" {
+		t.Fatalf("notes table = %+v", notes)
+	}
+	if cell := notes.Rows[0].Cells[0]; cell.ColSpan != 1 {
+		t.Errorf("merge past XFD applied: %+v", cell)
+	}
+	if cell := notes.Rows[1].Cells[0]; cell.RowSpan != 1 {
+		t.Errorf("merge past the last row applied: %+v", cell)
+	}
+	far := notes.Rows[1].Cells[1]
+	if far.Content != "Far" || far.ColumnNumber != 2 || far.ColSpan != 16382 {
+		t.Errorf("cell after an invalid reference = %+v, want column C spanning to XFD", far)
+	}
+	if len(notes.Columns) != 16384 {
+		t.Errorf("notes columns = %d, want 16384", len(notes.Columns))
+	}
+	if got := notes.Rows[2].Cells[0].Content; got != "Past the last row" {
+		t.Errorf("row after an invalid row number = %q", got)
+	}
+}
@@ -0,0 +1,199 @@
+/**
+ * Redis Streams Backend Tests
+ *
+ * Validates pending-entry recovery of the streams Backend against miniredis:
+ * - XAUTOCLAIM takes over entries a dead consumer left pending
+ * - The heartbeat keeps a long-running entry from being claimed by others
+ * - A consumer gives a job up, unsettled, once another consumer takes its entry
+ */
+
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	testStream = testQueue + ":stream"
+	testGroup  = "test-workers"
+)
+
+// TestStreamClaimsStaleEntries tests that an entry left pending by a dead consumer is recovered
+func TestStreamClaimsStaleEntries(t *testing.T) {
+	server, client := startMiniredis(t)
+	proc := newFakeProcessor()
+	ctx := context.Background()
+
+	// A consumer read the entry and died before acking it
+	job := newTestJob("job-stale", 3)
+	storeJob(t, client, job)
+	client.XGroupCreateMkStream(ctx, testStream, testGroup, "0")
+	client.XAdd(ctx, &redis.XAddArgs{Stream: testStream, Values: map[string]interface{}{"id": job.ID}})
+	if err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: testGroup, Consumer: "worker-dead", Streams: []string{testStream, ">"}, Count: 1,
+	}).Err(); err != nil {
+		t.Fatalf("XREADGROUP failed: %v", err)
+	}
+
+	consumer, err := queue.NewStreamConsumer(streamConsumerConfig(server, proc, "worker-a"))
+	if err != nil {
+		t.Fatalf("NewStreamConsumer() failed: %v", err)
+	}
+	stop := startBackend(t, consumer)
+
+	// Not idle for the visibility timeout yet
+	time.Sleep(100 * time.Millisecond)
+	if got := proc.callCount(job.Payload.JobID); got != 0 {
+		t.Fatalf("entry claimed before the visibility timeout, processed %d times", got)
+	}
+
+	eventually(t, 10*time.Second, "the stale entry to complete", func() bool {
+		return isMember(t, client, "completed", job.Payload.JobID)
+	})
+	stop()
+
+	if got := proc.callCount(job.Payload.JobID); got != 1 {
+		t.Errorf("stale entry processed %d times, want 1", got)
+	}
+	assertStreamSettled(t, client)
+}
+
+// TestStreamHeartbeatKeepsEntry tests that a job running longer than the visibility timeout
+// is not claimed by another consumer
+func TestStreamHeartbeatKeepsEntry(t *testing.T) {
+	server, client := startMiniredis(t)
+	proc := newFakeProcessor()
+	proc.process = func(ctx context.Context, req *processor.ProcessRequest) (*processor.ProcessResult, error) {
+		select {
+		case <-time.After(1500 * time.Millisecond):
+			return &processor.ProcessResult{Confidence: 1}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	for _, workerID := range []string{"worker-a", "worker-b"} {
+		consumer, err := queue.NewStreamConsumer(streamConsumerConfig(server, proc, workerID))
+		if err != nil {
+			t.Fatalf("NewStreamConsumer(%s) failed: %v", workerID, err)
+		}
+		startBackend(t, consumer)
+	}
+
+	job := newTestJob("job-long", 3)
+	storeJob(t, client, job)
+	client.XAdd(context.Background(), &redis.XAddArgs{Stream: testStream, Values: map[string]interface{}{"id": job.ID}})
+
+	eventually(t, 5*time.Second, "the long-running job to complete", func() bool {
+		return isMember(t, client, "completed", job.Payload.JobID)
+	})
+
+	if got := proc.callCount(job.Payload.JobID); got != 1 {
+		t.Errorf("long-running job processed %d times, want 1", got)
+	}
+	assertStreamSettled(t, client)
+}
+
+// TestStreamGivesUpTakenEntry tests that a consumer aborts a job whose entry another consumer took
+func TestStreamGivesUpTakenEntry(t *testing.T) {
+	server, client := startMiniredis(t)
+	proc := newFakeProcessor()
+	ctx := context.Background()
+
+	started := make(chan struct{})
+	aborted := make(chan struct{})
+	var once sync.Once
+	proc.process = func(ctx context.Context, req *processor.ProcessRequest) (*processor.ProcessResult, error) {
+		once.Do(func() { close(started) })
+		<-ctx.Done()
+		close(aborted)
+		return nil, ctx.Err()
+	}
+
+	// Long visibility timeout, so worker-a does not claim the entry back from the new owner
+	cfg := streamConsumerConfig(server, proc, "worker-a")
+	cfg.VisibilityTimeout = 60000
+	consumer, err := queue.NewStreamConsumer(cfg)
+	if err != nil {
+		t.Fatalf("NewStreamConsumer() failed: %v", err)
+	}
+	stop := startBackend(t, consumer)
+
+	job := newTestJob("job-taken", 3)
+	storeJob(t, client, job)
+	entryID := client.XAdd(ctx, &redis.XAddArgs{Stream: testStream, Values: map[string]interface{}{"id": job.ID}}).Val()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the job to start")
+	}
+
+	// Another consumer takes the entry over
+	if err := client.XClaimJustID(ctx, &redis.XClaimArgs{
+		Stream: testStream, Group: testGroup, Consumer: "worker-b", Messages: []string{entryID},
+	}).Err(); err != nil {
+		t.Fatalf("XCLAIM failed: %v", err)
+	}
+
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the job to be given up")
+	}
+	time.Sleep(100 * time.Millisecond)
+	stop()
+
+	// Left pending for its new owner and not settled by worker-a
+	pending, err := client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: testStream, Group: testGroup, Start: "-", End: "+", Count: 10,
+	}).Result()
+	if err != nil {
+		t.Fatalf("XPENDING failed: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != entryID || pending[0].Consumer != "worker-b" {
+		t.Errorf("pending entries = %+v, want %s owned by worker-b", pending, entryID)
+	}
+	for _, set := range []string{"failed", "completed", "cancelled"} {
+		if isMember(t, client, set, job.Payload.JobID) {
+			t.Errorf("given-up job is in the %s set", set)
+		}
+	}
+	if n := client.ZCard(ctx, testQueue+":delayed").Val(); n != 0 {
+		t.Errorf("%d job(s) scheduled for retry, want 0", n)
+	}
+}
+
+// streamConsumerConfig returns a streams consumer configuration with a short visibility timeout
+func streamConsumerConfig(server *miniredis.Miniredis, proc *fakeProcessor, workerID string) *queue.RedisConsumerConfig {
+	cfg := redisConsumerConfig(server, proc, workerID)
+	cfg.ConsumerGroup = testGroup
+	cfg.VisibilityTimeout = 300
+	cfg.HeartbeatInterval = 50
+	return cfg
+}
+
+// assertStreamSettled checks that every stream entry was acked and deleted
+func assertStreamSettled(t *testing.T, client *redis.Client) {
+	t.Helper()
+	ctx := context.Background()
+
+	if n := client.XLen(ctx, testStream).Val(); n != 0 {
+		t.Errorf("%d entr(ies) left in the stream, want 0", n)
+	}
+	pending, err := client.XPending(ctx, testStream, testGroup).Result()
+	if err != nil {
+		t.Fatalf("XPENDING failed: %v", err)
+	}
+	if pending.Count != 0 {
+		t.Errorf("%d entr(ies) still pending, want 0", pending.Count)
+	}
+}
@@ -14,7 +14,6 @@ package tests
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -200,7 +199,6 @@ func TestTableExtractionPerformance(t *testing.T) {
 	}
 
 	mageAgentClient := clients.NewMageAgentClient(mageagentURL)
-	analyzer := processor.NewLayoutAnalyzer(mageAgentClient, true)
 
 	imageData, err := loadTestImage("testdata/table_sample.png")
 	if err != nil {
@@ -208,10 +206,6 @@ func TestTableExtractionPerformance(t *testing.T) {
 		return
 	}
 
-	ocrResult := &processor.OCRResult{
-		ImageData: imageData,
-	}
-
 	// Benchmark extraction time
 	iterations := 5
 	totalDuration := int64(0)
@@ -0,0 +1,187 @@
+/**
+ * Completion Webhook Tests
+ *
+ * Validates request signing, the versioned event body sent to receivers and
+ * that deliveries cannot reach internal addresses.
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adverant/nexus/fileprocess-worker/internal/processor"
+	"github.com/adverant/nexus/fileprocess-worker/internal/queue"
+	"github.com/adverant/nexus/fileprocess-worker/internal/storage"
+	"github.com/adverant/nexus/fileprocess-worker/internal/webhook"
+)
+
+// TestWebhookSignature tests that signatures verify and any change to secret, body or timestamp breaks them
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"version":"1","type":"job.completed"}`)
+	now := time.Now().Unix()
+	signature := webhook.Sign("secret", now, body)
+
+	if err := webhook.Verify("secret", strconv.FormatInt(now, 10), signature, body, 5*time.Minute); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+	}{
+		{"wrong secret", "other", now, body},
+		{"tampered body", "secret", now, []byte(`{"version":"1","type":"job.failed"}`)},
+		{"different timestamp", "secret", now - 1, body},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := webhook.Verify(tt.secret, strconv.FormatInt(tt.timestamp, 10), signature, tt.body, 5*time.Minute); err == nil {
+				t.Errorf("expected signature to be rejected")
+			}
+		})
+	}
+
+	stale := now - 3600
+	if err := webhook.Verify("secret", strconv.FormatInt(stale, 10), webhook.Sign("secret", stale, body), body, 5*time.Minute); err == nil {
+		t.Errorf("expected stale timestamp to be rejected")
+	}
+}
+
+// TestWebhookEvent tests the JSON shape of a completed-job event
+func TestWebhookEvent(t *testing.T) {
+	outcome := &queue.JobOutcome{
+		JobID:    "job-1",
+		UserID:   "user-1",
+		Status:   queue.JobStatusCompleted,
+		Metadata: map[string]interface{}{"callbackUrl": "https://example.com/hook"},
+		Result: &processor.ProcessResult{
+			DocumentDNAID: "dna-1",
+			Confidence:    0.9,
+			OCRTierUsed:   "tesseract",
+		},
+		FinishedAt: time.Now(),
+	}
+
+	event := webhook.NewEvent("delivery-1", outcome)
+	if event.Type != webhook.EventJobCompleted || event.Version != webhook.PayloadVersion || event.ID != "delivery-1" {
+		t.Fatalf("unexpected event header: %+v", event)
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	data := decoded["data"].(map[string]interface{})
+	if data["documentDnaId"] != "dna-1" || data["jobId"] != "job-1" {
+		t.Errorf("unexpected event data: %v", data)
+	}
+	if result := data["result"].(map[string]interface{}); result["ocrTierUsed"] != "tesseract" {
+		t.Errorf("unexpected result: %v", result)
+	}
+
+	if got := webhook.EventType(queue.JobStatusFailed); got != webhook.EventJobFailed {
+		t.Errorf("EventType(failed) = %s", got)
+	}
+}
+
+// webhookStore hands out one delivery to its URL and reports the recorded attempt
+type webhookStore struct {
+	mu       sync.Mutex
+	url      string
+	claimed  bool
+	attempts chan *storage.WebhookAttempt
+}
+
+func (s *webhookStore) EnqueueWebhookDeliveries(ctx context.Context, jobID, userID, event, callbackURL string, buildPayload func(deliveryID string) (json.RawMessage, error)) (int, error) {
+	return 1, nil
+}
+
+func (s *webhookStore) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*storage.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.claimed {
+		return nil, nil
+	}
+	s.claimed = true
+	return []*storage.WebhookDelivery{{
+		ID: "delivery-1", JobID: "job-1", Event: webhook.EventJobCompleted, URL: s.url, Payload: json.RawMessage(`{}`),
+	}}, nil
+}
+
+func (s *webhookStore) RecordWebhookAttempt(ctx context.Context, attempt *storage.WebhookAttempt) error {
+	s.attempts <- attempt
+	return nil
+}
+
+// deliverOnce runs a dispatcher until it has made one attempt
+func deliverOnce(t *testing.T, url string, allowedNetworks []string) *storage.WebhookAttempt {
+	t.Helper()
+	store := &webhookStore{url: url, attempts: make(chan *storage.WebhookAttempt, 1)}
+	dispatcher, err := webhook.NewDispatcher(store, &webhook.Config{
+		SigningSecret:   "secret",
+		PollInterval:    10 * time.Millisecond,
+		AllowedNetworks: allowedNetworks,
+	})
+	if err != nil {
+		t.Fatalf("NewDispatcher failed: %v", err)
+	}
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	select {
+	case attempt := <-store.attempts:
+		return attempt
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery attempt recorded")
+		return nil
+	}
+}
+
+// TestWebhookInternalAddresses tests that deliveries to internal addresses are refused unless allowed
+func TestWebhookInternalAddresses(t *testing.T) {
+	received := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer server.Close()
+
+	for _, url := range []string{server.URL, "http://169.254.169.254/latest/meta-data/", "http://[::1]:9/"} {
+		attempt := deliverOnce(t, url, nil)
+		if attempt.Delivered || !strings.Contains(attempt.Error, "not publicly routable") {
+			t.Errorf("delivery to %s: delivered=%v error=%q, want it blocked", url, attempt.Delivered, attempt.Error)
+		}
+		if attempt.NextAttempt != nil {
+			t.Errorf("blocked delivery to %s should not be retried", url)
+		}
+	}
+	select {
+	case <-received:
+		t.Errorf("blocked delivery reached the server")
+	default:
+	}
+
+	attempt := deliverOnce(t, server.URL, []string{"127.0.0.0/8"})
+	if !attempt.Delivered {
+		t.Errorf("delivery to an allowed network failed: %s", attempt.Error)
+	}
+
+	if _, err := webhook.NewDispatcher(&webhookStore{}, &webhook.Config{AllowedNetworks: []string{"10.0.0.0/33"}}); err == nil {
+		t.Errorf("NewDispatcher with an invalid network should fail")
+	}
+}